@@ -0,0 +1,146 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package parlante
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	DEFAULT_AKISMET_ENDPOINT = "https://rest.akismet.com"
+	AKISMET_TIMEOUT          = 10 * time.Second
+)
+
+// AkismetClient checks comments using akismet compatible services and
+// reports their mistakes. Each client uses its own endpoint and key
+// and the comments of clients without them are not checked.
+type AkismetClient struct {
+	HTTPClient *http.Client
+	// Score given to comments the service says are spam.
+	Weight float64
+}
+
+func NewAkismetClient() AkismetClient {
+	return AkismetClient{
+		HTTPClient: &http.Client{Timeout: AKISMET_TIMEOUT},
+		Weight:     DEFAULT_SPAM_CHECKER_WEIGHT * 2,
+	}
+}
+
+func (a AkismetClient) CheckComment(comment Comment) (SpamCheckResult, error) {
+	if comment.Client == nil || !comment.Client.UsesAkismet() {
+		return NewSpamCheckResult(0), nil
+	}
+	body, header, err := a.call("comment-check", comment)
+	if err != nil {
+		return SpamCheckResult{}, err
+	}
+	switch body {
+	case "true":
+		reason := "akismet spam"
+		if header.Get("X-akismet-pro-tip") == "discard" {
+			reason = "akismet blatant spam"
+		}
+		return NewSpamCheckResult(a.Weight, reason), nil
+	case "false":
+		return NewSpamCheckResult(0), nil
+	}
+	msg := header.Get("X-akismet-debug-help")
+	if msg == "" {
+		msg = body
+	}
+	return SpamCheckResult{}, fmt.Errorf("akismet error: %s", msg)
+}
+
+// ReportSpam tells the service a comment is spam.
+func (a AkismetClient) ReportSpam(comment Comment) error {
+	return a.report("submit-spam", comment)
+}
+
+// ReportHam tells the service a comment is not spam.
+func (a AkismetClient) ReportHam(comment Comment) error {
+	return a.report("submit-ham", comment)
+}
+
+func (a AkismetClient) report(method string, comment Comment) error {
+	if comment.Client == nil || !comment.Client.UsesAkismet() {
+		return nil
+	}
+	_, _, err := a.call(method, comment)
+	return err
+}
+
+// call sends a comment to an akismet method. Returns the response body
+// and headers.
+func (a AkismetClient) call(method string, comment Comment) (
+	string, http.Header, error) {
+	endpoint := strings.TrimRight(comment.Client.AkismetEndpoint, "/")
+	u := endpoint + "/1.1/" + method
+	params := akismetParams(comment)
+	resp, err := a.HTTPClient.PostForm(u, params)
+	if err != nil {
+		return "", nil, err
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", nil, errors.New("akismet error: " + resp.Status)
+	}
+	return strings.TrimSpace(string(raw)), resp.Header, nil
+}
+
+func akismetParams(comment Comment) url.Values {
+	params := url.Values{}
+	params.Set("api_key", comment.Client.AkismetKey)
+	params.Set("permalink", comment.PageURL)
+	if u, err := url.Parse(comment.PageURL); err == nil {
+		params.Set("blog", u.Scheme+"://"+u.Host)
+	}
+	commentType := "comment"
+	if comment.ParentID != 0 {
+		commentType = "reply"
+	}
+	params.Set("comment_type", commentType)
+	params.Set("comment_author", comment.Author)
+	params.Set("comment_content", comment.Content)
+	if comment.Email != "" {
+		params.Set("comment_author_email", comment.Email)
+	}
+	if comment.Website != "" {
+		params.Set("comment_author_url", comment.Website)
+	}
+	if comment.Timestamp != 0 {
+		date := time.Unix(comment.Timestamp, 0).UTC().Format(time.RFC3339)
+		params.Set("comment_date_gmt", date)
+	}
+	if comment.RequestInfo != nil {
+		params.Set("user_ip", comment.RequestInfo.UserIP)
+		params.Set("user_agent", comment.RequestInfo.UserAgent)
+		params.Set("referrer", comment.RequestInfo.Referrer)
+	}
+	return params
+}
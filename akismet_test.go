@@ -0,0 +1,197 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package parlante
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// akismetStub is a local akismet compatible service
+type akismetStub struct {
+	server *httptest.Server
+	calls  []string
+	params []map[string]string
+}
+
+func newAkismetStub() *akismetStub {
+	stub := &akismetStub{}
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		params := make(map[string]string)
+		for k := range r.PostForm {
+			params[k] = r.PostForm.Get(k)
+		}
+		stub.calls = append(stub.calls, r.URL.Path)
+		stub.params = append(stub.params, params)
+		if params["api_key"] != "the-key" {
+			w.Header().Set("X-akismet-debug-help", "bad key")
+			w.Write([]byte("invalid"))
+			return
+		}
+		switch r.URL.Path {
+		case "/1.1/comment-check":
+			switch params["comment_content"] {
+			case "buy pills":
+				w.Write([]byte("true"))
+			case "buy more pills":
+				w.Header().Set("X-akismet-pro-tip", "discard")
+				w.Write([]byte("true"))
+			case "broken":
+				w.WriteHeader(http.StatusInternalServerError)
+			default:
+				w.Write([]byte("false"))
+			}
+		case "/1.1/submit-spam", "/1.1/submit-ham":
+			w.Write([]byte("Thanks for making the web a better place."))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+	stub.server = httptest.NewServer(http.HandlerFunc(handler))
+	return stub
+}
+
+func TestAkismetClient_CheckComment(t *testing.T) {
+	stub := newAkismetStub()
+	defer stub.server.Close()
+
+	akismet := NewAkismetClient()
+	client := Client{
+		Name:            "client",
+		AkismetEndpoint: stub.server.URL + "/",
+		AkismetKey:      "the-key",
+	}
+	badKeyClient := client
+	badKeyClient.AkismetKey = "bad-key"
+	info := CommentRequestInfo{
+		UserIP:    "1.2.3.4",
+		UserAgent: "the-agent",
+		Referrer:  "https://bla.net/",
+	}
+
+	var tests = []struct {
+		testName string
+		client   *Client
+		content  string
+		verdict  SpamVerdict
+		reason   string
+		err      bool
+		calls    int
+	}{
+		{"no client", nil, "buy pills", SpamHam, "", false, 0},
+		{"client without akismet", &Client{}, "buy pills", SpamHam, "", false, 0},
+		{"ham", &client, "nice post", SpamHam, "", false, 1},
+		{"spam", &client, "buy pills", SpamSpam, "akismet spam", false, 1},
+		{"blatant spam", &client, "buy more pills", SpamSpam,
+			"akismet blatant spam", false, 1},
+		{"bad key", &badKeyClient, "nice post", "", "", true, 1},
+		{"server error", &client, "broken", "", "", true, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			stub.calls = nil
+			stub.params = nil
+			comment := Comment{
+				Author:      "zé",
+				Content:     test.content,
+				PageURL:     "https://bla.net/post",
+				Email:       "ze@bla.net",
+				Website:     "https://ze.net",
+				Client:      test.client,
+				RequestInfo: &info,
+			}
+			r, err := akismet.CheckComment(comment)
+			if (err != nil) != test.err {
+				t.Fatalf("bad err %s", err)
+			}
+			if r.Verdict != test.verdict {
+				t.Fatalf("bad verdict %s", r.Verdict)
+			}
+			if test.reason != "" && r.Reasons[0] != test.reason {
+				t.Fatalf("bad reason %v", r.Reasons)
+			}
+			if len(stub.calls) != test.calls {
+				t.Fatalf("bad calls %v", stub.calls)
+			}
+			if test.calls == 0 {
+				return
+			}
+			params := stub.params[0]
+			if params["blog"] != "https://bla.net" ||
+				params["permalink"] != comment.PageURL ||
+				params["comment_author"] != "zé" ||
+				params["comment_type"] != "comment" ||
+				params["comment_author_email"] != comment.Email ||
+				params["comment_author_url"] != comment.Website ||
+				params["user_ip"] != info.UserIP ||
+				params["user_agent"] != info.UserAgent ||
+				params["referrer"] != info.Referrer {
+				t.Fatalf("bad params %v", params)
+			}
+		})
+	}
+}
+
+func TestAkismetClient_Report(t *testing.T) {
+	stub := newAkismetStub()
+	defer stub.server.Close()
+
+	akismet := NewAkismetClient()
+	client := Client{AkismetEndpoint: stub.server.URL, AkismetKey: "the-key"}
+	comment := Comment{
+		Author:    "zé",
+		Content:   "a reply",
+		PageURL:   "https://bla.net/post",
+		ParentID:  1,
+		Timestamp: 1,
+		Client:    &client,
+	}
+
+	err := akismet.ReportSpam(comment)
+	if err != nil {
+		t.Fatalf("error reporting spam %s", err.Error())
+	}
+	err = akismet.ReportHam(comment)
+	if err != nil {
+		t.Fatalf("error reporting ham %s", err.Error())
+	}
+	if len(stub.calls) != 2 || stub.calls[0] != "/1.1/submit-spam" ||
+		stub.calls[1] != "/1.1/submit-ham" {
+		t.Fatalf("bad calls %v", stub.calls)
+	}
+	params := stub.params[0]
+	if params["comment_type"] != "reply" ||
+		params["comment_date_gmt"] != "1970-01-01T00:00:01Z" {
+		t.Fatalf("bad params %v", params)
+	}
+
+	comment.Client = &Client{}
+	err = akismet.ReportSpam(comment)
+	if err != nil || len(stub.calls) != 2 {
+		t.Fatalf("reported comment of client without akismet")
+	}
+
+	comment.Client = &Client{AkismetEndpoint: "http://localhost:0", AkismetKey: "k"}
+	err = akismet.ReportHam(comment)
+	if err == nil {
+		t.Fatalf("no error for unavailable service")
+	}
+}
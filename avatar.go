@@ -0,0 +1,158 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package parlante
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"strings"
+)
+
+const (
+	DEFAULT_AVATAR_SIZE = 64
+	MAX_AVATAR_SIZE     = 512
+	// Number of cells in each side of the avatar
+	avatarCells = 5
+	// Length of the avatar keys
+	avatarKeyLength = 32
+)
+
+var avatarBackground = color.RGBA{R: 0xf0, G: 0xf0, B: 0xf0, A: 0xff}
+
+// AvatarKey returns the key of the avatar for a commenter. The email is
+// used if informed, otherwise the name. The key is signed so the email
+// can't be guessed from it.
+func AvatarKey(signer Signer, email string, name string) string {
+	identity := "email:" + strings.ToLower(strings.TrimSpace(email))
+	if strings.TrimSpace(email) == "" {
+		identity = "name:" + strings.ToLower(strings.TrimSpace(name))
+	}
+	return signer.Sign("avatar", identity)[:avatarKeyLength]
+}
+
+// IsAvatarKey says if a string may be an avatar key.
+func IsAvatarKey(key string) bool {
+	if len(key) != avatarKeyLength {
+		return false
+	}
+	_, err := hex.DecodeString(key)
+	return err == nil
+}
+
+// Identicon is a symmetric avatar drawn from a hash.
+type Identicon struct {
+	Cells [avatarCells][avatarCells]bool
+	Color color.RGBA
+}
+
+// NewIdenticon returns the identicon for an avatar key. The same key
+// always gives the same identicon.
+func NewIdenticon(key string) Identicon {
+	sum := sha256.Sum256([]byte(key))
+	hue := float64(int(sum[0])<<8|int(sum[1])) / 65536 * 360
+	icon := Identicon{Color: hslToRGB(hue, 0.55, 0.5)}
+	half := (avatarCells + 1) / 2
+	for row := 0; row < avatarCells; row++ {
+		for col := 0; col < half; col++ {
+			on := sum[2+row*half+col]&1 == 1
+			icon.Cells[row][col] = on
+			icon.Cells[row][avatarCells-1-col] = on
+		}
+	}
+	return icon
+}
+
+// SVG returns the identicon as a svg image.
+func (i Identicon) SVG(size int) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" `+
+		`width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		size, size, avatarCells, avatarCells)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="%s"/>`,
+		avatarCells, avatarCells, hexColor(avatarBackground))
+	for row := range i.Cells {
+		for col, on := range i.Cells[row] {
+			if !on {
+				continue
+			}
+			fmt.Fprintf(&b, `<rect x="%d" y="%d" width="1" height="1" fill="%s"/>`,
+				col, row, hexColor(i.Color))
+		}
+	}
+	b.WriteString("</svg>")
+	return b.Bytes()
+}
+
+// PNG returns the identicon as a png image.
+func (i Identicon) PNG(size int) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			c := avatarBackground
+			if i.Cells[y*avatarCells/size][x*avatarCells/size] {
+				c = i.Color
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	var b bytes.Buffer
+	err := png.Encode(&b, img)
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// hslToRGB converts a hue in degrees and saturation and lightness
+// between 0 and 1 to a rgb color.
+func hslToRGB(h float64, s float64, l float64) color.RGBA {
+	c := (1 - math.Abs(2*l-1)) * s
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	m := l - c/2
+	var r, g, b float64
+	switch {
+	case h < 60:
+		r, g, b = c, x, 0
+	case h < 120:
+		r, g, b = x, c, 0
+	case h < 180:
+		r, g, b = 0, c, x
+	case h < 240:
+		r, g, b = 0, x, c
+	case h < 300:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+	return color.RGBA{
+		R: uint8(math.Round((r + m) * 255)),
+		G: uint8(math.Round((g + m) * 255)),
+		B: uint8(math.Round((b + m) * 255)),
+		A: 0xff,
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package parlante
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestAvatarKey(t *testing.T) {
+	signer := NewSigner("secret")
+	key := AvatarKey(signer, "Ze@Bla.net ", "zé")
+
+	if !IsAvatarKey(key) {
+		t.Fatalf("bad avatar key %s", key)
+	}
+	if AvatarKey(signer, "ze@bla.net", "other name") != key {
+		t.Fatalf("avatar key not from email")
+	}
+	if AvatarKey(signer, "", "zé") == key {
+		t.Fatalf("same avatar key for name and email")
+	}
+	if AvatarKey(signer, "", "Zé") != AvatarKey(signer, "", "zé") {
+		t.Fatalf("avatar key for name not case insensitive")
+	}
+	if AvatarKey(NewSigner("other"), "ze@bla.net", "zé") == key {
+		t.Fatalf("same avatar key for other signer")
+	}
+	if IsAvatarKey("bla") || IsAvatarKey(strings.Repeat("x", avatarKeyLength)) {
+		t.Fatalf("invalid avatar key accepted")
+	}
+}
+
+func TestIdenticon(t *testing.T) {
+	key := AvatarKey(NewSigner("secret"), "ze@bla.net", "zé")
+	icon := NewIdenticon(key)
+
+	if icon != NewIdenticon(key) {
+		t.Fatalf("identicon not deterministic")
+	}
+	if icon == NewIdenticon(key[1:]+"0") {
+		t.Fatalf("same identicon for other key")
+	}
+	for _, row := range icon.Cells {
+		for col := range row {
+			if row[col] != row[avatarCells-1-col] {
+				t.Fatalf("identicon not symmetric %v", icon.Cells)
+			}
+		}
+	}
+
+	svg := string(icon.SVG(32))
+	if !strings.HasPrefix(svg, "<svg") || !strings.Contains(svg, `width="32"`) ||
+		!strings.Contains(svg, hexColor(icon.Color)) {
+		t.Fatalf("bad svg %s", svg)
+	}
+
+	b, err := icon.PNG(40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Dx() != 40 || img.Bounds().Dy() != 40 {
+		t.Fatalf("bad png size %v", img.Bounds())
+	}
+}
+
+func TestHslToRGB(t *testing.T) {
+	var tests = []struct {
+		h, s, l  float64
+		expected string
+	}{
+		{0, 1, 0.5, "#ff0000"},
+		{120, 1, 0.5, "#00ff00"},
+		{240, 1, 0.5, "#0000ff"},
+		{0, 0, 1, "#ffffff"},
+	}
+	for _, test := range tests {
+		c := hexColor(hslToRGB(test.h, test.s, test.l))
+		if c != test.expected {
+			t.Fatalf("bad color for %v: %s", test, c)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package parlante
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// Minimum number of spam and ham comments used to train the model
+	// before it is used to check comments.
+	DEFAULT_BAYES_MIN_COMMENTS = 5
+	DEFAULT_BAYES_SUSPECT_PROB = 0.8
+	DEFAULT_BAYES_SPAM_PROB    = 0.95
+	// Number of tokens used to classify a comment.
+	BAYES_INTERESTING_TOKENS = 15
+	// Strength and probability of the background information about
+	// tokens seen only a few times.
+	bayesStrength       = 1.0
+	bayesUnknownProb    = 0.5
+	bayesMinTokenLength = 2
+	bayesMaxTokenLength = 40
+)
+
+// SpamTokenCount is the number of spam and ham comments a token
+// was seen in.
+type SpamTokenCount struct {
+	Spam int64
+	Ham  int64
+}
+
+// SpamModel is the data of the bayesian spam model needed to classify
+// a comment.
+type SpamModel struct {
+	SpamComments int64
+	HamComments  int64
+	Tokens       map[string]SpamTokenCount
+}
+
+// SpamTokenScore is the spam probability of a token.
+type SpamTokenScore struct {
+	Token       string
+	Probability float64
+}
+
+// SpamClassification is the result of the classification of a comment.
+type SpamClassification struct {
+	// Probability of the comment being spam.
+	Probability float64
+	// Tokens used in the classification, the most significant first.
+	Tokens []SpamTokenScore
+}
+
+var linkHostRegex = regexp.MustCompile(`(?i)https?://[^\s"'<>]+`)
+
+// SpamTokens returns the tokens of a comment used by the spam model.
+// Besides the words in the content the author and the hosts of links
+// are tokens too.
+func SpamTokens(comment Comment) []string {
+	seen := make(map[string]bool)
+	add := func(prefix string, words []string) {
+		for _, w := range words {
+			n := utf8.RuneCountInString(w)
+			if n < bayesMinTokenLength || n > bayesMaxTokenLength {
+				continue
+			}
+			seen[prefix+w] = true
+		}
+	}
+	add("", splitWords(comment.Content))
+	add("author:", splitWords(comment.Author))
+	for _, link := range linkHostRegex.FindAllString(comment.Content, -1) {
+		u, err := url.Parse(link)
+		if err != nil || u.Hostname() == "" {
+			continue
+		}
+		add("url:", []string{strings.ToLower(u.Hostname())})
+	}
+	tokens := make([]string, 0, len(seen))
+	for t := range seen {
+		tokens = append(tokens, t)
+	}
+	slices.Sort(tokens)
+	return tokens
+}
+
+func splitWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// TokenProbability returns the probability of a comment containing
+// the token being spam. Tokens seen only a few times stay close to the
+// unknown probability.
+func (m SpamModel) TokenProbability(token string) float64 {
+	count := m.Tokens[token]
+	n := float64(count.Spam + count.Ham)
+	if n == 0 {
+		return bayesUnknownProb
+	}
+	var spamFreq, hamFreq float64
+	if m.SpamComments > 0 {
+		spamFreq = math.Min(1, float64(count.Spam)/float64(m.SpamComments))
+	}
+	if m.HamComments > 0 {
+		hamFreq = math.Min(1, float64(count.Ham)/float64(m.HamComments))
+	}
+	p := bayesUnknownProb
+	if spamFreq+hamFreq > 0 {
+		p = spamFreq / (spamFreq + hamFreq)
+	}
+	p = (bayesStrength*bayesUnknownProb + n*p) / (bayesStrength + n)
+	return math.Max(0.01, math.Min(0.99, p))
+}
+
+// Classify returns the spam probability for a list of tokens. Only the
+// tokens with probabilities far from the unknown probability are used.
+func (m SpamModel) Classify(tokens []string) SpamClassification {
+	scores := make([]SpamTokenScore, 0)
+	for _, t := range tokens {
+		p := m.TokenProbability(t)
+		if p == bayesUnknownProb {
+			continue
+		}
+		scores = append(scores, SpamTokenScore{Token: t, Probability: p})
+	}
+	slices.SortStableFunc(scores, func(a, b SpamTokenScore) int {
+		da := math.Abs(a.Probability - bayesUnknownProb)
+		db := math.Abs(b.Probability - bayesUnknownProb)
+		if da > db {
+			return -1
+		}
+		if da < db {
+			return 1
+		}
+		return strings.Compare(a.Token, b.Token)
+	})
+	if len(scores) > BAYES_INTERESTING_TOKENS {
+		scores = scores[:BAYES_INTERESTING_TOKENS]
+	}
+	if len(scores) == 0 {
+		return SpamClassification{Probability: bayesUnknownProb, Tokens: scores}
+	}
+	// the probabilities are combined using logs to avoid underflow.
+	var eta float64
+	for _, s := range scores {
+		eta += math.Log(1-s.Probability) - math.Log(s.Probability)
+	}
+	prob := 1 / (1 + math.Exp(eta))
+	return SpamClassification{Probability: prob, Tokens: scores}
+}
+
+// Trained says if the model has enough data to classify comments.
+func (m SpamModel) Trained(minComments int64) bool {
+	return m.SpamComments >= minComments && m.HamComments >= minComments
+}
+
+// ClassifyComment returns the spam classification of a comment using
+// the model in the storage.
+func ClassifyComment(storage CommentStorage, comment Comment) (
+	SpamClassification, SpamModel, error) {
+	tokens := SpamTokens(comment)
+	model, err := storage.GetSpamModel(tokens)
+	if err != nil {
+		return SpamClassification{}, SpamModel{}, err
+	}
+	return model.Classify(tokens), model, nil
+}
+
+// RetrainSpamModel discards the spam model and trains it again using
+// all the approved and spam comments. Returns the number of comments
+// used in the training.
+func RetrainSpamModel(storage CommentStorage) (int, error) {
+	err := storage.ResetSpamModel()
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, status := range []CommentStatus{CommentApproved, CommentSpam} {
+		comments, err := storage.ListComments(CommentsFilter{Status: &status})
+		if err != nil {
+			return n, err
+		}
+		for _, c := range comments {
+			err := storage.TrainSpamModel(c, status == CommentSpam)
+			if err != nil {
+				return n, err
+			}
+			n++
+		}
+	}
+	return n, nil
+}
+
+// BayesSpamChecker checks comments using the bayesian spam model
+// trained from the moderation decisions.
+type BayesSpamChecker struct {
+	Storage CommentStorage
+	// The model is not used before it is trained with at least
+	// MinComments spam and ham comments.
+	MinComments int64
+	SuspectProb float64
+	SpamProb    float64
+	// Score given to suspect comments. Spam comments get twice it.
+	Weight float64
+}
+
+func NewBayesSpamChecker(storage CommentStorage) BayesSpamChecker {
+	return BayesSpamChecker{
+		Storage:     storage,
+		MinComments: DEFAULT_BAYES_MIN_COMMENTS,
+		SuspectProb: DEFAULT_BAYES_SUSPECT_PROB,
+		SpamProb:    DEFAULT_BAYES_SPAM_PROB,
+		Weight:      DEFAULT_SPAM_CHECKER_WEIGHT,
+	}
+}
+
+func (c BayesSpamChecker) CheckComment(comment Comment) (SpamCheckResult, error) {
+	r, model, err := ClassifyComment(c.Storage, comment)
+	if err != nil {
+		return SpamCheckResult{}, err
+	}
+	if !model.Trained(c.MinComments) {
+		return NewSpamCheckResult(0), nil
+	}
+	reason := fmt.Sprintf("spam probability %.2f", r.Probability)
+	switch {
+	case r.Probability >= c.SpamProb:
+		return NewSpamCheckResult(c.Weight*2, reason), nil
+	case r.Probability >= c.SuspectProb:
+		return NewSpamCheckResult(c.Weight, reason), nil
+	}
+	return NewSpamCheckResult(0), nil
+}
@@ -0,0 +1,132 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package parlante
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSpamTokens(t *testing.T) {
+	comment := Comment{
+		Author:  "Zé Buyer",
+		Content: "Buy, buy CHEAP pills at https://Pills.net/buy now! a",
+	}
+	tokens := SpamTokens(comment)
+	expected := []string{
+		"at", "author:buyer", "author:zé", "buy", "cheap", "https", "net",
+		"now", "pills", "url:pills.net",
+	}
+	if !slices.Equal(tokens, expected) {
+		t.Fatalf("bad tokens %v", tokens)
+	}
+}
+
+func TestSpamModel(t *testing.T) {
+	model := SpamModel{
+		SpamComments: 10,
+		HamComments:  10,
+		Tokens: map[string]SpamTokenCount{
+			"pills":  {Spam: 9, Ham: 0},
+			"cheap":  {Spam: 6, Ham: 1},
+			"thanks": {Spam: 0, Ham: 8},
+			"post":   {Spam: 1, Ham: 7},
+			"the":    {Spam: 5, Ham: 5},
+		},
+	}
+
+	if p := model.TokenProbability("unknown"); p != 0.5 {
+		t.Fatalf("bad probability for unknown token %f", p)
+	}
+	if p := model.TokenProbability("the"); p != 0.5 {
+		t.Fatalf("bad probability for neutral token %f", p)
+	}
+	if p := model.TokenProbability("pills"); p <= 0.9 {
+		t.Fatalf("bad probability for spam token %f", p)
+	}
+
+	spam := model.Classify([]string{"cheap", "pills", "the", "unknown"})
+	if spam.Probability < 0.95 {
+		t.Fatalf("bad probability for spam %f", spam.Probability)
+	}
+	if len(spam.Tokens) != 2 || spam.Tokens[0].Token != "pills" {
+		t.Fatalf("bad tokens for spam %v", spam.Tokens)
+	}
+
+	ham := model.Classify([]string{"thanks", "post", "the"})
+	if ham.Probability > 0.05 {
+		t.Fatalf("bad probability for ham %f", ham.Probability)
+	}
+
+	unknown := model.Classify([]string{"unknown"})
+	if unknown.Probability != 0.5 || len(unknown.Tokens) != 0 {
+		t.Fatalf("bad classification for unknown %v", unknown)
+	}
+}
+
+func TestBayesSpamChecker(t *testing.T) {
+	storage := NewCommentStorageInMemory()
+	client := Client{Name: "client"}
+	domain := ClientDomain{Domain: "bla.net"}
+	checker := NewBayesSpamChecker(&storage)
+	checker.MinComments = 2
+	spamComment := Comment{Author: "bot", Content: "cheap pills at pills.net"}
+
+	r, err := checker.CheckComment(spamComment)
+	if err != nil || r.Verdict != SpamHam || r.Score != 0 {
+		t.Fatalf("untrained model used %v %s", r, err)
+	}
+
+	hams := []string{"nice post, thanks", "thanks for the post", "good post"}
+	spams := []string{"cheap pills", "buy pills now", "pills pills pills"}
+	for _, content := range hams {
+		c, _ := storage.CreateComment(client, domain, "zé", content, "https://bla.net/p")
+		storage.SetCommentStatus(c, CommentApproved)
+	}
+	for _, content := range spams {
+		c, _ := storage.CreateComment(client, domain, "bot", content, "https://bla.net/p")
+		storage.SetCommentStatus(c, CommentSpam)
+	}
+	n, err := RetrainSpamModel(&storage)
+	if err != nil {
+		t.Fatalf("error retraining %s", err.Error())
+	}
+	if n != 6 {
+		t.Fatalf("bad number of trained comments %d", n)
+	}
+
+	r, err = checker.CheckComment(spamComment)
+	if err != nil || r.Verdict != SpamSpam {
+		t.Fatalf("bad verdict for spam %v %s", r, err)
+	}
+	r, err = checker.CheckComment(Comment{Author: "zé", Content: "thanks, nice post"})
+	if err != nil || r.Verdict != SpamHam {
+		t.Fatalf("bad verdict for ham %v %s", r, err)
+	}
+
+	storage.ForceListError(true)
+	defer storage.ForceListError(false)
+	_, err = checker.CheckComment(spamComment)
+	if err == nil {
+		t.Fatalf("no error for bad storage")
+	}
+	_, err = RetrainSpamModel(&storage)
+	if err == nil {
+		t.Fatalf("no error retraining with bad storage")
+	}
+}
@@ -0,0 +1,264 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package parlante
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"math/bits"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Number of leading zero bits the hash of a solution must have.
+	DEFAULT_CHALLENGE_DIFFICULTY = 14
+	// The difficulty never rises more than this above the base one.
+	MAX_CHALLENGE_EXTRA_DIFFICULTY = 8
+	DEFAULT_CHALLENGE_TTL          = 10 * time.Minute
+	// Submissions from an ip in the window above this number make the
+	// challenges harder, one bit for each submission.
+	CHALLENGE_FREE_SUBMISSIONS = 3
+	CHALLENGE_WINDOW           = time.Hour
+	// Max number of ips with recent submissions kept. When full the ip
+	// that submitted least recently is forgotten.
+	MAX_CHALLENGE_SUBMISSION_IPS = 10000
+	// Number of calls between removals of old data.
+	challengerSweepInterval = 1000
+)
+
+var ErrChallengeInvalid = errors.New("Invalid challenge")
+var ErrChallengeExpired = errors.New("Challenge expired")
+var ErrChallengeReplayed = errors.New("Challenge already used")
+var ErrChallengeUnsolved = errors.New("Wrong challenge solution")
+
+// Challenge is a proof of work puzzle. The solution is a string that
+// appended to the challenge gives a sha256 hash with Difficulty leading
+// zero bits.
+type Challenge struct {
+	Challenge  string `json:"challenge"`
+	Difficulty int    `json:"difficulty"`
+}
+
+// Challenger creates and checks the challenges solved by the browsers
+// before sending a form. The challenges are signed so only the used
+// ones are kept to avoid replays.
+type Challenger struct {
+	Signer     Signer
+	Difficulty int
+	TTL        time.Duration
+
+	mu sync.Mutex
+	// challenges already used and when they expire.
+	used map[string]time.Time
+	// recent submissions by ip.
+	submissions map[string][]time.Time
+	maxIPs      int
+	calls       int
+	now         func() time.Time
+}
+
+func NewChallenger(signer Signer, difficulty int) *Challenger {
+	if difficulty <= 0 {
+		difficulty = DEFAULT_CHALLENGE_DIFFICULTY
+	}
+	return &Challenger{
+		Signer:      signer,
+		Difficulty:  difficulty,
+		TTL:         DEFAULT_CHALLENGE_TTL,
+		used:        make(map[string]time.Time),
+		submissions: make(map[string][]time.Time),
+		maxIPs:      MAX_CHALLENGE_SUBMISSION_IPS,
+		now:         time.Now,
+	}
+}
+
+// IPDifficulty returns the difficulty of the challenges for an ip. It
+// rises with the number of recent submissions from the ip.
+func (c *Challenger) IPDifficulty(ip string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ipDifficulty(ip, c.now())
+}
+
+func (c *Challenger) ipDifficulty(ip string, now time.Time) int {
+	extra := len(c.recentSubmissions(ip, now)) - CHALLENGE_FREE_SUBMISSIONS
+	extra = max(0, min(extra, MAX_CHALLENGE_EXTRA_DIFFICULTY))
+	return c.Difficulty + extra
+}
+
+// New returns a new challenge for a client to be solved by a browser
+// with an ip. The challenge is only valid for that ip.
+func (c *Challenger) New(clientUUID string, ip string) (Challenge, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return Challenge{}, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	c.maybeSweep(now)
+
+	nonce := hex.EncodeToString(b)
+	expires := strconv.FormatInt(now.Add(c.TTL).Unix(), 10)
+	difficulty := c.ipDifficulty(ip, now)
+	rawDifficulty := strconv.Itoa(difficulty)
+	sig := c.Signer.Sign(
+		"challenge", clientUUID, ip, nonce, expires, rawDifficulty)
+	ch := Challenge{
+		Challenge: strings.Join(
+			[]string{nonce, expires, rawDifficulty, sig}, "."),
+		Difficulty: difficulty,
+	}
+	return ch, nil
+}
+
+// Check checks the solution of a challenge sent by a browser with an
+// ip. Every check counts as a submission from the ip.
+func (c *Challenger) Check(
+	clientUUID string, ip string, challenge string, solution string) error {
+	parts := strings.Split(challenge, ".")
+	if len(parts) != 4 {
+		return ErrChallengeInvalid
+	}
+	nonce, rawExpires, rawDifficulty, sig := parts[0], parts[1], parts[2], parts[3]
+	if !c.Signer.Verify(sig, "challenge", clientUUID, ip, nonce, rawExpires,
+		rawDifficulty) {
+		return ErrChallengeInvalid
+	}
+	expires, err := strconv.ParseInt(rawExpires, 10, 64)
+	if err != nil {
+		return ErrChallengeInvalid
+	}
+	difficulty, err := strconv.Atoi(rawDifficulty)
+	if err != nil {
+		return ErrChallengeInvalid
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	c.maybeSweep(now)
+	c.addSubmission(ip, now)
+
+	if now.Unix() > expires {
+		return ErrChallengeExpired
+	}
+	if _, ok := c.used[nonce]; ok {
+		return ErrChallengeReplayed
+	}
+	if challengeZeroBits(challenge, solution) < difficulty {
+		return ErrChallengeUnsolved
+	}
+	c.used[nonce] = time.Unix(expires, 0)
+	return nil
+}
+
+func (c *Challenger) recentSubmissions(ip string, now time.Time) []time.Time {
+	start := now.Add(-CHALLENGE_WINDOW)
+	recent := make([]time.Time, 0)
+	for _, t := range c.submissions[ip] {
+		if t.After(start) {
+			recent = append(recent, t)
+		}
+	}
+	return recent
+}
+
+// addSubmission adds a submission from the ip. Only the submissions
+// that change the difficulty are kept.
+func (c *Challenger) addSubmission(ip string, now time.Time) {
+	if _, ok := c.submissions[ip]; !ok && len(c.submissions) >= c.maxIPs {
+		c.sweep(now)
+		if len(c.submissions) >= c.maxIPs {
+			c.forgetOldestIP()
+		}
+	}
+	recent := append(c.recentSubmissions(ip, now), now)
+	keep := CHALLENGE_FREE_SUBMISSIONS + MAX_CHALLENGE_EXTRA_DIFFICULTY
+	c.submissions[ip] = recent[max(0, len(recent)-keep):]
+}
+
+// forgetOldestIP removes the ip with the oldest last submission.
+func (c *Challenger) forgetOldestIP() {
+	var oldestIP string
+	var oldest time.Time
+	for ip, subs := range c.submissions {
+		last := subs[len(subs)-1]
+		if oldestIP == "" || last.Before(oldest) {
+			oldestIP, oldest = ip, last
+		}
+	}
+	delete(c.submissions, oldestIP)
+}
+
+// maybeSweep removes the expired challenges and old submissions
+// from time to time.
+func (c *Challenger) maybeSweep(now time.Time) {
+	c.calls++
+	if c.calls%challengerSweepInterval != 0 {
+		return
+	}
+	c.sweep(now)
+}
+
+func (c *Challenger) sweep(now time.Time) {
+	for nonce, expires := range c.used {
+		if now.After(expires) {
+			delete(c.used, nonce)
+		}
+	}
+	for ip := range c.submissions {
+		recent := c.recentSubmissions(ip, now)
+		if len(recent) == 0 {
+			delete(c.submissions, ip)
+			continue
+		}
+		c.submissions[ip] = recent
+	}
+}
+
+// SolveChallenge returns the solution for a challenge. This is what the
+// browsers do before sending a form.
+func SolveChallenge(ch Challenge) string {
+	for i := 0; ; i++ {
+		solution := strconv.Itoa(i)
+		if challengeZeroBits(ch.Challenge, solution) >= ch.Difficulty {
+			return solution
+		}
+	}
+}
+
+// challengeZeroBits returns the number of leading zero bits of the
+// hash of a challenge solution.
+func challengeZeroBits(challenge string, solution string) int {
+	sum := sha256.Sum256([]byte(challenge + solution))
+	n := 0
+	for _, b := range sum {
+		if b != 0 {
+			return n + bits.LeadingZeros8(b)
+		}
+		n += 8
+	}
+	return n
+}
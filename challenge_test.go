@@ -0,0 +1,165 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package parlante
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChallenger(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewChallenger(NewSigner("secret"), 8)
+	c.now = func() time.Time { return now }
+
+	ch, err := c.New("the-uuid", "1.2.3.4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ch.Difficulty != 8 {
+		t.Fatalf("bad difficulty %d", ch.Difficulty)
+	}
+	solution := SolveChallenge(ch)
+	if challengeZeroBits(ch.Challenge, solution) < 8 {
+		t.Fatalf("bad solution %s", solution)
+	}
+	wrong := solution + "x"
+	for challengeZeroBits(ch.Challenge, wrong) >= 8 {
+		wrong += "x"
+	}
+	nonce, _, _ := strings.Cut(ch.Challenge, ".")
+	easier := strings.Replace(ch.Challenge, ".8.", ".1.", 1)
+
+	var tests = []struct {
+		testName  string
+		uuid      string
+		ip        string
+		challenge string
+		solution  string
+		elapsed   time.Duration
+		err       error
+	}{
+		{"invalid challenge", "the-uuid", "1.2.3.4", "bla", solution, 0,
+			ErrChallengeInvalid},
+		{"tampered difficulty", "the-uuid", "1.2.3.4", easier, solution, 0,
+			ErrChallengeInvalid},
+		{"other client", "other-uuid", "1.2.3.4", ch.Challenge, solution, 0,
+			ErrChallengeInvalid},
+		{"other ip", "the-uuid", "4.3.2.1", ch.Challenge, solution, 0,
+			ErrChallengeInvalid},
+		{"wrong solution", "the-uuid", "1.2.3.4", ch.Challenge, wrong, 0,
+			ErrChallengeUnsolved},
+		{"expired", "the-uuid", "1.2.3.4", ch.Challenge, solution, time.Hour,
+			ErrChallengeExpired},
+		{"ok", "the-uuid", "1.2.3.4", ch.Challenge, solution, 0, nil},
+		{"replayed", "the-uuid", "1.2.3.4", ch.Challenge, solution, 0,
+			ErrChallengeReplayed},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			c.now = func() time.Time { return now.Add(test.elapsed) }
+			err := c.Check(test.uuid, test.ip, test.challenge, test.solution)
+			if err != test.err {
+				t.Fatalf("bad error for check %v", err)
+			}
+		})
+	}
+
+	c.now = func() time.Time { return now.Add(2 * time.Hour) }
+	c.calls = challengerSweepInterval - 1
+	c.IPDifficulty("1.2.3.4")
+	c.New("the-uuid", "1.2.3.4")
+	if _, ok := c.used[nonce]; ok {
+		t.Fatalf("expired challenge not removed")
+	}
+	if _, ok := c.submissions["1.2.3.4"]; ok {
+		t.Fatalf("old submissions not removed")
+	}
+}
+
+func TestChallenger_IPDifficulty(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewChallenger(NewSigner("secret"), 0)
+	c.now = func() time.Time { return now }
+
+	if c.Difficulty != DEFAULT_CHALLENGE_DIFFICULTY {
+		t.Fatalf("bad default difficulty %d", c.Difficulty)
+	}
+	for i := 0; i < CHALLENGE_FREE_SUBMISSIONS; i++ {
+		c.Check("the-uuid", "1.2.3.4", "bad.1.1.sig", "")
+		c.Check("the-uuid", "1.2.3.4", "bla", "")
+	}
+	// only well formed challenges count
+	if d := c.IPDifficulty("1.2.3.4"); d != c.Difficulty {
+		t.Fatalf("bad difficulty for free submissions %d", d)
+	}
+	for i := 0; i < CHALLENGE_FREE_SUBMISSIONS+2; i++ {
+		ch, _ := c.New("the-uuid", "1.2.3.4")
+		c.Check("the-uuid", "1.2.3.4", ch.Challenge, "")
+	}
+	if d := c.IPDifficulty("1.2.3.4"); d != c.Difficulty+2 {
+		t.Fatalf("bad difficulty for many submissions %d", d)
+	}
+	for i := 0; i < 20; i++ {
+		c.submissions["1.2.3.4"] = append(c.submissions["1.2.3.4"], now)
+	}
+	if d := c.IPDifficulty("1.2.3.4"); d != c.Difficulty+MAX_CHALLENGE_EXTRA_DIFFICULTY {
+		t.Fatalf("bad max difficulty %d", d)
+	}
+	if d := c.IPDifficulty("4.3.2.1"); d != c.Difficulty {
+		t.Fatalf("bad difficulty for other ip %d", d)
+	}
+	c.now = func() time.Time { return now.Add(CHALLENGE_WINDOW) }
+	if d := c.IPDifficulty("1.2.3.4"); d != c.Difficulty {
+		t.Fatalf("bad difficulty after window %d", d)
+	}
+}
+
+func TestChallenger_MaxIPs(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewChallenger(NewSigner("secret"), 1)
+	c.maxIPs = 3
+
+	check := func(ip string, elapsed time.Duration) {
+		c.now = func() time.Time { return now.Add(elapsed) }
+		ch, _ := c.New("the-uuid", ip)
+		c.Check("the-uuid", ip, ch.Challenge, SolveChallenge(ch))
+	}
+	check("1.1.1.1", 0)
+	check("2.2.2.2", time.Minute)
+	check("3.3.3.3", 2*time.Minute)
+	check("1.1.1.1", 3*time.Minute)
+	check("4.4.4.4", 4*time.Minute)
+
+	if len(c.submissions) != 3 {
+		t.Fatalf("bad number of ips %d", len(c.submissions))
+	}
+	if _, ok := c.submissions["2.2.2.2"]; ok {
+		t.Fatalf("least recent ip not removed")
+	}
+
+	for i := 0; i < 20; i++ {
+		check("1.1.1.1", 5*time.Minute)
+	}
+	keep := CHALLENGE_FREE_SUBMISSIONS + MAX_CHALLENGE_EXTRA_DIFFICULTY
+	if len(c.submissions["1.1.1.1"]) != keep {
+		t.Fatalf("bad number of submissions %d", len(c.submissions["1.1.1.1"]))
+	}
+}
@@ -0,0 +1,94 @@
+// go:build !test
+
+package main
+
+// notest
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/jucacrispim/parlante"
+)
+
+var parsers = map[string]func(io.Reader) (parlante.ImportSource, error){
+	"disqus":    parlante.ParseDisqusExport,
+	"wordpress": parlante.ParseWordPressExport,
+}
+
+func main() {
+	dbpath := flag.String("dbpath", parlante.DEFAULT_DB_PATH, "path for database file")
+	clientuuid := flag.String("client", "", "uuid of the client that owns the comments")
+	domain := flag.String("domain", "", "domain of the imported comments")
+	format := flag.String("format", "disqus", "format of the export file: disqus or wordpress")
+	baseurl := flag.String("baseurl", "",
+		"replaces the scheme and host of the pages in the export file")
+	dryrun := flag.Bool("dryrun", false, "only report what would be imported")
+	flag.CommandLine.Parse(os.Args[1:])
+
+	parse, ok := parsers[*format]
+	if !ok || flag.NArg() != 1 || *clientuuid == "" || *domain == "" {
+		fmt.Println("Usage: parlante-import -client <uuid> -domain <domain> [options] <file>")
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+
+	err := parlante.SetupDB(*dbpath)
+	if err != nil {
+		panic(err.Error())
+	}
+	err = parlante.MigrateDB(*dbpath)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	cs := parlante.ClientStorageSQLite{}
+	c, err := cs.GetClientByUUID(*clientuuid)
+	if err != nil {
+		fmt.Printf("Client not found: %s\n", *clientuuid)
+		os.Exit(1)
+	}
+	ds := parlante.ClientDomainStorageSQLite{}
+	d, err := ds.GetClientDomain(c, *domain)
+	if err != nil || d.ID == 0 {
+		fmt.Printf("Domain not found: %s\n", *domain)
+		os.Exit(1)
+	}
+
+	f, err := os.Open(flag.Arg(0))
+	if err != nil {
+		fmt.Printf("Error opening export file: %v\n", err)
+		os.Exit(1)
+	}
+	defer f.Close()
+	src, err := parse(f)
+	if err != nil {
+		fmt.Printf("Error reading export file: %v\n", err)
+		os.Exit(1)
+	}
+
+	opts := parlante.ImportOptions{BaseURL: *baseurl, DryRun: *dryrun}
+	r, err := parlante.ImportComments(
+		parlante.CommentStorageSQLite{}, c, d, src, opts)
+	if err != nil {
+		fmt.Printf("Error importing comments: %v\n", err)
+		os.Exit(1)
+	}
+	action := "Imported"
+	if *dryrun {
+		action = "Would import"
+	}
+	pages := make([]string, 0, len(r.Pages))
+	for p := range r.Pages {
+		pages = append(pages, p)
+	}
+	slices.Sort(pages)
+	for _, p := range pages {
+		fmt.Printf("  %s: %d\n", p, r.Pages[p])
+	}
+	fmt.Printf("%s %d comments in %d pages\n", action, r.Created, len(pages))
+	fmt.Printf("  already imported: %d\n", r.Existing)
+	fmt.Printf("  skipped: %d\n", r.Skipped)
+}
@@ -0,0 +1,41 @@
+// go:build !test
+
+package main
+
+// notest
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/jucacrispim/parlante"
+)
+
+func main() {
+	dbpath := flag.String("dbpath", parlante.DEFAULT_DB_PATH, "path for database file")
+	dryrun := flag.Bool("dryrun", false, "only count the orphans, don't fix them")
+	flag.CommandLine.Parse(os.Args[1:])
+
+	err := parlante.SetupDB(*dbpath)
+	if err != nil {
+		panic(err.Error())
+	}
+	err = parlante.MigrateDB(*dbpath)
+	if err != nil {
+		panic(err.Error())
+	}
+	r, err := parlante.RepairDB(*dryrun)
+	if err != nil {
+		fmt.Printf("Error repairing database: %v\n", err)
+		os.Exit(1)
+	}
+	action := "fixed"
+	if *dryrun {
+		action = "found"
+	}
+	fmt.Printf("Orphans %s:\n", action)
+	fmt.Printf("  domains: %d\n", r.Domains)
+	fmt.Printf("  comments: %d\n", r.Comments)
+	fmt.Printf("  replies: %d\n", r.Replies)
+	fmt.Printf("  revisions: %d\n", r.Revisions)
+}
@@ -27,7 +27,9 @@ func main() {
 	cs := parlante.ClientStorageSQLite{}
 	ds := parlante.ClientDomainStorageSQLite{}
 	cos := parlante.CommentStorageSQLite{}
-	p := tui.NewTui(cs, ds, cos)
+	ts := parlante.TrashStorageSQLite{}
+	obs := parlante.OutboxStorageSQLite{}
+	p := tui.NewTui(cs, ds, cos, ts, obs)
 	if _, err := p.Run(); err != nil {
 		fmt.Printf("Alas, there's been an error: %v", err)
 		os.Exit(1)
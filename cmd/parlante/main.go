@@ -6,6 +6,7 @@ package main
 import (
 	"flag"
 	"os"
+	"strings"
 
 	"github.com/jucacrispim/parlante"
 )
@@ -18,7 +19,88 @@ func main() {
 	certfile := flag.String("certfile", "", "Path for the tls certificate file")
 	keyfile := flag.String("keyfile", "", "Path for the tls key file")
 	loglevel := flag.String("loglevel", "info", "log level for the server")
+	pagesize := flag.Int("pagesize", parlante.DEFAULT_COMMENTS_PAGE_SIZE,
+		"number of comments returned in each page")
+	trashdays := flag.Int("trashdays", parlante.DEFAULT_TRASH_PURGE_DAYS,
+		"days before removed items are purged from trash. 0 to never purge")
+	maxlinks := flag.Int("maxlinks", parlante.DEFAULT_SPAM_MAX_LINKS,
+		"comments with more links than this are suspect of spam")
+	blockedwords := flag.String("blockedwords", "",
+		"comma separated list of words that mark a comment as spam")
+	minlength := flag.Int("minlength", parlante.DEFAULT_COMMENT_MIN_LENGTH,
+		"comments shorter than this are suspect of spam")
+	maxlength := flag.Int("maxlength", parlante.DEFAULT_COMMENT_MAX_LENGTH,
+		"comments longer than this are suspect of spam")
+	commentlimit := flag.String("commentlimit", parlante.DEFAULT_COMMENT_RATE_LIMIT,
+		"max comments from an ip to a page, like 5/1m. Empty for no limit")
+	pingmelimit := flag.String("pingmelimit", parlante.DEFAULT_PINGME_RATE_LIMIT,
+		"max messages from an ip to a page, like 2/1m. Empty for no limit")
+	reactlimit := flag.String("reactlimit", parlante.DEFAULT_REACT_RATE_LIMIT,
+		"max reactions from an ip to a page, like 20/1m. Empty for no limit")
+	reactortokenlimit := flag.String("reactortokenlimit",
+		parlante.DEFAULT_REACTOR_TOKEN_RATE_LIMIT,
+		"max new reactor tokens for an ip, like 5/1h. Empty for no limit")
+	secretkey := flag.String("secretkey", "",
+		"key used to sign tokens. If empty a key is created in the database")
+	formdelay := flag.Duration("formdelay", parlante.DEFAULT_FORM_MIN_DELAY,
+		"min time to fill a form. Faster forms are rejected")
+	difficulty := flag.Int("difficulty", parlante.DEFAULT_CHALLENGE_DIFFICULTY,
+		"base difficulty of the proof of work challenges")
+	publicurl := flag.String("publicurl", "",
+		"url of the server used in the links sent by email, like https://comments.example.com. Required for subscriptions")
+	smtphost := flag.String("smtphost", "",
+		"smtp server used to send emails. If empty emails are written to the maildir")
+	smtpport := flag.Int("smtpport", parlante.DEFAULT_SMTP_PORT, "smtp server port")
+	smtpuser := flag.String("smtpuser", "", "user for the smtp server")
+	smtppassword := flag.String("smtppassword", os.Getenv("PARLANTE_SMTP_PASSWORD"),
+		"password for the smtp server. Defaults to the PARLANTE_SMTP_PASSWORD env var")
+	smtpauth := flag.String("smtpauth", string(parlante.SMTPAuthPlain),
+		"smtp auth mechanism: plain or login")
+	smtpsecurity := flag.String("smtpsecurity", string(parlante.SMTPStartTLS),
+		"smtp connection security: starttls, tls or none")
+	smtptimeout := flag.Duration("smtptimeout", parlante.DEFAULT_SMTP_TIMEOUT,
+		"timeout for the smtp connections")
+	outboxattempts := flag.Int("outboxattempts", parlante.DEFAULT_OUTBOX_MAX_ATTEMPTS,
+		"number of attempts to send an email before giving up")
+	reactions := flag.String("reactions", strings.Join(parlante.DEFAULT_REACTIONS, ","),
+		"comma separated list of reactions to the comments")
+	trustedproxies := flag.String("trustedproxies", "",
+		"comma separated list of proxy ips or networks allowed to set X-Forwarded-For")
 	flag.CommandLine.Parse(os.Args[1:])
+	commentRateLimit, err := parlante.ParseRateLimit(*commentlimit)
+	if err != nil {
+		panic(err.Error())
+	}
+	pingMeRateLimit, err := parlante.ParseRateLimit(*pingmelimit)
+	if err != nil {
+		panic(err.Error())
+	}
+	reactRateLimit, err := parlante.ParseRateLimit(*reactlimit)
+	if err != nil {
+		panic(err.Error())
+	}
+	reactorTokenRateLimit, err := parlante.ParseRateLimit(*reactortokenlimit)
+	if err != nil {
+		panic(err.Error())
+	}
+	words := make([]string, 0)
+	for _, w := range strings.Split(*blockedwords, ",") {
+		if strings.TrimSpace(w) != "" {
+			words = append(words, strings.TrimSpace(w))
+		}
+	}
+	reactionList := make([]string, 0)
+	for _, r := range strings.Split(*reactions, ",") {
+		if strings.TrimSpace(r) != "" {
+			reactionList = append(reactionList, strings.TrimSpace(r))
+		}
+	}
+	proxyList := make([]string, 0)
+	for _, p := range strings.Split(*trustedproxies, ",") {
+		if strings.TrimSpace(p) != "" {
+			proxyList = append(proxyList, strings.TrimSpace(p))
+		}
+	}
 	c := parlante.Config{
 		Host:         *host,
 		Port:         *port,
@@ -27,8 +109,36 @@ func main() {
 		DBPath:       *dbpath,
 		LogLevel:     *loglevel,
 		MaildirPath:  *maildir,
+
+		CommentsPageSize: *pagesize,
+		TrashPurgeDays:   *trashdays,
+		SpamMaxLinks:     *maxlinks,
+		SpamBlockedWords: words,
+		CommentMinLength: *minlength,
+		CommentMaxLength: *maxlength,
+		CommentRateLimit: commentRateLimit,
+		PingMeRateLimit:  pingMeRateLimit,
+		ReactRateLimit:   reactRateLimit,
+		SecretKey:        *secretkey,
+		FormMinDelay:     *formdelay,
+
+		ReactorTokenRateLimit: reactorTokenRateLimit,
+		ChallengeDifficulty:   *difficulty,
+		PublicURL:             *publicurl,
+
+		SMTPHost:     *smtphost,
+		SMTPPort:     *smtpport,
+		SMTPUsername: *smtpuser,
+		SMTPPassword: *smtppassword,
+		SMTPAuth:     parlante.SMTPAuthMechanism(*smtpauth),
+		SMTPSecurity: parlante.SMTPSecurity(*smtpsecurity),
+		SMTPTimeout:  *smtptimeout,
+
+		OutboxMaxAttempts: *outboxattempts,
+		Reactions:         reactionList,
+		TrustedProxies:    proxyList,
 	}
-	err := parlante.SetupDB(c.DBPath)
+	err = parlante.SetupDB(c.DBPath)
 	if err != nil {
 		panic(err.Error())
 	}
@@ -36,6 +146,12 @@ func main() {
 	if err != nil {
 		panic(err.Error())
 	}
+	if c.SecretKey == "" {
+		c.SecretKey, err = parlante.GetSecretKey()
+		if err != nil {
+			panic(err.Error())
+		}
+	}
 	s := parlante.NewServer(c)
 	s.Run()
 }
@@ -20,13 +20,16 @@ package parlante
 import (
 	"database/sql"
 	"embed"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/golang-migrate/migrate/v4"
-	_ "github.com/golang-migrate/migrate/v4/database/sqlite3"
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite"
 	"github.com/golang-migrate/migrate/v4/source/iofs"
 	_ "modernc.org/sqlite"
 )
@@ -54,18 +57,22 @@ func (s ClientStorageSQLite) CreateClient(name string) (Client, string, error) {
 }
 
 func (s ClientStorageSQLite) GetClientByUUID(uuid string) (Client, error) {
-	raw_query := "select * from clients where uuid = ?"
+	raw_query := "select " + clientColumns + " from clients "
+	raw_query += "where uuid = ? and deleted_at is null"
 	row := DB.QueryRow(raw_query, uuid)
-	client := Client{}
-	err := row.Scan(&client.ID, &client.Name, &client.UUID, &client.Key)
-	if err != nil {
-		return Client{}, err
-	}
-	return client, nil
+	return scanClient(row)
+}
+
+func (s ClientStorageSQLite) GetClientByID(id int64) (Client, error) {
+	raw_query := "select " + clientColumns + " from clients "
+	raw_query += "where id = ? and deleted_at is null"
+	row := DB.QueryRow(raw_query, id)
+	return scanClient(row)
 }
 
 func (s ClientStorageSQLite) ListClients() ([]Client, error) {
-	raw_query := "select * from clients"
+	raw_query := "select " + clientColumns + " from clients "
+	raw_query += "where deleted_at is null"
 	rows, err := DB.Query(raw_query)
 	if err != nil {
 		return nil, err
@@ -73,8 +80,7 @@ func (s ClientStorageSQLite) ListClients() ([]Client, error) {
 	clients := make([]Client, 0)
 
 	for rows.Next() {
-		client := Client{}
-		err := rows.Scan(&client.ID, &client.Name, &client.UUID, &client.Key)
+		client, err := scanClient(rows)
 
 		if err != nil {
 			return nil, err
@@ -85,9 +91,19 @@ func (s ClientStorageSQLite) ListClients() ([]Client, error) {
 
 }
 
+func (s ClientStorageSQLite) SetClientAkismet(
+	c Client, endpoint string, key string) error {
+	raw_query := "update clients set akismet_endpoint = ?, akismet_key = ? "
+	raw_query += "where id = ?"
+	_, err := DB.Exec(raw_query, endpoint, key, c.ID)
+	return err
+}
+
+// RemoveClient moves the client to the trash
 func (s ClientStorageSQLite) RemoveClient(uuid string) error {
-	raw_query := "delete from clients where uuid = ?"
-	_, err := DB.Exec(raw_query, uuid)
+	raw_query := "update clients set deleted_at = ? "
+	raw_query += "where uuid = ? and deleted_at is null"
+	_, err := DB.Exec(raw_query, time.Now().Unix(), uuid)
 	return err
 }
 
@@ -110,20 +126,23 @@ func (s ClientDomainStorageSQLite) AddClientDomain(c Client, domain string) (
 	return d, nil
 }
 
+// RemoveClientDomain moves the domain to the trash
 func (s ClientDomainStorageSQLite) RemoveClientDomain(c Client, domain string) error {
-	raw_query := "delete from client_domains where domain = ? "
-	raw_query += "and client_id = ?"
-	_, err := DB.Exec(raw_query, domain, c.ID)
+	raw_query := "update client_domains set deleted_at = ? where domain = ? "
+	raw_query += "and client_id = ? and deleted_at is null"
+	_, err := DB.Exec(raw_query, time.Now().Unix(), domain, c.ID)
 	return err
 }
 
 func (s ClientDomainStorageSQLite) GetClientDomain(c Client, domain string) (
 	ClientDomain, error) {
-	raw_query := "select * from client_domains where client_id = ? "
-	raw_query += "and domain = ?"
+	raw_query := "select id, client_id, domain, moderate, markdown, "
+	raw_query += "comments_order from client_domains "
+	raw_query += "where client_id = ? and domain = ? and deleted_at is null"
 	row := DB.QueryRow(raw_query, c.ID, domain)
 	d := ClientDomain{}
-	err := row.Scan(&d.ID, &d.ClientID, &d.Domain)
+	err := row.Scan(
+		&d.ID, &d.ClientID, &d.Domain, &d.Moderate, &d.Markdown, &d.Order)
 	if err != nil {
 		return ClientDomain{}, nil
 	}
@@ -133,7 +152,8 @@ func (s ClientDomainStorageSQLite) GetClientDomain(c Client, domain string) (
 func (s ClientDomainStorageSQLite) ListDomains() ([]ClientDomain, error) {
 	raw_query := `
 select
-  cd.id, cd.client_id, cd.domain,
+  cd.id, cd.client_id, cd.domain, cd.moderate, cd.markdown,
+  cd.comments_order,
   c.id, c.name, c.uuid, c.key
 
 from
@@ -141,6 +161,9 @@ from
 
 join
   clients c on c.id = cd.client_id
+
+where
+  cd.deleted_at is null and c.deleted_at is null
 `
 
 	rows, err := DB.Query(raw_query)
@@ -156,6 +179,9 @@ join
 			&cd.ID,
 			&cd.ClientID,
 			&cd.Domain,
+			&cd.Moderate,
+			&cd.Markdown,
+			&cd.Order,
 			&c.ID,
 			&c.Name,
 			&c.UUID,
@@ -171,6 +197,135 @@ join
 	return domains, nil
 }
 
+func (s ClientDomainStorageSQLite) SetDomainModeration(
+	d ClientDomain, moderate bool) error {
+	raw_query := "update client_domains set moderate = ? where id = ?"
+	_, err := DB.Exec(raw_query, moderate, d.ID)
+	return err
+}
+
+func (s ClientDomainStorageSQLite) SetDomainMarkdown(
+	d ClientDomain, markdown bool) error {
+	raw_query := "update client_domains set markdown = ? where id = ?"
+	_, err := DB.Exec(raw_query, markdown, d.ID)
+	return err
+}
+
+func (s ClientDomainStorageSQLite) SetDomainOrder(
+	d ClientDomain, order CommentOrder) error {
+	raw_query := "update client_domains set comments_order = ? where id = ?"
+	_, err := DB.Exec(raw_query, order, d.ID)
+	return err
+}
+
+func (s ClientDomainStorageSQLite) GetClientRateLimit(
+	c Client, route RateLimitRoute) (RateLimit, error) {
+	return getRateLimit("client_rate_limits", "client_id", c.ID, route)
+}
+
+func (s ClientDomainStorageSQLite) SetClientRateLimit(
+	c Client, route RateLimitRoute, limit RateLimit) error {
+	return setRateLimit("client_rate_limits", "client_id", c.ID, route, limit)
+}
+
+func (s ClientDomainStorageSQLite) GetDomainRateLimit(
+	d ClientDomain, route RateLimitRoute) (RateLimit, error) {
+	return getRateLimit("domain_rate_limits", "domain_id", d.ID, route)
+}
+
+func (s ClientDomainStorageSQLite) SetDomainRateLimit(
+	d ClientDomain, route RateLimitRoute, limit RateLimit) error {
+	return setRateLimit("domain_rate_limits", "domain_id", d.ID, route, limit)
+}
+
+func getRateLimit(table string, column string, id int64,
+	route RateLimitRoute) (RateLimit, error) {
+	raw_query := fmt.Sprintf(
+		"select requests, period from %s where %s = ? and route = ?",
+		table, column)
+	var requests int
+	var period int64
+	err := DB.QueryRow(raw_query, id, route).Scan(&requests, &period)
+	if err == sql.ErrNoRows {
+		return RateLimit{}, nil
+	}
+	if err != nil {
+		return RateLimit{}, err
+	}
+	limit := RateLimit{
+		Requests: requests,
+		Period:   time.Duration(period) * time.Second,
+	}
+	return limit, nil
+}
+
+func setRateLimit(table string, column string, id int64,
+	route RateLimitRoute, limit RateLimit) error {
+	if limit.IsZero() {
+		raw_query := fmt.Sprintf(
+			"delete from %s where %s = ? and route = ?", table, column)
+		_, err := DB.Exec(raw_query, id, route)
+		return err
+	}
+	raw_query := fmt.Sprintf(`
+insert into %s (%s, route, requests, period) values (?, ?, ?, ?)
+on conflict(%s, route) do update set requests = excluded.requests,
+period = excluded.period`, table, column, column)
+	_, err := DB.Exec(raw_query, id, route, limit.Requests,
+		int64(limit.Period.Seconds()))
+	return err
+}
+
+func (s ClientDomainStorageSQLite) GetClientNotifications(c Client) (
+	NotificationSettings, error) {
+	return getNotifications("client_notification_settings", "client_id", c.ID)
+}
+
+func (s ClientDomainStorageSQLite) SetClientNotifications(
+	c Client, n NotificationSettings) error {
+	return setNotifications("client_notification_settings", "client_id", c.ID, n)
+}
+
+func (s ClientDomainStorageSQLite) GetDomainNotifications(d ClientDomain) (
+	NotificationSettings, error) {
+	return getNotifications("domain_notification_settings", "domain_id", d.ID)
+}
+
+func (s ClientDomainStorageSQLite) SetDomainNotifications(
+	d ClientDomain, n NotificationSettings) error {
+	return setNotifications("domain_notification_settings", "domain_id", d.ID, n)
+}
+
+func getNotifications(table string, column string, id int64) (
+	NotificationSettings, error) {
+	raw_query := fmt.Sprintf(
+		"select sender, recipients from %s where %s = ?", table, column)
+	var sender, recipients string
+	err := DB.QueryRow(raw_query, id).Scan(&sender, &recipients)
+	if err == sql.ErrNoRows {
+		return NotificationSettings{}, nil
+	}
+	if err != nil {
+		return NotificationSettings{}, err
+	}
+	return ParseNotificationSettings(sender, recipients)
+}
+
+func setNotifications(table string, column string, id int64,
+	n NotificationSettings) error {
+	if n.IsZero() {
+		raw_query := fmt.Sprintf("delete from %s where %s = ?", table, column)
+		_, err := DB.Exec(raw_query, id)
+		return err
+	}
+	raw_query := fmt.Sprintf(`
+insert into %s (%s, sender, recipients) values (?, ?, ?)
+on conflict(%s) do update set sender = excluded.sender,
+recipients = excluded.recipients`, table, column, column)
+	_, err := DB.Exec(raw_query, id, n.Sender, n.RecipientsString())
+	return err
+}
+
 type CommentStorageSQLite struct {
 }
 
@@ -180,38 +335,83 @@ func (s CommentStorageSQLite) CreateComment(
 	content string,
 	page_url string) (Comment, error) {
 
-	raw_query := `
-insert into comments (client_id, domain_id, name, content, page_url, timestamp)
-values (?, ?, ?, ?, ?, ?)`
-
 	comment, err := NewComment(c, d, name, content, page_url)
 	if err != nil {
 		return Comment{}, err
 	}
-	row, err := DB.Exec(raw_query, c.ID, d.ID, comment.Author,
-		comment.Content, comment.PageURL, comment.Timestamp)
+	err = insertComment(DB, &comment)
 	if err != nil {
 		return Comment{}, err
 	}
-	id, err := row.LastInsertId()
+	return comment, nil
+
+}
+
+func (s CommentStorageSQLite) CreateReply(
+	c Client, d ClientDomain,
+	parent Comment,
+	name string,
+	content string,
+	page_url string) (Comment, error) {
+
+	comment, err := NewReply(c, d, parent, name, content, page_url)
+	if err != nil {
+		return Comment{}, err
+	}
+	err = insertComment(DB, &comment)
 	if err != nil {
 		return Comment{}, err
 	}
-	comment.ID = id
 	return comment, nil
+}
 
+func (s CommentStorageSQLite) GetCommentByID(id int64) (Comment, error) {
+	raw_query := "select " + commentColumns + " from comments "
+	raw_query += "where id = ? and deleted_at is null"
+	row := DB.QueryRow(raw_query, id)
+	comment, err := scanComment(row)
+	if err != nil {
+		return Comment{}, err
+	}
+	return comment, nil
 }
 
 func (s CommentStorageSQLite) ListComments(filter CommentsFilter) (
 	[]Comment, error) {
+	return listComments(filter, []string{"1 = 1"}, []any{})
+}
+
+func (s CommentStorageSQLite) SearchComments(
+	query string, filter CommentsFilter) ([]Comment, error) {
+	q := ftsQuery(query)
+	if q == "" {
+		return nil, errors.New("Empty search query")
+	}
+	where := []string{
+		"id in (select rowid from comments_fts where comments_fts match ?)"}
+	return listComments(filter, where, []any{q})
+}
+
+// listComments returns the comments matching a filter and the where
+// conditions.
+func listComments(filter CommentsFilter, where []string, args []any) (
+	[]Comment, error) {
 
-	where, args := []string{"1 = 1"}, []any{}
 	tb := make(map[string]any)
 
 	tb["client_id = ?"] = filter.ClientID
 	tb["domain_id = ?"] = filter.DomainID
 	tb["page_url = ?"] = filter.PageURL
 	tb["hidden = ?"] = filter.Hidden
+	tb["parent_id = ?"] = filter.ParentID
+	tb["status = ?"] = filter.Status
+	tb[`id in (
+  with recursive thread(id) as (
+    select id from comments where id = ?
+    union all
+    select c.id from comments c join thread t on c.parent_id = t.id
+  )
+  select id from thread)`] = filter.ThreadID
 
 	for k, v := range tb {
 		if !reflect.ValueOf(v).IsNil() {
@@ -219,8 +419,22 @@ func (s CommentStorageSQLite) ListComments(filter CommentsFilter) (
 		}
 	}
 
-	raw_query := "select * from comments where " + strings.Join(where, " and ")
-	raw_query += " order by timestamp asc"
+	orderBy, cond, cargs, err := commentsOrder(filter.Order, filter.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	if cond != "" {
+		where, args = append(where, cond), append(args, cargs...)
+	}
+
+	where = append(where, "deleted_at is null", commentOwnersNotRemoved)
+	raw_query := "select " + commentColumns + " from comments where "
+	raw_query += strings.Join(where, " and ")
+	raw_query += orderBy
+	if filter.Limit > 0 {
+		raw_query += " limit ?"
+		args = append(args, filter.Limit)
+	}
 	rows, err := DB.Query(raw_query, args...)
 	if err != nil {
 		return nil, err
@@ -228,10 +442,7 @@ func (s CommentStorageSQLite) ListComments(filter CommentsFilter) (
 	comments := make([]Comment, 0)
 
 	for rows.Next() {
-		comment := Comment{}
-		err := rows.Scan(&comment.ID, &comment.ClientID, &comment.DomainID,
-			&comment.Author, &comment.Content, &comment.PageURL, &comment.Hidden,
-			&comment.Timestamp)
+		comment, err := scanComment(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -240,6 +451,71 @@ func (s CommentStorageSQLite) ListComments(filter CommentsFilter) (
 	return comments, nil
 }
 
+// commentOrderKey is the expression used to order the comments before
+// the timestamp and if it is in descending order.
+type commentOrderKey struct {
+	expr    string
+	desc    bool
+	numeric bool
+}
+
+var commentOrderKeys = map[CommentOrder]commentOrderKey{
+	OrderScore:  {expr: commentScore, desc: true, numeric: true},
+	OrderAuthor: {expr: "name collate nocase"},
+	OrderPage:   {expr: "page_url"},
+	OrderHidden: {expr: "hidden", numeric: true},
+}
+
+// commentsOrder returns the order by clause for a listing and the
+// condition, with its args, for the comments after the cursor.
+func commentsOrder(order CommentOrder, cursor *CommentCursor) (
+	string, string, []any, error) {
+	if order == OrderNewest {
+		orderBy := " order by timestamp desc, id desc"
+		if cursor == nil {
+			return orderBy, "", nil, nil
+		}
+		cond := "(timestamp < ? or (timestamp = ? and id < ?))"
+		args := []any{cursor.Timestamp, cursor.Timestamp, cursor.ID}
+		return orderBy, cond, args, nil
+	}
+
+	orderBy := " order by timestamp asc, id asc"
+	key, hasKey := commentOrderKeys[order]
+	if hasKey {
+		dir := "asc"
+		if key.desc {
+			dir = "desc"
+		}
+		orderBy = fmt.Sprintf(" order by %s %s, timestamp asc, id asc",
+			key.expr, dir)
+	}
+	if cursor == nil {
+		return orderBy, "", nil, nil
+	}
+	cond := "(timestamp > ? or (timestamp = ? and id > ?))"
+	args := []any{cursor.Timestamp, cursor.Timestamp, cursor.ID}
+	if !hasKey {
+		return orderBy, cond, args, nil
+	}
+	var value any = cursor.Key
+	if key.numeric {
+		n, err := strconv.ParseInt(cursor.Key, 10, 64)
+		if err != nil {
+			return "", "", nil, errors.New("Invalid cursor")
+		}
+		value = n
+	}
+	op := ">"
+	if key.desc {
+		op = "<"
+	}
+	cond = fmt.Sprintf("(%s %s ? or (%s = ? and %s))",
+		key.expr, op, key.expr, cond)
+	args = append([]any{value, value}, args...)
+	return orderBy, cond, args, nil
+}
+
 func (s CommentStorageSQLite) CountComments(urls ...string) ([]CommentCount, error) {
 	if len(urls) == 0 {
 		return nil, errors.New("At least one url is required")
@@ -260,11 +536,12 @@ select
     coalesce(count(c.id), 0) as total_comments
 from urls u
 left join comments c
-       on c.page_url = u.url
+       on c.page_url = u.url and c.status = 'approved'
+       and c.deleted_at is null and %s
 group by u.url
 order by u.url;
 `,
-		instr)
+		instr, commentOwnersNotRemoved)
 	rows, err := DB.Query(raw_query, anyurls...)
 	if err != nil {
 		return nil, err
@@ -281,15 +558,749 @@ order by u.url;
 	return count, nil
 }
 
+// RemoveComment moves the comment to the trash
 func (s CommentStorageSQLite) RemoveComment(comment Comment) error {
-	raw_query := "delete from comments where id = ? "
-	_, err := DB.Exec(raw_query, comment.ID)
+	raw_query := "update comments set deleted_at = ? "
+	raw_query += "where id = ? and deleted_at is null"
+	_, err := DB.Exec(raw_query, time.Now().Unix(), comment.ID)
+	return err
+
+}
+
+func (s CommentStorageSQLite) SetCommentStatus(
+	comment Comment, status CommentStatus) error {
+	current, err := s.GetCommentByID(comment.ID)
+	if err != nil {
+		return err
+	}
+	err = current.SetStatus(status)
+	if err != nil {
+		return err
+	}
+	raw_query := "update comments set status = ?, hidden = ? where id = ?"
+	_, err = DB.Exec(raw_query, current.Status, current.Hidden, current.ID)
+	return err
+}
+
+func (s CommentStorageSQLite) UpdateComment(
+	comment Comment, content string, editor string) (Comment, error) {
+	current, err := s.GetCommentByID(comment.ID)
+	if err != nil {
+		return Comment{}, err
+	}
+	rev, err := current.Edit(content, editor)
+	if err != nil {
+		return Comment{}, err
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return Comment{}, err
+	}
+	defer tx.Rollback()
+
+	raw_query := `
+insert into comment_revisions (comment_id, content, editor, timestamp)
+values (?, ?, ?, ?)`
+	_, err = tx.Exec(raw_query, rev.CommentID, rev.Content, rev.Editor,
+		rev.Timestamp)
+	if err != nil {
+		return Comment{}, err
+	}
+	raw_query = "update comments set content = ?, edited_at = ? where id = ?"
+	_, err = tx.Exec(raw_query, current.Content, current.EditedAt, current.ID)
+	if err != nil {
+		return Comment{}, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return Comment{}, err
+	}
+	return current, nil
+}
+
+func (s CommentStorageSQLite) ListCommentRevisions(comment Comment) (
+	[]CommentRevision, error) {
+	raw_query := `
+select * from comment_revisions where comment_id = ?
+order by timestamp desc, id desc`
+	rows, err := DB.Query(raw_query, comment.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	revs := make([]CommentRevision, 0)
+	for rows.Next() {
+		rev := CommentRevision{}
+		err := rows.Scan(&rev.ID, &rev.CommentID, &rev.Content, &rev.Editor,
+			&rev.Timestamp)
+		if err != nil {
+			return nil, err
+		}
+		revs = append(revs, rev)
+	}
+	return revs, rows.Err()
+}
+
+func (s CommentStorageSQLite) TrainSpamModel(comment Comment, spam bool) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var oldSpam bool
+	var oldTokens string
+	raw_query := `
+select spam, tokens from spam_trained_comments where comment_id = ?`
+	err = tx.QueryRow(raw_query, comment.ID).Scan(&oldSpam, &oldTokens)
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		return err
+	default:
+		err = updateSpamTokens(tx, strings.Fields(oldTokens), oldSpam, -1)
+		if err != nil {
+			return err
+		}
+	}
+
+	tokens := SpamTokens(comment)
+	err = updateSpamTokens(tx, tokens, spam, 1)
+	if err != nil {
+		return err
+	}
+	raw_query = `
+insert into spam_trained_comments (comment_id, spam, tokens) values (?, ?, ?)
+on conflict(comment_id) do update set spam = excluded.spam,
+tokens = excluded.tokens`
+	_, err = tx.Exec(raw_query, comment.ID, spam, strings.Join(tokens, " "))
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// updateSpamTokens adds n to the spam or ham count of the tokens.
+func updateSpamTokens(tx *sql.Tx, tokens []string, spam bool, n int) error {
+	var spamN, hamN int
+	if spam {
+		spamN = n
+	} else {
+		hamN = n
+	}
+	raw_query := `
+insert into spam_tokens (token, spam, ham) values (?, ?, ?)
+on conflict(token) do update set spam = spam + excluded.spam,
+ham = ham + excluded.ham`
+	stmt, err := tx.Prepare(raw_query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, token := range tokens {
+		_, err := stmt.Exec(token, spamN, hamN)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = tx.Exec("delete from spam_tokens where spam <= 0 and ham <= 0")
+	return err
+}
+
+func (s CommentStorageSQLite) ResetSpamModel() error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, table := range []string{"spam_tokens", "spam_trained_comments"} {
+		_, err := tx.Exec("delete from " + table)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s CommentStorageSQLite) GetSpamModel(tokens []string) (SpamModel, error) {
+	model := SpamModel{Tokens: make(map[string]SpamTokenCount)}
+	raw_query := `
+select coalesce(sum(spam), 0), coalesce(sum(not spam), 0)
+from spam_trained_comments`
+	err := DB.QueryRow(raw_query).Scan(&model.SpamComments, &model.HamComments)
+	if err != nil {
+		return SpamModel{}, err
+	}
+	if len(tokens) == 0 {
+		return model, nil
+	}
+	placeholders := strings.Repeat("?, ", len(tokens)-1) + "?"
+	args := make([]any, 0, len(tokens))
+	for _, t := range tokens {
+		args = append(args, t)
+	}
+	raw_query = fmt.Sprintf(
+		"select token, spam, ham from spam_tokens where token in (%s)",
+		placeholders)
+	rows, err := DB.Query(raw_query, args...)
+	if err != nil {
+		return SpamModel{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var token string
+		var count SpamTokenCount
+		err := rows.Scan(&token, &count.Spam, &count.Ham)
+		if err != nil {
+			return SpamModel{}, err
+		}
+		model.Tokens[token] = count
+	}
+	return model, rows.Err()
+}
+
+func (s CommentStorageSQLite) SetCommentRequestInfo(
+	comment Comment, info CommentRequestInfo) error {
+	raw_query := `
+insert into comment_request_info (comment_id, user_ip, user_agent, referrer)
+values (?, ?, ?, ?)
+on conflict(comment_id) do update set user_ip = excluded.user_ip,
+user_agent = excluded.user_agent, referrer = excluded.referrer`
+	_, err := DB.Exec(raw_query, comment.ID, info.UserIP, info.UserAgent,
+		info.Referrer)
+	return err
+}
+
+func (s CommentStorageSQLite) ToggleReaction(
+	comment Comment, reaction string, reactor string) (bool, error) {
+	raw_query := "delete from reactions where comment_id = ? and reaction = ? "
+	raw_query += "and reactor = ?"
+	r, err := DB.Exec(raw_query, comment.ID, reaction, reactor)
+	if err != nil {
+		return false, err
+	}
+	n, err := r.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if n > 0 {
+		return false, nil
+	}
+	raw_query = "insert into reactions (comment_id, reaction, reactor, "
+	raw_query += "timestamp) values (?, ?, ?, ?)"
+	_, err = DB.Exec(raw_query, comment.ID, reaction, reactor,
+		time.Now().Unix())
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s CommentStorageSQLite) CountReactions(ids ...int64) (
+	map[int64]ReactionCounts, error) {
+	counts := make(map[int64]ReactionCounts)
+	if len(ids) == 0 {
+		return counts, nil
+	}
+	in := make([]string, 0)
+	args := make([]any, 0)
+	for _, id := range ids {
+		in = append(in, "?")
+		args = append(args, id)
+		counts[id] = make(ReactionCounts)
+	}
+	raw_query := fmt.Sprintf(`
+select comment_id, reaction, count(*) from reactions
+where comment_id in (%s)
+group by comment_id, reaction`, strings.Join(in, ","))
+	rows, err := DB.Query(raw_query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, count int64
+		var reaction string
+		err := rows.Scan(&id, &reaction, &count)
+		if err != nil {
+			return nil, err
+		}
+		counts[id][reaction] = count
+	}
+	return counts, nil
+}
+
+func (s CommentStorageSQLite) SetCommentAuthorContact(
+	comment Comment, email string, website string) error {
+	raw_query := "update comments set email = ?, website = ? where id = ?"
+	_, err := DB.Exec(raw_query, email, website, comment.ID)
+	return err
+}
+
+func (s CommentStorageSQLite) GetCommentRequestInfo(comment Comment) (
+	CommentRequestInfo, error) {
+	raw_query := `
+select user_ip, user_agent, referrer from comment_request_info
+where comment_id = ?`
+	info := CommentRequestInfo{}
+	err := DB.QueryRow(raw_query, comment.ID).Scan(
+		&info.UserIP, &info.UserAgent, &info.Referrer)
+	if err == sql.ErrNoRows {
+		return CommentRequestInfo{}, nil
+	}
+	return info, err
+}
+
+func (s CommentStorageSQLite) ImportComment(comment Comment, source string) (
+	Comment, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return Comment{}, err
+	}
+	defer tx.Rollback()
+
+	err = insertComment(tx, &comment)
+	if err != nil {
+		return Comment{}, err
+	}
+	raw_query := `
+insert into comment_imports (comment_id, domain_id, source, timestamp)
+values (?, ?, ?, ?)`
+	_, err = tx.Exec(raw_query, comment.ID, comment.DomainID, source,
+		time.Now().Unix())
+	if err != nil {
+		return Comment{}, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return Comment{}, err
+	}
+	return comment, nil
+}
+
+func (s CommentStorageSQLite) GetImportedComment(
+	d ClientDomain, source string) (Comment, error) {
+	raw_query := "select " + commentColumns + " from comments "
+	raw_query += "where id = (select comment_id from comment_imports "
+	raw_query += "where domain_id = ? and source = ?)"
+	comment, err := scanComment(DB.QueryRow(raw_query, d.ID, source))
+	if err == sql.ErrNoRows {
+		return Comment{}, nil
+	}
+	return comment, err
+}
+
+type TrashStorageSQLite struct {
+}
+
+// trashTables are the tables for each type of item in the trash
+var trashTables = map[TrashItemType]string{
+	TrashClient:  "clients",
+	TrashDomain:  "client_domains",
+	TrashComment: "comments",
+}
+
+func (s TrashStorageSQLite) ListTrash() ([]TrashItem, error) {
+	raw_query := `
+select 'client', id, name, deleted_at from clients
+where deleted_at is not null
+
+union all
+
+select 'domain', id, domain, deleted_at from client_domains
+where deleted_at is not null
+
+union all
+
+select 'comment', id, name || ' @ ' || page_url, deleted_at from comments
+where deleted_at is not null
+
+order by 4 desc, 2 desc
+`
+	rows, err := DB.Query(raw_query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := make([]TrashItem, 0)
+	for rows.Next() {
+		item := TrashItem{}
+		err := rows.Scan(&item.Type, &item.ID, &item.Name, &item.DeletedAt)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func (s TrashStorageSQLite) RestoreItem(item TrashItem) error {
+	table, ok := trashTables[item.Type]
+	if !ok {
+		return fmt.Errorf("Invalid trash item type %s", item.Type)
+	}
+	raw_query := fmt.Sprintf(
+		"update %s set deleted_at = null where id = ? and deleted_at is not null",
+		table)
+	_, err := DB.Exec(raw_query, item.ID)
+	return err
+}
+
+func (s TrashStorageSQLite) PurgeItem(item TrashItem) error {
+	table, ok := trashTables[item.Type]
+	if !ok {
+		return fmt.Errorf("Invalid trash item type %s", item.Type)
+	}
+	// the related items are removed by the cascade rules
+	raw_query := fmt.Sprintf(
+		"delete from %s where id = ? and deleted_at is not null", table)
+	_, err := DB.Exec(raw_query, item.ID)
+	return err
+}
+
+func (s TrashStorageSQLite) PurgeOlderThan(timestamp int64) (int64, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	var total int64
+	for _, table := range []string{"comments", "client_domains", "clients"} {
+		raw_query := fmt.Sprintf(
+			"delete from %s where deleted_at is not null and deleted_at < ?",
+			table)
+		r, err := tx.Exec(raw_query, timestamp)
+		if err != nil {
+			return 0, err
+		}
+		n, err := r.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		total += n
+	}
+	return total, tx.Commit()
+}
+
+type SubscriptionStorageSQLite struct {
+}
+
+func (s SubscriptionStorageSQLite) Subscribe(
+	cd ClientDomain, pageURL string, email string) (Subscription, error) {
+	raw_query := "insert into subscriptions (domain_id, page_url, email, "
+	raw_query += "timestamp) values (?, ?, ?, ?)"
+	_, err := DB.Exec(raw_query, cd.ID, pageURL, email, time.Now().Unix())
+	if err != nil {
+		return Subscription{}, err
+	}
+	// the insert is ignored if the subscription already exists.
+	raw_query = "select " + subscriptionColumns + " from subscriptions "
+	raw_query += "where domain_id = ? and page_url = ? and email = ?"
+	row := DB.QueryRow(raw_query, cd.ID, pageURL, email)
+	return scanSubscription(row)
+}
+
+func (s SubscriptionStorageSQLite) GetSubscription(id int64) (
+	Subscription, error) {
+	raw_query := "select " + subscriptionColumns + " from subscriptions "
+	raw_query += "where id = ?"
+	row := DB.QueryRow(raw_query, id)
+	return scanSubscription(row)
+}
+
+func (s SubscriptionStorageSQLite) ListSubscriptions(
+	cd ClientDomain, pageURL string) ([]Subscription, error) {
+	raw_query := "select " + subscriptionColumns + " from subscriptions "
+	raw_query += "where domain_id = ? and page_url = ? and confirmed = 1 "
+	raw_query += "order by id"
+	rows, err := DB.Query(raw_query, cd.ID, pageURL)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	subs := make([]Subscription, 0)
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+func (s SubscriptionStorageSQLite) ConfirmSubscription(sub Subscription) error {
+	raw_query := "update subscriptions set confirmed = 1 where id = ?"
+	_, err := DB.Exec(raw_query, sub.ID)
 	return err
+}
+
+func (s SubscriptionStorageSQLite) MarkConfirmSent(
+	sub Subscription) (bool, error) {
+	raw_query := "update subscriptions set confirm_sent_at = ? "
+	raw_query += "where id = ? and confirm_sent_at = 0"
+	r, err := DB.Exec(raw_query, time.Now().Unix(), sub.ID)
+	if err != nil {
+		return false, err
+	}
+	n, err := r.RowsAffected()
+	return n > 0, err
+}
+
+func (s SubscriptionStorageSQLite) Unsubscribe(sub Subscription) error {
+	raw_query := "delete from subscriptions where id = ?"
+	_, err := DB.Exec(raw_query, sub.ID)
+	return err
+}
+
+type OutboxStorageSQLite struct {
+}
+
+func (s OutboxStorageSQLite) Enqueue(msg EmailMessage) (OutboxMessage, error) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return OutboxMessage{}, err
+	}
+	m := OutboxMessage{
+		Message:   msg,
+		Status:    OutboxPending,
+		Timestamp: time.Now().Unix(),
+	}
+	m.NextAttempt = m.Timestamp
+	raw_query := "insert into outbox (message, status, next_attempt, "
+	raw_query += "timestamp) values (?, ?, ?, ?)"
+	r, err := DB.Exec(raw_query, string(data), m.Status, m.NextAttempt,
+		m.Timestamp)
+	if err != nil {
+		return OutboxMessage{}, err
+	}
+	m.ID, err = r.LastInsertId()
+	if err != nil {
+		return OutboxMessage{}, err
+	}
+	return m, nil
+}
+
+func (s OutboxStorageSQLite) ListDue(timestamp int64, limit int) (
+	[]OutboxMessage, error) {
+	raw_query := "select " + outboxColumns + " from outbox "
+	raw_query += "where status = ? and next_attempt <= ? "
+	raw_query += "order by next_attempt, id limit ?"
+	return s.listMessages(raw_query, OutboxPending, timestamp, limit)
+}
+
+func (s OutboxStorageSQLite) ListFailed() ([]OutboxMessage, error) {
+	raw_query := "select " + outboxColumns + " from outbox "
+	raw_query += "where status = ? order by timestamp desc, id desc"
+	return s.listMessages(raw_query, OutboxFailed)
+}
+
+func (s OutboxStorageSQLite) UpdateMessage(msg OutboxMessage) error {
+	raw_query := "update outbox set status = ?, attempts = ?, "
+	raw_query += "next_attempt = ?, last_error = ? where id = ?"
+	_, err := DB.Exec(raw_query, msg.Status, msg.Attempts, msg.NextAttempt,
+		msg.LastError, msg.ID)
+	return err
+}
+
+func (s OutboxStorageSQLite) RemoveMessage(msg OutboxMessage) error {
+	_, err := DB.Exec("delete from outbox where id = ?", msg.ID)
+	return err
+}
+
+func (s OutboxStorageSQLite) RetryMessage(msg OutboxMessage) error {
+	raw_query := "update outbox set status = ?, attempts = 0, "
+	raw_query += "next_attempt = ? where id = ?"
+	_, err := DB.Exec(raw_query, OutboxPending, time.Now().Unix(), msg.ID)
+	return err
+}
+
+func (s OutboxStorageSQLite) listMessages(raw_query string, args ...any) (
+	[]OutboxMessage, error) {
+	rows, err := DB.Query(raw_query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	msgs := make([]OutboxMessage, 0)
+	for rows.Next() {
+		m, err := scanOutboxMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, m)
+	}
+	return msgs, rows.Err()
+}
+
+type DigestStorageSQLite struct {
+}
+
+func (s DigestStorageSQLite) AddDigestItem(item DigestItem) (DigestItem, error) {
+	raw_query := `
+insert into digest_items (domain_id, domain, recipient, mode, kind, sender,
+                          page_url, author, email, content, status, timestamp)
+values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	r, err := DB.Exec(raw_query, item.DomainID, item.Domain, item.Recipient,
+		item.Mode, item.Kind, item.Sender, item.PageURL, item.Author,
+		item.Email, item.Content, item.Status, item.Timestamp)
+	if err != nil {
+		return DigestItem{}, err
+	}
+	item.ID, err = r.LastInsertId()
+	if err != nil {
+		return DigestItem{}, err
+	}
+	return item, nil
+}
 
+func (s DigestStorageSQLite) ListDigestItems(
+	mode NotificationMode, before int64) ([]DigestItem, error) {
+	raw_query := "select " + digestItemColumns + " from digest_items "
+	raw_query += "where mode = ? and timestamp < ? "
+	raw_query += "order by recipient, domain, page_url, timestamp, id"
+	rows, err := DB.Query(raw_query, mode, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := make([]DigestItem, 0)
+	for rows.Next() {
+		item, err := scanDigestItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
 }
 
+func (s DigestStorageSQLite) RemoveDigestItems(items []DigestItem) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, item := range items {
+		_, err := tx.Exec("delete from digest_items where id = ?", item.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// RepairReport has the number of orphan rows fixed by RepairDB
+type RepairReport struct {
+	// Domains of clients that don't exist. They are removed.
+	Domains int64
+	// Comments with a client or domain that don't exist or a domain
+	// of other client. They are removed.
+	Comments int64
+	// Replies to comments that don't exist. They become top level
+	// comments.
+	Replies int64
+	// Revisions of comments that don't exist. They are removed.
+	Revisions int64
+}
+
+// repairQueries fix the orphans in the database. The order matters
+// so the rows removed by the cascade rules are counted.
+var repairQueries = []struct {
+	query string
+	count func(r *RepairReport, n int64)
+}{
+	{
+		fmt.Sprintf(`
+delete from comment_revisions
+where comment_id not in (select id from comments where %s)`, validComment),
+		func(r *RepairReport, n int64) { r.Revisions = n },
+	},
+	{
+		fmt.Sprintf(`
+update comments set parent_id = null
+where parent_id is not null and %s
+      and parent_id not in (select id from comments where %s)`,
+			validComment, validComment),
+		func(r *RepairReport, n int64) { r.Replies = n },
+	},
+	{
+		fmt.Sprintf("delete from comments where not (%s)", validComment),
+		func(r *RepairReport, n int64) { r.Comments = n },
+	},
+	{
+		"delete from client_domains where client_id not in (select id from clients)",
+		func(r *RepairReport, n int64) { r.Domains = n },
+	},
+}
+
+// validComment is the condition for comments that are not orphans
+const validComment = `(
+client_id in (select id from clients) and
+domain_id in (select d.id from client_domains d
+              join clients c on c.id = d.client_id
+              where d.client_id = comments.client_id))`
+
+// RepairDB finds and fixes orphan rows left by removals made when the
+// foreign keys were not enforced. If dryRun is true the orphans are
+// only counted.
+func RepairDB(dryRun bool) (RepairReport, error) {
+	report := RepairReport{}
+	tx, err := DB.Begin()
+	if err != nil {
+		return report, err
+	}
+	defer tx.Rollback()
+
+	for _, q := range repairQueries {
+		r, err := tx.Exec(q.query)
+		if err != nil {
+			return RepairReport{}, err
+		}
+		n, err := r.RowsAffected()
+		if err != nil {
+			return RepairReport{}, err
+		}
+		q.count(&report, n)
+	}
+	if dryRun {
+		return report, nil
+	}
+	return report, tx.Commit()
+}
+
+// GetSecretKey returns the secret key used by the server to sign
+// tokens. The key is created in the first call.
+func GetSecretKey() (string, error) {
+	key, err := GenKey()
+	if err != nil {
+		return "", err
+	}
+	_, err = DB.Exec(
+		"insert into settings (name, value) values ('secret_key', ?) "+
+			"on conflict(name) do nothing", key)
+	if err != nil {
+		return "", err
+	}
+	row := DB.QueryRow("select value from settings where name = 'secret_key'")
+	err = row.Scan(&key)
+	if err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// dbPragmas are set for every new connection. Foreign keys are off by
+// default in sqlite and the cascade rules depend on them.
+const dbPragmas = "_pragma=foreign_keys(1)"
+
 func SetupDB(connURI string) error {
-	db, err := sql.Open("sqlite", connURI)
+	sep := "?"
+	if strings.Contains(connURI, "?") {
+		sep = "&"
+	}
+	db, err := sql.Open("sqlite", connURI+sep+dbPragmas)
 	if err != nil {
 		return err
 	}
@@ -299,7 +1310,7 @@ func SetupDB(connURI string) error {
 
 func MigrateDB(dbfile string) error {
 
-	connURI := "sqlite3://" + dbfile
+	connURI := "sqlite://" + dbfile
 	d, err := iofs.New(embeddedMigrations, "migrations")
 	if err != nil {
 		return err
@@ -316,6 +1327,155 @@ func MigrateDB(dbfile string) error {
 	return nil
 }
 
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// sqlExecer is either the database or a transaction
+type sqlExecer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// clientColumns are the columns scanned by scanClient
+const clientColumns = "id, name, uuid, key, akismet_endpoint, akismet_key"
+
+func scanClient(row rowScanner) (Client, error) {
+	client := Client{}
+	err := row.Scan(&client.ID, &client.Name, &client.UUID, &client.Key,
+		&client.AkismetEndpoint, &client.AkismetKey)
+	if err != nil {
+		return Client{}, err
+	}
+	return client, nil
+}
+
+// commentOwnersNotRemoved filters out the comments of removed clients
+// and domains
+const commentOwnersNotRemoved = `client_id in
+(select id from clients where deleted_at is null)
+and domain_id in (select id from client_domains where deleted_at is null)`
+
+// commentScore is the number of reactions to a comment
+const commentScore = `(select count(*) from reactions
+where reactions.comment_id = comments.id)`
+
+// commentColumns are the columns scanned by scanComment
+const commentColumns = `id, client_id, domain_id, name, content, page_url,
+hidden, timestamp, parent_id, status, edited_at, email, website, ` +
+	commentScore + " as score"
+
+func scanComment(row rowScanner) (Comment, error) {
+	comment := Comment{}
+	var parentID sql.NullInt64
+	err := row.Scan(&comment.ID, &comment.ClientID, &comment.DomainID,
+		&comment.Author, &comment.Content, &comment.PageURL, &comment.Hidden,
+		&comment.Timestamp, &parentID, &comment.Status, &comment.EditedAt,
+		&comment.Email, &comment.Website, &comment.Score)
+	if err != nil {
+		return Comment{}, err
+	}
+	comment.ParentID = parentID.Int64
+	return comment, nil
+}
+
+// subscriptionColumns are the columns scanned by scanSubscription
+const subscriptionColumns = `id, domain_id, page_url, email, timestamp,
+confirmed, confirm_sent_at`
+
+func scanSubscription(row rowScanner) (Subscription, error) {
+	sub := Subscription{}
+	err := row.Scan(
+		&sub.ID, &sub.DomainID, &sub.PageURL, &sub.Email, &sub.Timestamp,
+		&sub.Confirmed, &sub.ConfirmSentAt)
+	if err != nil {
+		return Subscription{}, err
+	}
+	return sub, nil
+}
+
+// outboxColumns are the columns scanned by scanOutboxMessage
+const outboxColumns = `id, message, status, attempts, next_attempt,
+last_error, timestamp`
+
+func scanOutboxMessage(row rowScanner) (OutboxMessage, error) {
+	m := OutboxMessage{}
+	var data string
+	err := row.Scan(&m.ID, &data, &m.Status, &m.Attempts, &m.NextAttempt,
+		&m.LastError, &m.Timestamp)
+	if err != nil {
+		return OutboxMessage{}, err
+	}
+	err = json.Unmarshal([]byte(data), &m.Message)
+	if err != nil {
+		return OutboxMessage{}, err
+	}
+	return m, nil
+}
+
+// digestItemColumns are the columns scanned by scanDigestItem
+const digestItemColumns = `id, domain_id, domain, recipient, mode, kind,
+sender, page_url, author, email, content, status, timestamp`
+
+func scanDigestItem(row rowScanner) (DigestItem, error) {
+	item := DigestItem{}
+	err := row.Scan(&item.ID, &item.DomainID, &item.Domain, &item.Recipient,
+		&item.Mode, &item.Kind, &item.Sender, &item.PageURL, &item.Author,
+		&item.Email, &item.Content, &item.Status, &item.Timestamp)
+	if err != nil {
+		return DigestItem{}, err
+	}
+	return item, nil
+}
+
+func insertComment(ex sqlExecer, comment *Comment) error {
+	raw_query := `
+insert into comments (client_id, domain_id, name, content, page_url, timestamp,
+                      parent_id, status, hidden, email, website)
+values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	row, err := ex.Exec(raw_query, comment.ClientID, comment.DomainID,
+		comment.Author, comment.Content, comment.PageURL, comment.Timestamp,
+		nullableID(comment.ParentID), comment.Status, comment.Hidden,
+		comment.Email, comment.Website)
+	if err != nil {
+		return err
+	}
+	id, err := row.LastInsertId()
+	if err != nil {
+		return err
+	}
+	comment.ID = id
+	return nil
+}
+
+// nullableID returns nil for zero ids so they are stored as null
+func nullableID(id int64) any {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// ftsQuery converts a search string to a fts query. Each term is
+// quoted so the user input is never parsed as fts syntax. A term ending
+// with * is a prefix search.
+func ftsQuery(q string) string {
+	terms := make([]string, 0)
+	for _, term := range strings.Fields(q) {
+		prefix := strings.HasSuffix(term, "*")
+		term = strings.TrimRight(term, "*")
+		if term == "" {
+			continue
+		}
+		term = `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+		if prefix {
+			term += "*"
+		}
+		terms = append(terms, term)
+	}
+	return strings.Join(terms, " ")
+}
+
 func insertClient(client *Client) error {
 	raw_query := `insert into clients (name, uuid, key) values (?, ?, ?)`
 	stmt, err := DB.Prepare(raw_query)
@@ -18,10 +18,15 @@
 package parlante
 
 import (
+	"context"
+	"fmt"
 	"os"
+	"reflect"
+	"slices"
 	"testing"
+	"time"
 
-	_ "github.com/golang-migrate/migrate/v4/database/sqlite3"
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 )
 
@@ -55,6 +60,20 @@ func TestClient(t *testing.T) {
 		t.Fatalf("bad id for get client by uuid")
 	}
 
+	err = s.SetClientAkismet(c, "https://akismet.bla", "the-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c3, err := s.GetClientByID(c.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c3.UUID != c.UUID || !c3.UsesAkismet() ||
+		c3.AkismetEndpoint != "https://akismet.bla" ||
+		c3.AkismetKey != "the-key" {
+		t.Fatalf("bad client by id %v", c3)
+	}
+
 	clients, _ := s.ListClients()
 
 	if len(clients) != 1 {
@@ -130,6 +149,146 @@ func TestClientDomain(t *testing.T) {
 
 }
 
+func TestRateLimits(t *testing.T) {
+	err := setupTestDB()
+	defer os.Remove(DBFILE)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cs := ClientStorageSQLite{}
+	cds := ClientDomainStorageSQLite{}
+	c, _, _ := cs.CreateClient("the test client")
+	d, _ := cds.AddClientDomain(c, "mydomain.net")
+
+	limit, err := cds.GetClientRateLimit(c, RateLimitComment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !limit.IsZero() {
+		t.Fatalf("bad client limit without limit %v", limit)
+	}
+
+	clientLimit := RateLimit{Requests: 10, Period: time.Hour}
+	domainLimit := RateLimit{Requests: 3, Period: time.Minute}
+	err = cds.SetClientRateLimit(c, RateLimitComment, clientLimit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cds.SetDomainRateLimit(d, RateLimitComment, domainLimit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// changing an existing limit
+	err = cds.SetClientRateLimit(c, RateLimitComment, clientLimit)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	limit, _ = cds.GetClientRateLimit(c, RateLimitComment)
+	if limit != clientLimit {
+		t.Fatalf("bad client limit %v", limit)
+	}
+	limit, _ = cds.GetDomainRateLimit(d, RateLimitComment)
+	if limit != domainLimit {
+		t.Fatalf("bad domain limit %v", limit)
+	}
+	limit, _ = cds.GetDomainRateLimit(d, RateLimitPingMe)
+	if !limit.IsZero() {
+		t.Fatalf("bad limit for other route %v", limit)
+	}
+
+	err = cds.SetDomainRateLimit(d, RateLimitComment, RateLimit{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	limit, _ = cds.GetDomainRateLimit(d, RateLimitComment)
+	if !limit.IsZero() {
+		t.Fatalf("domain limit not removed %v", limit)
+	}
+}
+
+func TestNotificationSettingsStorage(t *testing.T) {
+	err := setupTestDB()
+	defer os.Remove(DBFILE)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cs := ClientStorageSQLite{}
+	cds := ClientDomainStorageSQLite{}
+	c, _, _ := cs.CreateClient("the test client")
+	d, _ := cds.AddClientDomain(c, "mydomain.net")
+
+	n, err := cds.GetClientNotifications(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !n.IsZero() {
+		t.Fatalf("bad client notifications without settings %+v", n)
+	}
+
+	clientN := NotificationSettings{
+		Sender:     "blog@mydomain.net",
+		Recipients: []string{"me@mydomain.net", "you@mydomain.net"},
+		Modes:      map[string]NotificationMode{"you@mydomain.net": NotifyHourly},
+	}
+	domainN := NotificationSettings{Recipients: []string{"other@mydomain.net"}}
+	err = cds.SetClientNotifications(c, NotificationSettings{Sender: "x@x.net"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// changing existing settings
+	err = cds.SetClientNotifications(c, clientN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cds.SetDomainNotifications(d, domainN)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, _ = cds.GetClientNotifications(c)
+	if n.Sender != clientN.Sender ||
+		!slices.Equal(n.Recipients, clientN.Recipients) ||
+		n.Mode("you@mydomain.net") != NotifyHourly {
+		t.Fatalf("bad client notifications %+v", n)
+	}
+	n, _ = cds.GetDomainNotifications(d)
+	if n.Sender != "" || !slices.Equal(n.Recipients, domainN.Recipients) {
+		t.Fatalf("bad domain notifications %+v", n)
+	}
+
+	err = cds.SetDomainNotifications(d, NotificationSettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, _ = cds.GetDomainNotifications(d)
+	if !n.IsZero() {
+		t.Fatalf("domain notifications not removed %+v", n)
+	}
+}
+
+func TestGetSecretKey(t *testing.T) {
+	err := setupTestDB()
+	defer os.Remove(DBFILE)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := GetSecretKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(key) != 32 {
+		t.Fatalf("bad secret key %s", key)
+	}
+	other, _ := GetSecretKey()
+	if other != key {
+		t.Fatalf("secret key changed %s", other)
+	}
+}
+
 func TestComments(t *testing.T) {
 
 	err := setupTestDB()
@@ -194,47 +353,1235 @@ func TestComments(t *testing.T) {
 
 }
 
-func TestCommentCount_NoURLs(t *testing.T) {
+func TestCommentReplies(t *testing.T) {
+	err := setupTestDB()
+	defer os.Remove(DBFILE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs := ClientStorageSQLite{}
+	cds := ClientDomainStorageSQLite{}
 	comms := CommentStorageSQLite{}
-	_, err := comms.CountComments()
+	c, _, _ := cs.CreateClient("the test client")
+	d, _ := cds.AddClientDomain(c, "bla.net")
+	url := "http://bla.net/post"
+
+	root, _ := comms.CreateComment(c, d, "zé", "some comment", url)
+	other, _ := comms.CreateComment(c, d, "tião", "other comment", url)
+	reply, err := comms.CreateReply(c, d, root, "jão", "a reply", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = comms.CreateReply(c, d, reply, "zé", "a reply to reply", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = comms.CreateReply(c, d, root, "jão", "a reply", url+"2")
 	if err == nil {
-		t.Fatalf("No error for no urls on comment count")
+		t.Fatalf("no error for reply in other page")
+	}
+
+	r, err := comms.GetCommentByID(reply.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.ParentID != root.ID {
+		t.Fatalf("bad parent id %d", r.ParentID)
+	}
+
+	direct, err := comms.ListComments(CommentsFilter{ParentID: &root.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(direct) != 1 {
+		t.Fatalf("bad len for direct replies %d", len(direct))
+	}
+
+	thread, err := comms.ListComments(CommentsFilter{ThreadID: &root.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(thread) != 3 {
+		t.Fatalf("bad len for thread %d", len(thread))
+	}
+
+	thread, err = comms.ListComments(CommentsFilter{ThreadID: &other.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(thread) != 1 {
+		t.Fatalf("bad len for other thread %d", len(thread))
 	}
 }
 
-func TestCommentCount(t *testing.T) {
+func TestDomainMarkdown(t *testing.T) {
+	err := setupTestDB()
+	defer os.Remove(DBFILE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs := ClientStorageSQLite{}
+	cds := ClientDomainStorageSQLite{}
+	c, _, _ := cs.CreateClient("the test client")
+	d, _ := cds.AddClientDomain(c, "bla.net")
+	if d.Markdown {
+		t.Fatalf("markdown on by default")
+	}
+
+	err = cds.SetDomainMarkdown(d, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, _ = cds.GetClientDomain(c, "bla.net")
+	if !d.Markdown {
+		t.Fatalf("domain markdown not set")
+	}
+	domains, _ := cds.ListDomains()
+	if len(domains) != 1 || !domains[0].Markdown {
+		t.Fatalf("bad domains markdown %+v", domains)
+	}
+}
+
+func TestDomainOrder(t *testing.T) {
 	err := setupTestDB()
 	defer os.Remove(DBFILE)
 	if err != nil {
 		t.Fatal(err)
 	}
+	cs := ClientStorageSQLite{}
+	cds := ClientDomainStorageSQLite{}
+	c, _, _ := cs.CreateClient("the test client")
+	d, _ := cds.AddClientDomain(c, "bla.net")
+	if d.Order != "" {
+		t.Fatalf("bad default order %s", d.Order)
+	}
+
+	err = cds.SetDomainOrder(d, OrderNewest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, _ = cds.GetClientDomain(c, "bla.net")
+	if d.Order != OrderNewest {
+		t.Fatalf("domain order not set %s", d.Order)
+	}
+	domains, _ := cds.ListDomains()
+	if len(domains) != 1 || domains[0].Order != OrderNewest {
+		t.Fatalf("bad domains order %+v", domains)
+	}
+}
 
+func TestCommentModeration(t *testing.T) {
+	err := setupTestDB()
+	defer os.Remove(DBFILE)
+	if err != nil {
+		t.Fatal(err)
+	}
 	cs := ClientStorageSQLite{}
 	cds := ClientDomainStorageSQLite{}
 	comms := CommentStorageSQLite{}
 	c, _, _ := cs.CreateClient("the test client")
 	d, _ := cds.AddClientDomain(c, "bla.net")
+	url := "http://bla.net/post"
 
-	urls := []string{"http://bla.net/count-1", "http://bla.net/count-2", "http://bla.net/count-3"}
-	for _, url := range urls[:2] {
-		_, err := comms.CreateComment(c, d, "zé", "blabla", url)
-		if err != nil {
-			t.Fatalf("error creating comment %s", err.Error())
-		}
+	err = cds.SetDomainModeration(d, true)
+	if err != nil {
+		t.Fatal(err)
 	}
-	count, err := comms.CountComments(urls...)
+	d, _ = cds.GetClientDomain(c, "bla.net")
+	if !d.Moderate {
+		t.Fatalf("domain moderation not set")
+	}
+
+	pending, _ := comms.CreateComment(c, d, "zé", "some comment", url)
+	spam, _ := comms.CreateComment(c, d, "bot", "buy stuff", url)
+
+	status := CommentApproved
+	approved, err := comms.ListComments(CommentsFilter{Status: &status})
 	if err != nil {
-		t.Fatalf("error comment count! %s", err.Error())
+		t.Fatal(err)
+	}
+	if len(approved) != 0 {
+		t.Fatalf("bad len for approved before moderation %d", len(approved))
 	}
 
-	if len(count) != 3 {
-		t.Fatalf("bad len for comment count %d", len(count))
+	err = comms.SetCommentStatus(pending, CommentApproved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = comms.SetCommentStatus(spam, CommentSpam)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = comms.SetCommentStatus(spam, CommentPending)
+	if err == nil {
+		t.Fatalf("no error changing comment back to pending")
+	}
+
+	approved, _ = comms.ListComments(CommentsFilter{Status: &status})
+	if len(approved) != 1 || approved[0].ID != pending.ID {
+		t.Fatalf("bad approved comments %v", approved)
+	}
+
+	hidden := true
+	hiddenComms, _ := comms.ListComments(CommentsFilter{Hidden: &hidden})
+	if len(hiddenComms) != 1 || hiddenComms[0].Status != CommentSpam {
+		t.Fatalf("bad hidden comments %v", hiddenComms)
+	}
+
+	count, err := comms.CountComments(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count[0].Count != 1 {
+		t.Fatalf("bad count with moderation %d", count[0].Count)
 	}
 }
 
-func setupTestDB() error {
-	SetupDB(DBFILE)
-	err := MigrateDB(DBFILE)
+func TestCommentsPagination(t *testing.T) {
+	err := setupTestDB()
+	defer os.Remove(DBFILE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs := ClientStorageSQLite{}
+	cds := ClientDomainStorageSQLite{}
+	comms := CommentStorageSQLite{}
+	c, _, _ := cs.CreateClient("the test client")
+	d, _ := cds.AddClientDomain(c, "bla.net")
+	url := "http://bla.net/post"
 
-	return err
+	for range 5 {
+		comms.CreateComment(c, d, "zé", "some comment", url)
+	}
+
+	first, err := comms.ListComments(CommentsFilter{Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first) != 2 {
+		t.Fatalf("bad len for first page %d", len(first))
+	}
+
+	cursor := NewCommentCursor(first[1], OrderOldest)
+	rest, err := comms.ListComments(CommentsFilter{Cursor: &cursor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rest) != 3 {
+		t.Fatalf("bad len for rest %d", len(rest))
+	}
+	if rest[0].ID != first[1].ID+1 {
+		t.Fatalf("bad first comment after cursor %d", rest[0].ID)
+	}
+}
+
+func TestListComments_Orders(t *testing.T) {
+	err := setupTestDB()
+	defer os.Remove(DBFILE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs := ClientStorageSQLite{}
+	cds := ClientDomainStorageSQLite{}
+	comms := CommentStorageSQLite{}
+	c, _, _ := cs.CreateClient("the test client")
+	d, _ := cds.AddClientDomain(c, "bla.net")
+
+	authors := []string{"zé", "Ana", "bob", "ana", "Carl"}
+	for i, author := range authors {
+		url := fmt.Sprintf("http://bla.net/post-%d", i%2)
+		comment, _ := comms.CreateComment(c, d, author, "some comment", url)
+		if i%3 == 0 {
+			comms.SetCommentStatus(comment, CommentRejected)
+		}
+	}
+	comments, _ := comms.ListComments(CommentsFilter{})
+	comms.ToggleReaction(comments[3], "👍", "someone")
+
+	for _, order := range CommentOrders {
+		t.Run(string(order), func(t *testing.T) {
+			all, err := comms.ListComments(CommentsFilter{Order: order})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(all) != len(authors) {
+				t.Fatalf("bad len %d", len(all))
+			}
+			for i := 1; i < len(all); i++ {
+				cursor := NewCommentCursor(all[i], order)
+				if !cursor.IsBeforeInOrder(all[i-1], order) {
+					t.Fatalf("bad order %+v %+v", all[i-1], all[i])
+				}
+			}
+
+			paged := make([]Comment, 0)
+			filter := CommentsFilter{Order: order, Limit: 2}
+			for range len(all) {
+				page, err := comms.ListComments(filter)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(page) == 0 {
+					break
+				}
+				paged = append(paged, page...)
+				cursor := NewCommentCursor(page[len(page)-1], order)
+				filter.Cursor = &cursor
+			}
+			if len(paged) != len(all) {
+				t.Fatalf("bad len for pages %d", len(paged))
+			}
+			for i := range all {
+				if paged[i].ID != all[i].ID {
+					t.Fatalf("bad page order %+v", paged)
+				}
+			}
+		})
+	}
+}
+
+func TestCommentReactions(t *testing.T) {
+	err := setupTestDB()
+	defer os.Remove(DBFILE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs := ClientStorageSQLite{}
+	cds := ClientDomainStorageSQLite{}
+	comms := CommentStorageSQLite{}
+	c, _, _ := cs.CreateClient("the test client")
+	d, _ := cds.AddClientDomain(c, "bla.net")
+	url := "http://bla.net/post"
+
+	comments := make([]Comment, 0)
+	for range 4 {
+		comment, _ := comms.CreateComment(c, d, "zé", "some comment", url)
+		comments = append(comments, comment)
+	}
+
+	var tests = []struct {
+		comment  Comment
+		reaction string
+		reactor  string
+		added    bool
+	}{
+		{comments[2], "👍", "a", true},
+		{comments[2], "👍", "b", true},
+		{comments[2], "❤️", "a", true},
+		{comments[1], "👍", "a", true},
+		{comments[3], "👍", "a", true},
+		// twice removes it
+		{comments[3], "👍", "a", false},
+	}
+	for _, test := range tests {
+		added, err := comms.ToggleReaction(test.comment, test.reaction, test.reactor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if added != test.added {
+			t.Fatalf("bad added for %+v", test)
+		}
+	}
+
+	counts, err := comms.CountReactions(
+		comments[0].ID, comments[1].ID, comments[2].ID, comments[3].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counts) != 4 || len(counts[comments[0].ID]) != 0 ||
+		len(counts[comments[3].ID]) != 0 {
+		t.Fatalf("bad counts %+v", counts)
+	}
+	if counts[comments[2].ID]["👍"] != 2 || counts[comments[2].ID]["❤️"] != 1 ||
+		counts[comments[1].ID]["👍"] != 1 {
+		t.Fatalf("bad counts %+v", counts)
+	}
+
+	comment, _ := comms.GetCommentByID(comments[2].ID)
+	if comment.Score != 3 {
+		t.Fatalf("bad score %d", comment.Score)
+	}
+
+	first, err := comms.ListComments(CommentsFilter{Limit: 2, Order: OrderScore})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first) != 2 || first[0].ID != comments[2].ID ||
+		first[1].ID != comments[1].ID {
+		t.Fatalf("bad first page by score %+v", first)
+	}
+	cursor := NewCommentCursor(first[1], OrderScore)
+	rest, err := comms.ListComments(
+		CommentsFilter{Cursor: &cursor, Order: OrderScore})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rest) != 2 || rest[0].ID != comments[0].ID ||
+		rest[1].ID != comments[3].ID {
+		t.Fatalf("bad rest by score %+v", rest)
+	}
+}
+
+func TestImportComment(t *testing.T) {
+	err := setupTestDB()
+	defer os.Remove(DBFILE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs := ClientStorageSQLite{}
+	cds := ClientDomainStorageSQLite{}
+	comms := CommentStorageSQLite{}
+	c, _, _ := cs.CreateClient("the test client")
+	d, _ := cds.AddClientDomain(c, "bla.net")
+	other, _ := cds.AddClientDomain(c, "other.net")
+
+	comment, _ := NewComment(c, d, "zé", "old comment", "http://bla.net/post")
+	comment.Timestamp = 1000
+	comment.Status = CommentPending
+	comment.Hidden = true
+	comment, err = comms.ImportComment(comment, "disqus:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply, _ := NewReply(c, d, comment, "maria", "reply", "http://bla.net/post")
+	reply, err = comms.ImportComment(reply, "disqus:2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = comms.ImportComment(comment, "disqus:1")
+	if err == nil {
+		t.Fatalf("comment imported twice")
+	}
+
+	imported, err := comms.GetImportedComment(d, "disqus:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.ID != comment.ID || imported.Timestamp != 1000 ||
+		imported.Status != CommentPending || !imported.Hidden {
+		t.Fatalf("bad imported comment %+v", imported)
+	}
+	imported, _ = comms.GetImportedComment(d, "disqus:2")
+	if imported.ParentID != comment.ID {
+		t.Fatalf("bad imported reply %+v", imported)
+	}
+	imported, err = comms.GetImportedComment(other, "disqus:1")
+	if err != nil || imported.ID != 0 {
+		t.Fatalf("bad comment for other domain %+v %v", imported, err)
+	}
+
+	comms.RemoveComment(reply)
+	imported, _ = comms.GetImportedComment(d, "disqus:2")
+	if imported.ID != reply.ID {
+		t.Fatalf("removed comment not returned %+v", imported)
+	}
+}
+
+func TestCommentUpdate(t *testing.T) {
+	err := setupTestDB()
+	defer os.Remove(DBFILE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs := ClientStorageSQLite{}
+	cds := ClientDomainStorageSQLite{}
+	comms := CommentStorageSQLite{}
+	c, _, _ := cs.CreateClient("the test client")
+	d, _ := cds.AddClientDomain(c, "bla.net")
+	comment, _ := comms.CreateComment(c, d, "zé", "some coment", "http://bla.net/post")
+
+	revs, err := comms.ListCommentRevisions(comment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revs) != 0 {
+		t.Fatalf("bad len for revisions of new comment %d", len(revs))
+	}
+
+	updated, err := comms.UpdateComment(comment, "some comment", "zé")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = comms.UpdateComment(updated, "", "zé")
+	if err == nil {
+		t.Fatalf("no error updating comment without content")
+	}
+	_, err = comms.UpdateComment(updated, "[redacted]", "moderator")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	current, _ := comms.GetCommentByID(comment.ID)
+	if current.Content != "[redacted]" || !current.Edited() {
+		t.Fatalf("comment not updated %v", current)
+	}
+
+	revs, err = comms.ListCommentRevisions(comment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revs) != 2 {
+		t.Fatalf("bad len for revisions %d", len(revs))
+	}
+	if revs[0].Content != "some comment" || revs[0].Editor != "moderator" {
+		t.Fatalf("bad last revision %v", revs[0])
+	}
+	if revs[1].Content != "some coment" || revs[1].Editor != "zé" {
+		t.Fatalf("bad first revision %v", revs[1])
+	}
+
+	found, _ := comms.SearchComments("redacted", CommentsFilter{})
+	if len(found) != 1 {
+		t.Fatalf("search index not updated %d", len(found))
+	}
+}
+
+func TestCommentsSearch(t *testing.T) {
+	err := setupTestDB()
+	defer os.Remove(DBFILE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs := ClientStorageSQLite{}
+	cds := ClientDomainStorageSQLite{}
+	comms := CommentStorageSQLite{}
+	c, _, _ := cs.CreateClient("the test client")
+	d, _ := cds.AddClientDomain(c, "bla.net")
+	other, _, _ := cs.CreateClient("other client")
+	od, _ := cds.AddClientDomain(other, "ble.net")
+
+	comms.CreateComment(c, d, "zé", "some comment about go", "http://bla.net/post")
+	comms.CreateComment(c, d, "jão", "say \"hello\" to python", "http://bla.net/post2")
+	spam, _ := comms.CreateComment(c, d, "bot", "buy stuff about go", "http://bla.net/post")
+	comms.CreateComment(other, od, "zé", "other comment about go", "http://ble.net/post")
+	comms.SetCommentStatus(spam, CommentSpam)
+
+	status := CommentApproved
+	url := "http://bla.net/post2"
+	var tests = []struct {
+		testName string
+		query    string
+		filter   CommentsFilter
+		count    int
+		hasError bool
+	}{
+		{"search empty query", "  ", CommentsFilter{}, 0, true},
+		{"search content", "go", CommentsFilter{}, 3, false},
+		{"search many terms", "comment go", CommentsFilter{}, 2, false},
+		{"search author", "jão", CommentsFilter{}, 1, false},
+		{"search prefix", "pyth*", CommentsFilter{}, 1, false},
+		{"search quotes", "\"hello", CommentsFilter{}, 1, false},
+		{"search operators", "go OR NOT", CommentsFilter{}, 0, false},
+		{"search client", "go", CommentsFilter{ClientID: &c.ID}, 2, false},
+		{"search status", "go", CommentsFilter{Status: &status}, 2, false},
+		{"search page", "hello", CommentsFilter{PageURL: &url}, 1, false},
+		{"search limit", "go", CommentsFilter{Limit: 1}, 1, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			r, err := comms.SearchComments(test.query, test.filter)
+			if err == nil && test.hasError {
+				t.Fatalf("No error")
+			}
+
+			if err != nil && !test.hasError {
+				t.Fatalf("Error! %s", err.Error())
+			}
+			if len(r) != test.count {
+				t.Fatalf("bad len for search %d", len(r))
+			}
+		})
+	}
+}
+
+func TestTrash(t *testing.T) {
+	err := setupTestDB()
+	defer os.Remove(DBFILE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs := ClientStorageSQLite{}
+	cds := ClientDomainStorageSQLite{}
+	comms := CommentStorageSQLite{}
+	ts := TrashStorageSQLite{}
+	c, _, _ := cs.CreateClient("the test client")
+	d, _ := cds.AddClientDomain(c, "bla.net")
+	url := "http://bla.net/post"
+	comment, _ := comms.CreateComment(c, d, "zé", "some comment", url)
+	comms.UpdateComment(comment, "some edited comment", "zé")
+	other, _ := comms.CreateComment(c, d, "jão", "other comment", url)
+
+	comms.RemoveComment(comment)
+	comms.RemoveComment(other)
+	cds.RemoveClientDomain(c, d.Domain)
+	cs.RemoveClient(c.UUID)
+
+	_, err = cs.GetClientByUUID(c.UUID)
+	if err == nil {
+		t.Fatalf("removed client returned")
+	}
+	count, _ := comms.CountComments(url)
+	if count[0].Count != 0 {
+		t.Fatalf("removed comments counted %d", count[0].Count)
+	}
+
+	items, err := ts.ListTrash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 4 {
+		t.Fatalf("bad len for trash %d", len(items))
+	}
+
+	restore := map[TrashItemType]TrashItem{}
+	for _, item := range items {
+		restore[item.Type] = item
+	}
+	for _, typ := range []TrashItemType{TrashClient, TrashDomain, TrashComment} {
+		err = ts.RestoreItem(restore[typ])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = ts.RestoreItem(TrashItem{Type: "bad", ID: 1})
+	if err == nil {
+		t.Fatalf("no error restoring bad item type")
+	}
+
+	if _, err := cs.GetClientByUUID(c.UUID); err != nil {
+		t.Fatalf("client not restored %s", err.Error())
+	}
+	if d, _ := cds.GetClientDomain(c, d.Domain); d.ID == 0 {
+		t.Fatalf("domain not restored")
+	}
+	restored, _ := comms.GetCommentByID(restore[TrashComment].ID)
+	if restored.ID == 0 {
+		t.Fatalf("comment not restored")
+	}
+
+	items, _ = ts.ListTrash()
+	if len(items) != 1 {
+		t.Fatalf("bad len for trash after restore %d", len(items))
+	}
+	err = ts.PurgeItem(items[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, _ = ts.ListTrash()
+	if len(items) != 0 {
+		t.Fatalf("item not purged %d", len(items))
+	}
+
+	comms.RemoveComment(restored)
+	n, err := ts.PurgeOlderThan(restored.Timestamp - 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Fatalf("new items purged %d", n)
+	}
+	n, err = ts.PurgeOlderThan(time.Now().Unix() + 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("old items not purged %d", n)
+	}
+	revs, _ := comms.ListCommentRevisions(restored)
+	if len(revs) != 0 {
+		t.Fatalf("revisions of purged comment not removed %d", len(revs))
+	}
+}
+
+func TestCascadeRemoval(t *testing.T) {
+	err := setupTestDB()
+	defer os.Remove(DBFILE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs := ClientStorageSQLite{}
+	cds := ClientDomainStorageSQLite{}
+	comms := CommentStorageSQLite{}
+	ts := TrashStorageSQLite{}
+	c, _, _ := cs.CreateClient("the test client")
+	d, _ := cds.AddClientDomain(c, "bla.net")
+	other, _, _ := cs.CreateClient("other client")
+	od, _ := cds.AddClientDomain(other, "ble.net")
+	url := "http://bla.net/post"
+	comment, _ := comms.CreateComment(c, d, "zé", "some comment", url)
+	comms.UpdateComment(comment, "some edited comment", "zé")
+	comms.CreateComment(other, od, "zé", "other comment", "http://ble.net/post")
+
+	_, err = comms.CreateComment(Client{ID: 999}, d, "zé", "bad comment", url)
+	if err == nil {
+		t.Fatalf("no error creating comment for unknown client")
+	}
+
+	cs.RemoveClient(c.UUID)
+	all, _ := comms.ListComments(CommentsFilter{})
+	if len(all) != 1 {
+		t.Fatalf("comments of removed client listed %d", len(all))
+	}
+
+	items, _ := ts.ListTrash()
+	err = ts.PurgeItem(items[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var count int
+	DB.QueryRow("select count(*) from client_domains").Scan(&count)
+	if count != 1 {
+		t.Fatalf("domains not removed with client %d", count)
+	}
+	DB.QueryRow("select count(*) from comments").Scan(&count)
+	if count != 1 {
+		t.Fatalf("comments not removed with client %d", count)
+	}
+	DB.QueryRow("select count(*) from comment_revisions").Scan(&count)
+	if count != 0 {
+		t.Fatalf("revisions not removed with client %d", count)
+	}
+}
+
+func TestRepairDB(t *testing.T) {
+	err := setupTestDB()
+	defer os.Remove(DBFILE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs := ClientStorageSQLite{}
+	cds := ClientDomainStorageSQLite{}
+	comms := CommentStorageSQLite{}
+	c, _, _ := cs.CreateClient("the test client")
+	d, _ := cds.AddClientDomain(c, "bla.net")
+	other, _, _ := cs.CreateClient("other client")
+	od, _ := cds.AddClientDomain(other, "ble.net")
+	url := "http://bla.net/post"
+	comment, _ := comms.CreateComment(c, d, "zé", "some comment", url)
+	comms.UpdateComment(comment, "some edited comment", "zé")
+	comms.CreateComment(c, d, "jão", "other comment", url)
+	parent, _ := comms.CreateComment(other, od, "zé", "parent", "http://ble.net/a")
+	comms.CreateReply(other, od, parent, "jão", "reply", "http://ble.net/a")
+
+	// orphans made like the removals did before the foreign keys
+	conn, _ := DB.Conn(context.Background())
+	for _, q := range []string{
+		"pragma foreign_keys = off",
+		"delete from clients where id = " + fmt.Sprint(c.ID),
+		"delete from comments where id = " + fmt.Sprint(parent.ID),
+		"pragma foreign_keys = on",
+	} {
+		_, err := conn.ExecContext(context.Background(), q)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	conn.Close()
+
+	expected := RepairReport{Domains: 1, Comments: 2, Replies: 1, Revisions: 1}
+	report, err := RepairDB(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report != expected {
+		t.Fatalf("bad report for dry run %+v", report)
+	}
+
+	report, err = RepairDB(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report != expected {
+		t.Fatalf("bad report for repair %+v", report)
+	}
+
+	report, _ = RepairDB(false)
+	if report != (RepairReport{}) {
+		t.Fatalf("orphans after repair %+v", report)
+	}
+	var violations int
+	DB.QueryRow("select count(*) from pragma_foreign_key_check").Scan(&violations)
+	if violations != 0 {
+		t.Fatalf("foreign key violations after repair %d", violations)
+	}
+}
+
+func TestCommentCount_NoURLs(t *testing.T) {
+	comms := CommentStorageSQLite{}
+	_, err := comms.CountComments()
+	if err == nil {
+		t.Fatalf("No error for no urls on comment count")
+	}
+}
+
+func TestCommentCount(t *testing.T) {
+	err := setupTestDB()
+	defer os.Remove(DBFILE)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cs := ClientStorageSQLite{}
+	cds := ClientDomainStorageSQLite{}
+	comms := CommentStorageSQLite{}
+	c, _, _ := cs.CreateClient("the test client")
+	d, _ := cds.AddClientDomain(c, "bla.net")
+
+	urls := []string{"http://bla.net/count-1", "http://bla.net/count-2", "http://bla.net/count-3"}
+	for _, url := range urls[:2] {
+		_, err := comms.CreateComment(c, d, "zé", "blabla", url)
+		if err != nil {
+			t.Fatalf("error creating comment %s", err.Error())
+		}
+	}
+	count, err := comms.CountComments(urls...)
+	if err != nil {
+		t.Fatalf("error comment count! %s", err.Error())
+	}
+
+	if len(count) != 3 {
+		t.Fatalf("bad len for comment count %d", len(count))
+	}
+}
+
+func TestCommentCount_RemovedDomain(t *testing.T) {
+	err := setupTestDB()
+	defer os.Remove(DBFILE)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cs := ClientStorageSQLite{}
+	cds := ClientDomainStorageSQLite{}
+	comms := CommentStorageSQLite{}
+	c, _, _ := cs.CreateClient("the test client")
+	d, _ := cds.AddClientDomain(c, "bla.net")
+	other, _, _ := cs.CreateClient("other client")
+	od, _ := cds.AddClientDomain(other, "ble.net")
+
+	url := "http://bla.net/post"
+	otherURL := "http://ble.net/post"
+	comms.CreateComment(c, d, "zé", "blabla", url)
+	comms.CreateComment(other, od, "zé", "blabla", otherURL)
+
+	err = cds.RemoveClientDomain(c, d.Domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cs.RemoveClient(other.UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count, err := comms.CountComments(url, otherURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, cc := range count {
+		if cc.Count != 0 {
+			t.Fatalf("bad count for removed domain %+v", cc)
+		}
+	}
+}
+
+func TestRemoveAndAddAgain(t *testing.T) {
+	err := setupTestDB()
+	defer os.Remove(DBFILE)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cs := ClientStorageSQLite{}
+	cds := ClientDomainStorageSQLite{}
+	c, _, _ := cs.CreateClient("the test client")
+	d, _ := cds.AddClientDomain(c, "bla.net")
+
+	_, err = cds.AddClientDomain(c, "bla.net")
+	if err == nil {
+		t.Fatalf("live domain added twice")
+	}
+	err = cds.RemoveClientDomain(c, d.Domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := cds.AddClientDomain(c, "bla.net")
+	if err != nil || again.ID == d.ID {
+		t.Fatalf("bad domain added again %+v %v", again, err)
+	}
+	got, err := cds.GetClientDomain(c, "bla.net")
+	if err != nil || got.ID != again.ID {
+		t.Fatalf("bad domain after adding again %+v %v", got, err)
+	}
+
+	_, _, err = cs.CreateClient("the test client")
+	if err == nil {
+		t.Fatalf("live client created twice")
+	}
+	err = cs.RemoveClient(c.UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _, err := cs.CreateClient("the test client")
+	if err != nil || other.ID == c.ID {
+		t.Fatalf("bad client created again %+v %v", other, err)
+	}
+}
+
+func setupTestDB() error {
+	SetupDB(DBFILE)
+	err := MigrateDB(DBFILE)
+
+	return err
+}
+
+func TestSpamModelSQLite(t *testing.T) {
+	err := setupTestDB()
+	defer os.Remove(DBFILE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs := ClientStorageSQLite{}
+	cds := ClientDomainStorageSQLite{}
+	comms := CommentStorageSQLite{}
+	c, _, _ := cs.CreateClient("the test client")
+	d, _ := cds.AddClientDomain(c, "bla.net")
+	spam, _ := comms.CreateComment(c, d, "bot", "cheap pills", "http://bla.net/post")
+	ham, _ := comms.CreateComment(c, d, "zé", "nice post", "http://bla.net/post")
+
+	err = comms.TrainSpamModel(spam, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = comms.TrainSpamModel(ham, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// training again with another label untrains the comment first
+	err = comms.TrainSpamModel(ham, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokens := []string{"pills", "post", "author:bot", "unknown"}
+	model, err := comms.GetSpamModel(tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if model.SpamComments != 1 || model.HamComments != 1 {
+		t.Fatalf("bad comment counts %d %d", model.SpamComments,
+			model.HamComments)
+	}
+	if model.Tokens["pills"] != (SpamTokenCount{Spam: 1}) ||
+		model.Tokens["post"] != (SpamTokenCount{Ham: 1}) ||
+		model.Tokens["author:bot"] != (SpamTokenCount{Spam: 1}) {
+		t.Fatalf("bad token counts %v", model.Tokens)
+	}
+	if _, ok := model.Tokens["unknown"]; ok {
+		t.Fatalf("unknown token in model")
+	}
+
+	err = comms.ResetSpamModel()
+	if err != nil {
+		t.Fatal(err)
+	}
+	model, err = comms.GetSpamModel(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if model.SpamComments != 0 || model.HamComments != 0 {
+		t.Fatalf("model not reset %v", model)
+	}
+}
+
+func TestCommentRequestInfo(t *testing.T) {
+	err := setupTestDB()
+	defer os.Remove(DBFILE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs := ClientStorageSQLite{}
+	cds := ClientDomainStorageSQLite{}
+	comms := CommentStorageSQLite{}
+	c, _, _ := cs.CreateClient("the test client")
+	d, _ := cds.AddClientDomain(c, "bla.net")
+	comment, _ := comms.CreateComment(c, d, "zé", "a comment", "http://bla.net/post")
+
+	info, err := comms.GetCommentRequestInfo(comment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info != (CommentRequestInfo{}) {
+		t.Fatalf("bad info for comment without info %v", info)
+	}
+
+	expected := CommentRequestInfo{
+		UserIP: "1.2.3.4", UserAgent: "the-agent", Referrer: "http://bla.net/"}
+	err = comms.SetCommentRequestInfo(comment, expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err = comms.GetCommentRequestInfo(comment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info != expected {
+		t.Fatalf("bad request info %v", info)
+	}
+}
+
+func TestCommentAuthorContact(t *testing.T) {
+	err := setupTestDB()
+	defer os.Remove(DBFILE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs := ClientStorageSQLite{}
+	cds := ClientDomainStorageSQLite{}
+	comms := CommentStorageSQLite{}
+	c, _, _ := cs.CreateClient("the test client")
+	d, _ := cds.AddClientDomain(c, "bla.net")
+	comment, _ := comms.CreateComment(c, d, "zé", "a comment", "http://bla.net/post")
+
+	err = comms.SetCommentAuthorContact(comment, "ze@bla.net", "https://ze.net")
+	if err != nil {
+		t.Fatal(err)
+	}
+	comment, err = comms.GetCommentByID(comment.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if comment.Email != "ze@bla.net" || comment.Website != "https://ze.net" {
+		t.Fatalf("bad author contact %s %s", comment.Email, comment.Website)
+	}
+}
+
+func TestSubscriptions(t *testing.T) {
+	err := setupTestDB()
+	defer os.Remove(DBFILE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs := ClientStorageSQLite{}
+	cds := ClientDomainStorageSQLite{}
+	ss := SubscriptionStorageSQLite{}
+	c, _, _ := cs.CreateClient("the test client")
+	d, _ := cds.AddClientDomain(c, "bla.net")
+	page := "http://bla.net/post"
+
+	sub, err := ss.Subscribe(d, page, "ze@bla.net")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub.ID == 0 || sub.Email != "ze@bla.net" || sub.PageURL != page {
+		t.Fatalf("bad subscription %+v", sub)
+	}
+	again, err := ss.Subscribe(d, page, "ze@bla.net")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.ID != sub.ID {
+		t.Fatalf("bad subscription id %d", again.ID)
+	}
+	maria, _ := ss.Subscribe(d, page, "maria@bla.net")
+	other, _ := ss.Subscribe(d, "http://bla.net/other", "ze@bla.net")
+
+	// not confirmed subscriptions are not listed
+	subs, err := ss.ListSubscriptions(d, page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subs) != 0 {
+		t.Fatalf("bad unconfirmed subscriptions %+v", subs)
+	}
+
+	for _, s := range []Subscription{sub, maria, other} {
+		err = ss.ConfirmSubscription(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	subs, err = ss.ListSubscriptions(d, page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subs) != 2 || subs[0].ID != sub.ID || !subs[0].Confirmed {
+		t.Fatalf("bad subscriptions %+v", subs)
+	}
+
+	// the confirmation is marked as sent only once
+	for i, want := range []bool{true, false} {
+		first, err := ss.MarkConfirmSent(sub)
+		if err != nil || first != want {
+			t.Fatalf("bad mark confirm sent %d: %t %v", i, first, err)
+		}
+	}
+	got, _ := ss.GetSubscription(sub.ID)
+	if got.ConfirmSentAt == 0 {
+		t.Fatalf("bad confirm sent at %+v", got)
+	}
+	sub.ConfirmSentAt = got.ConfirmSentAt
+
+	sub.Confirmed = true
+	got, err = ss.GetSubscription(sub.ID)
+	if err != nil || got != sub {
+		t.Fatalf("bad subscription %+v %v", got, err)
+	}
+
+	err = ss.Unsubscribe(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ss.GetSubscription(sub.ID)
+	if err == nil {
+		t.Fatalf("subscription not removed")
+	}
+	subs, _ = ss.ListSubscriptions(d, page)
+	if len(subs) != 1 {
+		t.Fatalf("bad subscriptions after unsubscribe %+v", subs)
+	}
+}
+
+func TestOutboxStorage(t *testing.T) {
+	err := setupTestDB()
+	defer os.Remove(DBFILE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	obs := OutboxStorageSQLite{}
+	msg := EmailMessage{
+		From:    "blog@bla.net",
+		To:      []string{"me@bla.net"},
+		Subject: "Hi",
+		Body:    "the body",
+		Headers: map[string]string{"List-Unsubscribe": "<http://bla.net/u>"},
+	}
+	m, err := obs.Enqueue(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.ID == 0 || m.Status != OutboxPending || m.NextAttempt == 0 {
+		t.Fatalf("bad outbox message %+v", m)
+	}
+	other, _ := obs.Enqueue(msg)
+
+	due, err := obs.ListDue(m.NextAttempt, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(due) != 2 || due[0].ID != m.ID ||
+		!reflect.DeepEqual(due[0].Message, msg) {
+		t.Fatalf("bad due messages %+v", due)
+	}
+	due, _ = obs.ListDue(m.NextAttempt, 1)
+	if len(due) != 1 {
+		t.Fatalf("bad due messages with limit %+v", due)
+	}
+	due, _ = obs.ListDue(m.NextAttempt-1, 10)
+	if len(due) != 0 {
+		t.Fatalf("bad due messages before next attempt %+v", due)
+	}
+
+	m.Attempts = 3
+	m.Status = OutboxFailed
+	m.LastError = "bad server"
+	err = obs.UpdateMessage(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	due, _ = obs.ListDue(m.NextAttempt, 10)
+	if len(due) != 1 || due[0].ID != other.ID {
+		t.Fatalf("bad due messages after failure %+v", due)
+	}
+	failed, err := obs.ListFailed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 1 || failed[0].Attempts != 3 ||
+		failed[0].LastError != "bad server" {
+		t.Fatalf("bad failed messages %+v", failed)
+	}
+
+	err = obs.RetryMessage(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed, _ = obs.ListFailed()
+	due, _ = obs.ListDue(time.Now().Unix(), 10)
+	if len(failed) != 0 || len(due) != 2 || due[0].Attempts != 0 {
+		t.Fatalf("bad messages after retry %+v %+v", failed, due)
+	}
+
+	err = obs.RemoveMessage(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	due, _ = obs.ListDue(time.Now().Unix(), 10)
+	if len(due) != 1 || due[0].ID != other.ID {
+		t.Fatalf("bad due messages after remove %+v", due)
+	}
+}
+
+func TestDigestStorage(t *testing.T) {
+	err := setupTestDB()
+	defer os.Remove(DBFILE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs := ClientStorageSQLite{}
+	cds := ClientDomainStorageSQLite{}
+	ds := DigestStorageSQLite{}
+	c, _, _ := cs.CreateClient("the test client")
+	d, _ := cds.AddClientDomain(c, "bla.net")
+
+	item := DigestItem{
+		DomainID: d.ID, Domain: d.Domain, Recipient: "me@bla.net",
+		Mode: NotifyDaily, Kind: DigestComment, Sender: "blog@bla.net",
+		PageURL: "https://bla.net/b", Author: "Zé", Content: "a comment",
+		Status: CommentApproved, Timestamp: 20,
+	}
+	first, err := ds.AddDigestItem(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.ID == 0 {
+		t.Fatalf("bad digest item %+v", first)
+	}
+	item.PageURL = "https://bla.net/a"
+	item.Timestamp = 30
+	second, _ := ds.AddDigestItem(item)
+	item.Recipient = "other@bla.net"
+	item.Mode = NotifyHourly
+	ds.AddDigestItem(item)
+
+	items, err := ds.ListDigestItems(NotifyDaily, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0] != second || items[1] != first {
+		t.Fatalf("bad digest items %+v", items)
+	}
+	items, _ = ds.ListDigestItems(NotifyDaily, 30)
+	if len(items) != 1 || items[0].ID != first.ID {
+		t.Fatalf("bad digest items before timestamp %+v", items)
+	}
+
+	err = ds.RemoveDigestItems([]DigestItem{first, second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, _ = ds.ListDigestItems(NotifyDaily, 100)
+	if len(items) != 0 {
+		t.Fatalf("digest items not removed %+v", items)
+	}
+
+	// the items are removed with the domain
+	cds.RemoveClientDomain(c, d.Domain)
+	ts := TrashStorageSQLite{}
+	ts.PurgeOlderThan(time.Now().Unix() + 1)
+	items, _ = ds.ListDigestItems(NotifyHourly, 100)
+	if len(items) != 0 {
+		t.Fatalf("digest items not removed with domain %+v", items)
+	}
 }
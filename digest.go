@@ -0,0 +1,130 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package parlante
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/leonelquinteros/gotext"
+)
+
+// Max time between the checks for digests to send.
+const DIGEST_INTERVAL = time.Minute
+
+// DigestEntry is a comment or message in a digest email.
+type DigestEntry struct {
+	Title     string
+	Timestamp int64
+	Content   string
+}
+
+// DigestPage are the entries of a page in a digest email.
+type DigestPage struct {
+	URL     string
+	Entries []DigestEntry
+}
+
+// DigestDomain are the pages of a domain in a digest email.
+type DigestDomain struct {
+	Domain string
+	Pages  []DigestPage
+}
+
+// DigestCutoff returns the time before which the items of a mode are
+// sent in the digest. Hourly digests are sent after each hour and daily
+// digests after midnight.
+func DigestCutoff(mode NotificationMode, now time.Time) time.Time {
+	switch mode {
+	case NotifyHourly:
+		return now.Truncate(time.Hour)
+	case NotifyDaily:
+		y, m, d := now.Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	}
+	return now
+}
+
+// GroupDigestItems groups the items by domain and page keeping the
+// order of the items.
+func GroupDigestItems(items []DigestItem, loc *gotext.Locale) []DigestDomain {
+	domains := make([]DigestDomain, 0)
+	for _, item := range items {
+		n := len(domains)
+		if n == 0 || domains[n-1].Domain != item.Domain {
+			domains = append(domains, DigestDomain{Domain: item.Domain})
+			n++
+		}
+		d := &domains[n-1]
+		p := len(d.Pages)
+		if p == 0 || d.Pages[p-1].URL != item.PageURL {
+			d.Pages = append(d.Pages, DigestPage{URL: item.PageURL})
+			p++
+		}
+		page := &d.Pages[p-1]
+		page.Entries = append(page.Entries, newDigestEntry(item, loc))
+	}
+	return domains
+}
+
+func newDigestEntry(item DigestItem, loc *gotext.Locale) DigestEntry {
+	data := make(map[string]any)
+	data["name"] = item.Author
+	data["email"] = item.Email
+	data["status"] = item.Status
+	var title string
+	if item.Kind == DigestMessage {
+		title = Tprintf(loc.Get("Message from {{.name}} <{{.email}}>"), data)
+	} else {
+		title = Tprintf(loc.Get("Comment from {{.name}} | {{.status}}"), data)
+	}
+	return DigestEntry{
+		Title: title, Timestamp: item.Timestamp, Content: item.Content}
+}
+
+// NewDigestEmail returns the digest email with the items of a
+// recipient. The items must be ordered by domain and page and all of
+// them are of the same mode.
+func NewDigestEmail(
+	recipient string, items []DigestItem, loc *gotext.Locale) (
+	EmailMessage, error) {
+	if len(items) == 0 {
+		return EmailMessage{}, errors.New("digest without items")
+	}
+	domains := GroupDigestItems(items, loc)
+	names := make([]string, 0, len(domains))
+	for _, d := range domains {
+		names = append(names, d.Domain)
+	}
+	data := make(map[string]any)
+	data["domains"] = strings.Join(names, ", ")
+	data["count"] = len(items)
+	var subject string
+	if items[0].Mode == NotifyDaily {
+		subject = Tprintf(loc.Get("Daily digest for {{.domains}}"), data)
+	} else {
+		subject = Tprintf(loc.Get("Hourly digest for {{.domains}}"), data)
+	}
+	tmplCtx := map[string]any{
+		"intro":   Tprintf(loc.Get("{{.count}} new comments and messages:"), data),
+		"domains": domains,
+	}
+	return NewTemplatedEmail(items[0].Sender, []string{recipient}, subject,
+		"email_digest", loc, tmplCtx)
+}
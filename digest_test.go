@@ -0,0 +1,125 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package parlante
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDigestCutoff(t *testing.T) {
+	now := time.Date(2025, 3, 10, 14, 35, 20, 0, time.UTC)
+	var tests = []struct {
+		mode     NotificationMode
+		expected time.Time
+	}{
+		{NotifyHourly, time.Date(2025, 3, 10, 14, 0, 0, 0, time.UTC)},
+		{NotifyDaily, time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)},
+		{NotifyImmediate, now},
+	}
+
+	for _, test := range tests {
+		cutoff := DigestCutoff(test.mode, now)
+		if !cutoff.Equal(test.expected) {
+			t.Fatalf("bad cutoff for %s %s", test.mode, cutoff)
+		}
+	}
+}
+
+func testDigestItems() []DigestItem {
+	return []DigestItem{
+		{ID: 1, Domain: "bla.net", Recipient: "me@bla.net", Mode: NotifyDaily,
+			Kind: DigestComment, Sender: "blog@bla.net",
+			PageURL: "https://bla.net/a", Author: "Zé", Content: "first\nlines",
+			Status: CommentApproved, Timestamp: 10},
+		{ID: 2, Domain: "bla.net", Recipient: "me@bla.net", Mode: NotifyDaily,
+			Kind: DigestMessage, Sender: "blog@bla.net",
+			PageURL: "https://bla.net/a", Author: "Maria", Email: "maria@bla.net",
+			Content: "a message", Timestamp: 20},
+		{ID: 3, Domain: "bla.net", Recipient: "me@bla.net", Mode: NotifyDaily,
+			Kind: DigestComment, Sender: "blog@bla.net",
+			PageURL: "https://bla.net/b", Author: "Zé", Content: "other",
+			Status: CommentPending, Timestamp: 30},
+		{ID: 4, Domain: "ble.net", Recipient: "me@bla.net", Mode: NotifyDaily,
+			Kind: DigestComment, Sender: "blog@bla.net",
+			PageURL: "https://ble.net/a", Author: "Zé", Content: "ble",
+			Status: CommentApproved, Timestamp: 40},
+	}
+}
+
+func TestGroupDigestItems(t *testing.T) {
+	domains := GroupDigestItems(testDigestItems(), GetLocale("en"))
+
+	if len(domains) != 2 || domains[0].Domain != "bla.net" ||
+		domains[1].Domain != "ble.net" {
+		t.Fatalf("bad domains %+v", domains)
+	}
+	pages := domains[0].Pages
+	if len(pages) != 2 || pages[0].URL != "https://bla.net/a" ||
+		len(pages[0].Entries) != 2 || len(pages[1].Entries) != 1 {
+		t.Fatalf("bad pages %+v", pages)
+	}
+	entry := pages[0].Entries[1]
+	if entry.Title != "Message from Maria <maria@bla.net>" ||
+		entry.Timestamp != 20 {
+		t.Fatalf("bad message entry %+v", entry)
+	}
+	if pages[0].Entries[0].Content != "first\nlines" {
+		t.Fatalf("bad entry content %q", pages[0].Entries[0].Content)
+	}
+}
+
+func TestNewDigestEmail(t *testing.T) {
+	items := testDigestItems()
+	msg, err := NewDigestEmail("me@bla.net", items, GetLocale("en"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.From != "blog@bla.net" || len(msg.To) != 1 ||
+		msg.To[0] != "me@bla.net" ||
+		msg.Subject != "Daily digest for bla.net, ble.net" {
+		t.Fatalf("bad digest email %+v", msg)
+	}
+	for _, s := range []string{"4 new comments and messages:",
+		"https://bla.net/a", "https://ble.net/a",
+		"Comment from Zé | pending", "Message from Maria <maria@bla.net>",
+		"first\n    lines"} {
+		if !strings.Contains(msg.Body, s) {
+			t.Fatalf("missing %q in digest body %s", s, msg.Body)
+		}
+	}
+	if strings.Contains(msg.Body, "&lt;") {
+		t.Fatalf("escaped digest body %s", msg.Body)
+	}
+	if !strings.Contains(msg.HTMLBody, "<h2>bla.net</h2>") ||
+		!strings.Contains(msg.HTMLBody, "Maria &lt;maria@bla.net&gt;") {
+		t.Fatalf("bad digest html body %s", msg.HTMLBody)
+	}
+
+	items[0].Mode = NotifyHourly
+	msg, _ = NewDigestEmail("me@bla.net", items, GetLocale("en"))
+	if msg.Subject != "Hourly digest for bla.net, ble.net" {
+		t.Fatalf("bad hourly subject %s", msg.Subject)
+	}
+
+	_, err = NewDigestEmail("me@bla.net", nil, GetLocale("en"))
+	if err == nil {
+		t.Fatalf("no error for digest without items")
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package parlante
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"time"
+)
+
+type disqusExport struct {
+	Threads []disqusThread `xml:"thread"`
+	Posts   []disqusPost   `xml:"post"`
+}
+
+type disqusThread struct {
+	ID        string `xml:"http://disqus.com/disqus-internals id,attr"`
+	Link      string `xml:"link"`
+	IsDeleted bool   `xml:"isDeleted"`
+}
+
+type disqusRef struct {
+	ID string `xml:"http://disqus.com/disqus-internals id,attr"`
+}
+
+type disqusAuthor struct {
+	Name     string `xml:"name"`
+	Email    string `xml:"email"`
+	Username string `xml:"username"`
+}
+
+type disqusPost struct {
+	ID        string       `xml:"http://disqus.com/disqus-internals id,attr"`
+	Message   string       `xml:"message"`
+	CreatedAt string       `xml:"createdAt"`
+	IsDeleted bool         `xml:"isDeleted"`
+	IsSpam    bool         `xml:"isSpam"`
+	Author    disqusAuthor `xml:"author"`
+	Thread    disqusRef    `xml:"thread"`
+	Parent    disqusRef    `xml:"parent"`
+}
+
+// ParseDisqusExport reads the comments of a Disqus XML export. The
+// page of a comment is the link of its thread. Deleted and spam posts
+// and the posts of deleted threads are skipped.
+func ParseDisqusExport(r io.Reader) (ImportSource, error) {
+	var export disqusExport
+	err := xml.NewDecoder(r).Decode(&export)
+	if err != nil {
+		return ImportSource{}, err
+	}
+	links := make(map[string]string)
+	for _, t := range export.Threads {
+		if t.IsDeleted {
+			continue
+		}
+		links[t.ID] = strings.TrimSpace(t.Link)
+	}
+
+	src := ImportSource{Comments: make([]ImportedComment, 0)}
+	for _, p := range export.Posts {
+		link, ok := links[p.Thread.ID]
+		if !ok || p.IsDeleted || p.IsSpam {
+			src.Skipped++
+			continue
+		}
+		author := strings.TrimSpace(p.Author.Name)
+		if author == "" {
+			author = strings.TrimSpace(p.Author.Username)
+		}
+		ic := ImportedComment{
+			Source:    disqusSource(p.ID),
+			Author:    author,
+			Email:     strings.TrimSpace(p.Author.Email),
+			Content:   importedText(p.Message),
+			PageURL:   link,
+			Timestamp: importTimestamp(time.RFC3339, p.CreatedAt),
+		}
+		if p.Parent.ID != "" {
+			ic.ParentSource = disqusSource(p.Parent.ID)
+		}
+		src.Comments = append(src.Comments, ic)
+	}
+	return src, nil
+}
+
+func disqusSource(id string) string {
+	return "disqus:" + id
+}
@@ -0,0 +1,123 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package parlante
+
+import (
+	"strings"
+	"testing"
+)
+
+const disqusExportXML = `<?xml version="1.0" encoding="utf-8"?>
+<disqus xmlns="http://disqus.com"
+        xmlns:dsq="http://disqus.com/disqus-internals"
+        xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+  <category dsq:id="1">
+    <forum>myblog</forum>
+    <title>General</title>
+    <isDefault>true</isDefault>
+  </category>
+  <thread dsq:id="10">
+    <id>post-1</id>
+    <forum>myblog</forum>
+    <category dsq:id="1" />
+    <link>http://bla.net/post-1#comments</link>
+    <title>Post 1</title>
+    <createdAt>2015-03-01T10:00:00Z</createdAt>
+    <isDeleted>false</isDeleted>
+  </thread>
+  <thread dsq:id="11">
+    <link>http://bla.net/removed</link>
+    <isDeleted>true</isDeleted>
+  </thread>
+  <post dsq:id="100">
+    <id />
+    <message><![CDATA[<p>First &amp; <b>best</b></p><p>Second line</p>]]></message>
+    <createdAt>2015-03-02T10:00:00Z</createdAt>
+    <isDeleted>false</isDeleted>
+    <isSpam>false</isSpam>
+    <author>
+      <email>ze@bla.net</email>
+      <name>Zé</name>
+      <isAnonymous>false</isAnonymous>
+      <username>ze</username>
+    </author>
+    <thread dsq:id="10" />
+  </post>
+  <post dsq:id="101">
+    <message><![CDATA[<p>A reply</p>]]></message>
+    <createdAt>2015-03-03T10:00:00Z</createdAt>
+    <isDeleted>false</isDeleted>
+    <isSpam>false</isSpam>
+    <author>
+      <name></name>
+      <username>maria</username>
+    </author>
+    <thread dsq:id="10" />
+    <parent dsq:id="100" />
+  </post>
+  <post dsq:id="102">
+    <message><![CDATA[<p>buy stuff</p>]]></message>
+    <createdAt>2015-03-03T11:00:00Z</createdAt>
+    <isDeleted>false</isDeleted>
+    <isSpam>true</isSpam>
+    <thread dsq:id="10" />
+  </post>
+  <post dsq:id="103">
+    <message><![CDATA[<p>gone</p>]]></message>
+    <isDeleted>true</isDeleted>
+    <isSpam>false</isSpam>
+    <thread dsq:id="10" />
+  </post>
+  <post dsq:id="104">
+    <message><![CDATA[<p>in removed thread</p>]]></message>
+    <isDeleted>false</isDeleted>
+    <isSpam>false</isSpam>
+    <thread dsq:id="11" />
+  </post>
+</disqus>`
+
+func TestParseDisqusExport(t *testing.T) {
+	src, err := ParseDisqusExport(strings.NewReader(disqusExportXML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(src.Comments) != 2 || src.Skipped != 3 {
+		t.Fatalf("bad comments %d skipped %d", len(src.Comments), src.Skipped)
+	}
+	first := src.Comments[0]
+	expected := ImportedComment{
+		Source:    "disqus:100",
+		Author:    "Zé",
+		Email:     "ze@bla.net",
+		Content:   "First & best\n\nSecond line",
+		PageURL:   "http://bla.net/post-1#comments",
+		Timestamp: 1425290400,
+	}
+	if first != expected {
+		t.Fatalf("bad first comment %+v", first)
+	}
+	reply := src.Comments[1]
+	if reply.ParentSource != "disqus:100" || reply.Author != "maria" {
+		t.Fatalf("bad reply %+v", reply)
+	}
+
+	_, err = ParseDisqusExport(strings.NewReader("<disqus><post>"))
+	if err == nil {
+		t.Fatalf("no error for bad xml")
+	}
+}
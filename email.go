@@ -0,0 +1,81 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package parlante
+
+import (
+	"github.com/leonelquinteros/gotext"
+)
+
+// NewTemplatedEmail returns an email with the text and html bodies
+// rendered from the templates <name>.txt and <name>.html. The labels in
+// data must be already translated.
+func NewTemplatedEmail(
+	from string,
+	to []string,
+	subject string,
+	name string,
+	loc *gotext.Locale,
+	data map[string]any) (EmailMessage, error) {
+
+	lang := loc.GetLanguage()
+	text, err := RenderTextTemplate(name+".txt", lang, "Local", data)
+	if err != nil {
+		return EmailMessage{}, err
+	}
+	html, err := RenderTemplate(name+".html", lang, "Local", data)
+	if err != nil {
+		return EmailMessage{}, err
+	}
+	msg, err := NewEmailMessage(from, to, subject, string(text))
+	if err != nil {
+		return EmailMessage{}, err
+	}
+	msg.HTMLBody = string(html)
+	return msg, nil
+}
+
+// NewNotificationEmail returns the email telling the site owners about
+// a new comment or message.
+func NewNotificationEmail(
+	from string, to []string, item DigestItem, loc *gotext.Locale) (
+	EmailMessage, error) {
+
+	data := make(map[string]any)
+	data["name"] = item.Author
+	data["domain"] = item.Domain
+	var subject string
+	tmplCtx := make(map[string]any)
+	if item.Kind == DigestMessage {
+		subject = Tprintf(loc.Get("New message from {{.name}} at {{.domain}}"), data)
+		tmplCtx["intro"] = Tprintf(
+			loc.Get("{{.name}} sent a message from {{.domain}}:"), data)
+	} else {
+		subject = Tprintf(loc.Get("New comment from {{.name}} at {{.domain}}"), data)
+		tmplCtx["intro"] = Tprintf(
+			loc.Get("{{.name}} commented at {{.domain}}:"), data)
+	}
+	tmplCtx["url"] = item.PageURL
+	tmplCtx["email"] = item.Email
+	tmplCtx["status"] = item.Status
+	tmplCtx["content"] = item.Content
+	tmplCtx["urlLabel"] = loc.Get("Page")
+	tmplCtx["emailLabel"] = loc.Get("Email")
+	tmplCtx["statusLabel"] = loc.Get("Status")
+	return NewTemplatedEmail(
+		from, to, subject, "email_notification", loc, tmplCtx)
+}
@@ -0,0 +1,90 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package parlante
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewNotificationEmail(t *testing.T) {
+	var tests = []struct {
+		testName string
+		item     DigestItem
+		subject  string
+		body     []string
+		html     []string
+	}{
+		{"comment",
+			DigestItem{Kind: DigestComment, Domain: "bla.net",
+				PageURL: "https://bla.net/post", Author: "Zé",
+				Content: "a <b>comment</b>", Status: CommentPending},
+			"New comment from Zé at bla.net",
+			[]string{"Zé commented at bla.net:", "Page: https://bla.net/post",
+				"Status: pending", "a <b>comment</b>"},
+			[]string{`<a href="https://bla.net/post">`,
+				"a &lt;b&gt;comment&lt;/b&gt;"},
+		},
+		{"message",
+			DigestItem{Kind: DigestMessage, Domain: "bla.net",
+				PageURL: "https://bla.net/contact", Author: "Maria",
+				Email: "maria@bla.net", Content: "a message"},
+			"New message from Maria at bla.net",
+			[]string{"Maria sent a message from bla.net:",
+				"Email: maria@bla.net", "a message"},
+			[]string{`<a href="mailto:maria@bla.net">`, "a message"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			msg, err := NewNotificationEmail("blog@bla.net",
+				[]string{"me@bla.net"}, test.item, GetLocale("en"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if msg.Subject != test.subject {
+				t.Fatalf("bad subject %s", msg.Subject)
+			}
+			for _, s := range test.body {
+				if !strings.Contains(msg.Body, s) {
+					t.Fatalf("missing %q in body %s", s, msg.Body)
+				}
+			}
+			for _, s := range test.html {
+				if !strings.Contains(msg.HTMLBody, s) {
+					t.Fatalf("missing %q in html body %s", s, msg.HTMLBody)
+				}
+			}
+		})
+	}
+}
+
+func TestNewTemplatedEmail_Errors(t *testing.T) {
+	loc := GetLocale("en")
+	_, err := NewTemplatedEmail("blog@bla.net", []string{"me@bla.net"},
+		"Hi", "email_missing", loc, nil)
+	if err == nil {
+		t.Fatalf("no error for missing template")
+	}
+	_, err = NewTemplatedEmail("", []string{"me@bla.net"},
+		"Hi", "email_notification", loc, map[string]any{})
+	if err == nil {
+		t.Fatalf("no error without sender")
+	}
+}
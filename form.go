@@ -0,0 +1,93 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package parlante
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Forms sent faster than this after being rendered are considered
+	// sent by bots.
+	DEFAULT_FORM_MIN_DELAY = 3 * time.Second
+	// Forms rendered more than this ago are not accepted anymore.
+	DEFAULT_FORM_MAX_AGE = 24 * time.Hour
+)
+
+var ErrFormHoneypot = errors.New("Honeypot filled")
+var ErrFormTooFast = errors.New("Form sent too fast")
+var ErrFormExpired = errors.New("Form expired")
+var ErrFormInvalidToken = errors.New("Invalid form token")
+
+// FormGuard protects the html forms against bots. The forms have a
+// hidden field, the honeypot, that people don't fill, and a token
+// with the time the form was rendered signed by the server.
+type FormGuard struct {
+	Signer   Signer
+	MinDelay time.Duration
+	MaxAge   time.Duration
+	now      func() time.Time
+}
+
+func NewFormGuard(signer Signer, minDelay time.Duration,
+	maxAge time.Duration) FormGuard {
+	if minDelay <= 0 {
+		minDelay = DEFAULT_FORM_MIN_DELAY
+	}
+	if maxAge <= 0 {
+		maxAge = DEFAULT_FORM_MAX_AGE
+	}
+	return FormGuard{
+		Signer:   signer,
+		MinDelay: minDelay,
+		MaxAge:   maxAge,
+		now:      time.Now,
+	}
+}
+
+// Token returns a token for a form rendered now for a client.
+func (g FormGuard) Token(clientUUID string) string {
+	ts := strconv.FormatInt(g.now().Unix(), 10)
+	return ts + "." + g.Signer.Sign("form", clientUUID, ts)
+}
+
+// Check checks the token and the honeypot of a form sent for a client.
+func (g FormGuard) Check(clientUUID string, token string, honeypot string) error {
+	if honeypot != "" {
+		return ErrFormHoneypot
+	}
+	ts, signature, ok := strings.Cut(token, ".")
+	if !ok || !g.Signer.Verify(signature, "form", clientUUID, ts) {
+		return ErrFormInvalidToken
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return ErrFormInvalidToken
+	}
+	elapsed := g.now().Sub(time.Unix(unix, 0))
+	if elapsed < g.MinDelay {
+		return ErrFormTooFast
+	}
+	if elapsed > g.MaxAge {
+		return ErrFormExpired
+	}
+	return nil
+}
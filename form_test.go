@@ -0,0 +1,78 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package parlante
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSigner(t *testing.T) {
+	s := NewSigner("secret")
+	sig := s.Sign("a", "b")
+	if !s.Verify(sig, "a", "b") {
+		t.Fatalf("valid signature not verified")
+	}
+	if s.Verify(sig, "a", "c") || s.Verify(sig, "ab") {
+		t.Fatalf("signature verified for other values")
+	}
+	if NewSigner("other").Verify(sig, "a", "b") {
+		t.Fatalf("signature verified with other key")
+	}
+	if NewRandomSigner().Sign("a") == NewRandomSigner().Sign("a") {
+		t.Fatalf("same signature for random signers")
+	}
+}
+
+func TestFormGuard(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	g := NewFormGuard(NewSigner("secret"), 0, 0)
+	g.now = func() time.Time { return now }
+	token := g.Token("the-uuid")
+
+	var tests = []struct {
+		testName string
+		uuid     string
+		token    string
+		honeypot string
+		elapsed  time.Duration
+		err      error
+	}{
+		{"ok", "the-uuid", token, "", time.Minute, nil},
+		{"honeypot", "the-uuid", token, "123", time.Minute, ErrFormHoneypot},
+		{"too fast", "the-uuid", token, "", time.Second, ErrFormTooFast},
+		{"expired", "the-uuid", token, "", 25 * time.Hour, ErrFormExpired},
+		{"other client", "other-uuid", token, "", time.Minute, ErrFormInvalidToken},
+		{"missing token", "the-uuid", "", "", time.Minute, ErrFormInvalidToken},
+		{"tampered timestamp", "the-uuid", "1" + token, "", time.Minute,
+			ErrFormInvalidToken},
+		{"tampered signature", "the-uuid", token + "0", "", time.Minute,
+			ErrFormInvalidToken},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			check := g
+			check.now = func() time.Time { return now.Add(test.elapsed) }
+			err := check.Check(test.uuid, test.token, test.honeypot)
+			if err != test.err {
+				t.Fatalf("bad error for check %v", err)
+			}
+		})
+	}
+}
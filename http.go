@@ -30,8 +30,16 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
+	"net"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/leonelquinteros/gotext"
 )
 
 type ctxKey string
@@ -39,7 +47,13 @@ type ctxKey string
 const ctxClientKey ctxKey = "client"
 const ctxDomainKey ctxKey = "domain"
 
-const emailAddr = "blog@pdj01.poraodojuca.dev"
+const DEFAULT_COMMENTS_PAGE_SIZE = 50
+const MAX_COMMENTS_PAGE_SIZE = 500
+
+const DEFAULT_TRASH_PURGE_DAYS = 30
+
+// How often the old items in the trash are purged
+const TRASH_PURGE_INTERVAL = time.Hour
 
 //go:embed js/parlante.js
 var parlanteJS []byte
@@ -81,6 +95,63 @@ func (l RequestLogger) getIp(req *http.Request) string {
 	return ip
 }
 
+// requestIP returns the ip of the user that made the request. The
+// proxy headers are only used when the request comes from one of the
+// trusted proxies, otherwise anyone could choose their own ip.
+func requestIP(req *http.Request, trustedProxies []string) string {
+	ip, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		ip = req.RemoteAddr
+	}
+	if !isTrustedProxy(ip, trustedProxies) {
+		return ip
+	}
+	if realIP := strings.TrimSpace(req.Header.Get("X-Real-Ip")); realIP != "" {
+		return realIP
+	}
+	// The proxies append the address they got the request from, so
+	// the first address that is not a trusted proxy, from the right,
+	// is the user.
+	forwarded := strings.Split(req.Header.Get("X-Forwarded-For"), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		addr := strings.TrimSpace(forwarded[i])
+		if addr == "" {
+			continue
+		}
+		ip = addr
+		if !isTrustedProxy(addr, trustedProxies) {
+			break
+		}
+	}
+	return ip
+}
+
+// requestIP returns the ip of the user that made the request, using the
+// headers of the trusted proxies.
+func (s ParlanteServer) requestIP(r *http.Request) string {
+	return requestIP(r, s.Config.TrustedProxies)
+}
+
+// isTrustedProxy says if the ip is in the trusted proxies. The proxies
+// may be ips or networks in cidr notation.
+func isTrustedProxy(ip string, trustedProxies []string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, proxy := range trustedProxies {
+		_, network, err := net.ParseCIDR(proxy)
+		if err == nil && network.Contains(addr) {
+			return true
+		}
+		if proxyAddr := net.ParseIP(proxy); proxyAddr != nil &&
+			proxyAddr.Equal(addr) {
+			return true
+		}
+	}
+	return false
+}
+
 // StatusedResponseWriter is a reponse writer that knows the
 // return status of the request
 type StatusedResponseWriter struct {
@@ -99,6 +170,30 @@ func (w *StatusedResponseWriter) WriteHeader(code int) {
 type CreateCommentRequest struct {
 	Name    string `json:"name"`
 	Content string `json:"content"`
+	// Optional contact of the author. The email is never published.
+	Email   string `json:"email,omitempty"`
+	Website string `json:"website,omitempty"`
+	// Subscribe the email to the new comments in the page. Ignored
+	// without an email.
+	Subscribe bool `json:"subscribe,omitempty"`
+	// The id of the comment being replied. Empty for top level comments.
+	ParentID int64 `json:"parent_id,omitempty"`
+	// The token returned with the comments. Not needed when the client
+	// key is sent.
+	FormToken string `json:"form_token,omitempty"`
+	// Honeypot. Must be empty.
+	Phone string `json:"phone,omitempty"`
+	// Challenge returned by the challenge endpoint and its solution.
+	// Not needed when the client key is sent.
+	Challenge string `json:"challenge,omitempty"`
+	Solution  string `json:"solution,omitempty"`
+}
+
+// CreateCommentResponse is the response for a new comment. Status
+// is pending when the comment waits for moderation.
+type CreateCommentResponse struct {
+	Msg    string        `json:"msg"`
+	Status CommentStatus `json:"status"`
 }
 
 // MsgResponse is a response with a single msg string field
@@ -109,14 +204,81 @@ type MsgResponse struct {
 // CommentResponse is the information about a comment returned in the
 // comments list json
 type CommentResponse struct {
+	ID        int64  `json:"id"`
+	ParentID  int64  `json:"parent_id,omitempty"`
 	Author    string `json:"author"`
 	Content   string `json:"content"`
 	Timestamp int64  `json:"timestamp"`
+	// Edited is true if the comment was changed after its creation
+	Edited   bool   `json:"edited"`
+	EditedAt int64  `json:"edited_at,omitempty"`
+	Website  string `json:"website,omitempty"`
+	// Key of the author avatar. The image is at /avatar/<key>.svg
+	// or /avatar/<key>.png
+	Avatar string `json:"avatar"`
+	// The content rendered as html. Only in domains with markdown.
+	ContentHTML string `json:"content_html,omitempty"`
+	// Count of each reaction to the comment
+	Reactions ReactionCounts `json:"reactions"`
+	// Total of reactions to the comment
+	Score int64 `json:"score"`
 }
 
 type ListCommentsResponse struct {
 	Total    int               `json:"total"`
 	Comments []CommentResponse `json:"comments"`
+	// Cursor for the next page of comments. Empty in the last page.
+	Next string `json:"next,omitempty"`
+	// Token to be sent with a new comment.
+	FormToken string `json:"form_token"`
+}
+
+// SearchCommentResponse is a comment returned by a search. As search is
+// only for authenticated clients it has the moderation status.
+type SearchCommentResponse struct {
+	ID        int64         `json:"id"`
+	ParentID  int64         `json:"parent_id,omitempty"`
+	Author    string        `json:"author"`
+	Content   string        `json:"content"`
+	PageURL   string        `json:"page_url"`
+	Status    CommentStatus `json:"status"`
+	Timestamp int64         `json:"timestamp"`
+}
+
+type SearchCommentsResponse struct {
+	Comments []SearchCommentResponse `json:"comments"`
+	// Cursor for the next page of results. Empty in the last page.
+	Next string `json:"next,omitempty"`
+}
+
+// PreviewCommentRequest is the content of a comment not sent yet
+type PreviewCommentRequest struct {
+	Content string `json:"content"`
+}
+
+type PreviewCommentResponse struct {
+	// The content rendered as it is shown in the page.
+	HTML string `json:"html"`
+}
+
+// ReactRequest is a reaction of a reader to a comment. Sending the
+// same reaction again removes it.
+type ReactRequest struct {
+	CommentID int64  `json:"comment_id"`
+	Reaction  string `json:"reaction"`
+	// Token returned in a previous reaction. If empty or invalid a new
+	// token is returned.
+	Token string `json:"token,omitempty"`
+}
+
+type ReactResponse struct {
+	// Token that identifies the browser. It must be sent in the next
+	// reactions.
+	Token string `json:"token"`
+	// Reacted is false if the reaction was removed.
+	Reacted bool `json:"reacted"`
+	// Count of each reaction to the comment
+	Reactions ReactionCounts `json:"reactions"`
 }
 
 type CountCommentsRequest struct {
@@ -149,6 +311,15 @@ type PingMeRequest struct {
 	Name    string `json:"name"`
 	Email   string `json:"email"`
 	Message string `json:"message"`
+	// The token rendered in the form. Not needed when the client key
+	// is sent.
+	FormToken string `json:"form_token,omitempty"`
+	// Honeypot. Must be empty.
+	Phone string `json:"phone,omitempty"`
+	// Challenge returned by the challenge endpoint and its solution.
+	// Not needed when the client key is sent.
+	Challenge string `json:"challenge,omitempty"`
+	Solution  string `json:"solution,omitempty"`
 }
 
 // Config holds the configuration values used by the parlante server
@@ -161,6 +332,97 @@ type Config struct {
 	MaildirPath  string
 	LogLevel     string
 	Auth         bool
+	// Default number of comments returned in a listing.
+	CommentsPageSize int
+	// Items in the trash for more than this number of days are
+	// purged. Zero means never purge.
+	TrashPurgeDays int
+	// Spam check settings. Zero values use the defaults.
+	SpamMaxLinks     int
+	SpamBlockedWords []string
+	CommentMinLength int
+	CommentMaxLength int
+	SpamSuspectScore float64
+	SpamScore        float64
+	// Rate limits for the routes that create things. Clients and
+	// domains may have their own limits. Zero means no limit.
+	CommentRateLimit RateLimit
+	PingMeRateLimit  RateLimit
+	ReactRateLimit   RateLimit
+	// Max new reactor tokens given to an ip. Zero means no limit.
+	ReactorTokenRateLimit RateLimit
+	// Key used to sign the tokens sent to the browsers. If empty a
+	// random key is used.
+	SecretKey string
+	// Min time between rendering and sending a form and max age of the
+	// forms. Zero values use the defaults.
+	FormMinDelay time.Duration
+	FormMaxAge   time.Duration
+	// Base difficulty of the proof of work challenges. Zero uses
+	// the default.
+	ChallengeDifficulty int
+	// URL where the users reach the server, used in the links sent by
+	// email. Subscriptions are disabled if empty.
+	PublicURL string
+	// Emails are sent using the smtp server if SMTPHost is set,
+	// otherwise they are written to the maildir. Zero values use
+	// the defaults.
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	SMTPAuth     SMTPAuthMechanism
+	SMTPSecurity SMTPSecurity
+	SMTPTimeout  time.Duration
+	// Number of attempts to send an email before giving up. Zero uses
+	// the default.
+	OutboxMaxAttempts int
+	// Reactions the readers can give to the comments. Empty uses
+	// DEFAULT_REACTIONS.
+	Reactions []string
+	// Ips or networks of the proxies allowed to set the X-Real-Ip and
+	// X-Forwarded-For headers. The headers of other requests are
+	// ignored.
+	TrustedProxies []string
+}
+
+// rateLimit returns the rate limit of the route set in the config
+func (c Config) rateLimit(route RateLimitRoute) RateLimit {
+	switch route {
+	case RateLimitComment:
+		return c.CommentRateLimit
+	case RateLimitPingMe:
+		return c.PingMeRateLimit
+	case RateLimitReact:
+		return c.ReactRateLimit
+	}
+	return RateLimit{}
+}
+
+// reactions returns the reactions allowed in the comments
+func (c Config) reactions() []string {
+	if len(c.Reactions) == 0 {
+		return DEFAULT_REACTIONS
+	}
+	return c.Reactions
+}
+
+// emailSender returns the sender for the emails set in the config
+func (c Config) emailSender() EmailSender {
+	if c.SMTPHost == "" {
+		return NewMaildirSender(c.MaildirPath)
+	}
+	s := NewSMTPSender(c.SMTPHost, c.SMTPPort, c.SMTPUsername, c.SMTPPassword)
+	if c.SMTPAuth != "" {
+		s.Auth = c.SMTPAuth
+	}
+	if c.SMTPSecurity != "" {
+		s.Security = c.SMTPSecurity
+	}
+	if c.SMTPTimeout > 0 {
+		s.Timeout = c.SMTPTimeout
+	}
+	return s
 }
 
 func (c Config) UsesSSL() bool {
@@ -172,23 +434,41 @@ type ParlanteServer struct {
 	ClientStorage       ClientStorage
 	ClientDomainStorage ClientDomainStorage
 	CommentStorage      CommentStorage
-	EmailSender         EmailSender
-	mux                 *http.ServeMux
-	BodyReader          bodyReader
-	JsonMarshaler       jsonMarshaler
-	HtmlRenderer        htmlRenderer
-	Config              Config
-	AuthFn              authFn
+	TrashStorage        TrashStorage
+	SubscriptionStorage SubscriptionStorage
+	DigestStorage       DigestStorage
+	// EmailSender is the Outbox by default, so the emails are not
+	// lost when the mail server is down.
+	EmailSender EmailSender
+	Outbox      *Outbox
+	// SpamChecker checks new comments. If nil the checker built from
+	// the config is used.
+	SpamChecker   SpamChecker
+	RateLimiter   *RateLimiter
+	FormGuard     FormGuard
+	Challenger    *Challenger
+	mux           *http.ServeMux
+	BodyReader    bodyReader
+	JsonMarshaler jsonMarshaler
+	HtmlRenderer  htmlRenderer
+	Config        Config
+	AuthFn        authFn
 }
 
 // CreateComment add a new comment to a given page
 // @Summary Create Comment
-// @Description Adds a new comment to a given web page
+// @Description Adds a new comment to a given web page. If parent_id is
+// @Description informed the comment is a reply to that comment. In domains
+// @Description with moderation the comment is created as pending.
+// @Description Without the client key the form_token returned with the
+// @Description comments and a solved challenge are required.
+// @Description With subscribe the email gets the new comments in the page.
 // @Accept json
 // @Produce json
 // @Param X-PageURL header string true "URL for the page originating the comment"
 // @Param data body CreateCommentRequest true "The comment"
-// @Success 200  {object} MsgResponse
+// @Success 200  {object} CreateCommentResponse
+// @Failure 429 "Too many requests. See the Retry-After header"
 // @Router /comments/ [post]
 func (s ParlanteServer) CreateComment(w http.ResponseWriter, r *http.Request) {
 	if r.Body == nil {
@@ -212,28 +492,130 @@ func (s ParlanteServer) CreateComment(w http.ResponseWriter, r *http.Request) {
 	c := r.Context().Value(ctxClientKey).(Client)
 	cd := r.Context().Value(ctxDomainKey).(ClientDomain)
 
+	email, err := NormalizeEmail(body.Email)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	website, err := NormalizeWebsite(body.Website)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = s.checkForm(r, c, body.FormToken, body.Phone)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = s.checkChallenge(r, c, body.Challenge, body.Solution)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	page_url := r.Header.Get("X-PageURL")
 
-	_, err = s.CommentStorage.CreateComment(
-		c, cd, body.Name, body.Content, page_url)
+	info := CommentRequestInfo{
+		UserIP:    s.requestIP(r),
+		UserAgent: r.UserAgent(),
+		Referrer:  r.Referer(),
+	}
+	candidate := Comment{
+		ClientID:    c.ID,
+		DomainID:    cd.ID,
+		Author:      body.Name,
+		Content:     body.Content,
+		PageURL:     page_url,
+		ParentID:    body.ParentID,
+		Email:       email,
+		Website:     website,
+		Client:      &c,
+		RequestInfo: &info,
+	}
+	verdict := s.checkSpam(candidate)
+	spamStatus := verdict.CommentStatus()
+	if spamStatus != "" {
+		// suspect comments wait for moderation even if the domain
+		// is not moderated.
+		cd.Moderate = true
+	}
+
+	var comment Comment
+	if body.ParentID != 0 {
+		var parent Comment
+		parent, err = s.CommentStorage.GetCommentByID(body.ParentID)
+		if err != nil || parent.Status != CommentApproved {
+			http.Error(w, "Invalid parent comment", http.StatusBadRequest)
+			return
+		}
+		comment, err = s.CommentStorage.CreateReply(
+			c, cd, parent, body.Name, body.Content, page_url)
+	} else {
+		comment, err = s.CommentStorage.CreateComment(
+			c, cd, body.Name, body.Content, page_url)
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	loc := GetDefaultLocale()
-	go func() {
-		data := make(map[string]any)
-		data["name"] = body.Name
-		data["domain"] = cd.Domain
-		subject := Tprintf(loc.Get("New comment from {{.name}} at {{.domain}}"), data)
-		mailBody := fmt.Sprintf("url: %s\n\n%s", page_url, body.Content)
-		err := s.sendEmail(subject, mailBody)
+	err = s.CommentStorage.SetCommentRequestInfo(comment, info)
+	if err != nil {
+		Errorf("error saving comment request info %s", err.Error())
+	}
+	if email != "" || website != "" {
+		err = s.CommentStorage.SetCommentAuthorContact(comment, email, website)
 		if err != nil {
-			Errorf("error sending email %s", err.Error())
+			Errorf("error saving comment author contact %s", err.Error())
 		}
-
-	}()
-	resp := MsgResponse{Msg: "Ok"}
+	}
+	var sub Subscription
+	if spamStatus == CommentSpam {
+		err = s.CommentStorage.SetCommentStatus(comment, CommentSpam)
+		if err != nil {
+			Errorf("error marking comment as spam %s", err.Error())
+		}
+	} else if body.Subscribe && email != "" {
+		sub, err = s.subscribe(cd, page_url, email)
+		if err != nil {
+			Errorf("error subscribing to page %s", err.Error())
+		}
+	}
+	comment.Email = email
+	loc := GetLocale(getRequestLanguage(r))
+	// no need to bother anyone with spam.
+	if spamStatus != CommentSpam {
+		go func() {
+			n := s.getNotifications(c, cd)
+			item := DigestItem{
+				DomainID: cd.ID,
+				Domain:   cd.Domain,
+				Kind:     DigestComment,
+				PageURL:  page_url,
+				Author:   body.Name,
+				Email:    email,
+				Content:  body.Content,
+				Status:   comment.Status,
+			}
+			err := s.notifyOwners(n, item)
+			if err == ErrNoRecipients {
+				Debugf("no notification recipients for %s", cd.Domain)
+			} else if err != nil {
+				Errorf("error sending email %s", err.Error())
+			}
+			// the subscription is only active after the email is confirmed.
+			if sub.ID != 0 && !sub.Confirmed && sub.ConfirmSentAt == 0 {
+				s.sendSubscriptionConfirm(n, sub, loc)
+			}
+			// comments waiting for moderation are not published yet.
+			if comment.Status == CommentApproved {
+				s.notifySubscribers(n, cd, comment)
+			}
+		}()
+	}
+	// comment.Status is still pending for spam comments, so spammers
+	// don't know they were caught.
+	resp := CreateCommentResponse{Msg: "Ok", Status: comment.Status}
 	j, _ := json.Marshal(resp)
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", r.Header.Get("Origin"))
@@ -248,36 +630,76 @@ func (s ParlanteServer) CreateComment(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param X-PageURL header string true "URL for the page originating the comment"
 // @Param X-ClientUUID header string true "The client uuid"
+// @Param limit query int false "Max number of comments returned"
+// @Param cursor query string false "Cursor returned as next in the previous page"
+// @Param order query string false "oldest, newest, score or author. Defaults to the domain order"
 // @Success 200  {object} ListCommentsResponse
 // @Router /comments/ [get]
 func (s ParlanteServer) ListComments(w http.ResponseWriter, r *http.Request) {
 	c := r.Context().Value(ctxClientKey).(Client)
 	cd := r.Context().Value(ctxDomainKey).(ClientDomain)
 	page_url := r.Header.Get("X-PageURL")
+	limit, cursor, err := s.getPaginationParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	order, err := getPageCommentsOrder(r, cd)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	status := CommentApproved
 	filter := CommentsFilter{
 		ClientID: &c.ID,
 		DomainID: &cd.ID,
 		PageURL:  &page_url,
+		Status:   &status,
+		Cursor:   cursor,
+		Order:    order,
 	}
 
-	comments, err := s.CommentStorage.ListComments(filter)
+	comments, next, err := s.listCommentsPage(
+		filter, limit, s.CommentStorage.ListComments)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	total, err := s.countPageComments(page_url)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	reactions, err := s.countReactions(comments)
 	if err != nil {
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
-	total := len(comments)
 	cresp := make([]CommentResponse, 0)
 	for _, c := range comments {
 		resp := CommentResponse{
+			ID:        c.ID,
+			ParentID:  c.ParentID,
 			Author:    c.Author,
 			Content:   c.Content,
 			Timestamp: c.Timestamp,
+			Edited:    c.Edited(),
+			EditedAt:  c.EditedAt,
+			Website:   c.Website,
+			Avatar:    AvatarKey(s.FormGuard.Signer, c.Email, c.Author),
+			Reactions: reactions[c.ID],
+			Score:     c.Score,
+		}
+		if cd.Markdown {
+			resp.ContentHTML = string(RenderMarkdown(c.Content))
 		}
 		cresp = append(cresp, resp)
 	}
 	resp := ListCommentsResponse{
-		Total:    total,
-		Comments: cresp,
+		Total:     total,
+		Comments:  cresp,
+		Next:      next,
+		FormToken: s.FormGuard.Token(c.UUID),
 	}
 	j, err := s.JsonMarshaler(resp)
 	if err != nil {
@@ -299,6 +721,9 @@ func (s ParlanteServer) ListComments(w http.ResponseWriter, r *http.Request) {
 // @Param X-Timezone header string true "User local timezone"
 // @Param X-ClientUUID header string true "The client uuid"
 // @Param Accepted-Language header string true "Idioma do usuário"
+// @Param limit query int false "Max number of comments returned"
+// @Param cursor query string false "Cursor for the next page of comments"
+// @Param order query string false "oldest, newest, score or author. Defaults to the domain order"
 // @Success 200
 // @Router /comments/html [get]
 func (s ParlanteServer) ListCommentsHTML(w http.ResponseWriter, r *http.Request) {
@@ -309,14 +734,39 @@ func (s ParlanteServer) ListCommentsHTML(w http.ResponseWriter, r *http.Request)
 	page_url := r.Header.Get("X-PageURL")
 	lang := getRequestLanguage(r)
 	tz := r.Header.Get("X-Timezone")
+	limit, cursor, err := s.getPaginationParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	order, err := getPageCommentsOrder(r, cd)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
+	status := CommentApproved
 	filter := CommentsFilter{
 		ClientID: &c.ID,
 		DomainID: &cd.ID,
 		PageURL:  &page_url,
+		Status:   &status,
+		Cursor:   cursor,
+		Order:    order,
 	}
 
-	comments, err := s.CommentStorage.ListComments(filter)
+	comments, next, err := s.listCommentsPage(
+		filter, limit, s.CommentStorage.ListComments)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	total, err := s.countPageComments(page_url)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	reactions, err := s.countReactions(comments)
 	if err != nil {
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
@@ -324,20 +774,46 @@ func (s ParlanteServer) ListCommentsHTML(w http.ResponseWriter, r *http.Request)
 
 	loc := GetLocale(lang)
 	tmplCtx := make(map[string]any)
-	total := len(comments)
 
 	header := loc.Get("Comments (%d)", total)
 	tmplCtx["header"] = header
 	tmplCtx["addCommentHeader"] = loc.Get("Leave your comment!")
 	tmplCtx["noComments"] = loc.Get("No comments.")
-	tmplCtx["comments"] = comments
+	tree := s.setAvatars(BuildCommentTree(comments))
+	tree = setReactions(tree, reactions)
+	tmplCtx["comments"] = renderContents(tree, cd)
+	tmplCtx["reactions"] = s.Config.reactions()
+	tmplCtx["order"] = string(order)
+	tmplCtx["orderLabel"] = loc.Get("Order by")
+	tmplCtx["orders"] = orderOptions(loc)
+	tmplCtx["next"] = next
+	tmplCtx["loadMoreLabel"] = loc.Get("Load more comments")
+	tmplCtx["replyLabel"] = loc.Get("Reply")
+	tmplCtx["editedLabel"] = loc.Get("edited")
+	tmplCtx["replyingToLabel"] = loc.Get("Replying to")
+	tmplCtx["cancelReplyLabel"] = loc.Get("Cancel")
 	tmplCtx["nameLabel"] = loc.Get("Name")
+	tmplCtx["emailLabel"] = loc.Get("Email (optional, not published)")
+	tmplCtx["websiteLabel"] = loc.Get("Website (optional)")
+	tmplCtx["subscribeLabel"] = loc.Get("Notify me of new comments by email")
 	tmplCtx["commentLabel"] = loc.Get("Comment")
 	tmplCtx["submitComment"] = loc.Get("Send comment")
+	tmplCtx["markdown"] = cd.Markdown
+	tmplCtx["markdownHelp"] = loc.Get(
+		"Use *emphasis*, **bold**, `code`, [text](url), ``` blocks and > quotes.")
+	tmplCtx["previewLabel"] = loc.Get("Preview")
 	tmplCtx["commentAddOkMsg"] = loc.Get("Comment sent. Thank you!")
+	tmplCtx["commentPendingMsg"] = loc.Get(
+		"Comment sent. It will be published after moderation. Thank you!")
 	tmplCtx["commentAddErrorMsg"] = loc.Get("Error sending comment.")
+	tmplCtx["formToken"] = s.FormGuard.Token(c.UUID)
 
-	b, err := s.HtmlRenderer("comments.html", lang, tz, tmplCtx)
+	// the next pages only need the comments, not the whole form
+	tmpl := "comments.html"
+	if cursor != nil {
+		tmpl = "parlante-comments-page"
+	}
+	b, err := s.HtmlRenderer(tmpl, lang, tz, tmplCtx)
 	if err != nil {
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
@@ -345,10 +821,231 @@ func (s ParlanteServer) ListCommentsHTML(w http.ResponseWriter, r *http.Request)
 
 	w.Header().Set("Content-Type", "text/html")
 	w.Header().Set("Access-Control-Allow-Origin", r.Header.Get("Origin"))
+	w.Header().Set("Access-Control-Expose-Headers", "X-Next-Cursor")
+	w.Header().Set("X-Next-Cursor", next)
 	w.WriteHeader(http.StatusOK)
 	w.Write(b)
 }
 
+// PreviewComment renders the content of a comment before it is sent
+// @Summary Preview comment
+// @Description Returns the content of a comment rendered as html the way
+// @Description it is shown in the page. In domains with markdown the content
+// @Description is rendered as markdown, otherwise it is plain text.
+// @Accept json
+// @Produce json
+// @Param X-PageURL header string true "URL for the page originating the comment"
+// @Param X-ClientUUID header string true "The client uuid"
+// @Param data body PreviewCommentRequest true "The comment content"
+// @Success 200 {object} PreviewCommentResponse
+// @Router /comments/preview [post]
+func (s ParlanteServer) PreviewComment(w http.ResponseWriter, r *http.Request) {
+	if r.Body == nil {
+		http.Error(w, "Missing body", http.StatusBadRequest)
+		return
+	}
+	rawbody, err := s.BodyReader(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	var body PreviewCommentRequest
+	err = json.Unmarshal(rawbody, &body)
+	if err != nil {
+		http.Error(w, "Malformed json", http.StatusBadRequest)
+		return
+	}
+	maxLength := s.Config.CommentMaxLength
+	if maxLength <= 0 {
+		maxLength = DEFAULT_COMMENT_MAX_LENGTH
+	}
+	if utf8.RuneCountInString(body.Content) > maxLength {
+		http.Error(w, "Comment too long", http.StatusBadRequest)
+		return
+	}
+
+	cd := r.Context().Value(ctxDomainKey).(ClientDomain)
+	resp := PreviewCommentResponse{
+		HTML: string(RenderCommentContent(cd, body.Content)),
+	}
+	j, err := s.JsonMarshaler(resp)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", r.Header.Get("Origin"))
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
+
+// React adds a reaction to a comment
+// @Summary React to comment
+// @Description Adds a reaction to a comment, or removes it if the browser
+// @Description already reacted the same way. The browsers are identified by
+// @Description an anonymous token returned in the response that must be sent
+// @Description in the next reactions.
+// @Accept json
+// @Produce json
+// @Param X-PageURL header string true "URL for the page originating the reaction"
+// @Param X-ClientUUID header string true "The client uuid"
+// @Param data body ReactRequest true "The reaction"
+// @Success 200 {object} ReactResponse
+// @Failure 429 "Too many requests"
+// @Router /comments/react [post]
+func (s ParlanteServer) React(w http.ResponseWriter, r *http.Request) {
+	if r.Body == nil {
+		http.Error(w, "Missing body", http.StatusBadRequest)
+		return
+	}
+	rawbody, err := s.BodyReader(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	var body ReactRequest
+	err = json.Unmarshal(rawbody, &body)
+	if err != nil {
+		http.Error(w, "Malformed json", http.StatusBadRequest)
+		return
+	}
+	if !slices.Contains(s.Config.reactions(), body.Reaction) {
+		http.Error(w, "Invalid reaction", http.StatusBadRequest)
+		return
+	}
+
+	cd := r.Context().Value(ctxDomainKey).(ClientDomain)
+	comment, err := s.CommentStorage.GetCommentByID(body.CommentID)
+	if err != nil || comment.DomainID != cd.ID ||
+		comment.Status != CommentApproved {
+		http.Error(w, "Invalid comment", http.StatusBadRequest)
+		return
+	}
+
+	signer := s.FormGuard.Signer
+	token := body.Token
+	reactor, err := ReactorID(signer, token)
+	if err != nil {
+		ok, wait := s.RateLimiter.Allow("reactor-token "+s.requestIP(r),
+			s.Config.ReactorTokenRateLimit)
+		if !ok {
+			retry := int(math.Ceil(wait.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(retry))
+			w.Header().Set("Access-Control-Allow-Origin", r.Header.Get("Origin"))
+			http.Error(w, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+		token = NewReactorToken(signer)
+		reactor, _ = ReactorID(signer, token)
+	}
+	reacted, err := s.CommentStorage.ToggleReaction(
+		comment, body.Reaction, reactor)
+	if err != nil {
+		Errorf("error saving reaction %s", err.Error())
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	counts, err := s.CommentStorage.CountReactions(comment.ID)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	resp := ReactResponse{
+		Token:     token,
+		Reacted:   reacted,
+		Reactions: counts[comment.ID],
+	}
+	j, err := s.JsonMarshaler(resp)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", r.Header.Get("Origin"))
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
+
+// SearchComments searches the comments of a client domain
+// @Summary Search comments
+// @Description Returns the comments with all the terms of the query in the
+// @Description author, content or page url. Comments in any moderation status
+// @Description are returned so the client key is always required.
+// @Produce json
+// @Param X-ClientUUID header string true "The client uuid"
+// @Param X-APIKey header string true "The client key"
+// @Param q query string true "The search terms"
+// @Param page_url query string false "Search only in this page"
+// @Param status query string false "Search only comments with this status"
+// @Param limit query int false "Max number of comments returned"
+// @Param cursor query string false "Cursor returned as next in the previous page"
+// @Success 200 {object} SearchCommentsResponse
+// @Router /comments/search [get]
+func (s ParlanteServer) SearchComments(w http.ResponseWriter, r *http.Request) {
+	c := r.Context().Value(ctxClientKey).(Client)
+	cd := r.Context().Value(ctxDomainKey).(ClientDomain)
+	q := r.URL.Query()
+	query := q.Get("q")
+	if strings.TrimSpace(query) == "" {
+		http.Error(w, "Missing query", http.StatusBadRequest)
+		return
+	}
+	limit, cursor, err := s.getPaginationParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter := CommentsFilter{
+		ClientID: &c.ID,
+		DomainID: &cd.ID,
+		Cursor:   cursor,
+	}
+	if page_url := q.Get("page_url"); page_url != "" {
+		filter.PageURL = &page_url
+	}
+	if status := CommentStatus(q.Get("status")); status != "" {
+		filter.Status = &status
+	}
+
+	search := func(f CommentsFilter) ([]Comment, error) {
+		return s.CommentStorage.SearchComments(query, f)
+	}
+	comments, next, err := s.listCommentsPage(filter, limit, search)
+	if err != nil {
+		Errorf(err.Error())
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	resp := SearchCommentsResponse{
+		Comments: make([]SearchCommentResponse, 0),
+		Next:     next,
+	}
+	for _, c := range comments {
+		resp.Comments = append(resp.Comments, SearchCommentResponse{
+			ID:        c.ID,
+			ParentID:  c.ParentID,
+			Author:    c.Author,
+			Content:   c.Content,
+			PageURL:   c.PageURL,
+			Status:    c.Status,
+			Timestamp: c.Timestamp,
+		})
+	}
+	j, err := s.JsonMarshaler(resp)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", r.Header.Get("Origin"))
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
+
 // CountComments Returns the comments count for each url passed in the request
 // @Summary Count comments
 // @Description Counts the comments in the requested urls
@@ -478,6 +1175,7 @@ func (s ParlanteServer) CountCommentsHTML(w http.ResponseWriter, r *http.Request
 // @Success 200
 // @Router /pingme/ [get]
 func (s ParlanteServer) GetPingMeForm(w http.ResponseWriter, r *http.Request) {
+	c := r.Context().Value(ctxClientKey).(Client)
 	lang := getRequestLanguage(r)
 	tz := ""
 	loc := GetLocale(lang)
@@ -487,6 +1185,7 @@ func (s ParlanteServer) GetPingMeForm(w http.ResponseWriter, r *http.Request) {
 	tmplCtx["submitMessage"] = loc.Get("Send message")
 	tmplCtx["pingMeAddOkMsg"] = loc.Get("Message sent. Thank you!")
 	tmplCtx["pingMeAddErrorMsg"] = loc.Get("Error sending message.")
+	tmplCtx["formToken"] = s.FormGuard.Token(c.UUID)
 	b, err := s.HtmlRenderer("pingme.html", lang, tz, tmplCtx)
 	if err != nil {
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -501,12 +1200,15 @@ func (s ParlanteServer) GetPingMeForm(w http.ResponseWriter, r *http.Request) {
 
 // PingMe Send a contact message
 // @Summary Ping me
-// @Description Send a contact message
+// @Description Send a contact message. Without the client key the
+// @Description form_token rendered in the form and a solved challenge
+// @Description are required.
 // @Accept json
 // @Produce json
 // @Param X-ClientUUID header string true "The client uuid"
 // @Param data body PingMeRequest true "Body for the contact request"
 // @Success 200
+// @Failure 429 "Too many requests. See the Retry-After header"
 // @Router /pingme/ [post]
 func (s ParlanteServer) PingMe(w http.ResponseWriter, r *http.Request) {
 	if r.Body == nil {
@@ -532,15 +1234,29 @@ func (s ParlanteServer) PingMe(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cd := r.Context().Value(ctxDomainKey).(ClientDomain)
-	loc := GetDefaultLocale()
-	data := make(map[string]any)
-	data["name"] = body.Name
-	data["domain"] = cd.Domain
-	subject := Tprintf(loc.Get("New message from {{.name}} at {{.domain}}"), data)
-	mailBody := fmt.Sprintf("email: %s\n\n%s", body.Email, body.Message)
+	c := r.Context().Value(ctxClientKey).(Client)
+	err = s.checkForm(r, c, body.FormToken, body.Phone)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = s.checkChallenge(r, c, body.Challenge, body.Solution)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	err = s.sendEmail(subject, mailBody)
+	cd := r.Context().Value(ctxDomainKey).(ClientDomain)
+	item := DigestItem{
+		DomainID: cd.ID,
+		Domain:   cd.Domain,
+		Kind:     DigestMessage,
+		PageURL:  r.Header.Get("X-PageURL"),
+		Author:   body.Name,
+		Email:    body.Email,
+		Content:  body.Message,
+	}
+	err = s.notifyOwners(s.getNotifications(c, cd), item)
 	if err != nil {
 		Errorf(err.Error())
 		http.Error(w, "Error sending message", http.StatusInternalServerError)
@@ -554,6 +1270,197 @@ func (s ParlanteServer) PingMe(w http.ResponseWriter, r *http.Request) {
 	w.Write(j)
 }
 
+// GetChallenge returns a proof of work challenge
+// @Summary Get challenge
+// @Description Returns a challenge that must be solved before creating a
+// @Description comment or sending a message. The solution is a string that
+// @Description appended to the challenge gives a sha256 hash with
+// @Description difficulty leading zero bits. The difficulty rises for ips
+// @Description that send too many requests.
+// @Produce json
+// @Param X-ClientUUID header string true "The client uuid"
+// @Success 200 {object} Challenge
+// @Router /challenge/ [get]
+func (s ParlanteServer) GetChallenge(w http.ResponseWriter, r *http.Request) {
+	c := r.Context().Value(ctxClientKey).(Client)
+	ch, err := s.Challenger.New(c.UUID, s.requestIP(r))
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	j, err := s.JsonMarshaler(ch)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", r.Header.Get("Origin"))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
+
+// GetAvatar returns the avatar of a commenter
+// @Summary Get avatar
+// @Description Returns an identicon drawn from the avatar key of a
+// @Description comment. The same key always gives the same image.
+// @Produce png
+// @Produce svg
+// @Param file path string true "The avatar key with the .svg or .png extension"
+// @Param size query int false "Size of the image in pixels"
+// @Success 200
+// @Router /avatar/{file} [get]
+func (s ParlanteServer) GetAvatar(w http.ResponseWriter, r *http.Request) {
+	key, ext, _ := strings.Cut(r.PathValue("file"), ".")
+	if !IsAvatarKey(key) || (ext != "svg" && ext != "png") {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	size := DEFAULT_AVATAR_SIZE
+	if rawSize := r.URL.Query().Get("size"); rawSize != "" {
+		n, err := strconv.Atoi(rawSize)
+		if err != nil || n <= 0 || n > MAX_AVATAR_SIZE {
+			http.Error(w, "Invalid size", http.StatusBadRequest)
+			return
+		}
+		size = n
+	}
+	icon := NewIdenticon(key)
+	var b []byte
+	var err error
+	contentType := "image/svg+xml"
+	if ext == "png" {
+		contentType = "image/png"
+		b, err = icon.PNG(size)
+	} else {
+		b = icon.SVG(size)
+	}
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "public, max-age=2592000")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+// Unsubscribe removes a subscription to the comments of a page
+// @Summary Unsubscribe
+// @Description Removes a subscription using the link sent in the
+// @Description notification emails. GET only shows a page asking to
+// @Description confirm, so link scanners don't unsubscribe anyone. POST
+// @Description unsubscribes and is used for one click unsubscribe.
+// @Produce html
+// @Param id query int true "The subscription id"
+// @Param token query string true "The token sent in the email"
+// @Success 200
+// @Failure 400 "Invalid link"
+// @Failure 404 "Subscription not found"
+// @Router /unsubscribe/ [post]
+func (s ParlanteServer) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid link", http.StatusBadRequest)
+		return
+	}
+	sub, err := s.SubscriptionStorage.GetSubscription(id)
+	if err != nil {
+		http.Error(w, "Subscription not found", http.StatusNotFound)
+		return
+	}
+	token := r.URL.Query().Get("token")
+	if !VerifyUnsubscribeToken(s.FormGuard.Signer, sub, token) {
+		http.Error(w, "Invalid link", http.StatusBadRequest)
+		return
+	}
+	lang := getRequestLanguage(r)
+	loc := GetLocale(lang)
+	if r.Method == http.MethodGet {
+		tmplCtx := make(map[string]any)
+		tmplCtx["message"] = loc.Get(
+			"Do you want to stop receiving emails about this page?")
+		tmplCtx["submitLabel"] = loc.Get("Unsubscribe")
+		tmplCtx["action"] = r.URL.RequestURI()
+		s.renderSubscriptionLink(w, lang, tmplCtx)
+		return
+	}
+	err = s.SubscriptionStorage.Unsubscribe(sub)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	msg := loc.Get("You will not receive more emails about this page.")
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(msg))
+}
+
+// ConfirmSubscription activates a subscription to the comments of a page
+// @Summary Confirm subscription
+// @Description Confirms a subscription using the link sent by email to
+// @Description the subscriber. GET only shows a page asking to confirm,
+// @Description POST confirms the subscription.
+// @Produce html
+// @Param id query int true "The subscription id"
+// @Param token query string true "The token sent in the email"
+// @Success 200
+// @Failure 400 "Invalid link"
+// @Failure 404 "Subscription not found"
+// @Router /subscribe/confirm/ [post]
+func (s ParlanteServer) ConfirmSubscription(
+	w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid link", http.StatusBadRequest)
+		return
+	}
+	sub, err := s.SubscriptionStorage.GetSubscription(id)
+	if err != nil {
+		http.Error(w, "Subscription not found", http.StatusNotFound)
+		return
+	}
+	token := r.URL.Query().Get("token")
+	if !VerifyConfirmSubscriptionToken(s.FormGuard.Signer, sub, token) {
+		http.Error(w, "Invalid link", http.StatusBadRequest)
+		return
+	}
+	lang := getRequestLanguage(r)
+	loc := GetLocale(lang)
+	if r.Method == http.MethodGet {
+		tmplCtx := make(map[string]any)
+		tmplCtx["message"] = loc.Get(
+			"Do you want to receive emails about new comments in this page?")
+		tmplCtx["submitLabel"] = loc.Get("Subscribe")
+		tmplCtx["action"] = r.URL.RequestURI()
+		s.renderSubscriptionLink(w, lang, tmplCtx)
+		return
+	}
+	err = s.SubscriptionStorage.ConfirmSubscription(sub)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	msg := loc.Get("You will receive emails about new comments in this page.")
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(msg))
+}
+
+// renderSubscriptionLink renders the page that asks to confirm the
+// action of a link sent by email.
+func (s ParlanteServer) renderSubscriptionLink(
+	w http.ResponseWriter, lang string, tmplCtx map[string]any) {
+	b, err := s.HtmlRenderer("subscription_link.html", lang, "Local", tmplCtx)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
 // ServeParlanteJS returns the parlante.js file that is used to render the
 // comments in a web page.
 func (s ParlanteServer) ServeParlanteJS(w http.ResponseWriter, r *http.Request) {
@@ -568,6 +1475,9 @@ func (s ParlanteServer) Run() {
 	var err error
 	logger := RequestLogger{loggerFn: Infof}
 	loggedMux := logger.Log(s.mux)
+	go s.runTrashPurger(TRASH_PURGE_INTERVAL)
+	go s.Outbox.Run(OUTBOX_INTERVAL)
+	go s.runDigestScheduler(DIGEST_INTERVAL)
 	if s.Config.UsesSSL() {
 		err = http.ListenAndServeTLS(addr, s.Config.CertFilePath,
 			s.Config.KeyFilePath, loggedMux)
@@ -580,6 +1490,30 @@ func (s ParlanteServer) Run() {
 	}
 }
 
+// PurgeTrash removes for good the items in the trash for more than
+// Config.TrashPurgeDays days.
+func (s ParlanteServer) PurgeTrash() (int64, error) {
+	if s.Config.TrashPurgeDays <= 0 {
+		return 0, nil
+	}
+	limit := time.Now().AddDate(0, 0, -s.Config.TrashPurgeDays)
+	return s.TrashStorage.PurgeOlderThan(limit.Unix())
+}
+
+// runTrashPurger purges the trash from time to time. It never returns.
+func (s ParlanteServer) runTrashPurger(interval time.Duration) {
+	// notest
+	for {
+		n, err := s.PurgeTrash()
+		if err != nil {
+			Errorf("error purging trash %s", err.Error())
+		} else if n > 0 {
+			Infof("%d items purged from trash", n)
+		}
+		time.Sleep(interval)
+	}
+}
+
 // NewServer returns a new instance of ParlanteServer. Only one per process
 // must be used
 func NewServer(c Config) ParlanteServer {
@@ -592,23 +1526,229 @@ func NewServer(c Config) ParlanteServer {
 	s.ClientStorage = ClientStorageSQLite{}
 	s.ClientDomainStorage = ClientDomainStorageSQLite{}
 	s.CommentStorage = CommentStorageSQLite{}
-	sender := NewMaildirSender(s.Config.MaildirPath)
-	s.EmailSender = sender
+	s.TrashStorage = TrashStorageSQLite{}
+	s.SubscriptionStorage = SubscriptionStorageSQLite{}
+	s.DigestStorage = DigestStorageSQLite{}
+	s.Outbox = NewOutbox(OutboxStorageSQLite{}, c.emailSender())
+	if c.OutboxMaxAttempts > 0 {
+		s.Outbox.MaxAttempts = c.OutboxMaxAttempts
+	}
+	s.EmailSender = s.Outbox
 	s.AuthFn = AuthClient
+	s.RateLimiter = NewRateLimiter()
+	signer := NewRandomSigner()
+	if c.SecretKey != "" {
+		signer = NewSigner(c.SecretKey)
+	}
+	s.FormGuard = NewFormGuard(signer, c.FormMinDelay, c.FormMaxAge)
+	s.Challenger = NewChallenger(signer, c.ChallengeDifficulty)
 	SetLogLevelStr(c.LogLevel)
 	s.setupUrls()
 	return s
 }
 
+// checkSpam returns the spam verdict for a new comment. Errors
+// checking the comment are logged and the comment is considered ham.
+func (s ParlanteServer) checkSpam(comment Comment) SpamVerdict {
+	checker := s.SpamChecker
+	if checker == nil {
+		checker = NewDefaultSpamChecker(s.Config, s.CommentStorage)
+	}
+	r, err := checker.CheckComment(comment)
+	if err != nil {
+		Errorf("error checking spam %s", err.Error())
+		return SpamHam
+	}
+	if r.Verdict != SpamHam {
+		Infof("comment from %s is %s: %s", comment.Author, r.Verdict,
+			strings.Join(r.Reasons, ", "))
+	}
+	return r.Verdict
+}
+
+// rateLimit limits the requests to a route from the same ip to the
+// same client and page. The requests to all the pages of the client
+// have a limit too, so changing the page does not escape the limit.
+// Must be used after checkClient.
+func (s ParlanteServer) rateLimit(
+	route RateLimitRoute, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := r.Context().Value(ctxClientKey).(Client)
+		cd := r.Context().Value(ctxDomainKey).(ClientDomain)
+		limit := s.getRateLimit(c, cd, route)
+		clientKey := strings.Join(
+			[]string{string(route), s.requestIP(r), c.UUID}, " ")
+		pageKey := clientKey + " " + domainPageURL(r, cd)
+		ok, wait := s.RateLimiter.Allow(pageKey, limit)
+		if ok {
+			clientLimit := RateLimit{
+				Requests: limit.Requests * RATE_LIMIT_PAGES,
+				Period:   limit.Period,
+			}
+			ok, wait = s.RateLimiter.Allow(clientKey, clientLimit)
+		}
+		if !ok {
+			retry := int(math.Ceil(wait.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(retry))
+			w.Header().Set("Access-Control-Allow-Origin", r.Header.Get("Origin"))
+			http.Error(w, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// domainPageURL returns the page of a request without the fragment.
+// Pages that are not in the domain are ignored and an empty string is
+// returned.
+func domainPageURL(r *http.Request, cd ClientDomain) string {
+	page := r.Header.Get("X-PageURL")
+	domain, err := getDomainFromURL(page)
+	if err != nil || domain != cd.Domain {
+		return ""
+	}
+	page, _, _ = strings.Cut(page, "#")
+	return page
+}
+
+// getRateLimit returns the rate limit of a route for the domain. The
+// domain limit is used if set, then the client limit and then the
+// config limit.
+func (s ParlanteServer) getRateLimit(
+	c Client, cd ClientDomain, route RateLimitRoute) RateLimit {
+	limit, err := s.ClientDomainStorage.GetDomainRateLimit(cd, route)
+	if err != nil {
+		Errorf("error getting domain rate limit %s", err.Error())
+	}
+	if !limit.IsZero() {
+		return limit
+	}
+	limit, err = s.ClientDomainStorage.GetClientRateLimit(c, route)
+	if err != nil {
+		Errorf("error getting client rate limit %s", err.Error())
+	}
+	if !limit.IsZero() {
+		return limit
+	}
+	return s.Config.rateLimit(route)
+}
+
+// getNotifications returns the notification settings of a domain.
+// Values set for the domain take precedence over the client ones.
+func (s ParlanteServer) getNotifications(
+	c Client, cd ClientDomain) NotificationSettings {
+	n, err := s.ClientDomainStorage.GetClientNotifications(c)
+	if err != nil {
+		Errorf("error getting client notifications %s", err.Error())
+	}
+	dn, err := s.ClientDomainStorage.GetDomainNotifications(cd)
+	if err != nil {
+		Errorf("error getting domain notifications %s", err.Error())
+	}
+	return n.Override(dn)
+}
+
+// renderContents renders the markdown content of the comments in a tree.
+func renderContents(tree []CommentTree, d ClientDomain) []CommentTree {
+	if !d.Markdown {
+		return tree
+	}
+	for i := range tree {
+		tree[i].ContentHTML = RenderMarkdown(tree[i].Content)
+		tree[i].Replies = renderContents(tree[i].Replies, d)
+	}
+	return tree
+}
+
+// setReactions sets the count of reactions of the comments
+func setReactions(tree []CommentTree,
+	counts map[int64]ReactionCounts) []CommentTree {
+	for i := range tree {
+		tree[i].Reactions = counts[tree[i].ID]
+		tree[i].Replies = setReactions(tree[i].Replies, counts)
+	}
+	return tree
+}
+
+// countReactions returns the count of reactions of the comments
+func (s ParlanteServer) countReactions(comments []Comment) (
+	map[int64]ReactionCounts, error) {
+	ids := make([]int64, 0, len(comments))
+	for _, c := range comments {
+		ids = append(ids, c.ID)
+	}
+	return s.CommentStorage.CountReactions(ids...)
+}
+
+// setAvatars sets the avatar keys of the comments in a tree.
+func (s ParlanteServer) setAvatars(tree []CommentTree) []CommentTree {
+	for i := range tree {
+		tree[i].Avatar = AvatarKey(
+			s.FormGuard.Signer, tree[i].Email, tree[i].Author)
+		tree[i].Replies = s.setAvatars(tree[i].Replies)
+	}
+	return tree
+}
+
+// hasClientKey says if the request has a valid client key. These
+// requests don't need the bot protections.
+func (s ParlanteServer) hasClientKey(r *http.Request, c Client) bool {
+	key := r.Header.Get("X-APIKey")
+	if key == "" {
+		return false
+	}
+	_, err := s.AuthFn(s.ClientStorage, c.UUID, key)
+	return err == nil
+}
+
+// checkForm checks the bot protection fields of a form. Requests with
+// the client key are not checked.
+func (s ParlanteServer) checkForm(
+	r *http.Request, c Client, token string, honeypot string) error {
+	if s.hasClientKey(r, c) {
+		return nil
+	}
+	err := s.FormGuard.Check(c.UUID, token, honeypot)
+	if err != nil {
+		Infof("form rejected for %s: %s", s.requestIP(r), err.Error())
+	}
+	return err
+}
+
+// checkChallenge checks the solution of the proof of work challenge.
+// Requests with the client key are not checked.
+func (s ParlanteServer) checkChallenge(
+	r *http.Request, c Client, challenge string, solution string) error {
+	if s.hasClientKey(r, c) {
+		return nil
+	}
+	err := s.Challenger.Check(c.UUID, s.requestIP(r), challenge, solution)
+	if err != nil {
+		Infof("challenge rejected for %s: %s", s.requestIP(r), err.Error())
+	}
+	return err
+}
+
 // checkClient checks if the client exists and the request origin
 // is a registered domain
 func (s ParlanteServer) checkClient(next http.Handler) http.Handler {
+	return s.checkClientAuth(next, s.Config.Auth)
+}
+
+// checkAuthClient is like checkClient, but the client key is always
+// required.
+func (s ParlanteServer) checkAuthClient(next http.Handler) http.Handler {
+	return s.checkClientAuth(next, true)
+}
+
+func (s ParlanteServer) checkClientAuth(
+	next http.Handler, auth bool) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		uuid := r.Header.Get("X-ClientUUID")
 		uuid = strings.ToLower(uuid)
 		var c Client
 		var err error
-		if s.Config.Auth {
+		if auth {
 			key := r.Header.Get("X-APIKey")
 			c, err = s.AuthFn(s.ClientStorage, uuid, key)
 		} else {
@@ -647,8 +1787,125 @@ func (s ParlanteServer) checkClient(next http.Handler) http.Handler {
 	})
 }
 
-func (s ParlanteServer) sendEmail(subject string, body string) error {
-	msg, err := NewEmailMessage(emailAddr, []string{emailAddr}, subject, body)
+// getPaginationParams returns the limit and cursor sent in the query
+// string of a request.
+func (s ParlanteServer) getPaginationParams(r *http.Request) (
+	int, *CommentCursor, error) {
+	limit := s.Config.CommentsPageSize
+	if limit <= 0 {
+		limit = DEFAULT_COMMENTS_PAGE_SIZE
+	}
+	q := r.URL.Query()
+	if q.Get("limit") != "" {
+		l, err := strconv.Atoi(q.Get("limit"))
+		if err != nil || l <= 0 {
+			return 0, nil, errors.New("Invalid limit")
+		}
+		limit = min(l, MAX_COMMENTS_PAGE_SIZE)
+	}
+	if q.Get("cursor") == "" {
+		return limit, nil, nil
+	}
+	cursor, err := ParseCommentCursor(q.Get("cursor"))
+	if err != nil {
+		return 0, nil, err
+	}
+	return limit, &cursor, nil
+}
+
+// orderOptions returns the options for the comments order switcher.
+func orderOptions(loc *gotext.Locale) []map[string]string {
+	labels := map[CommentOrder]string{
+		OrderOldest: loc.Get("Oldest first"),
+		OrderNewest: loc.Get("Newest first"),
+		OrderScore:  loc.Get("Most reactions"),
+		OrderAuthor: loc.Get("Author"),
+	}
+	options := make([]map[string]string, 0, len(PageCommentOrders))
+	for _, o := range PageCommentOrders {
+		options = append(options, map[string]string{
+			"value": string(o),
+			"label": labels[o],
+		})
+	}
+	return options
+}
+
+// getPageCommentsOrder returns the order requested for the comments
+// of a page. If no order was requested the domain default is used.
+func getPageCommentsOrder(r *http.Request, cd ClientDomain) (
+	CommentOrder, error) {
+	o := r.URL.Query().Get("order")
+	if o == "" {
+		o = string(cd.Order)
+	}
+	order, err := ParseCommentOrder(o)
+	if err != nil {
+		return "", err
+	}
+	if !slices.Contains(PageCommentOrders, order) {
+		return "", errors.New("Invalid order")
+	}
+	return order, nil
+}
+
+// listCommentsPage returns at most limit comments and the cursor for
+// the next page. The cursor is empty if there are no more comments.
+func (s ParlanteServer) listCommentsPage(
+	filter CommentsFilter,
+	limit int,
+	listFn func(CommentsFilter) ([]Comment, error)) ([]Comment, string, error) {
+	// one more comment to know if there is a next page
+	filter.Limit = limit + 1
+	comments, err := listFn(filter)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(comments) <= limit {
+		return comments, "", nil
+	}
+	comments = comments[:limit]
+	next := NewCommentCursor(comments[limit-1], filter.Order)
+	return comments, next.String(), nil
+}
+
+func (s ParlanteServer) countPageComments(page_url string) (int, error) {
+	count, err := s.CommentStorage.CountComments(page_url)
+	if err != nil {
+		return 0, err
+	}
+	return int(count[0].Count), nil
+}
+
+// notifyOwners sends an email to the recipients of the notifications
+// that want them immediately. For the other recipients the item is
+// kept to be sent in their digests. Returns ErrNoRecipients if there
+// are no recipients.
+func (s ParlanteServer) notifyOwners(
+	n NotificationSettings, item DigestItem) error {
+	if len(n.Recipients) == 0 {
+		return ErrNoRecipients
+	}
+	item.Sender = n.From()
+	item.Timestamp = time.Now().Unix()
+	to := make([]string, 0)
+	for _, r := range n.Recipients {
+		mode := n.Mode(r)
+		if mode == NotifyImmediate {
+			to = append(to, r)
+			continue
+		}
+		item.Recipient = r
+		item.Mode = mode
+		_, err := s.DigestStorage.AddDigestItem(item)
+		if err != nil {
+			return err
+		}
+	}
+	if len(to) == 0 {
+		return nil
+	}
+	msg, err := NewNotificationEmail(n.From(), to, item, GetDefaultLocale())
 	if err != nil {
 		return err
 	}
@@ -656,6 +1913,132 @@ func (s ParlanteServer) sendEmail(subject string, body string) error {
 	return s.EmailSender.SendEmail(msg)
 }
 
+// SendDigests sends the hourly and daily digests with the items
+// created before the last hour or day. Returns the number of digests
+// sent.
+func (s ParlanteServer) SendDigests(now time.Time) (int, error) {
+	loc := GetDefaultLocale()
+	sent := 0
+	for _, mode := range []NotificationMode{NotifyHourly, NotifyDaily} {
+		before := DigestCutoff(mode, now).Unix()
+		items, err := s.DigestStorage.ListDigestItems(mode, before)
+		if err != nil {
+			return sent, err
+		}
+		// the items are ordered by recipient.
+		for len(items) > 0 {
+			n := 1
+			for n < len(items) && items[n].Recipient == items[0].Recipient {
+				n++
+			}
+			recipientItems := items[:n]
+			items = items[n:]
+			msg, err := NewDigestEmail(
+				recipientItems[0].Recipient, recipientItems, loc)
+			if err != nil {
+				return sent, err
+			}
+			err = s.EmailSender.SendEmail(msg)
+			if err != nil {
+				return sent, err
+			}
+			err = s.DigestStorage.RemoveDigestItems(recipientItems)
+			if err != nil {
+				return sent, err
+			}
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// runDigestScheduler sends the digests when they are due. It never
+// returns.
+func (s ParlanteServer) runDigestScheduler(interval time.Duration) {
+	// notest
+	for {
+		n, err := s.SendDigests(time.Now())
+		if err != nil {
+			Errorf("error sending digests %s", err.Error())
+		} else if n > 0 {
+			Infof("%d digests sent", n)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// subscribe subscribes the email to the new comments of a page. The
+// links in the emails to the subscribers use the public url, so
+// nobody is subscribed without one. Otherwise the links would point to
+// the host sent in the request.
+func (s ParlanteServer) subscribe(
+	cd ClientDomain, pageURL string, email string) (Subscription, error) {
+	if s.Config.PublicURL == "" {
+		return Subscription{}, errors.New("public url not set")
+	}
+	return s.SubscriptionStorage.Subscribe(cd, pageURL, email)
+}
+
+// sendSubscriptionConfirm sends the link that confirms a subscription.
+// The link is sent only once for each subscription. Like the
+// notifications to subscribers, nothing is sent without a notification
+// sender.
+func (s ParlanteServer) sendSubscriptionConfirm(n NotificationSettings,
+	sub Subscription, loc *gotext.Locale) {
+	from := n.From()
+	if from == "" || s.Config.PublicURL == "" {
+		return
+	}
+	first, err := s.SubscriptionStorage.MarkConfirmSent(sub)
+	if err != nil {
+		Errorf("error marking subscription confirmation %s", err.Error())
+		return
+	}
+	if !first {
+		return
+	}
+	link := ConfirmSubscriptionURL(
+		s.Config.PublicURL, s.FormGuard.Signer, sub)
+	msg, err := NewSubscriptionConfirmEmail(from, sub, link, loc)
+	if err == nil {
+		err = s.EmailSender.SendEmail(msg)
+	}
+	if err != nil {
+		Errorf("error sending subscription confirmation %s", err.Error())
+	}
+}
+
+// notifySubscribers sends the new comment to the subscribers of the
+// page. The author of the comment is not notified. The emails are sent
+// from the notification sender, so nothing is sent without one, nor
+// without the public url used in the unsubscribe links.
+func (s ParlanteServer) notifySubscribers(n NotificationSettings,
+	cd ClientDomain, comment Comment) {
+	from := n.From()
+	if from == "" || s.Config.PublicURL == "" {
+		return
+	}
+	subs, err := s.SubscriptionStorage.ListSubscriptions(cd, comment.PageURL)
+	if err != nil {
+		Errorf("error listing subscriptions %s", err.Error())
+		return
+	}
+	loc := GetDefaultLocale()
+	for _, sub := range subs {
+		if strings.EqualFold(sub.Email, comment.Email) {
+			continue
+		}
+		link := UnsubscribeURL(s.Config.PublicURL, s.FormGuard.Signer, sub)
+		msg, err := NewSubscriptionEmail(from, sub, comment, link, loc)
+		if err == nil {
+			err = s.EmailSender.SendEmail(msg)
+		}
+		if err != nil {
+			Errorf("error sending email to subscriber %s", err.Error())
+		}
+	}
+}
+
 func getDomainFromURL(url string) (string, error) {
 	parts := strings.Split(url, "://")
 	if len(parts) != 2 {
@@ -681,7 +2064,8 @@ func getValidURLsForDomain(d ClientDomain, urls []string) []string {
 
 func (s ParlanteServer) setupUrls() {
 	s.mux.Handle("POST /comment/",
-		s.checkClient(http.HandlerFunc(s.CreateComment)))
+		s.checkClient(s.rateLimit(RateLimitComment,
+			http.HandlerFunc(s.CreateComment))))
 
 	s.mux.Handle("GET /comment/",
 		s.checkClient(http.HandlerFunc(s.ListComments)))
@@ -691,7 +2075,21 @@ func (s ParlanteServer) setupUrls() {
 		s.checkClient(http.HandlerFunc(s.ListCommentsHTML)))
 	s.mux.Handle("OPTIONS /comment/html", http.HandlerFunc(handleCORS))
 
+	s.mux.Handle("POST /comment/preview",
+		s.checkClient(http.HandlerFunc(s.PreviewComment)))
+	s.mux.Handle("OPTIONS /comment/preview", http.HandlerFunc(handleCORS))
+
+	s.mux.Handle("POST /comment/react",
+		s.checkClient(s.rateLimit(RateLimitReact,
+			http.HandlerFunc(s.React))))
+	s.mux.Handle("OPTIONS /comment/react", http.HandlerFunc(handleCORS))
+
+	s.mux.Handle("GET /comment/search",
+		s.checkAuthClient(http.HandlerFunc(s.SearchComments)))
+	s.mux.Handle("OPTIONS /comment/search", http.HandlerFunc(handleCORS))
+
 	s.mux.Handle("GET /parlante.js", http.HandlerFunc(s.ServeParlanteJS))
+	s.mux.Handle("GET /avatar/{file}", http.HandlerFunc(s.GetAvatar))
 
 	s.mux.Handle("POST /comment/count",
 		s.checkClient(http.HandlerFunc(s.CountComments)))
@@ -706,10 +2104,23 @@ func (s ParlanteServer) setupUrls() {
 	s.mux.Handle("GET /pingme/",
 		s.checkClient(http.HandlerFunc(s.GetPingMeForm)))
 	s.mux.Handle("POST /pingme/",
-		s.checkClient(http.HandlerFunc(s.PingMe)))
+		s.checkClient(s.rateLimit(RateLimitPingMe,
+			http.HandlerFunc(s.PingMe))))
 	s.mux.Handle("OPTIONS /pingme/",
 		http.HandlerFunc(handleCORS))
 
+	s.mux.Handle("GET /unsubscribe/", http.HandlerFunc(s.Unsubscribe))
+	s.mux.Handle("POST /unsubscribe/", http.HandlerFunc(s.Unsubscribe))
+	s.mux.Handle("GET /subscribe/confirm/",
+		http.HandlerFunc(s.ConfirmSubscription))
+	s.mux.Handle("POST /subscribe/confirm/",
+		http.HandlerFunc(s.ConfirmSubscription))
+
+	s.mux.Handle("GET /challenge/",
+		s.checkClient(http.HandlerFunc(s.GetChallenge)))
+	s.mux.Handle("OPTIONS /challenge/",
+		http.HandlerFunc(handleCORS))
+
 }
 
 func handleCORS(w http.ResponseWriter, r *http.Request) {
@@ -22,11 +22,17 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"html"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 )
 
 func readFn(reader io.Reader) ([]byte, error) {
@@ -37,6 +43,26 @@ func readFn(reader io.Reader) ([]byte, error) {
 	return r, err
 }
 
+// testFormToken returns a form token rendered a while ago, so it is
+// not sent too fast.
+func testFormToken(s ParlanteServer, uuid string) string {
+	g := s.FormGuard
+	g.now = func() time.Time { return time.Now().Add(-time.Minute) }
+	return g.Token(uuid)
+}
+
+// testChallenge returns a new challenge and its solution.
+func testChallenge(s ParlanteServer, uuid string) (string, string) {
+	return testChallengeIP(s, uuid, "")
+}
+
+// testChallengeIP returns a solved challenge for the ip of the request.
+func testChallengeIP(s ParlanteServer, uuid string, ip string) (
+	string, string) {
+	ch, _ := s.Challenger.New(uuid, ip)
+	return ch.Challenge, SolveChallenge(ch)
+}
+
 func errorMarshal(v any) ([]byte, error) {
 	return nil, errors.New("bad")
 }
@@ -62,7 +88,9 @@ func TestCreateComment(t *testing.T) {
 	s.setupUrls()
 
 	c, _, _ := s.ClientStorage.CreateClient("test client")
-	s.ClientDomainStorage.AddClientDomain(c, "bla.net")
+	d, _ := s.ClientDomainStorage.AddClientDomain(c, "bla.net")
+	parent, _ := s.CommentStorage.CreateComment(
+		c, d, "Tião", "The parent", "https://bla.net/post")
 
 	var test_data = []struct {
 		testName string
@@ -73,9 +101,13 @@ func TestCreateComment(t *testing.T) {
 			"comment with bad client",
 			func() *http.Request {
 				uuid, _ := GenUUID4()
+				challenge, solution := testChallenge(s, c.UUID)
 				payload := CreateCommentRequest{
-					Name:    "Zé",
-					Content: "A comment",
+					Challenge: challenge,
+					Solution:  solution,
+					FormToken: testFormToken(s, c.UUID),
+					Name:      "Zé",
+					Content:   "A comment",
 				}
 				j, _ := json.Marshal(payload)
 				body := bytes.NewBuffer(j)
@@ -90,9 +122,13 @@ func TestCreateComment(t *testing.T) {
 		{
 			"comment with wrong origin",
 			func() *http.Request {
+				challenge, solution := testChallenge(s, c.UUID)
 				payload := CreateCommentRequest{
-					Name:    "Zé",
-					Content: "A comment",
+					Challenge: challenge,
+					Solution:  solution,
+					FormToken: testFormToken(s, c.UUID),
+					Name:      "Zé",
+					Content:   "A comment",
 				}
 				j, _ := json.Marshal(payload)
 				body := bytes.NewBuffer(j)
@@ -130,9 +166,79 @@ func TestCreateComment(t *testing.T) {
 		{
 			"comment ok",
 			func() *http.Request {
+				challenge, solution := testChallenge(s, c.UUID)
+				payload := CreateCommentRequest{
+					Challenge: challenge,
+					Solution:  solution,
+					FormToken: testFormToken(s, c.UUID),
+					Name:      "Zé",
+					Content:   "A comment",
+				}
+				j, _ := json.Marshal(payload)
+				body := bytes.NewBuffer(j)
+				req, _ := http.NewRequest("POST", "/comment/", body)
+				req.Header.Set("Origin", "https://bla.net")
+				req.Header.Set("X-PageURL", "https://bla.net/post")
+				req.Header.Set("X-ClientUUID", c.UUID)
+				return req
+
+			}(),
+			201},
+		{
+			"reply with bad parent",
+			func() *http.Request {
+				challenge, solution := testChallenge(s, c.UUID)
+				payload := CreateCommentRequest{
+					Challenge: challenge,
+					Solution:  solution,
+					FormToken: testFormToken(s, c.UUID),
+					Name:      "Zé",
+					Content:   "A reply",
+					ParentID:  999,
+				}
+				j, _ := json.Marshal(payload)
+				body := bytes.NewBuffer(j)
+				req, _ := http.NewRequest("POST", "/comment/", body)
+				req.Header.Set("Origin", "https://bla.net")
+				req.Header.Set("X-PageURL", "https://bla.net/post")
+				req.Header.Set("X-ClientUUID", c.UUID)
+				return req
+
+			}(),
+			400},
+		{
+			"reply in other page",
+			func() *http.Request {
+				challenge, solution := testChallenge(s, c.UUID)
+				payload := CreateCommentRequest{
+					Challenge: challenge,
+					Solution:  solution,
+					FormToken: testFormToken(s, c.UUID),
+					Name:      "Zé",
+					Content:   "A reply",
+					ParentID:  parent.ID,
+				}
+				j, _ := json.Marshal(payload)
+				body := bytes.NewBuffer(j)
+				req, _ := http.NewRequest("POST", "/comment/", body)
+				req.Header.Set("Origin", "https://bla.net")
+				req.Header.Set("X-PageURL", "https://bla.net/other-post")
+				req.Header.Set("X-ClientUUID", c.UUID)
+				return req
+
+			}(),
+			400},
+		{
+			"reply ok",
+			func() *http.Request {
+				challenge, solution := testChallenge(s, c.UUID)
 				payload := CreateCommentRequest{
-					Name:    "Zé",
-					Content: "A comment",
+					Challenge: challenge,
+					Solution:  solution,
+					FormToken: testFormToken(s, c.UUID),
+					Name:      "Zé",
+					Content:   "A reply",
+					ParentID:  parent.ID,
 				}
 				j, _ := json.Marshal(payload)
 				body := bytes.NewBuffer(j)
@@ -146,22 +252,1909 @@ func TestCreateComment(t *testing.T) {
 			201},
 	}
 
-	for _, test := range test_data {
+	for _, test := range test_data {
+		t.Run(test.testName, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.mux.ServeHTTP(w, test.req)
+
+			if w.Code != test.status {
+				t.Fatalf("bad status for %d", w.Code)
+			}
+
+		})
+	}
+
+}
+
+func TestCreateComment_Moderation(t *testing.T) {
+
+	co := Config{}
+	s := NewServer(co)
+	s.ClientStorage = NewClientStorageInMemory()
+	s.ClientDomainStorage = NewClientDomainStorageInMemory()
+	s.CommentStorage = NewCommentStorageInMemory()
+	s.mux = http.NewServeMux()
+	s.BodyReader = io.ReadAll
+	s.EmailSender = TestMailSender{}
+	s.setupUrls()
+
+	c, _, _ := s.ClientStorage.CreateClient("test client")
+	d, _ := s.ClientDomainStorage.AddClientDomain(c, "bla.net")
+	s.ClientDomainStorage.SetDomainModeration(d, true)
+
+	challenge, solution := testChallenge(s, c.UUID)
+	payload := CreateCommentRequest{
+		Challenge: challenge,
+		Solution:  solution,
+		FormToken: testFormToken(s, c.UUID),
+		Name:      "Zé",
+		Content:   "A comment",
+	}
+	j, _ := json.Marshal(payload)
+	req, _ := http.NewRequest("POST", "/comment/", bytes.NewBuffer(j))
+	req.Header.Set("Origin", "https://bla.net")
+	req.Header.Set("X-PageURL", "https://bla.net/post")
+	req.Header.Set("X-ClientUUID", c.UUID)
+	w := httptest.NewRecorder()
+	s.mux.ServeHTTP(w, req)
+
+	if w.Code != 201 {
+		t.Fatalf("bad status for %d", w.Code)
+	}
+	var resp CreateCommentResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Status != CommentPending {
+		t.Fatalf("bad comment status %s", resp.Status)
+	}
+
+	req, _ = http.NewRequest("GET", "/comment/", nil)
+	req.Header.Set("Origin", "https://bla.net")
+	req.Header.Set("X-PageURL", "https://bla.net/post")
+	req.Header.Set("X-ClientUUID", c.UUID)
+	w = httptest.NewRecorder()
+	s.mux.ServeHTTP(w, req)
+
+	var list ListCommentsResponse
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if list.Total != 0 {
+		t.Fatalf("pending comment listed %d", list.Total)
+	}
+}
+
+type fixedSpamChecker struct {
+	verdict SpamVerdict
+	err     error
+}
+
+func (c fixedSpamChecker) CheckComment(comment Comment) (SpamCheckResult, error) {
+	return SpamCheckResult{Verdict: c.verdict, Reasons: []string{"test"}}, c.err
+}
+
+var testNotifications = NotificationSettings{
+	Sender:     "blog@bla.net",
+	Recipients: []string{"me@bla.net"},
+}
+
+// chanMailSender sends the messages to a channel so the tests can
+// wait for the emails sent in goroutines.
+type chanMailSender chan EmailMessage
+
+func (s chanMailSender) SendEmail(msg EmailMessage) error {
+	s <- msg
+	return nil
+}
+
+func TestCreateComment_Subscribe(t *testing.T) {
+	var tests = []struct {
+		testName string
+		verdict  SpamVerdict
+		notified bool
+	}{
+		{"approved comment", SpamHam, true},
+		{"pending comment", SpamSuspect, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			s := NewServer(Config{PublicURL: "https://parlante.net"})
+			s.ClientStorage = NewClientStorageInMemory()
+			s.ClientDomainStorage = NewClientDomainStorageInMemory()
+			s.CommentStorage = NewCommentStorageInMemory()
+			subs := NewSubscriptionStorageInMemory()
+			s.SubscriptionStorage = subs
+			sender := make(chanMailSender, 10)
+			s.EmailSender = sender
+			s.SpamChecker = fixedSpamChecker{verdict: test.verdict}
+			s.mux = http.NewServeMux()
+			s.setupUrls()
+
+			c, _, _ := s.ClientStorage.CreateClient("test client")
+			cd, _ := s.ClientDomainStorage.AddClientDomain(c, "bla.net")
+			s.ClientDomainStorage.SetClientNotifications(c, testNotifications)
+			page := "https://bla.net/post"
+			maria, _ := subs.Subscribe(cd, page, "maria@bla.net")
+			subs.ConfirmSubscription(maria)
+
+			challenge, solution := testChallenge(s, c.UUID)
+			payload := CreateCommentRequest{
+				Name:      "Zé",
+				Content:   "A comment",
+				Email:     "ze@bla.net",
+				Subscribe: true,
+				FormToken: testFormToken(s, c.UUID),
+				Challenge: challenge,
+				Solution:  solution,
+			}
+			j, _ := json.Marshal(payload)
+			req, _ := http.NewRequest("POST", "/comment/", bytes.NewBuffer(j))
+			req.Header.Set("Origin", "https://bla.net")
+			req.Header.Set("X-PageURL", page)
+			req.Header.Set("X-ClientUUID", c.UUID)
+			w := httptest.NewRecorder()
+			s.mux.ServeHTTP(w, req)
+
+			if w.Code != 201 {
+				t.Fatalf("bad status %d", w.Code)
+			}
+			// ze only gets notifications after confirming the email
+			pageSubs, _ := subs.ListSubscriptions(cd, page)
+			if len(pageSubs) != 1 || pageSubs[0].ID != maria.ID {
+				t.Fatalf("bad subscriptions %+v", pageSubs)
+			}
+			ze, err := subs.GetSubscription(maria.ID + 1)
+			if err != nil || ze.Email != "ze@bla.net" || ze.Confirmed {
+				t.Fatalf("bad subscription %+v %v", ze, err)
+			}
+
+			// the site owner is always notified and ze always gets
+			// the confirmation link
+			msgs := make(map[string]EmailMessage)
+			timeout := time.After(time.Second)
+		wait:
+			for {
+				select {
+				case msg := <-sender:
+					msgs[msg.To[0]] = msg
+				case <-timeout:
+					break wait
+				}
+			}
+			if _, ok := msgs["me@bla.net"]; !ok {
+				t.Fatalf("owner not notified %+v", msgs)
+			}
+			confirm, ok := msgs["ze@bla.net"]
+			link := ConfirmSubscriptionURL(
+				"https://parlante.net", s.FormGuard.Signer, ze)
+			if !ok || !strings.Contains(confirm.Body, link) {
+				t.Fatalf("bad confirmation email %+v", confirm)
+			}
+			msg, ok := msgs["maria@bla.net"]
+			if test.notified != ok || len(msgs) > 3 {
+				t.Fatalf("bad emails %+v", msgs)
+			}
+			if !test.notified {
+				return
+			}
+			link = UnsubscribeURL(
+				"https://parlante.net", s.FormGuard.Signer, maria)
+			if msg.Headers["List-Unsubscribe"] != "<"+link+">" {
+				t.Fatalf("bad unsubscribe header %s", msg.Headers)
+			}
+		})
+	}
+}
+
+func TestCreateComment_SubscribeConfirmation(t *testing.T) {
+	var tests = []struct {
+		testName   string
+		publicURL  string
+		subscribed bool
+	}{
+		{"with public url", "https://parlante.net", true},
+		{"without public url", "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			s := NewServer(Config{PublicURL: test.publicURL})
+			s.ClientStorage = NewClientStorageInMemory()
+			s.ClientDomainStorage = NewClientDomainStorageInMemory()
+			s.CommentStorage = NewCommentStorageInMemory()
+			subs := NewSubscriptionStorageInMemory()
+			s.SubscriptionStorage = subs
+			sender := make(chanMailSender, 10)
+			s.EmailSender = sender
+			s.SpamChecker = fixedSpamChecker{verdict: SpamHam}
+			s.mux = http.NewServeMux()
+			s.setupUrls()
+
+			c, _, _ := s.ClientStorage.CreateClient("test client")
+			s.ClientDomainStorage.AddClientDomain(c, "bla.net")
+			s.ClientDomainStorage.SetClientNotifications(c, testNotifications)
+
+			// the links must not use the host of the request
+			for _, content := range []string{"A comment", "Other comment"} {
+				challenge, solution := testChallenge(s, c.UUID)
+				payload := CreateCommentRequest{
+					Name:      "Zé",
+					Content:   content,
+					Email:     "ze@bla.net",
+					Subscribe: true,
+					FormToken: testFormToken(s, c.UUID),
+					Challenge: challenge,
+					Solution:  solution,
+				}
+				j, _ := json.Marshal(payload)
+				req, _ := http.NewRequest(
+					"POST", "/comment/", bytes.NewBuffer(j))
+				req.Host = "evil.example"
+				req.Header.Set("Origin", "https://bla.net")
+				req.Header.Set("X-PageURL", "https://bla.net/post")
+				req.Header.Set("X-ClientUUID", c.UUID)
+				w := httptest.NewRecorder()
+				s.mux.ServeHTTP(w, req)
+				if w.Code != 201 {
+					t.Fatalf("bad status %d", w.Code)
+				}
+			}
+
+			_, err := subs.GetSubscription(1)
+			if (err == nil) != test.subscribed {
+				t.Fatalf("bad subscription %v", err)
+			}
+			confirmations := 0
+			timeout := time.After(time.Second)
+		wait:
+			for {
+				select {
+				case msg := <-sender:
+					if msg.To[0] != "ze@bla.net" {
+						continue
+					}
+					confirmations++
+					if strings.Contains(msg.Body, "evil.example") {
+						t.Fatalf("bad confirmation link %s", msg.Body)
+					}
+				case <-timeout:
+					break wait
+				}
+			}
+			// the confirmation is sent only once
+			if (confirmations == 1) != test.subscribed || confirmations > 1 {
+				t.Fatalf("bad number of confirmations %d", confirmations)
+			}
+		})
+	}
+}
+
+func TestNotificationRecipients(t *testing.T) {
+	var tests = []struct {
+		testName   string
+		client     NotificationSettings
+		domain     NotificationSettings
+		from       string
+		recipients []string
+	}{
+		{"without settings", NotificationSettings{}, NotificationSettings{},
+			"", nil},
+		{"client settings", testNotifications, NotificationSettings{},
+			"blog@bla.net", []string{"me@bla.net"}},
+		{"domain recipients", testNotifications,
+			NotificationSettings{Recipients: []string{"other@bla.net"}},
+			"blog@bla.net", []string{"other@bla.net"}},
+		{"domain without sender", NotificationSettings{},
+			NotificationSettings{Recipients: []string{"other@bla.net"}},
+			"other@bla.net", []string{"other@bla.net"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			s := NewServer(Config{})
+			s.ClientStorage = NewClientStorageInMemory()
+			s.ClientDomainStorage = NewClientDomainStorageInMemory()
+			s.CommentStorage = NewCommentStorageInMemory()
+			s.SubscriptionStorage = NewSubscriptionStorageInMemory()
+			sender := make(chanMailSender, 10)
+			s.EmailSender = sender
+			s.SpamChecker = fixedSpamChecker{verdict: SpamHam}
+			s.mux = http.NewServeMux()
+			s.setupUrls()
+
+			c, key, _ := s.ClientStorage.CreateClient("test client")
+			cd, _ := s.ClientDomainStorage.AddClientDomain(c, "bla.net")
+			s.ClientDomainStorage.SetClientNotifications(c, test.client)
+			s.ClientDomainStorage.SetDomainNotifications(cd, test.domain)
+
+			comment := CreateCommentRequest{Name: "Zé", Content: "A comment"}
+			message := PingMeRequest{
+				Name: "Zé", Email: "ze@bla.net", Message: "A message"}
+			for _, route := range []string{"/comment/", "/pingme/"} {
+				var j []byte
+				if route == "/comment/" {
+					j, _ = json.Marshal(comment)
+				} else {
+					j, _ = json.Marshal(message)
+				}
+				req, _ := http.NewRequest("POST", route, bytes.NewBuffer(j))
+				req.Header.Set("Origin", "https://bla.net")
+				req.Header.Set("X-PageURL", "https://bla.net/post")
+				req.Header.Set("X-ClientUUID", c.UUID)
+				req.Header.Set("X-APIKey", key)
+				w := httptest.NewRecorder()
+				s.mux.ServeHTTP(w, req)
+
+				// messages can't be sent without recipients
+				status := 201
+				if test.recipients == nil && route == "/pingme/" {
+					status = 500
+				}
+				if w.Code != status {
+					t.Fatalf("bad status for %s %d", route, w.Code)
+				}
+				if test.recipients == nil {
+					continue
+				}
+				var msg EmailMessage
+				select {
+				case msg = <-sender:
+				case <-time.After(time.Second):
+					t.Fatalf("email not sent for %s", route)
+				}
+				if msg.From != test.from ||
+					!slices.Equal(msg.To, test.recipients) {
+					t.Fatalf("bad email addresses %s %s", msg.From, msg.To)
+				}
+			}
+			select {
+			case msg := <-sender:
+				t.Fatalf("unexpected email %+v", msg)
+			case <-time.After(100 * time.Millisecond):
+			}
+		})
+	}
+}
+
+func TestNotificationDigests(t *testing.T) {
+	s := NewServer(Config{})
+	s.ClientStorage = NewClientStorageInMemory()
+	s.ClientDomainStorage = NewClientDomainStorageInMemory()
+	digests := NewDigestStorageInMemory()
+	s.DigestStorage = digests
+	sender := make(chanMailSender, 10)
+	s.EmailSender = sender
+	s.mux = http.NewServeMux()
+	s.setupUrls()
+
+	c, key, _ := s.ClientStorage.CreateClient("test client")
+	s.ClientDomainStorage.AddClientDomain(c, "bla.net")
+	n, _ := ParseNotificationSettings("blog@bla.net",
+		"me@bla.net, hourly@bla.net (hourly), daily@bla.net (daily)")
+	s.ClientDomainStorage.SetClientNotifications(c, n)
+
+	message := PingMeRequest{
+		Name: "Zé", Email: "ze@bla.net", Message: "A message"}
+	j, _ := json.Marshal(message)
+	req, _ := http.NewRequest("POST", "/pingme/", bytes.NewBuffer(j))
+	req.Header.Set("Origin", "https://bla.net")
+	req.Header.Set("X-PageURL", "https://bla.net/contact")
+	req.Header.Set("X-ClientUUID", c.UUID)
+	req.Header.Set("X-APIKey", key)
+	w := httptest.NewRecorder()
+	s.mux.ServeHTTP(w, req)
+	if w.Code != 201 {
+		t.Fatalf("bad status %d", w.Code)
+	}
+
+	msg := <-sender
+	if !slices.Equal(msg.To, []string{"me@bla.net"}) {
+		t.Fatalf("bad immediate recipients %s", msg.To)
+	}
+	if len(digests.data) != 2 {
+		t.Fatalf("bad digest items %+v", digests.data)
+	}
+	for _, item := range digests.data {
+		if item.Kind != DigestMessage || item.Sender != "blog@bla.net" ||
+			item.PageURL != "https://bla.net/contact" ||
+			item.Domain != "bla.net" || item.Email != "ze@bla.net" {
+			t.Fatalf("bad digest item %+v", item)
+		}
+	}
+
+	// fixed times so the hourly digest is not sent at the end of a day
+	now := time.Date(2025, 3, 10, 14, 30, 0, 0, time.Local)
+	for id, item := range digests.data {
+		item.Timestamp = now.Unix()
+		digests.data[id] = item
+	}
+
+	// nothing is due before the next hour
+	sent, err := s.SendDigests(now)
+	if err != nil || sent != 0 {
+		t.Fatalf("bad digests sent %d %v", sent, err)
+	}
+
+	sent, err = s.SendDigests(now.Add(time.Hour))
+	if err != nil || sent != 1 {
+		t.Fatalf("bad hourly digests sent %d %v", sent, err)
+	}
+	msg = <-sender
+	if !slices.Equal(msg.To, []string{"hourly@bla.net"}) ||
+		!strings.Contains(msg.Body, "A message") {
+		t.Fatalf("bad hourly digest %+v", msg)
+	}
+
+	sent, err = s.SendDigests(now.Add(24 * time.Hour))
+	if err != nil || sent != 1 {
+		t.Fatalf("bad daily digests sent %d %v", sent, err)
+	}
+	msg = <-sender
+	if !slices.Equal(msg.To, []string{"daily@bla.net"}) {
+		t.Fatalf("bad daily digest %+v", msg)
+	}
+	if len(digests.data) != 0 {
+		t.Fatalf("digest items not removed %+v", digests.data)
+	}
+
+	digests.ForceListError(true)
+	s.DigestStorage = digests
+	_, err = s.SendDigests(now)
+	if err == nil {
+		t.Fatalf("no error listing digest items")
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	s := NewServer(Config{})
+	subs := NewSubscriptionStorageInMemory()
+	s.SubscriptionStorage = subs
+	s.mux = http.NewServeMux()
+	s.setupUrls()
+
+	cd := ClientDomain{ID: 1}
+	page := "https://bla.net/post"
+
+	var tests = []struct {
+		testName string
+		method   string
+		query    func(Subscription) string
+		status   int
+		removed  bool
+	}{
+		{"confirmation page", "GET", func(sub Subscription) string {
+			return fmt.Sprintf("id=%d&token=%s", sub.ID,
+				UnsubscribeToken(s.FormGuard.Signer, sub))
+		}, 200, false},
+		{"unsubscribe", "POST", func(sub Subscription) string {
+			return fmt.Sprintf("id=%d&token=%s", sub.ID,
+				UnsubscribeToken(s.FormGuard.Signer, sub))
+		}, 200, true},
+		{"bad id", "POST", func(sub Subscription) string {
+			return "id=bad"
+		}, 400, false},
+		{"missing subscription", "GET", func(sub Subscription) string {
+			return "id=1000&token=bad"
+		}, 404, false},
+		{"bad token", "POST", func(sub Subscription) string {
+			return fmt.Sprintf("id=%d&token=bad", sub.ID)
+		}, 400, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			sub, _ := subs.Subscribe(cd, page, "ze@bla.net")
+			path := "/unsubscribe/?" + test.query(sub)
+			req, _ := http.NewRequest(test.method, path, nil)
+			w := httptest.NewRecorder()
+			s.mux.ServeHTTP(w, req)
+
+			if w.Code != test.status {
+				t.Fatalf("bad status %d", w.Code)
+			}
+			_, err := subs.GetSubscription(sub.ID)
+			if (err != nil) != test.removed {
+				t.Fatalf("bad subscription after unsubscribe %v", err)
+			}
+			if test.method == "GET" && test.status == 200 {
+				form := `<form method="post" action="` +
+					html.EscapeString(path) + `">`
+				if !strings.Contains(w.Body.String(), form) {
+					t.Fatalf("bad confirmation page %s", w.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestConfirmSubscription(t *testing.T) {
+	s := NewServer(Config{})
+	subs := NewSubscriptionStorageInMemory()
+	s.SubscriptionStorage = subs
+	s.mux = http.NewServeMux()
+	s.setupUrls()
+
+	cd := ClientDomain{ID: 1}
+
+	var tests = []struct {
+		testName  string
+		method    string
+		query     func(Subscription) string
+		status    int
+		confirmed bool
+	}{
+		{"confirmation page", "GET", func(sub Subscription) string {
+			return fmt.Sprintf("id=%d&token=%s", sub.ID,
+				ConfirmSubscriptionToken(s.FormGuard.Signer, sub))
+		}, 200, false},
+		{"confirm", "POST", func(sub Subscription) string {
+			return fmt.Sprintf("id=%d&token=%s", sub.ID,
+				ConfirmSubscriptionToken(s.FormGuard.Signer, sub))
+		}, 200, true},
+		{"unsubscribe token", "POST", func(sub Subscription) string {
+			return fmt.Sprintf("id=%d&token=%s", sub.ID,
+				UnsubscribeToken(s.FormGuard.Signer, sub))
+		}, 400, false},
+		{"bad id", "POST", func(sub Subscription) string {
+			return "id=bad"
+		}, 400, false},
+		{"missing subscription", "GET", func(sub Subscription) string {
+			return "id=1000&token=bad"
+		}, 404, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			page := "https://bla.net/" + test.testName
+			sub, _ := subs.Subscribe(cd, page, "ze@bla.net")
+			path := "/subscribe/confirm/?" + test.query(sub)
+			req, _ := http.NewRequest(test.method, path, nil)
+			w := httptest.NewRecorder()
+			s.mux.ServeHTTP(w, req)
+
+			if w.Code != test.status {
+				t.Fatalf("bad status %d", w.Code)
+			}
+			got, _ := subs.GetSubscription(sub.ID)
+			if got.Confirmed != test.confirmed {
+				t.Fatalf("bad confirmed %t", got.Confirmed)
+			}
+			if test.method == "GET" && test.status == 200 {
+				form := `<form method="post" action="` +
+					html.EscapeString(path) + `">`
+				if !strings.Contains(w.Body.String(), form) {
+					t.Fatalf("bad confirmation page %s", w.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestCreateComment_Spam(t *testing.T) {
+	var tests = []struct {
+		testName   string
+		checker    SpamChecker
+		respStatus CommentStatus
+		status     CommentStatus
+	}{
+		{
+			"ham",
+			fixedSpamChecker{verdict: SpamHam},
+			CommentApproved,
+			CommentApproved,
+		},
+		{
+			"suspect",
+			fixedSpamChecker{verdict: SpamSuspect},
+			CommentPending,
+			CommentPending,
+		},
+		{
+			"spam",
+			fixedSpamChecker{verdict: SpamSpam},
+			CommentPending,
+			CommentSpam,
+		},
+		{
+			"checker error",
+			fixedSpamChecker{err: errors.New("bad checker")},
+			CommentApproved,
+			CommentApproved,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			s := NewServer(Config{})
+			s.ClientStorage = NewClientStorageInMemory()
+			s.ClientDomainStorage = NewClientDomainStorageInMemory()
+			storage := NewCommentStorageInMemory()
+			s.CommentStorage = storage
+			s.EmailSender = TestMailSender{}
+			s.SpamChecker = test.checker
+			s.mux = http.NewServeMux()
+			s.setupUrls()
+
+			c, _, _ := s.ClientStorage.CreateClient("test client")
+			s.ClientDomainStorage.AddClientDomain(c, "bla.net")
+
+			challenge, solution := testChallengeIP(s, c.UUID, "1.2.3.4")
+			payload := CreateCommentRequest{
+				Challenge: challenge,
+				Solution:  solution,
+				FormToken: testFormToken(s, c.UUID),
+				Name:      "Zé",
+				Content:   "A comment",
+			}
+			j, _ := json.Marshal(payload)
+			req, _ := http.NewRequest("POST", "/comment/", bytes.NewBuffer(j))
+			req.Header.Set("Origin", "https://bla.net")
+			req.Header.Set("X-PageURL", "https://bla.net/post")
+			req.Header.Set("X-ClientUUID", c.UUID)
+			req.Header.Set("User-Agent", "the-agent")
+			req.RemoteAddr = "1.2.3.4:5678"
+			w := httptest.NewRecorder()
+			s.mux.ServeHTTP(w, req)
+
+			if w.Code != 201 {
+				t.Fatalf("bad status for %d", w.Code)
+			}
+			var resp CreateCommentResponse
+			json.Unmarshal(w.Body.Bytes(), &resp)
+			if resp.Status != test.respStatus {
+				t.Fatalf("bad response status %s", resp.Status)
+			}
+			comment := storage.GetComment()
+			if comment.Status != test.status {
+				t.Fatalf("bad comment status %s", comment.Status)
+			}
+			info, _ := storage.GetCommentRequestInfo(comment)
+			if info.UserIP != "1.2.3.4" || info.UserAgent != "the-agent" {
+				t.Fatalf("bad request info %v", info)
+			}
+		})
+	}
+}
+
+func TestCreateComment_RateLimit(t *testing.T) {
+	var tests = []struct {
+		testName     string
+		config       Config
+		clientLimit  RateLimit
+		domainLimit  RateLimit
+		storageError bool
+		allowed      int
+	}{
+		{
+			"no limit",
+			Config{},
+			RateLimit{},
+			RateLimit{},
+			false,
+			5,
+		},
+		{
+			"config limit",
+			Config{CommentRateLimit: RateLimit{Requests: 2, Period: time.Minute}},
+			RateLimit{},
+			RateLimit{},
+			false,
+			2,
+		},
+		{
+			"client limit",
+			Config{CommentRateLimit: RateLimit{Requests: 2, Period: time.Minute}},
+			RateLimit{Requests: 3, Period: time.Minute},
+			RateLimit{},
+			false,
+			3,
+		},
+		{
+			"domain limit",
+			Config{CommentRateLimit: RateLimit{Requests: 2, Period: time.Minute}},
+			RateLimit{Requests: 3, Period: time.Minute},
+			RateLimit{Requests: 1, Period: time.Minute},
+			false,
+			1,
+		},
+		{
+			"storage error",
+			Config{CommentRateLimit: RateLimit{Requests: 2, Period: time.Minute}},
+			RateLimit{},
+			RateLimit{},
+			true,
+			2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			s := NewServer(test.config)
+			s.ClientStorage = NewClientStorageInMemory()
+			cds := NewClientDomainStorageInMemory()
+			s.ClientDomainStorage = cds
+			s.CommentStorage = NewCommentStorageInMemory()
+			s.EmailSender = TestMailSender{}
+			s.SpamChecker = fixedSpamChecker{verdict: SpamHam}
+			s.mux = http.NewServeMux()
+			s.setupUrls()
+
+			c, _, _ := s.ClientStorage.CreateClient("test client")
+			d, _ := cds.AddClientDomain(c, "bla.net")
+			cds.SetClientRateLimit(c, RateLimitComment, test.clientLimit)
+			cds.SetDomainRateLimit(d, RateLimitComment, test.domainLimit)
+			cds.ForceListError(test.storageError)
+
+			post := func(page string, ip string) *httptest.ResponseRecorder {
+				challenge, solution := testChallengeIP(s, c.UUID, ip)
+				payload := CreateCommentRequest{
+					Challenge: challenge,
+					Solution:  solution,
+					FormToken: testFormToken(s, c.UUID),
+					Name:      "Zé",
+					Content:   "A comment",
+				}
+				j, _ := json.Marshal(payload)
+				req, _ := http.NewRequest(
+					"POST", "/comment/", bytes.NewBuffer(j))
+				req.Header.Set("Origin", "https://bla.net")
+				req.Header.Set("X-PageURL", page)
+				req.Header.Set("X-ClientUUID", c.UUID)
+				req.RemoteAddr = ip + ":5678"
+				w := httptest.NewRecorder()
+				s.mux.ServeHTTP(w, req)
+				return w
+			}
+
+			for i := 0; i < test.allowed; i++ {
+				w := post("https://bla.net/post", "1.2.3.4")
+				if w.Code != 201 {
+					t.Fatalf("bad status for request %d: %d", i, w.Code)
+				}
+			}
+			if test.config.CommentRateLimit.IsZero() {
+				return
+			}
+			w := post("https://bla.net/post", "1.2.3.4")
+			if w.Code != 429 {
+				t.Fatalf("bad status over the limit %d", w.Code)
+			}
+			if w.Header().Get("Retry-After") == "" {
+				t.Fatalf("missing Retry-After header")
+			}
+
+			w = post("https://bla.net/other", "1.2.3.4")
+			if w.Code != 201 {
+				t.Fatalf("bad status for other page %d", w.Code)
+			}
+			w = post("https://bla.net/post", "4.3.2.1")
+			if w.Code != 201 {
+				t.Fatalf("bad status for other ip %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestCreateComment_RateLimitPages(t *testing.T) {
+	co := Config{CommentRateLimit: RateLimit{Requests: 2, Period: time.Minute}}
+	s := NewServer(co)
+	s.ClientStorage = NewClientStorageInMemory()
+	s.ClientDomainStorage = NewClientDomainStorageInMemory()
+	s.CommentStorage = NewCommentStorageInMemory()
+	s.EmailSender = TestMailSender{}
+	s.SpamChecker = fixedSpamChecker{verdict: SpamHam}
+	s.mux = http.NewServeMux()
+	s.setupUrls()
+
+	c, _, _ := s.ClientStorage.CreateClient("test client")
+	s.ClientDomainStorage.AddClientDomain(c, "bla.net")
+
+	post := func(page string, ip string, forwarded string) int {
+		challenge, solution := testChallengeIP(s, c.UUID, ip)
+		payload := CreateCommentRequest{
+			Challenge: challenge,
+			Solution:  solution,
+			FormToken: testFormToken(s, c.UUID),
+			Name:      "Zé",
+			Content:   "A comment",
+		}
+		j, _ := json.Marshal(payload)
+		req, _ := http.NewRequest("POST", "/comment/", bytes.NewBuffer(j))
+		req.Header.Set("Origin", "https://bla.net")
+		req.Header.Set("X-PageURL", page)
+		req.Header.Set("X-ClientUUID", c.UUID)
+		req.Header.Set("X-Forwarded-For", forwarded)
+		req.RemoteAddr = ip + ":5678"
+		w := httptest.NewRecorder()
+		s.mux.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// pages of other domains share the same bucket
+	for i, status := range []int{201, 201, 429} {
+		page := fmt.Sprintf("https://other.net/post-%d", i)
+		if code := post(page, "1.2.3.4", ""); code != status {
+			t.Fatalf("bad status for other domain page %d: %d", i, code)
+		}
+	}
+
+	// changing the forwarded ip doesn't help without a trusted proxy
+	for i, status := range []int{201, 201, 429} {
+		page := "https://bla.net/forwarded"
+		forwarded := fmt.Sprintf("5.6.7.%d", i)
+		if code := post(page, "6.6.6.6", forwarded); code != status {
+			t.Fatalf("bad status for forwarded ip %d: %d", i, code)
+		}
+	}
+
+	// changing the page only helps up to the client limit
+	allowed := co.CommentRateLimit.Requests * RATE_LIMIT_PAGES
+	for i := range allowed + 1 {
+		status := 201
+		if i == allowed {
+			status = 429
+		}
+		page := fmt.Sprintf("https://bla.net/post-%d", i)
+		if code := post(page, "4.3.2.1", ""); code != status {
+			t.Fatalf("bad status for page %d: %d", i, code)
+		}
+	}
+}
+
+func TestFormProtection(t *testing.T) {
+	s := NewServer(Config{})
+	s.ClientStorage = NewClientStorageInMemory()
+	s.ClientDomainStorage = NewClientDomainStorageInMemory()
+	s.CommentStorage = NewCommentStorageInMemory()
+	s.EmailSender = &TestMailSender{}
+	s.SpamChecker = fixedSpamChecker{verdict: SpamHam}
+	s.mux = http.NewServeMux()
+	s.setupUrls()
+
+	c, key, _ := s.ClientStorage.CreateClient("test client")
+	s.ClientDomainStorage.AddClientDomain(c, "bla.net")
+	s.ClientDomainStorage.SetClientNotifications(c, testNotifications)
+	token := testFormToken(s, c.UUID)
+
+	var tests = []struct {
+		testName string
+		route    string
+		token    string
+		honeypot string
+		key      string
+		status   int
+	}{
+		{"comment ok", "/comment/", token, "", "", 201},
+		{"comment honeypot", "/comment/", token, "123", "", 400},
+		{"comment too fast", "/comment/", s.FormGuard.Token(c.UUID), "", "", 400},
+		{"comment tampered token", "/comment/", token + "0", "", "", 400},
+		{"comment without token", "/comment/", "", "", "", 400},
+		{"comment bad key", "/comment/", "", "", "bad", 400},
+		{"comment with key", "/comment/", "", "", key, 201},
+		{"pingme ok", "/pingme/", token, "", "", 201},
+		{"pingme honeypot", "/pingme/", token, "123", "", 400},
+		{"pingme tampered token", "/pingme/", "1" + token, "", "", 400},
+		{"pingme with key", "/pingme/", "", "", key, 201},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			challenge, solution := testChallenge(s, c.UUID)
+			var payload any
+			if test.route == "/comment/" {
+				payload = CreateCommentRequest{
+					Name:      "Zé",
+					Content:   "A comment",
+					FormToken: test.token,
+					Phone:     test.honeypot,
+					Challenge: challenge,
+					Solution:  solution,
+				}
+			} else {
+				payload = PingMeRequest{
+					Name:      "Zé",
+					Email:     "a@a.com",
+					Message:   "A message",
+					FormToken: test.token,
+					Phone:     test.honeypot,
+					Challenge: challenge,
+					Solution:  solution,
+				}
+			}
+			j, _ := json.Marshal(payload)
+			req, _ := http.NewRequest("POST", test.route, bytes.NewBuffer(j))
+			req.Header.Set("Origin", "https://bla.net")
+			req.Header.Set("X-PageURL", "https://bla.net/post")
+			req.Header.Set("X-ClientUUID", c.UUID)
+			if test.key != "" {
+				req.Header.Set("X-APIKey", test.key)
+			}
+			w := httptest.NewRecorder()
+			s.mux.ServeHTTP(w, req)
+
+			if w.Code != test.status {
+				t.Fatalf("bad status %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestFormTokenRendered(t *testing.T) {
+	s := NewServer(Config{})
+	s.ClientStorage = NewClientStorageInMemory()
+	s.ClientDomainStorage = NewClientDomainStorageInMemory()
+	s.CommentStorage = NewCommentStorageInMemory()
+	s.mux = http.NewServeMux()
+	s.setupUrls()
+
+	c, _, _ := s.ClientStorage.CreateClient("test client")
+	s.ClientDomainStorage.AddClientDomain(c, "bla.net")
+
+	var tests = []struct {
+		testName string
+		route    string
+		tokenFn  func(body []byte) string
+	}{
+		{
+			"comments html",
+			"/comment/html",
+			func(body []byte) string {
+				_, after, _ := strings.Cut(string(body),
+					`id="parlante-form-token" value="`)
+				token, _, _ := strings.Cut(after, `"`)
+				return token
+			},
+		},
+		{
+			"comments json",
+			"/comment/",
+			func(body []byte) string {
+				var resp ListCommentsResponse
+				json.Unmarshal(body, &resp)
+				return resp.FormToken
+			},
+		},
+		{
+			"pingme form",
+			"/pingme/",
+			func(body []byte) string {
+				_, after, _ := strings.Cut(string(body),
+					`id="parlante-pingme-form-token" value="`)
+				token, _, _ := strings.Cut(after, `"`)
+				return token
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", test.route, nil)
+			req.Header.Set("Origin", "https://bla.net")
+			req.Header.Set("X-PageURL", "https://bla.net/post")
+			req.Header.Set("X-ClientUUID", c.UUID)
+			w := httptest.NewRecorder()
+			s.mux.ServeHTTP(w, req)
+
+			token := test.tokenFn(w.Body.Bytes())
+			g := s.FormGuard
+			g.now = func() time.Time { return time.Now().Add(time.Minute) }
+			err := g.Check(c.UUID, token, "")
+			if err != nil {
+				t.Fatalf("bad form token %s %s", token, err.Error())
+			}
+		})
+	}
+}
+
+func TestChallengeProtection(t *testing.T) {
+	s := NewServer(Config{})
+	s.ClientStorage = NewClientStorageInMemory()
+	s.ClientDomainStorage = NewClientDomainStorageInMemory()
+	s.CommentStorage = NewCommentStorageInMemory()
+	s.EmailSender = &TestMailSender{}
+	s.SpamChecker = fixedSpamChecker{verdict: SpamHam}
+	s.mux = http.NewServeMux()
+	s.setupUrls()
+
+	c, key, _ := s.ClientStorage.CreateClient("test client")
+	s.ClientDomainStorage.AddClientDomain(c, "bla.net")
+	s.ClientDomainStorage.SetClientNotifications(c, testNotifications)
+
+	req, _ := http.NewRequest("GET", "/challenge/", nil)
+	req.Header.Set("Origin", "https://bla.net")
+	req.Header.Set("X-ClientUUID", c.UUID)
+	w := httptest.NewRecorder()
+	s.mux.ServeHTTP(w, req)
+	if w.Code != 200 {
+		t.Fatalf("bad status for challenge %d", w.Code)
+	}
+	var ch Challenge
+	json.Unmarshal(w.Body.Bytes(), &ch)
+	if ch.Difficulty != DEFAULT_CHALLENGE_DIFFICULTY || ch.Challenge == "" {
+		t.Fatalf("bad challenge %v", ch)
+	}
+	solution := SolveChallenge(ch)
+
+	var tests = []struct {
+		testName  string
+		route     string
+		challenge string
+		solution  string
+		key       string
+		status    int
+	}{
+		{"comment without challenge", "/comment/", "", "", "", 400},
+		{"comment wrong solution", "/comment/", ch.Challenge, "x", "", 400},
+		{"comment ok", "/comment/", ch.Challenge, solution, "", 201},
+		{"comment replayed", "/comment/", ch.Challenge, solution, "", 400},
+		{"pingme replayed", "/pingme/", ch.Challenge, solution, "", 400},
+		{"comment with key", "/comment/", "", "", key, 201},
+		{"pingme with key", "/pingme/", "", "", key, 201},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			var payload any
+			if test.route == "/comment/" {
+				payload = CreateCommentRequest{
+					Name:      "Zé",
+					Content:   "A comment",
+					FormToken: testFormToken(s, c.UUID),
+					Challenge: test.challenge,
+					Solution:  test.solution,
+				}
+			} else {
+				payload = PingMeRequest{
+					Name:      "Zé",
+					Email:     "a@a.com",
+					Message:   "A message",
+					FormToken: testFormToken(s, c.UUID),
+					Challenge: test.challenge,
+					Solution:  test.solution,
+				}
+			}
+			j, _ := json.Marshal(payload)
+			req, _ := http.NewRequest("POST", test.route, bytes.NewBuffer(j))
+			req.Header.Set("Origin", "https://bla.net")
+			req.Header.Set("X-PageURL", "https://bla.net/post")
+			req.Header.Set("X-ClientUUID", c.UUID)
+			if test.key != "" {
+				req.Header.Set("X-APIKey", test.key)
+			}
+			w := httptest.NewRecorder()
+			s.mux.ServeHTTP(w, req)
+
+			if w.Code != test.status {
+				t.Fatalf("bad status %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestCreateComment_AuthorContact(t *testing.T) {
+	s := NewServer(Config{})
+	s.ClientStorage = NewClientStorageInMemory()
+	s.ClientDomainStorage = NewClientDomainStorageInMemory()
+	storage := NewCommentStorageInMemory()
+	s.CommentStorage = storage
+	s.EmailSender = TestMailSender{}
+	s.SpamChecker = fixedSpamChecker{verdict: SpamHam}
+	s.mux = http.NewServeMux()
+	s.setupUrls()
+
+	c, _, _ := s.ClientStorage.CreateClient("test client")
+	s.ClientDomainStorage.AddClientDomain(c, "bla.net")
+
+	var tests = []struct {
+		testName string
+		email    string
+		website  string
+		status   int
+	}{
+		{"without contact", "", "", 201},
+		{"with contact", "ze@bla.net", "https://ze.net", 201},
+		{"invalid email", "ze", "", 400},
+		{"invalid website", "", "javascript:alert(1)", 400},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			challenge, solution := testChallenge(s, c.UUID)
+			payload := CreateCommentRequest{
+				Name:      "Zé",
+				Content:   "A comment",
+				Email:     test.email,
+				Website:   test.website,
+				FormToken: testFormToken(s, c.UUID),
+				Challenge: challenge,
+				Solution:  solution,
+			}
+			j, _ := json.Marshal(payload)
+			req, _ := http.NewRequest("POST", "/comment/", bytes.NewBuffer(j))
+			req.Header.Set("Origin", "https://bla.net")
+			req.Header.Set("X-PageURL", "https://bla.net/post")
+			req.Header.Set("X-ClientUUID", c.UUID)
+			w := httptest.NewRecorder()
+			s.mux.ServeHTTP(w, req)
+
+			if w.Code != test.status {
+				t.Fatalf("bad status %d", w.Code)
+			}
+			if w.Code != 201 {
+				return
+			}
+			comments, _ := storage.ListComments(CommentsFilter{})
+			comment := comments[len(comments)-1]
+			if comment.Email != test.email || comment.Website != test.website {
+				t.Fatalf("bad author contact %s %s", comment.Email, comment.Website)
+			}
+		})
+	}
+
+	// the email is never returned in the comments
+	for _, route := range []string{"/comment/", "/comment/html"} {
+		req, _ := http.NewRequest("GET", route, nil)
+		req.Header.Set("Origin", "https://bla.net")
+		req.Header.Set("X-PageURL", "https://bla.net/post")
+		req.Header.Set("X-ClientUUID", c.UUID)
+		w := httptest.NewRecorder()
+		s.mux.ServeHTTP(w, req)
+
+		body := w.Body.String()
+		avatar := AvatarKey(s.FormGuard.Signer, "ze@bla.net", "Zé")
+		if strings.Contains(body, "ze@bla.net") {
+			t.Fatalf("email returned in %s", route)
+		}
+		if !strings.Contains(body, "https://ze.net") ||
+			!strings.Contains(body, avatar) {
+			t.Fatalf("website or avatar missing in %s: %s", route, body)
+		}
+	}
+}
+
+func TestGetAvatar(t *testing.T) {
+	s := NewServer(Config{})
+	key := AvatarKey(s.FormGuard.Signer, "ze@bla.net", "zé")
+
+	var tests = []struct {
+		testName    string
+		path        string
+		status      int
+		contentType string
+	}{
+		{"svg", "/avatar/" + key + ".svg", 200, "image/svg+xml"},
+		{"png", "/avatar/" + key + ".png?size=32", 200, "image/png"},
+		{"bad extension", "/avatar/" + key + ".gif", 404, ""},
+		{"bad key", "/avatar/bla.svg", 404, ""},
+		{"bad size", "/avatar/" + key + ".png?size=10000", 400, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", test.path, nil)
+			w := httptest.NewRecorder()
+			s.mux.ServeHTTP(w, req)
+
+			if w.Code != test.status {
+				t.Fatalf("bad status %d", w.Code)
+			}
+			if test.contentType != "" &&
+				w.Header().Get("Content-Type") != test.contentType {
+				t.Fatalf("bad content type %s", w.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestRequestIP(t *testing.T) {
+	proxies := []string{"1.2.3.4", "10.0.0.0/8"}
+	var tests = []struct {
+		testName string
+		headers  map[string]string
+		remote   string
+		ip       string
+	}{
+		{"remote addr", nil, "1.2.3.4:5678", "1.2.3.4"},
+		{"remote addr without port", nil, "1.2.3.4", "1.2.3.4"},
+		{"forwarded for from untrusted",
+			map[string]string{"X-Forwarded-For": "5.6.7.8"},
+			"4.3.2.1:5678", "4.3.2.1"},
+		{"real ip from untrusted", map[string]string{"X-Real-Ip": "9.8.7.6"},
+			"4.3.2.1:5678", "4.3.2.1"},
+		{"forwarded for from trusted",
+			map[string]string{"X-Forwarded-For": "5.6.7.8, 9.9.9.9"},
+			"1.2.3.4:5678", "9.9.9.9"},
+		{"forwarded for through trusted network",
+			map[string]string{"X-Forwarded-For": "5.6.7.8, 10.1.1.1"},
+			"1.2.3.4:5678", "5.6.7.8"},
+		{"real ip from trusted", map[string]string{"X-Real-Ip": "9.8.7.6"},
+			"10.0.0.2:5678", "9.8.7.6"},
+	}
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/", nil)
+			for k, v := range test.headers {
+				req.Header.Set(k, v)
+			}
+			req.RemoteAddr = test.remote
+			if ip := requestIP(req, proxies); ip != test.ip {
+				t.Fatalf("bad ip %s", ip)
+			}
+		})
+	}
+}
+
+func TestCreateComment_Auth(t *testing.T) {
+
+	co := Config{}
+	s := NewServer(co)
+	s.ClientStorage = NewClientStorageInMemory()
+	s.ClientDomainStorage = NewClientDomainStorageInMemory()
+	s.CommentStorage = NewCommentStorageInMemory()
+	s.mux = http.NewServeMux()
+	s.BodyReader = io.ReadAll
+	s.EmailSender = TestMailSender{}
+	s.Config.Auth = true
+	s.setupUrls()
+
+	c, key, _ := s.ClientStorage.CreateClient("test client")
+	s.ClientDomainStorage.AddClientDomain(c, "bla.net")
+
+	var test_data = []struct {
+		testName string
+		req      *http.Request
+		status   int
+	}{
+		{
+			"comment with bad key",
+			func() *http.Request {
+				challenge, solution := testChallenge(s, c.UUID)
+				payload := CreateCommentRequest{
+					Challenge: challenge,
+					Solution:  solution,
+					FormToken: testFormToken(s, c.UUID),
+					Name:      "Zé",
+					Content:   "A comment",
+				}
+				j, _ := json.Marshal(payload)
+				body := bytes.NewBuffer(j)
+				req, _ := http.NewRequest("POST", "/comment/", body)
+				req.Header.Set("X-APIKey", "bad")
+				req.Header.Set("Origin", "https://bla.net")
+				req.Header.Set("X-PageURL", "https://bla.net/post")
+				req.Header.Set("X-ClientUUID", c.UUID)
+				return req
+
+			}(),
+			403},
+		{
+			"comment ok",
+			func() *http.Request {
+				challenge, solution := testChallenge(s, c.UUID)
+				payload := CreateCommentRequest{
+					Challenge: challenge,
+					Solution:  solution,
+					FormToken: testFormToken(s, c.UUID),
+					Name:      "Zé",
+					Content:   "A comment",
+				}
+				j, _ := json.Marshal(payload)
+				body := bytes.NewBuffer(j)
+				req, _ := http.NewRequest("POST", "/comment/", body)
+				req.Header.Set("X-APIKey", key)
+				req.Header.Set("Origin", "https://bla.net")
+				req.Header.Set("X-ClientUUID", c.UUID)
+				req.Header.Set("X-PageURL", "https://bla.net/post")
+				return req
+
+			}(),
+			201},
+	}
+
+	for _, test := range test_data {
+		t.Run(test.testName, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.mux.ServeHTTP(w, test.req)
+
+			if w.Code != test.status {
+				t.Fatalf("bad status for %d", w.Code)
+			}
+
+		})
+	}
+
+}
+
+func TestListComments(t *testing.T) {
+	co := Config{}
+	s := NewServer(co)
+	s.ClientStorage = NewClientStorageInMemory()
+	s.ClientDomainStorage = NewClientDomainStorageInMemory()
+	s.CommentStorage = NewCommentStorageInMemory()
+	s.mux = http.NewServeMux()
+	s.BodyReader = io.ReadAll
+	s.setupUrls()
+
+	c, _, _ := s.ClientStorage.CreateClient("test client")
+	d, _ := s.ClientDomainStorage.AddClientDomain(c, "bla.net")
+
+	comments := []Comment{
+		{
+			Author:  "Zé",
+			Content: "The comment",
+			PageURL: "http://bla.net/post1",
+		},
+		{
+			Author:  "Tião",
+			Content: "The other comment",
+			PageURL: "http://bla.net/post1",
+		},
+		{
+			Author:  "Jão",
+			Content: "The new comment",
+			PageURL: "http://bla.net/post1",
+		},
+		{
+			Author:  "Zé",
+			Content: "The new new comment",
+			PageURL: "http://bla.net/post2",
+		},
+		{
+			Author:  "Jão",
+			Content: "Another comment",
+			PageURL: "http://bla.net/post2",
+		},
+	}
+
+	for _, co := range comments {
+		s.CommentStorage.CreateComment(c, d, co.Author, co.Content, co.PageURL)
+	}
+
+	var test_data = []struct {
+		testName string
+		req      *http.Request
+		status   int
+	}{
+		{
+			"list comments with bad client",
+			func() *http.Request {
+				uuid, _ := GenUUID4()
+				req, _ := http.NewRequest("GET", "/comment/", nil)
+				req.Header.Set("Origin", "https://bla.net")
+				req.Header.Set("X-PageURL", "https://bla.net/post1")
+				req.Header.Set("X-ClientUUID", uuid)
+				return req
+			}(),
+			403,
+		},
+		{
+			"list comments with wrong origin",
+			func() *http.Request {
+				req, _ := http.NewRequest("GET", "/comment/", nil)
+				req.Header.Set("Origin", "https://bleble.net")
+				req.Header.Set("X-PageURL", "https://bla.net/post1")
+				req.Header.Set("X-ClientUUID", c.UUID)
+				return req
+			}(),
+			403,
+		},
+		{
+			"list comments without origin",
+			func() *http.Request {
+				req, _ := http.NewRequest("GET", "/comment/", nil)
+				req.Header.Set("X-PageURL", "https://bla.net/post1")
+				req.Header.Set("X-ClientUUID", c.UUID)
+				return req
+			}(),
+			403,
+		},
+		{
+			"list comments options",
+			func() *http.Request {
+				req, _ := http.NewRequest("OPTIONS", "/comment/", nil)
+				req.Header.Set("X-PageURL", "https://bla.net/post1")
+				return req
+			}(),
+			204,
+		},
+		{
+			"list comments ok",
+			func() *http.Request {
+				req, _ := http.NewRequest("GET", "/comment/", nil)
+				req.Header.Set("Origin", "https://bla.net")
+				req.Header.Set("X-PageURL", "https://bla.net/post1")
+				req.Header.Set("X-ClientUUID", c.UUID)
+				return req
+			}(),
+			200,
+		},
+	}
+
+	for _, test := range test_data {
+		t.Run(test.testName, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.mux.ServeHTTP(w, test.req)
+
+			if w.Code != test.status {
+				t.Fatalf("bad status for %d", w.Code)
+			}
+
+		})
+	}
+}
+
+func TestListComments_Pagination(t *testing.T) {
+	co := Config{CommentsPageSize: 2}
+	s := NewServer(co)
+	s.ClientStorage = NewClientStorageInMemory()
+	s.ClientDomainStorage = NewClientDomainStorageInMemory()
+	s.CommentStorage = NewCommentStorageInMemory()
+	s.mux = http.NewServeMux()
+	s.BodyReader = io.ReadAll
+	s.setupUrls()
+
+	c, _, _ := s.ClientStorage.CreateClient("test client")
+	d, _ := s.ClientDomainStorage.AddClientDomain(c, "bla.net")
+	for range 5 {
+		s.CommentStorage.CreateComment(
+			c, d, "Zé", "The comment", "https://bla.net/post1")
+	}
+
+	listPage := func(query string) (int, ListCommentsResponse) {
+		req, _ := http.NewRequest("GET", "/comment/"+query, nil)
+		req.Header.Set("Origin", "https://bla.net")
+		req.Header.Set("X-PageURL", "https://bla.net/post1")
+		req.Header.Set("X-ClientUUID", c.UUID)
+		w := httptest.NewRecorder()
+		s.mux.ServeHTTP(w, req)
+		var resp ListCommentsResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	code, resp := listPage("")
+	if code != 200 || len(resp.Comments) != 2 || resp.Next == "" {
+		t.Fatalf("bad first page %d %+v", code, resp)
+	}
+	if resp.Total != 5 {
+		t.Fatalf("bad total %d", resp.Total)
+	}
+
+	code, resp = listPage("?limit=10&cursor=" + resp.Next)
+	if code != 200 || len(resp.Comments) != 3 || resp.Next != "" {
+		t.Fatalf("bad last page %d %+v", code, resp)
+	}
+
+	code, _ = listPage("?cursor=bad!")
+	if code != 400 {
+		t.Fatalf("bad status for bad cursor %d", code)
+	}
+
+	code, _ = listPage("?limit=-1")
+	if code != 400 {
+		t.Fatalf("bad status for bad limit %d", code)
+	}
+
+	cursor := NewCommentCursor(Comment{}, OrderOldest)
+	req, _ := http.NewRequest("GET", "/comment/html?cursor="+cursor.String(), nil)
+	req.Header.Set("Origin", "https://bla.net")
+	req.Header.Set("X-PageURL", "https://bla.net/post1")
+	req.Header.Set("X-ClientUUID", c.UUID)
+	w := httptest.NewRecorder()
+	s.mux.ServeHTTP(w, req)
+	body := w.Body.String()
+	if w.Code != 200 || strings.Contains(body, "parlante-add-comment") {
+		t.Fatalf("bad html next page %d %s", w.Code, body)
+	}
+	if !strings.Contains(body, "parlante-load-more") ||
+		w.Header().Get("X-Next-Cursor") == "" {
+		t.Fatalf("no next cursor in html page %s", body)
+	}
+}
+
+func TestListComments_Edited(t *testing.T) {
+	co := Config{}
+	s := NewServer(co)
+	s.ClientStorage = NewClientStorageInMemory()
+	s.ClientDomainStorage = NewClientDomainStorageInMemory()
+	s.CommentStorage = NewCommentStorageInMemory()
+	s.mux = http.NewServeMux()
+	s.BodyReader = io.ReadAll
+	s.setupUrls()
+
+	c, _, _ := s.ClientStorage.CreateClient("test client")
+	d, _ := s.ClientDomainStorage.AddClientDomain(c, "bla.net")
+	comment, _ := s.CommentStorage.CreateComment(
+		c, d, "Zé", "The coment", "https://bla.net/post1")
+	s.CommentStorage.CreateComment(
+		c, d, "Jão", "Other comment", "https://bla.net/post1")
+	s.CommentStorage.UpdateComment(comment, "The comment", "Zé")
+
+	req, _ := http.NewRequest("GET", "/comment/", nil)
+	req.Header.Set("Origin", "https://bla.net")
+	req.Header.Set("X-PageURL", "https://bla.net/post1")
+	req.Header.Set("X-ClientUUID", c.UUID)
+	w := httptest.NewRecorder()
+	s.mux.ServeHTTP(w, req)
+	var resp ListCommentsResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != 200 || len(resp.Comments) != 2 {
+		t.Fatalf("bad response %d %s", w.Code, w.Body.String())
+	}
+	if !resp.Comments[0].Edited || resp.Comments[0].Content != "The comment" {
+		t.Fatalf("bad edited comment %+v", resp.Comments[0])
+	}
+	if resp.Comments[1].Edited {
+		t.Fatalf("bad not edited comment %+v", resp.Comments[1])
+	}
+}
+
+func TestListComments_Markdown(t *testing.T) {
+	co := Config{}
+	s := NewServer(co)
+	s.ClientStorage = NewClientStorageInMemory()
+	s.ClientDomainStorage = NewClientDomainStorageInMemory()
+	s.CommentStorage = NewCommentStorageInMemory()
+	s.mux = http.NewServeMux()
+	s.BodyReader = io.ReadAll
+	s.setupUrls()
+
+	c, _, _ := s.ClientStorage.CreateClient("test client")
+	d, _ := s.ClientDomainStorage.AddClientDomain(c, "bla.net")
+	s.CommentStorage.CreateComment(
+		c, d, "Zé", "The **comment** <b>", "https://bla.net/post1")
+
+	newReq := func(path string) *http.Request {
+		req, _ := http.NewRequest("GET", path, nil)
+		req.Header.Set("Origin", "https://bla.net")
+		req.Header.Set("X-PageURL", "https://bla.net/post1")
+		req.Header.Set("X-ClientUUID", c.UUID)
+		return req
+	}
+
+	w := httptest.NewRecorder()
+	s.mux.ServeHTTP(w, newReq("/comment/html"))
+	body := w.Body.String()
+	if w.Code != 200 || !strings.Contains(body, "The **comment** &lt;b&gt;") {
+		t.Fatalf("bad plain text comment %d %s", w.Code, body)
+	}
+	if strings.Contains(body, "parlante-preview-button") {
+		t.Fatalf("preview without markdown %s", body)
+	}
+
+	s.ClientDomainStorage.SetDomainMarkdown(d, true)
+	w = httptest.NewRecorder()
+	s.mux.ServeHTTP(w, newReq("/comment/html"))
+	body = w.Body.String()
+	if w.Code != 200 ||
+		!strings.Contains(body, "<p>The <strong>comment</strong> &lt;b&gt;</p>") {
+		t.Fatalf("bad markdown comment %d %s", w.Code, body)
+	}
+	if !strings.Contains(body, "parlante-preview-button") {
+		t.Fatalf("no preview with markdown %s", body)
+	}
+
+	w = httptest.NewRecorder()
+	s.mux.ServeHTTP(w, newReq("/comment/"))
+	var resp ListCommentsResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != 200 || len(resp.Comments) != 1 {
+		t.Fatalf("bad response %d %s", w.Code, w.Body.String())
+	}
+	cr := resp.Comments[0]
+	if cr.Content != "The **comment** <b>" ||
+		cr.ContentHTML != "<p>The <strong>comment</strong> &lt;b&gt;</p>\n" {
+		t.Fatalf("bad markdown comment %+v", cr)
+	}
+}
+
+func TestPreviewComment(t *testing.T) {
+	co := Config{CommentMaxLength: 20}
+	s := NewServer(co)
+	s.ClientStorage = NewClientStorageInMemory()
+	s.ClientDomainStorage = NewClientDomainStorageInMemory()
+	s.CommentStorage = NewCommentStorageInMemory()
+	s.mux = http.NewServeMux()
+	s.BodyReader = io.ReadAll
+	s.setupUrls()
+
+	c, _, _ := s.ClientStorage.CreateClient("test client")
+	d, _ := s.ClientDomainStorage.AddClientDomain(c, "bla.net")
+	s.ClientDomainStorage.AddClientDomain(c, "ble.net")
+	s.ClientDomainStorage.SetDomainMarkdown(d, true)
+
+	var tests = []struct {
+		testName string
+		domain   string
+		body     string
+		status   int
+		html     string
+	}{
+		{
+			"preview markdown",
+			"bla.net",
+			`{"content": "*a* <b>"}`,
+			200,
+			"<p><em>a</em> &lt;b&gt;</p>\n",
+		},
+		{
+			"preview plain text",
+			"ble.net",
+			`{"content": "*a* <b>"}`,
+			200,
+			"*a* &lt;b&gt;",
+		},
+		{
+			"preview too long",
+			"bla.net",
+			`{"content": "a very very long comment"}`,
+			400,
+			"",
+		},
+		{
+			"preview malformed json",
+			"bla.net",
+			`{"content":`,
+			400,
+			"",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			req, _ := http.NewRequest(
+				"POST", "/comment/preview", strings.NewReader(test.body))
+			req.Header.Set("Origin", "https://"+test.domain)
+			req.Header.Set("X-PageURL", "https://"+test.domain+"/post1")
+			req.Header.Set("X-ClientUUID", c.UUID)
+			w := httptest.NewRecorder()
+			s.mux.ServeHTTP(w, req)
+			if w.Code != test.status {
+				t.Fatalf("bad status %d %s", w.Code, w.Body.String())
+			}
+			if test.status != 200 {
+				return
+			}
+			var resp PreviewCommentResponse
+			json.Unmarshal(w.Body.Bytes(), &resp)
+			if resp.HTML != test.html {
+				t.Fatalf("bad preview %q", resp.HTML)
+			}
+		})
+	}
+}
+
+func TestReact(t *testing.T) {
+	co := Config{Reactions: []string{"👍", "❤️"}}
+	s := NewServer(co)
+	s.ClientStorage = NewClientStorageInMemory()
+	s.ClientDomainStorage = NewClientDomainStorageInMemory()
+	s.CommentStorage = NewCommentStorageInMemory()
+	s.mux = http.NewServeMux()
+	s.BodyReader = io.ReadAll
+	s.setupUrls()
+
+	c, _, _ := s.ClientStorage.CreateClient("test client")
+	d, _ := s.ClientDomainStorage.AddClientDomain(c, "bla.net")
+	other, _ := s.ClientDomainStorage.AddClientDomain(c, "ble.net")
+	// in memory domains have no ids
+	other.ID = d.ID + 1
+	comment, _ := s.CommentStorage.CreateComment(
+		c, d, "Zé", "The comment", "https://bla.net/post1")
+	otherComment, _ := s.CommentStorage.CreateComment(
+		c, other, "Zé", "The comment", "https://ble.net/post1")
+	rejected, _ := s.CommentStorage.CreateComment(
+		c, d, "Zé", "The comment", "https://bla.net/post1")
+	s.CommentStorage.SetCommentStatus(rejected, CommentRejected)
+	token := NewReactorToken(s.FormGuard.Signer)
+
+	react := func(body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(
+			"POST", "/comment/react", strings.NewReader(body))
+		req.Header.Set("Origin", "https://bla.net")
+		req.Header.Set("X-PageURL", "https://bla.net/post1")
+		req.Header.Set("X-ClientUUID", c.UUID)
+		w := httptest.NewRecorder()
+		s.mux.ServeHTTP(w, req)
+		return w
+	}
+
+	var tests = []struct {
+		testName  string
+		body      string
+		status    int
+		reacted   bool
+		reactions ReactionCounts
+		newToken  bool
+	}{
+		{
+			"react without token",
+			fmt.Sprintf(`{"comment_id": %d, "reaction": "👍"}`, comment.ID),
+			200,
+			true,
+			ReactionCounts{"👍": 1},
+			true,
+		},
+		{
+			"react with token",
+			fmt.Sprintf(`{"comment_id": %d, "reaction": "👍", "token": "%s"}`,
+				comment.ID, token),
+			200,
+			true,
+			ReactionCounts{"👍": 2},
+			false,
+		},
+		{
+			"other reaction",
+			fmt.Sprintf(`{"comment_id": %d, "reaction": "❤️", "token": "%s"}`,
+				comment.ID, token),
+			200,
+			true,
+			ReactionCounts{"👍": 2, "❤️": 1},
+			false,
+		},
+		{
+			"same reaction again removes it",
+			fmt.Sprintf(`{"comment_id": %d, "reaction": "👍", "token": "%s"}`,
+				comment.ID, token),
+			200,
+			false,
+			ReactionCounts{"👍": 1, "❤️": 1},
+			false,
+		},
+		{
+			"bad token",
+			fmt.Sprintf(`{"comment_id": %d, "reaction": "❤️", "token": "a.b"}`,
+				comment.ID),
+			200,
+			true,
+			ReactionCounts{"👍": 1, "❤️": 2},
+			true,
+		},
+		{
+			"invalid reaction",
+			fmt.Sprintf(`{"comment_id": %d, "reaction": "💩"}`, comment.ID),
+			400,
+			false,
+			nil,
+			false,
+		},
+		{
+			"comment from other domain",
+			fmt.Sprintf(`{"comment_id": %d, "reaction": "👍"}`, otherComment.ID),
+			400,
+			false,
+			nil,
+			false,
+		},
+		{
+			"rejected comment",
+			fmt.Sprintf(`{"comment_id": %d, "reaction": "👍"}`, rejected.ID),
+			400,
+			false,
+			nil,
+			false,
+		},
+		{
+			"missing comment",
+			`{"comment_id": 1000, "reaction": "👍"}`,
+			400,
+			false,
+			nil,
+			false,
+		},
+		{
+			"malformed json",
+			`{"comment_id":`,
+			400,
+			false,
+			nil,
+			false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			w := react(test.body)
+			if w.Code != test.status {
+				t.Fatalf("bad status %d %s", w.Code, w.Body.String())
+			}
+			if test.status != 200 {
+				return
+			}
+			var resp ReactResponse
+			json.Unmarshal(w.Body.Bytes(), &resp)
+			if resp.Reacted != test.reacted ||
+				!reflect.DeepEqual(resp.Reactions, test.reactions) {
+				t.Fatalf("bad response %+v", resp)
+			}
+			if (resp.Token != token) != test.newToken {
+				t.Fatalf("bad token %s", resp.Token)
+			}
+			if _, err := ReactorID(s.FormGuard.Signer, resp.Token); err != nil {
+				t.Fatalf("bad token %s", resp.Token)
+			}
+		})
+	}
+}
+
+func TestReact_RateLimit(t *testing.T) {
+	var tests = []struct {
+		testName  string
+		config    Config
+		withToken bool
+		allowed   int
+	}{
+		{"no limit", Config{}, false, 6},
+		{"react limit",
+			Config{ReactRateLimit: RateLimit{Requests: 3, Period: time.Minute}},
+			true, 3},
+		{"reactor token limit",
+			Config{
+				ReactorTokenRateLimit: RateLimit{Requests: 2, Period: time.Hour}},
+			false, 2},
+		{"reactor token limit behind trusted proxy",
+			Config{
+				ReactorTokenRateLimit: RateLimit{Requests: 2, Period: time.Hour},
+				TrustedProxies:        []string{"1.2.3.4"}},
+			false, 6},
+		{"reactor token limit with token",
+			Config{
+				ReactorTokenRateLimit: RateLimit{Requests: 2, Period: time.Hour}},
+			true, 6},
+	}
+
+	for _, test := range tests {
 		t.Run(test.testName, func(t *testing.T) {
-			w := httptest.NewRecorder()
-			s.mux.ServeHTTP(w, test.req)
-
-			if w.Code != test.status {
-				t.Fatalf("bad status for %d", w.Code)
+			test.config.Reactions = []string{"👍"}
+			s := NewServer(test.config)
+			s.ClientStorage = NewClientStorageInMemory()
+			s.ClientDomainStorage = NewClientDomainStorageInMemory()
+			s.CommentStorage = NewCommentStorageInMemory()
+			s.mux = http.NewServeMux()
+			s.BodyReader = io.ReadAll
+			s.setupUrls()
+
+			c, _, _ := s.ClientStorage.CreateClient("test client")
+			d, _ := s.ClientDomainStorage.AddClientDomain(c, "bla.net")
+			comment, _ := s.CommentStorage.CreateComment(
+				c, d, "Zé", "The comment", "https://bla.net/post1")
+			body := fmt.Sprintf(`{"comment_id": %d, "reaction": "👍"}`,
+				comment.ID)
+			if test.withToken {
+				body = fmt.Sprintf(
+					`{"comment_id": %d, "reaction": "👍", "token": "%s"}`,
+					comment.ID, NewReactorToken(s.FormGuard.Signer))
 			}
 
+			allowed := 0
+			for i := 0; i < 6; i++ {
+				req, _ := http.NewRequest(
+					"POST", "/comment/react", strings.NewReader(body))
+				req.Header.Set("Origin", "https://bla.net")
+				req.Header.Set("X-PageURL", "https://bla.net/post1")
+				req.Header.Set("X-ClientUUID", c.UUID)
+				// a new forwarded ip for each request doesn't escape
+				// the limits without a trusted proxy.
+				req.Header.Set("X-Forwarded-For", fmt.Sprintf("5.6.7.%d", i))
+				req.RemoteAddr = "1.2.3.4:1234"
+				w := httptest.NewRecorder()
+				s.mux.ServeHTTP(w, req)
+
+				if w.Code == 200 {
+					allowed++
+					continue
+				}
+				if w.Code != 429 || w.Header().Get("Retry-After") == "" {
+					t.Fatalf("bad status %d %s", w.Code, w.Header())
+				}
+			}
+			if allowed != test.allowed {
+				t.Fatalf("bad allowed reactions %d", allowed)
+			}
 		})
 	}
-
 }
 
-func TestCreateComment_Auth(t *testing.T) {
-
+func TestListComments_Reactions(t *testing.T) {
 	co := Config{}
 	s := NewServer(co)
 	s.ClientStorage = NewClientStorageInMemory()
@@ -169,71 +2162,82 @@ func TestCreateComment_Auth(t *testing.T) {
 	s.CommentStorage = NewCommentStorageInMemory()
 	s.mux = http.NewServeMux()
 	s.BodyReader = io.ReadAll
-	s.EmailSender = TestMailSender{}
-	s.Config.Auth = true
 	s.setupUrls()
 
-	c, key, _ := s.ClientStorage.CreateClient("test client")
-	s.ClientDomainStorage.AddClientDomain(c, "bla.net")
+	c, _, _ := s.ClientStorage.CreateClient("test client")
+	d, _ := s.ClientDomainStorage.AddClientDomain(c, "bla.net")
+	s.CommentStorage.CreateComment(
+		c, d, "Zé", "The comment", "https://bla.net/post1")
+	popular, _ := s.CommentStorage.CreateComment(
+		c, d, "Jão", "Other comment", "https://bla.net/post1")
+	s.CommentStorage.ToggleReaction(popular, "👍", "a")
+	s.CommentStorage.ToggleReaction(popular, "👍", "b")
+
+	newReq := func(path string) *http.Request {
+		req, _ := http.NewRequest("GET", path, nil)
+		req.Header.Set("Origin", "https://bla.net")
+		req.Header.Set("X-PageURL", "https://bla.net/post1")
+		req.Header.Set("X-ClientUUID", c.UUID)
+		return req
+	}
 
-	var test_data = []struct {
+	var tests = []struct {
 		testName string
-		req      *http.Request
+		path     string
 		status   int
+		firstID  int64
 	}{
-		{
-			"comment with bad key",
-			func() *http.Request {
-				payload := CreateCommentRequest{
-					Name:    "Zé",
-					Content: "A comment",
-				}
-				j, _ := json.Marshal(payload)
-				body := bytes.NewBuffer(j)
-				req, _ := http.NewRequest("POST", "/comment/", body)
-				req.Header.Set("X-APIKey", "bad")
-				req.Header.Set("Origin", "https://bla.net")
-				req.Header.Set("X-PageURL", "https://bla.net/post")
-				req.Header.Set("X-ClientUUID", c.UUID)
-				return req
-
-			}(),
-			403},
-		{
-			"comment ok",
-			func() *http.Request {
-				payload := CreateCommentRequest{
-					Name:    "Zé",
-					Content: "A comment",
-				}
-				j, _ := json.Marshal(payload)
-				body := bytes.NewBuffer(j)
-				req, _ := http.NewRequest("POST", "/comment/", body)
-				req.Header.Set("X-APIKey", key)
-				req.Header.Set("Origin", "https://bla.net")
-				req.Header.Set("X-ClientUUID", c.UUID)
-				req.Header.Set("X-PageURL", "https://bla.net/post")
-				return req
-
-			}(),
-			201},
+		{"default order", "/comment/", 200, 1},
+		{"order by score", "/comment/?order=score", 200, popular.ID},
+		{"bad order", "/comment/?order=bad", 400, 0},
 	}
 
-	for _, test := range test_data {
+	for _, test := range tests {
 		t.Run(test.testName, func(t *testing.T) {
 			w := httptest.NewRecorder()
-			s.mux.ServeHTTP(w, test.req)
-
+			s.mux.ServeHTTP(w, newReq(test.path))
 			if w.Code != test.status {
-				t.Fatalf("bad status for %d", w.Code)
+				t.Fatalf("bad status %d %s", w.Code, w.Body.String())
+			}
+			if test.status != 200 {
+				return
+			}
+			var resp ListCommentsResponse
+			json.Unmarshal(w.Body.Bytes(), &resp)
+			if len(resp.Comments) != 2 || resp.Comments[0].ID != test.firstID {
+				t.Fatalf("bad comments %+v", resp.Comments)
+			}
+			for _, cr := range resp.Comments {
+				expected := ReactionCounts{}
+				if cr.ID == popular.ID {
+					expected["👍"] = 2
+				}
+				if !reflect.DeepEqual(cr.Reactions, expected) ||
+					cr.Score != int64(len(expected)*2) {
+					t.Fatalf("bad reactions %+v", cr)
+				}
 			}
-
 		})
 	}
 
+	w := httptest.NewRecorder()
+	s.mux.ServeHTTP(w, newReq("/comment/html?order=score&limit=1"))
+	body := w.Body.String()
+	if w.Code != 200 || !strings.Contains(body, `data-reaction="👍"`) ||
+		!strings.Contains(body, `<span class="parlante-reaction-count">2</span>`) {
+		t.Fatalf("bad reactions in html %d %s", w.Code, body)
+	}
+	if !strings.Contains(body, `data-order="score"`) {
+		t.Fatalf("bad order for next page %s", body)
+	}
+	w = httptest.NewRecorder()
+	s.mux.ServeHTTP(w, newReq("/comment/html?order=bad"))
+	if w.Code != 400 {
+		t.Fatalf("bad status for bad order %d", w.Code)
+	}
 }
 
-func TestListComments(t *testing.T) {
+func TestListComments_Order(t *testing.T) {
 	co := Config{}
 	s := NewServer(co)
 	s.ClientStorage = NewClientStorageInMemory()
@@ -245,108 +2249,122 @@ func TestListComments(t *testing.T) {
 
 	c, _, _ := s.ClientStorage.CreateClient("test client")
 	d, _ := s.ClientDomainStorage.AddClientDomain(c, "bla.net")
+	s.ClientDomainStorage.SetDomainOrder(d, OrderNewest)
+	first, _ := s.CommentStorage.CreateComment(
+		c, d, "Zé", "The comment", "https://bla.net/post1")
+	s.CommentStorage.CreateComment(
+		c, d, "bob", "Other comment", "https://bla.net/post1")
+	last, _ := s.CommentStorage.CreateComment(
+		c, d, "Ana", "Last comment", "https://bla.net/post1")
+
+	newReq := func(path string) *http.Request {
+		req, _ := http.NewRequest("GET", path, nil)
+		req.Header.Set("Origin", "https://bla.net")
+		req.Header.Set("X-PageURL", "https://bla.net/post1")
+		req.Header.Set("X-ClientUUID", c.UUID)
+		return req
+	}
 
-	comments := []Comment{
-		{
-			Author:  "Zé",
-			Content: "The comment",
-			PageURL: "http://bla.net/post1",
-		},
-		{
-			Author:  "Tião",
-			Content: "The other comment",
-			PageURL: "http://bla.net/post1",
-		},
-		{
-			Author:  "Jão",
-			Content: "The new comment",
-			PageURL: "http://bla.net/post1",
-		},
-		{
-			Author:  "Zé",
-			Content: "The new new comment",
-			PageURL: "http://bla.net/post2",
-		},
-		{
-			Author:  "Jão",
-			Content: "Another comment",
-			PageURL: "http://bla.net/post2",
-		},
+	var tests = []struct {
+		testName string
+		path     string
+		status   int
+		firstID  int64
+	}{
+		{"domain default order", "/comment/", 200, last.ID},
+		{"oldest first", "/comment/?order=oldest", 200, first.ID},
+		{"by author", "/comment/?order=author", 200, last.ID},
+		{"admin order", "/comment/?order=hidden", 400, 0},
 	}
 
-	for _, co := range comments {
-		s.CommentStorage.CreateComment(c, d, co.Author, co.Content, co.PageURL)
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.mux.ServeHTTP(w, newReq(test.path))
+			if w.Code != test.status {
+				t.Fatalf("bad status %d %s", w.Code, w.Body.String())
+			}
+			if test.status != 200 {
+				return
+			}
+			var resp ListCommentsResponse
+			json.Unmarshal(w.Body.Bytes(), &resp)
+			if len(resp.Comments) != 3 || resp.Comments[0].ID != test.firstID {
+				t.Fatalf("bad comments %+v", resp.Comments)
+			}
+		})
 	}
 
-	var test_data = []struct {
+	w := httptest.NewRecorder()
+	s.mux.ServeHTTP(w, newReq("/comment/html"))
+	body := w.Body.String()
+	if w.Code != 200 || !strings.Contains(body, `id="parlante-order"`) ||
+		!strings.Contains(body, `<option value="newest" selected>`) {
+		t.Fatalf("bad order switcher %d %s", w.Code, body)
+	}
+	if strings.Index(body, "Last comment") > strings.Index(body, "The comment") {
+		t.Fatalf("bad order in html %s", body)
+	}
+}
+
+func TestSearchComments(t *testing.T) {
+	co := Config{}
+	s := NewServer(co)
+	s.ClientStorage = NewClientStorageInMemory()
+	s.ClientDomainStorage = NewClientDomainStorageInMemory()
+	s.CommentStorage = NewCommentStorageInMemory()
+	s.mux = http.NewServeMux()
+	s.BodyReader = io.ReadAll
+	s.setupUrls()
+
+	c, key, _ := s.ClientStorage.CreateClient("test client")
+	d, _ := s.ClientDomainStorage.AddClientDomain(c, "bla.net")
+	s.CommentStorage.CreateComment(
+		c, d, "Zé", "The first comment", "https://bla.net/post1")
+	s.CommentStorage.CreateComment(
+		c, d, "Jão", "The second comment", "https://bla.net/post2")
+	s.CommentStorage.CreateComment(
+		c, d, "Tião", "Something else", "https://bla.net/post1")
+
+	var tests = []struct {
 		testName string
-		req      *http.Request
+		query    string
+		key      string
 		status   int
+		count    int
 	}{
-		{
-			"list comments with bad client",
-			func() *http.Request {
-				uuid, _ := GenUUID4()
-				req, _ := http.NewRequest("GET", "/comment/", nil)
-				req.Header.Set("Origin", "https://bla.net")
-				req.Header.Set("X-PageURL", "https://bla.net/post1")
-				req.Header.Set("X-ClientUUID", uuid)
-				return req
-			}(),
-			403,
-		},
-		{
-			"list comments with wrong origin",
-			func() *http.Request {
-				req, _ := http.NewRequest("GET", "/comment/", nil)
-				req.Header.Set("Origin", "https://bleble.net")
-				req.Header.Set("X-PageURL", "https://bla.net/post1")
-				req.Header.Set("X-ClientUUID", c.UUID)
-				return req
-			}(),
-			403,
-		},
-		{
-			"list comments without origin",
-			func() *http.Request {
-				req, _ := http.NewRequest("GET", "/comment/", nil)
-				req.Header.Set("X-PageURL", "https://bla.net/post1")
-				req.Header.Set("X-ClientUUID", c.UUID)
-				return req
-			}(),
-			403,
-		},
-		{
-			"list comments options",
-			func() *http.Request {
-				req, _ := http.NewRequest("OPTIONS", "/comment/", nil)
-				req.Header.Set("X-PageURL", "https://bla.net/post1")
-				return req
-			}(),
-			204,
-		},
-		{
-			"list comments ok",
-			func() *http.Request {
-				req, _ := http.NewRequest("GET", "/comment/", nil)
-				req.Header.Set("Origin", "https://bla.net")
-				req.Header.Set("X-PageURL", "https://bla.net/post1")
-				req.Header.Set("X-ClientUUID", c.UUID)
-				return req
-			}(),
-			200,
-		},
+		{"search without key", "?q=comment", "", 403, 0},
+		{"search with bad key", "?q=comment", "bad", 403, 0},
+		{"search without query", "", key, 400, 0},
+		{"search bad cursor", "?q=comment&cursor=bad!", key, 400, 0},
+		{"search ok", "?q=comment", key, 200, 2},
+		{"search author", "?q=tião", key, 200, 1},
+		{"search page", "?q=comment&page_url=https://bla.net/post2", key, 200, 1},
+		{"search status", "?q=comment&status=spam", key, 200, 0},
+		{"search limit", "?q=comment&limit=1", key, 200, 1},
 	}
 
-	for _, test := range test_data {
+	for _, test := range tests {
 		t.Run(test.testName, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/comment/search"+test.query, nil)
+			req.Header.Set("Origin", "https://bla.net")
+			req.Header.Set("X-ClientUUID", c.UUID)
+			if test.key != "" {
+				req.Header.Set("X-APIKey", test.key)
+			}
 			w := httptest.NewRecorder()
-			s.mux.ServeHTTP(w, test.req)
-
+			s.mux.ServeHTTP(w, req)
 			if w.Code != test.status {
-				t.Fatalf("bad status for %d", w.Code)
+				t.Fatalf("bad status %d %s", w.Code, w.Body.String())
+			}
+			if w.Code != 200 {
+				return
+			}
+			var resp SearchCommentsResponse
+			json.Unmarshal(w.Body.Bytes(), &resp)
+			if len(resp.Comments) != test.count {
+				t.Fatalf("bad len for search results %d", len(resp.Comments))
 			}
-
 		})
 	}
 }
@@ -588,6 +2606,64 @@ func TestListCommentsHTML(t *testing.T) {
 	}
 }
 
+func TestListCommentsHTML_NewestSplitReply(t *testing.T) {
+	co := Config{}
+	s := NewServer(co)
+	s.ClientStorage = NewClientStorageInMemory()
+	s.ClientDomainStorage = NewClientDomainStorageInMemory()
+	s.CommentStorage = NewCommentStorageInMemory()
+	s.mux = http.NewServeMux()
+	s.BodyReader = io.ReadAll
+	s.setupUrls()
+
+	c, _, _ := s.ClientStorage.CreateClient("test client")
+	d, _ := s.ClientDomainStorage.AddClientDomain(c, "bla.net")
+	page := "https://bla.net/post1"
+	parent, _ := s.CommentStorage.CreateComment(
+		c, d, "Zé", "The parent", page)
+	s.CommentStorage.CreateComment(c, d, "bob", "Other comment", page)
+	reply, _ := s.CommentStorage.CreateReply(
+		c, d, parent, "Ana", "The reply", page)
+
+	load := func(path string) string {
+		req, _ := http.NewRequest("GET", path, nil)
+		req.Header.Set("Origin", "https://bla.net")
+		req.Header.Set("X-PageURL", page)
+		req.Header.Set("X-ClientUUID", c.UUID)
+		w := httptest.NewRecorder()
+		s.mux.ServeHTTP(w, req)
+		if w.Code != 200 {
+			t.Fatalf("bad status %d %s", w.Code, w.Body.String())
+		}
+		return w.Body.String()
+	}
+	parentEl := fmt.Sprintf(`id="parlante-comment-%d"`, parent.ID)
+	replyEl := fmt.Sprintf(`id="parlante-comment-%d"`, reply.ID)
+
+	// newest first the reply comes in the first page and the parent in
+	// the next one, so parlante.js must nest the reply after loading it.
+	first := load("/comment/html?order=newest&limit=2")
+	if !strings.Contains(first, replyEl) || strings.Contains(first, parentEl) {
+		t.Fatalf("bad first page %s", first)
+	}
+	parentID := fmt.Sprintf(`data-parent-id="%d"`, parent.ID)
+	if !strings.Contains(first, parentID) {
+		t.Fatalf("bad parent id in reply %s", first)
+	}
+	m := regexp.MustCompile(
+		`class="parlante-load-more" data-next="([^"]+)"\s+data-order="newest"`,
+	).FindStringSubmatch(first)
+	if m == nil {
+		t.Fatalf("bad load more button %s", first)
+	}
+
+	next := url.QueryEscape(html.UnescapeString(m[1]))
+	second := load("/comment/html?order=newest&limit=2&cursor=" + next)
+	if !strings.Contains(second, parentEl) || strings.Contains(second, replyEl) {
+		t.Fatalf("bad second page %s", second)
+	}
+}
+
 func TestListCommentsHTML_Auth(t *testing.T) {
 	co := Config{}
 	s := NewServer(co)
@@ -1331,6 +3407,7 @@ func TestPingMe(t *testing.T) {
 
 	c, _, _ := s.ClientStorage.CreateClient("test client")
 	s.ClientDomainStorage.AddClientDomain(c, "bla.net")
+	s.ClientDomainStorage.SetClientNotifications(c, testNotifications)
 
 	var test_data = []struct {
 		testName string
@@ -1343,10 +3420,14 @@ func TestPingMe(t *testing.T) {
 			"pingme with bad client",
 			func() *http.Request {
 				uuid, _ := GenUUID4()
+				challenge, solution := testChallenge(s, c.UUID)
 				payload := PingMeRequest{
-					Name:    "Zé",
-					Message: "A message",
-					Email:   "a@a.com",
+					Challenge: challenge,
+					Solution:  solution,
+					FormToken: testFormToken(s, c.UUID),
+					Name:      "Zé",
+					Message:   "A message",
+					Email:     "a@a.com",
 				}
 				j, _ := json.Marshal(payload)
 				body := bytes.NewBuffer(j)
@@ -1363,10 +3444,14 @@ func TestPingMe(t *testing.T) {
 		{
 			"pingme with wrong origin",
 			func() *http.Request {
+				challenge, solution := testChallenge(s, c.UUID)
 				payload := PingMeRequest{
-					Name:    "Zé",
-					Message: "A message",
-					Email:   "a@a.com",
+					Challenge: challenge,
+					Solution:  solution,
+					FormToken: testFormToken(s, c.UUID),
+					Name:      "Zé",
+					Message:   "A message",
+					Email:     "a@a.com",
 				}
 				j, _ := json.Marshal(payload)
 				body := bytes.NewBuffer(j)
@@ -1411,9 +3496,13 @@ func TestPingMe(t *testing.T) {
 		{
 			"pingme without name",
 			func() *http.Request {
+				challenge, solution := testChallenge(s, c.UUID)
 				payload := PingMeRequest{
-					Message: "A message",
-					Email:   "a@a.com",
+					Challenge: challenge,
+					Solution:  solution,
+					FormToken: testFormToken(s, c.UUID),
+					Message:   "A message",
+					Email:     "a@a.com",
 				}
 				j, _ := json.Marshal(payload)
 				body := bytes.NewBuffer(j)
@@ -1430,9 +3519,13 @@ func TestPingMe(t *testing.T) {
 		{
 			"pingme without message",
 			func() *http.Request {
+				challenge, solution := testChallenge(s, c.UUID)
 				payload := PingMeRequest{
-					Name:  "Zé",
-					Email: "a@a.com",
+					Challenge: challenge,
+					Solution:  solution,
+					FormToken: testFormToken(s, c.UUID),
+					Name:      "Zé",
+					Email:     "a@a.com",
 				}
 				j, _ := json.Marshal(payload)
 				body := bytes.NewBuffer(j)
@@ -1449,9 +3542,13 @@ func TestPingMe(t *testing.T) {
 		{
 			"pingme without email",
 			func() *http.Request {
+				challenge, solution := testChallenge(s, c.UUID)
 				payload := PingMeRequest{
-					Name:    "Zé",
-					Message: "A message",
+					Challenge: challenge,
+					Solution:  solution,
+					FormToken: testFormToken(s, c.UUID),
+					Name:      "Zé",
+					Message:   "A message",
 				}
 				j, _ := json.Marshal(payload)
 				body := bytes.NewBuffer(j)
@@ -1468,10 +3565,14 @@ func TestPingMe(t *testing.T) {
 		{
 			"pingme error sending email",
 			func() *http.Request {
+				challenge, solution := testChallenge(s, c.UUID)
 				payload := PingMeRequest{
-					Name:    "Zé",
-					Message: "A message",
-					Email:   "a@a.com",
+					Challenge: challenge,
+					Solution:  solution,
+					FormToken: testFormToken(s, c.UUID),
+					Name:      "Zé",
+					Message:   "A message",
+					Email:     "a@a.com",
 				}
 				j, _ := json.Marshal(payload)
 				body := bytes.NewBuffer(j)
@@ -1488,10 +3589,14 @@ func TestPingMe(t *testing.T) {
 		{
 			"pingme ok",
 			func() *http.Request {
+				challenge, solution := testChallenge(s, c.UUID)
 				payload := PingMeRequest{
-					Name:    "Zé",
-					Message: "A message",
-					Email:   "a@a.com",
+					Challenge: challenge,
+					Solution:  solution,
+					FormToken: testFormToken(s, c.UUID),
+					Name:      "Zé",
+					Message:   "A message",
+					Email:     "a@a.com",
 				}
 				j, _ := json.Marshal(payload)
 				body := bytes.NewBuffer(j)
@@ -1527,6 +3632,43 @@ func TestPingMe(t *testing.T) {
 
 }
 
+func TestPingMe_RateLimit(t *testing.T) {
+	co := Config{PingMeRateLimit: RateLimit{Requests: 1, Period: time.Minute}}
+	s := NewServer(co)
+	s.ClientStorage = NewClientStorageInMemory()
+	s.ClientDomainStorage = NewClientDomainStorageInMemory()
+	s.CommentStorage = NewCommentStorageInMemory()
+	s.EmailSender = &TestMailSender{}
+	s.mux = http.NewServeMux()
+	s.BodyReader = io.ReadAll
+	s.setupUrls()
+
+	c, _, _ := s.ClientStorage.CreateClient("test client")
+	s.ClientDomainStorage.AddClientDomain(c, "bla.net")
+	s.ClientDomainStorage.SetClientNotifications(c, testNotifications)
+
+	for _, status := range []int{201, 429} {
+		challenge, solution := testChallenge(s, c.UUID)
+		payload := PingMeRequest{
+			Challenge: challenge,
+			Solution:  solution,
+			FormToken: testFormToken(s, c.UUID),
+			Name:      "Zé",
+			Message:   "A message",
+			Email:     "a@a.com",
+		}
+		j, _ := json.Marshal(payload)
+		req, _ := http.NewRequest("POST", "/pingme/", bytes.NewBuffer(j))
+		req.Header.Set("Origin", "https://bla.net")
+		req.Header.Set("X-ClientUUID", c.UUID)
+		w := httptest.NewRecorder()
+		s.mux.ServeHTTP(w, req)
+		if w.Code != status {
+			t.Fatalf("bad status for pingme %d", w.Code)
+		}
+	}
+}
+
 func TestPingMe_Auth(t *testing.T) {
 
 	co := Config{}
@@ -1543,6 +3685,7 @@ func TestPingMe_Auth(t *testing.T) {
 
 	c, key, _ := s.ClientStorage.CreateClient("test client")
 	s.ClientDomainStorage.AddClientDomain(c, "bla.net")
+	s.ClientDomainStorage.SetClientNotifications(c, testNotifications)
 
 	var test_data = []struct {
 		testName string
@@ -1554,10 +3697,14 @@ func TestPingMe_Auth(t *testing.T) {
 		{
 			"pingme with bad key",
 			func() *http.Request {
+				challenge, solution := testChallenge(s, c.UUID)
 				payload := PingMeRequest{
-					Name:    "Zé",
-					Message: "A message",
-					Email:   "a@a.com",
+					Challenge: challenge,
+					Solution:  solution,
+					FormToken: testFormToken(s, c.UUID),
+					Name:      "Zé",
+					Message:   "A message",
+					Email:     "a@a.com",
 				}
 				j, _ := json.Marshal(payload)
 				body := bytes.NewBuffer(j)
@@ -1575,10 +3722,14 @@ func TestPingMe_Auth(t *testing.T) {
 		{
 			"pingme ok",
 			func() *http.Request {
+				challenge, solution := testChallenge(s, c.UUID)
 				payload := PingMeRequest{
-					Name:    "Zé",
-					Message: "A message",
-					Email:   "a@a.com",
+					Challenge: challenge,
+					Solution:  solution,
+					FormToken: testFormToken(s, c.UUID),
+					Name:      "Zé",
+					Message:   "A message",
+					Email:     "a@a.com",
 				}
 				j, _ := json.Marshal(payload)
 				body := bytes.NewBuffer(j)
@@ -1641,9 +3792,13 @@ func TestComments_WithErrors(t *testing.T) {
 		{
 			"comment with db error on get client",
 			func() *http.Request {
+				challenge, solution := testChallenge(s, c.UUID)
 				payload := CreateCommentRequest{
-					Name:    "Zé",
-					Content: "A comment",
+					Challenge: challenge,
+					Solution:  solution,
+					FormToken: testFormToken(s, c.UUID),
+					Name:      "Zé",
+					Content:   "A comment",
 				}
 				j, _ := json.Marshal(payload)
 				body := bytes.NewBuffer(j)
@@ -1660,9 +3815,13 @@ func TestComments_WithErrors(t *testing.T) {
 		{
 			"comment with db error on get domain",
 			func() *http.Request {
+				challenge, solution := testChallenge(s, c.UUID)
 				payload := CreateCommentRequest{
-					Name:    "Zé",
-					Content: "A comment",
+					Challenge: challenge,
+					Solution:  solution,
+					FormToken: testFormToken(s, c.UUID),
+					Name:      "Zé",
+					Content:   "A comment",
 				}
 				j, _ := json.Marshal(payload)
 				body := bytes.NewBuffer(j)
@@ -1678,9 +3837,13 @@ func TestComments_WithErrors(t *testing.T) {
 		{
 			"comment with db error creating comment",
 			func() *http.Request {
+				challenge, solution := testChallenge(s, c.UUID)
 				payload := CreateCommentRequest{
-					Name:    comment_storage.BadCommenter,
-					Content: "A comment",
+					Challenge: challenge,
+					Solution:  solution,
+					FormToken: testFormToken(s, c.UUID),
+					Name:      comment_storage.BadCommenter,
+					Content:   "A comment",
 				}
 				j, _ := json.Marshal(payload)
 				body := bytes.NewBuffer(j)
@@ -1693,6 +3856,17 @@ func TestComments_WithErrors(t *testing.T) {
 			}(),
 			400,
 		},
+		{
+			"challenge with marshal error",
+			func() *http.Request {
+				req, _ := http.NewRequest("GET", "/challenge/", nil)
+				req.Header.Set("Origin", "https://bla.net")
+				req.Header.Set("X-ClientUUID", c.UUID)
+				return req
+
+			}(),
+			500,
+		},
 		{
 			"create comment error reading body",
 			func() *http.Request {
@@ -1927,6 +4101,36 @@ func TestConfig(t *testing.T) {
 				}
 			},
 		},
+		{
+			"test config with maildir",
+			Config{MaildirPath: "/bla"},
+			func(c Config) {
+				sender, ok := c.emailSender().(MaildirSender)
+				if !ok || sender.MaildirPath != "/bla" {
+					t.Fatalf("Bad maildir sender %+v", c.emailSender())
+				}
+			},
+		},
+		{
+			"test config with smtp",
+			Config{SMTPHost: "smtp.bla.net", SMTPUsername: "me",
+				SMTPSecurity: SMTPTLS, SMTPAuth: SMTPAuthLogin,
+				SMTPTimeout: time.Second},
+			func(c Config) {
+				sender, ok := c.emailSender().(*SMTPSender)
+				if !ok {
+					t.Fatalf("Bad smtp sender %+v", c.emailSender())
+				}
+				if sender.Host != "smtp.bla.net" ||
+					sender.Port != DEFAULT_SMTP_PORT ||
+					sender.Username != "me" ||
+					sender.Security != SMTPTLS ||
+					sender.Auth != SMTPAuthLogin ||
+					sender.Timeout != time.Second {
+					t.Fatalf("Bad smtp sender config %+v", sender)
+				}
+			},
+		},
 	}
 
 	for _, test := range test_data {
@@ -1936,10 +4140,50 @@ func TestConfig(t *testing.T) {
 	}
 }
 
+func TestNewServer_Outbox(t *testing.T) {
+	s := NewServer(Config{MaildirPath: "/bla", OutboxMaxAttempts: 3})
+	if s.EmailSender != s.Outbox {
+		t.Fatalf("bad email sender %+v", s.EmailSender)
+	}
+	if s.Outbox.MaxAttempts != 3 {
+		t.Fatalf("bad outbox max attempts %d", s.Outbox.MaxAttempts)
+	}
+	if _, ok := s.Outbox.Sender.(MaildirSender); !ok {
+		t.Fatalf("bad outbox sender %+v", s.Outbox.Sender)
+	}
+}
+
+func TestPurgeTrash(t *testing.T) {
+	s := NewServer(Config{})
+	ts := NewTrashStorageInMemory()
+	s.TrashStorage = ts
+	old := time.Now().AddDate(0, 0, -40).Unix()
+	ts.AddItem(TrashItem{Type: TrashClient, ID: 1, DeletedAt: old})
+	ts.AddItem(TrashItem{Type: TrashComment, ID: 1, DeletedAt: time.Now().Unix()})
+
+	n, err := s.PurgeTrash()
+	if err != nil || n != 0 {
+		t.Fatalf("trash purged without purge days %d", n)
+	}
+
+	s.Config.TrashPurgeDays = 30
+	n, err = s.PurgeTrash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("bad number of purged items %d", n)
+	}
+	items, _ := ts.ListTrash()
+	if len(items) != 1 || items[0].Type != TrashComment {
+		t.Fatalf("bad items after purge %v", items)
+	}
+}
+
 func TestRequestLogger(t *testing.T) {
 	var s string
 	fn := func(format string, v ...any) {
-		s = fmt.Sprintf(format, v)
+		s = fmt.Sprintf(format, v...)
 	}
 	req, _ := http.NewRequest("GET", "/parlante.js", nil)
 	w := httptest.NewRecorder()
@@ -42,9 +42,14 @@ var embeddedLocales embed.FS
 // GetDefaultLocale returns the default system locatin defined
 // in the LANG environment variable
 func GetDefaultLocale() *gotext.Locale {
+	return GetLocale(GetDefaultLang())
+}
+
+// GetDefaultLang returns the language defined in the LANG environment
+// variable
+func GetDefaultLang() string {
 	lang := os.Getenv("LANG")
-	lang = strings.Split(lang, ".")[0]
-	return GetLocale(lang)
+	return strings.Split(lang, ".")[0]
 }
 
 // GetLocale returns a locale for strings transation
@@ -0,0 +1,315 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package parlante
+
+import (
+	"cmp"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DEFAULT_IMPORT_AUTHOR is the author of imported comments without one.
+const DEFAULT_IMPORT_AUTHOR = "Anonymous"
+
+// ImportedComment is a comment read from the export of other comment
+// system.
+type ImportedComment struct {
+	// Source identifies the comment in the other system.
+	Source string
+	// ParentSource is the source of the comment this one replies to.
+	// Empty for top level comments.
+	ParentSource string
+	Author       string
+	Email        string
+	Website      string
+	Content      string
+	PageURL      string
+	// unix timestamp for the comment. Zero means the time of the import.
+	Timestamp int64
+	// Empty means CommentApproved.
+	Status CommentStatus
+}
+
+// ImportSource is what was read from the export of other comment
+// system.
+type ImportSource struct {
+	Comments []ImportedComment
+	// Number of comments in the export that must not be imported, like
+	// the deleted ones.
+	Skipped int
+}
+
+// ImportOptions changes how the comments are imported.
+type ImportOptions struct {
+	// BaseURL replaces the scheme and host of the pages of the comments.
+	// Used when the pages are not in the same address anymore.
+	BaseURL string
+	// In a dry run nothing is stored, only reported.
+	DryRun bool
+}
+
+// ImportReport tells what was done by an import or what would be done
+// in a dry run.
+type ImportReport struct {
+	Created int
+	// Comments imported before. They are not imported again.
+	Existing int
+	// Comments not imported, like the deleted ones or the ones without
+	// content.
+	Skipped int
+	// Number of comments created in each page.
+	Pages map[string]int
+}
+
+// ImportComments stores the comments of an import source in a domain.
+// The comments keep their authors, timestamps and replies. Comments
+// imported before are not imported again, so an import can be run more
+// than once. All the pages must be in the domain, otherwise nothing is
+// imported.
+func ImportComments(storage CommentStorage, c Client, d ClientDomain,
+	src ImportSource, opts ImportOptions) (ImportReport, error) {
+	report := ImportReport{Skipped: src.Skipped, Pages: make(map[string]int)}
+	comments := make([]ImportedComment, 0, len(src.Comments))
+	for _, ic := range src.Comments {
+		if ic.Source == "" || strings.TrimSpace(ic.Content) == "" {
+			report.Skipped++
+			continue
+		}
+		page, err := importPageURL(ic.PageURL, opts.BaseURL)
+		if err != nil {
+			report.Skipped++
+			continue
+		}
+		domain, _ := getDomainFromURL(page)
+		if domain != d.Domain {
+			return ImportReport{}, fmt.Errorf(
+				"Page %s is not in domain %s", page, d.Domain)
+		}
+		ic.PageURL = page
+		comments = append(comments, ic)
+	}
+	// the oldest comments are created first so the order of the ids
+	// is the order of the timestamps.
+	slices.SortStableFunc(comments, func(a, b ImportedComment) int {
+		return cmp.Compare(a.Timestamp, b.Timestamp)
+	})
+
+	imp := commentImporter{
+		storage:  storage,
+		client:   c,
+		domain:   d,
+		dryRun:   opts.DryRun,
+		report:   &report,
+		bySource: make(map[string]ImportedComment),
+		imported: make(map[string]Comment),
+		visiting: make(map[string]bool),
+	}
+	for _, ic := range comments {
+		imp.bySource[ic.Source] = ic
+	}
+	for _, ic := range comments {
+		_, err := imp.importComment(ic)
+		if err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+type commentImporter struct {
+	storage  CommentStorage
+	client   Client
+	domain   ClientDomain
+	dryRun   bool
+	report   *ImportReport
+	bySource map[string]ImportedComment
+	// the comments already handled, by source. In dry runs the new
+	// comments have no id.
+	imported map[string]Comment
+	visiting map[string]bool
+}
+
+// importComment stores a comment after its parent. Replies whose parent
+// is not imported become top level comments.
+func (i *commentImporter) importComment(ic ImportedComment) (Comment, error) {
+	if comment, ok := i.imported[ic.Source]; ok {
+		return comment, nil
+	}
+	existing, err := i.storage.GetImportedComment(i.domain, ic.Source)
+	if err != nil {
+		return Comment{}, err
+	}
+	if existing.ID != 0 {
+		i.report.Existing++
+		i.imported[ic.Source] = existing
+		return existing, nil
+	}
+
+	i.visiting[ic.Source] = true
+	var parent Comment
+	pic, ok := i.bySource[ic.ParentSource]
+	if ok && !i.visiting[pic.Source] && pic.PageURL == ic.PageURL {
+		parent, err = i.importComment(pic)
+		if err != nil {
+			return Comment{}, err
+		}
+	}
+	delete(i.visiting, ic.Source)
+
+	author := strings.TrimSpace(ic.Author)
+	if author == "" {
+		author = DEFAULT_IMPORT_AUTHOR
+	}
+	comment, err := NewComment(i.client, i.domain, author,
+		strings.TrimSpace(ic.Content), ic.PageURL)
+	if err != nil {
+		return Comment{}, err
+	}
+	comment.ParentID = parent.ID
+	if ic.Timestamp > 0 {
+		comment.Timestamp = ic.Timestamp
+	}
+	comment.Status = ic.Status
+	if comment.Status == "" {
+		comment.Status = CommentApproved
+	}
+	comment.Hidden = comment.Status != CommentApproved
+	// invalid contacts are only dropped, the comment is still good.
+	comment.Email, _ = NormalizeEmail(ic.Email)
+	comment.Website, _ = NormalizeWebsite(ic.Website)
+
+	if !i.dryRun {
+		comment, err = i.storage.ImportComment(comment, ic.Source)
+		if err != nil {
+			return Comment{}, err
+		}
+	}
+	i.report.Created++
+	i.report.Pages[comment.PageURL]++
+	i.imported[ic.Source] = comment
+	return comment, nil
+}
+
+// importPageURL returns the url of the page of an imported comment
+// without the fragment. If baseURL is not empty its scheme and host
+// replace the ones of the page.
+func importPageURL(page string, baseURL string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(page))
+	if err != nil {
+		return "", err
+	}
+	if baseURL != "" {
+		base, err := url.Parse(baseURL)
+		if err != nil {
+			return "", err
+		}
+		u.Scheme = base.Scheme
+		u.Host = base.Host
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", errors.New("Invalid page url")
+	}
+	u.Fragment = ""
+	return u.String(), nil
+}
+
+var blankLinesRegex = regexp.MustCompile(`\n\s*\n\s*\n+`)
+
+// htmlBlocks are the elements whose content is a paragraph in the text.
+var htmlBlocks = []string{
+	"p", "div", "blockquote", "pre", "ul", "ol", "li", "h1", "h2", "h3",
+	"h4", "h5", "h6"}
+
+// importedText converts the html of an imported comment to text.
+// Links keep their urls and the paragraphs are separated by blank
+// lines. Text that is not html is returned as is.
+func importedText(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	d := xml.NewDecoder(strings.NewReader("<body>" + s + "</body>"))
+	d.Strict = false
+	d.AutoClose = xml.HTMLAutoClose
+	d.Entity = xml.HTMLEntity
+
+	var b strings.Builder
+	// hrefs of the open links and where their text starts.
+	links := make([]string, 0)
+	starts := make([]int, 0)
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return strings.TrimSpace(s)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			name := strings.ToLower(t.Name.Local)
+			switch {
+			case name == "br":
+				b.WriteString("\n")
+			case name == "a":
+				href := ""
+				for _, a := range t.Attr {
+					if strings.ToLower(a.Name.Local) == "href" {
+						href = a.Value
+					}
+				}
+				links = append(links, href)
+				starts = append(starts, b.Len())
+			case slices.Contains(htmlBlocks, name):
+				b.WriteString("\n\n")
+			}
+		case xml.EndElement:
+			name := strings.ToLower(t.Name.Local)
+			switch {
+			case name == "a" && len(links) > 0:
+				href := links[len(links)-1]
+				text := b.String()[starts[len(starts)-1]:]
+				links = links[:len(links)-1]
+				starts = starts[:len(starts)-1]
+				if href != "" && strings.TrimSpace(text) != href {
+					b.WriteString(" (" + href + ")")
+				}
+			case slices.Contains(htmlBlocks, name):
+				b.WriteString("\n\n")
+			}
+		case xml.CharData:
+			b.WriteString(string(t))
+		}
+	}
+	text := blankLinesRegex.ReplaceAllString(b.String(), "\n\n")
+	return strings.TrimSpace(text)
+}
+
+// importTimestamp returns the unix timestamp of a date in an export.
+// Zero if the date is invalid.
+func importTimestamp(layout string, date string) int64 {
+	t, err := time.Parse(layout, strings.TrimSpace(date))
+	if err != nil {
+		return 0
+	}
+	return t.Unix()
+}
@@ -0,0 +1,165 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package parlante
+
+import (
+	"testing"
+)
+
+func TestImportedText(t *testing.T) {
+	var tests = []struct {
+		testName string
+		html     string
+		expected string
+	}{
+		{"plain text", "just text\nwith lines", "just text\nwith lines"},
+		{"paragraphs", "<p>one</p>\n<p>two</p>", "one\n\ntwo"},
+		{"line breaks", "one<br>two<br/>three", "one\ntwo\nthree"},
+		{"entities", "<p>a &amp; b &lt;c&gt;</p>", "a & b <c>"},
+		{"link", `<a href="http://x.net">site</a>`, "site (http://x.net)"},
+		{"link with url text",
+			`<a href="http://x.net">http://x.net</a>`, "http://x.net"},
+		{"unknown tags", "<b>bold</b> <em>em</em>", "bold em"},
+		{"windows lines", "one\r\ntwo", "one\ntwo"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			text := importedText(test.html)
+			if text != test.expected {
+				t.Fatalf("bad text %q", text)
+			}
+		})
+	}
+}
+
+func TestImportPageURL(t *testing.T) {
+	var tests = []struct {
+		testName string
+		page     string
+		baseURL  string
+		expected string
+		err      bool
+	}{
+		{"page", "http://bla.net/post", "", "http://bla.net/post", false},
+		{"fragment", "http://bla.net/post#c", "", "http://bla.net/post", false},
+		{"base url", "http://old.net/post?p=1", "https://bla.net",
+			"https://bla.net/post?p=1", false},
+		{"no scheme", "bla.net/post", "", "", true},
+		{"bad scheme", "ftp://bla.net/post", "", "", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			page, err := importPageURL(test.page, test.baseURL)
+			if (err != nil) != test.err {
+				t.Fatalf("bad err %v", err)
+			}
+			if page != test.expected {
+				t.Fatalf("bad page %s", page)
+			}
+		})
+	}
+}
+
+func TestImportComments(t *testing.T) {
+	cs := NewClientStorageInMemory()
+	ds := NewClientDomainStorageInMemory()
+	comms := NewCommentStorageInMemory()
+	c, _, _ := cs.CreateClient("test client")
+	d, _ := ds.AddClientDomain(c, "bla.net")
+
+	src := ImportSource{
+		Comments: []ImportedComment{
+			{
+				Source:       "x:2",
+				ParentSource: "x:1",
+				Author:       "Maria",
+				Content:      "a reply",
+				PageURL:      "http://old.net/post",
+				Timestamp:    200,
+			},
+			{
+				Source:    "x:1",
+				Author:    "Zé",
+				Email:     "not an email",
+				Content:   "first",
+				PageURL:   "http://old.net/post#comments",
+				Timestamp: 100,
+			},
+			{
+				Source:       "x:3",
+				ParentSource: "x:404",
+				Content:      "orphan",
+				PageURL:      "http://old.net/other",
+				Timestamp:    300,
+			},
+			{Source: "x:4", Content: " ", PageURL: "http://old.net/post"},
+		},
+		Skipped: 1,
+	}
+
+	_, err := ImportComments(comms, c, d, src, ImportOptions{})
+	if err == nil {
+		t.Fatalf("no error for page in other domain")
+	}
+
+	opts := ImportOptions{BaseURL: "https://bla.net", DryRun: true}
+	r, err := ImportComments(comms, c, d, src, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Created != 3 || r.Skipped != 2 || r.Existing != 0 ||
+		r.Pages["https://bla.net/post"] != 2 {
+		t.Fatalf("bad dry run report %+v", r)
+	}
+	if comms.GetComment() != (Comment{}) {
+		t.Fatalf("comments created in dry run")
+	}
+
+	opts.DryRun = false
+	r, err = ImportComments(comms, c, d, src, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Created != 3 || r.Existing != 0 {
+		t.Fatalf("bad report %+v", r)
+	}
+	first, _ := comms.GetImportedComment(d, "x:1")
+	reply, _ := comms.GetImportedComment(d, "x:2")
+	orphan, _ := comms.GetImportedComment(d, "x:3")
+	if first.Timestamp != 100 || first.Email != "" ||
+		first.PageURL != "https://bla.net/post" ||
+		first.Status != CommentApproved {
+		t.Fatalf("bad first comment %+v", first)
+	}
+	if reply.ParentID != first.ID || reply.ID < first.ID {
+		t.Fatalf("bad reply %+v", reply)
+	}
+	if orphan.ParentID != 0 || orphan.Author != DEFAULT_IMPORT_AUTHOR {
+		t.Fatalf("bad orphan %+v", orphan)
+	}
+
+	r, err = ImportComments(comms, c, d, src, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Created != 0 || r.Existing != 3 {
+		t.Fatalf("bad report for second import %+v", r)
+	}
+}
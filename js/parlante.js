@@ -1,6 +1,4 @@
-async function parlanteLoadComments(parlante_url, client_uuid, container_id) {
-  let url = parlante_url + '/comment/html';
-  let container = document.getElementById(container_id);
+function parlanteListCommentsOpts(client_uuid) {
   let lang = navigator.language;
   let tz = Intl.DateTimeFormat().resolvedOptions().timeZone;
 
@@ -16,6 +14,13 @@ async function parlanteLoadComments(parlante_url, client_uuid, container_id) {
     mode: "cors",
     cache: "no-cache",
   }
+  return opts
+}
+
+async function parlanteLoadComments(parlante_url, client_uuid, container_id) {
+  let url = parlante_url + '/comment/html';
+  let container = document.getElementById(container_id);
+  let opts = parlanteListCommentsOpts(client_uuid)
 
   let response = null
   try{
@@ -31,21 +36,266 @@ async function parlanteLoadComments(parlante_url, client_uuid, container_id) {
   btn.onclick = function() {
     parlanteSubmitComment(parlante_url, client_uuid)
   }
+  // the preview is only there in domains with markdown
+  let previewBtn = document.getElementById('parlante-preview-button')
+  if (previewBtn) {
+    previewBtn.onclick = function() {
+      parlantePreviewComment(parlante_url, client_uuid)
+    }
+  }
+  parlanteSetupReplies(container)
+  parlanteSetupReactions(parlante_url, client_uuid, container)
+  parlanteSetupAvatars(parlante_url, container)
+  parlanteSetupLoadMore(parlante_url, client_uuid, container)
+  parlanteSetupOrder(parlante_url, client_uuid, container)
+}
+
+function parlanteSetupAvatars(parlante_url, container) {
+  container.querySelectorAll('.parlante-avatar:not([src])').forEach(img => {
+    img.src = parlante_url + '/avatar/' + img.dataset.avatar + '.svg'
+  })
+}
+
+function parlanteSetupLoadMore(parlante_url, client_uuid, container) {
+  let btn = container.querySelector('.parlante-load-more')
+  if (!btn) {
+    return
+  }
+  btn.onclick = function() {
+    parlanteLoadMoreComments(parlante_url, client_uuid, container, btn)
+  }
+}
+
+async function parlanteLoadMoreComments(parlante_url, client_uuid, container, btn) {
+  let url = parlante_url + '/comment/html?cursor=' + encodeURIComponent(btn.dataset.next);
+  if (btn.dataset.order) {
+    url += '&order=' + encodeURIComponent(btn.dataset.order)
+  }
+  let opts = parlanteListCommentsOpts(client_uuid)
+
+  let response = null
+  try{
+    response = await fetch(url, opts);
+  }catch{
+    return
+  }
+  if (!response.ok) {
+    return
+  }
+
+  let html = await response.text()
+  let list = document.getElementById('parlante-comments-list')
+  let page = document.createElement('div')
+  page.innerHTML = html
+  btn.remove()
+  for (let el of Array.from(page.children)) {
+    list.appendChild(el)
+  }
+  parlanteNestReplies(list)
+  parlanteSetupReplies(container)
+  parlanteSetupReactions(parlante_url, client_uuid, container)
+  parlanteSetupAvatars(parlante_url, container)
+  parlanteSetupLoadMore(parlante_url, client_uuid, container)
+}
+
+function parlanteSetupOrder(parlante_url, client_uuid, container) {
+  let select = document.getElementById('parlante-order')
+  if (!select) {
+    return
+  }
+  select.onchange = function() {
+    parlanteChangeOrder(parlante_url, client_uuid, container, select.value)
+  }
+}
+
+// Only the comments list is replaced so what was typed in the form
+// is not lost.
+async function parlanteChangeOrder(parlante_url, client_uuid, container, order) {
+  let url = parlante_url + '/comment/html?order=' + encodeURIComponent(order);
+  let opts = parlanteListCommentsOpts(client_uuid)
+
+  let response = null
+  try{
+    response = await fetch(url, opts);
+  }catch{
+    return
+  }
+  if (!response.ok) {
+    return
+  }
+
+  let html = await response.text()
+  let page = document.createElement('div')
+  page.innerHTML = html
+  let newList = page.querySelector('#parlante-comments-list')
+  let list = document.getElementById('parlante-comments-list')
+  if (!newList || !list) {
+    return
+  }
+  list.innerHTML = newList.innerHTML
+  parlanteSetupReplies(container)
+  parlanteSetupReactions(parlante_url, client_uuid, container)
+  parlanteSetupAvatars(parlante_url, container)
+  parlanteSetupLoadMore(parlante_url, client_uuid, container)
+}
+
+// Replies whose parent is in another page come as top level comments.
+// Here we move them to the parent replies. In orders like newest the
+// parent comes in a later page, so all the top level comments of the
+// list are checked, not only the ones of the new page.
+function parlanteNestReplies(list) {
+  list.querySelectorAll(':scope > .parlante-comment').forEach(el => {
+    let parentId = el.dataset.parentId
+    if (!parentId || parentId == '0') {
+      return
+    }
+    let parent = document.getElementById('parlante-comment-' + parentId)
+    if (!parent) {
+      return
+    }
+    let replies = parent.querySelector(':scope > .parlante-comment-replies')
+    if (!replies) {
+      replies = document.createElement('div')
+      replies.className = 'parlante-comment-replies'
+      parent.appendChild(replies)
+    }
+    // keeps the order of the list: a reply from a previous page goes
+    // before the replies that came with the parent.
+    let next = Array.from(replies.children).find(reply => {
+      return el.compareDocumentPosition(reply) & Node.DOCUMENT_POSITION_FOLLOWING
+    })
+    replies.insertBefore(el, next || null)
+  })
+}
+
+function parlanteSetupReplies(container) {
+  let parentEl = document.getElementById("parlante-parent-id")
+  let replyingEl = document.getElementById("parlante-replying-to")
+  let replyingAuthorEl = document.getElementById("parlante-replying-to-author")
+  let formEl = document.getElementById("parlante-add-comment")
+
+  container.querySelectorAll('.parlante-reply').forEach(btn => {
+    btn.onclick = function() {
+      parentEl.value = btn.dataset.commentId
+      replyingAuthorEl.innerText = btn.dataset.commentAuthor
+      replyingEl.style.display = 'block'
+      formEl.scrollIntoView()
+    }
+  })
+
+  let cancelBtn = document.getElementById("parlante-cancel-reply")
+  cancelBtn.onclick = function() {
+    parentEl.value = ''
+    replyingAuthorEl.innerText = ''
+    replyingEl.style.display = 'none'
+  }
+}
+
+function parlanteSetupReactions(parlante_url, client_uuid, container) {
+  container.querySelectorAll('.parlante-reaction').forEach(btn => {
+    btn.onclick = function() {
+      parlanteReact(parlante_url, client_uuid, btn)
+    }
+  })
+}
+
+// The browser is identified by a token returned by the server, so
+// the same reaction twice removes it.
+async function parlanteReact(parlante_url, client_uuid, btn) {
+  let url = parlante_url + '/comment/react';
+  let payload = {
+    comment_id: parseInt(btn.dataset.commentId),
+    reaction: btn.dataset.reaction,
+    token: localStorage.getItem('parlante-reactor') || '',
+  }
+  let headers = new Headers();
+  headers.append("X-PageURL", window.location.href.split('#')[0])
+  headers.append('X-ClientUUID', client_uuid)
+
+  let opts = {
+    method: "POST",
+    mode: "cors",
+    cache: "no-cache",
+    headers: headers,
+    body: JSON.stringify(payload),
+  }
+
+  let result = null
+  try{
+    let response = await fetch(url, opts)
+    if (!response.ok) {
+      return
+    }
+    result = await response.json()
+  }catch{
+    return
+  }
+  localStorage.setItem('parlante-reactor', result.token)
+  let comment = document.getElementById('parlante-comment-' + btn.dataset.commentId)
+  comment.querySelectorAll(':scope > .parlante-reactions > .parlante-reaction').forEach(el => {
+    let count = result.reactions[el.dataset.reaction] || ''
+    el.querySelector('.parlante-reaction-count').innerText = count
+  })
+  btn.classList.toggle('parlante-reacted', result.reacted)
+}
+
+async function parlantePreviewComment(parlante_url, client_uuid) {
+  let url = parlante_url + '/comment/preview';
+  let content = document.getElementById("parlante-content").value
+  let previewEl = document.getElementById("parlante-preview")
+  if (!content) {
+    previewEl.style.display = 'none'
+    return
+  }
+  let headers = new Headers();
+  headers.append("X-PageURL", window.location.href.split('#')[0])
+  headers.append('X-ClientUUID', client_uuid)
+
+  let opts = {
+    method: "POST",
+    mode: "cors",
+    cache: "no-cache",
+    headers: headers,
+    body: JSON.stringify({content: content}),
+  }
+
+  let result = null
+  try{
+    let response = await fetch(url, opts)
+    if (!response.ok) {
+      return
+    }
+    result = await response.json()
+  }catch{
+    return
+  }
+  // the html comes sanitized from the server.
+  previewEl.innerHTML = result.html
+  previewEl.style.display = 'block'
 }
 
 async function parlanteSubmitComment(parlante_url, client_uuid) {
   let url = parlante_url + '/comment/';
   let authorEl = document.getElementById("parlante-author")
   let contentEl = document.getElementById("parlante-content")
+  let parentEl = document.getElementById("parlante-parent-id")
   let author = authorEl.value;
   let content = contentEl.value
   if (!author || !content) {
     return
   }
-  let body = JSON.stringify({
+  let payload = {
     name: author,
     content: content,
-  })
+    email: document.getElementById("parlante-email").value,
+    website: document.getElementById("parlante-website").value,
+    subscribe: document.getElementById("parlante-subscribe").checked,
+    form_token: document.getElementById("parlante-form-token").value,
+    phone: document.getElementById("parlante-phone").value,
+  }
+  if (parentEl && parentEl.value) {
+    payload.parent_id = parseInt(parentEl.value)
+  }
   let headers = new Headers();
   headers.append("X-PageURL", window.location.href.split('#')[0])
   headers.append('X-ClientUUID', client_uuid)
@@ -56,24 +306,38 @@ async function parlanteSubmitComment(parlante_url, client_uuid) {
     cache: "no-cache",
     headers: headers,
     referrerPolicy: "unsafe-url",
-    body: body,
   }
 
   let container_id = "parlante-add-comment"
   let container_ok_id = "parlante-add-ok"
+  let container_pending_id = "parlante-add-pending"
   let container_error_id = "parlante-add-error"
   let container = document.getElementById(container_id)
   let container_ok = document.getElementById(container_ok_id)
+  let container_pending = document.getElementById(container_pending_id)
   let container_error = document.getElementById(container_error_id)
+  let result = null
   try{
+    let solved = await parlanteSolveChallenge(parlante_url, client_uuid)
+    payload.challenge = solved.challenge
+    payload.solution = solved.solution
+    opts.body = JSON.stringify(payload)
     let response = await fetch(url, opts)
+    if (!response.ok) {
+      throw new Error(response.statusText)
+    }
+    result = await response.json()
   }catch {
     container.style.display = 'none'
     container_error.style.display = 'block'
     return
   }
   container.style.display = 'none'
-  container_ok.style.display = 'block'
+  if (result.status == 'pending') {
+    container_pending.style.display = 'block'
+  } else {
+    container_ok.style.display = 'block'
+  }
 }
 
 async function parlanteCountComments(parlante_url, client_uuid, container_cls, comments_anchor) {
@@ -155,11 +419,13 @@ async function parlantePingMe(parlante_url, client_uuid) {
   if (!name || !email || !message) {
     return
   }
-  let body = JSON.stringify({
+  let payload = {
     name: name,
     message: message,
     email: email,
-  })
+    form_token: document.getElementById("parlante-pingme-form-token").value,
+    phone: document.getElementById("parlante-pingme-phone").value,
+  }
   let headers = new Headers();
   headers.append('X-ClientUUID', client_uuid)
 
@@ -169,7 +435,6 @@ async function parlantePingMe(parlante_url, client_uuid) {
     cache: "no-cache",
     headers: headers,
     referrerPolicy: "unsafe-url",
-    body: body,
   }
 
   let container_id = "parlante-pingme-send"
@@ -179,7 +444,16 @@ async function parlantePingMe(parlante_url, client_uuid) {
   let container_ok = document.getElementById(container_ok_id)
   let container_error = document.getElementById(container_error_id)
   try{
+    let solved = await parlanteSolveChallenge(parlante_url, client_uuid)
+    payload.challenge = solved.challenge
+    payload.solution = solved.solution
+    opts.body = JSON.stringify(payload)
     let response = await fetch(url, opts)
+    // rejected messages, like the ones over the rate limit, were
+    // not sent.
+    if (!response.ok) {
+      throw new Error(response.statusText)
+    }
   }catch {
     container.style.display = 'none'
     container_error.style.display = 'block'
@@ -188,3 +462,44 @@ async function parlantePingMe(parlante_url, client_uuid) {
   container.style.display = 'none'
   container_ok.style.display = 'block'
 }
+
+// Gets a proof of work challenge from the server and finds a solution
+// for it: a string that appended to the challenge gives a hash with
+// the required number of leading zero bits.
+async function parlanteSolveChallenge(parlante_url, client_uuid) {
+  let url = parlante_url + '/challenge/';
+  let headers = new Headers();
+  headers.append('X-ClientUUID', client_uuid)
+
+  let opts = {
+    method: "GET",
+    headers: headers,
+    mode: "cors",
+    cache: "no-cache",
+  }
+  let response = await fetch(url, opts)
+  if (!response.ok) {
+    throw new Error(response.statusText)
+  }
+  let ch = await response.json()
+  let encoder = new TextEncoder()
+  for (let i = 0; ; i++) {
+    let solution = i.toString()
+    let data = encoder.encode(ch.challenge + solution)
+    let hash = new Uint8Array(await crypto.subtle.digest('SHA-256', data))
+    if (parlanteZeroBits(hash) >= ch.difficulty) {
+      return {challenge: ch.challenge, solution: solution}
+    }
+  }
+}
+
+function parlanteZeroBits(hash) {
+  let n = 0
+  for (let b of hash) {
+    if (b != 0) {
+      return n + Math.clz32(b) - 24
+    }
+    n += 8
+  }
+  return n
+}
@@ -19,6 +19,10 @@ package parlante
 
 import (
 	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -94,19 +98,63 @@ func EmailMessage2Maildir(msg EmailMessage, gen keyGen) (string, error) {
 	}
 	msgId := fmt.Sprintf("<%d.%s@localhost>", msg.Timestamp, key)
 
-	mformat := fmt.Sprintf("From: %s\n", msg.From)
-	toStr := strings.Join(msg.To, ",")
-	mformat += fmt.Sprintf("To: %s\n", toStr)
-	mformat += fmt.Sprintf("Subject: %s\n", msg.Subject)
+	to := make([]string, 0, len(msg.To))
+	for _, addr := range msg.To {
+		to = append(to, encodeAddress(addr))
+	}
+	mformat := fmt.Sprintf("From: %s\n", encodeAddress(msg.From))
+	mformat += fmt.Sprintf("To: %s\n", strings.Join(to, ","))
+	mformat += fmt.Sprintf("Subject: %s\n", mime.QEncoding.Encode("UTF-8", msg.Subject))
 	mformat += fmt.Sprintf("Date: %s\n", dtStr)
 	mformat += fmt.Sprintf("Message-ID: %s\n", msgId)
+	names := make([]string, 0, len(msg.Headers))
+	for name := range msg.Headers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		mformat += fmt.Sprintf("%s: %s\n", name, msg.Headers[name])
+	}
 	mformat += fmt.Sprintf("MIME-Version: 1.0\n")
-	mformat += fmt.Sprintf("Content-Type: text/plain; charset=\"UTF-8\"\n")
-	mformat += "\n"
-	mformat += msg.Body
+	if msg.HTMLBody == "" {
+		return mformat + textPart("text/plain", msg.Body), nil
+	}
+	boundary := "parlante-" + key
+	mformat += fmt.Sprintf(
+		"Content-Type: multipart/alternative; boundary=\"%s\"\n\n", boundary)
+	mformat += fmt.Sprintf("--%s\n", boundary)
+	mformat += textPart("text/plain", msg.Body) + "\n"
+	mformat += fmt.Sprintf("--%s\n", boundary)
+	mformat += textPart("text/html", msg.HTMLBody) + "\n"
+	mformat += fmt.Sprintf("--%s--\n", boundary)
 	return mformat, nil
 }
 
+// textPart returns the headers and the quoted-printable body of a text
+// part of a message.
+func textPart(contentType string, body string) string {
+	var b strings.Builder
+	w := quotedprintable.NewWriter(&b)
+	// writing to a strings.Builder never fails
+	w.Write([]byte(body))
+	w.Close()
+	part := fmt.Sprintf("Content-Type: %s; charset=\"UTF-8\"\n", contentType)
+	part += "Content-Transfer-Encoding: quoted-printable\n\n"
+	// the line breaks are \n like in the headers.
+	part += strings.ReplaceAll(b.String(), "\r\n", "\n")
+	return part
+}
+
+// encodeAddress returns the address with a non-ascii name encoded as
+// in rfc 2047. Invalid addresses are returned as they are.
+func encodeAddress(addr string) string {
+	a, err := mail.ParseAddress(addr)
+	if err != nil || a.Name == "" {
+		return addr
+	}
+	return a.String()
+}
+
 func initMaildir(d maildir.Dir) error {
 	mu.Lock()
 	defer mu.Unlock()
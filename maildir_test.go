@@ -20,6 +20,9 @@ package parlante
 import (
 	"fmt"
 	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
 	"os"
 	"strings"
 	"testing"
@@ -40,6 +43,7 @@ func TestEmaiMessage2Maildir(t *testing.T) {
 	body := "How are you doing?"
 	msg, _ := NewEmailMessage(from, to, subject, body)
 	msg.Timestamp = time.Date(2025, 8, 14, 16, 43, 0, 0, time.UTC).Unix()
+	msg.Headers = map[string]string{"List-Unsubscribe": "<https://bla.net/u>"}
 	key, _ := gen()
 	expected := []string{
 		"From: me@bla.net",
@@ -47,6 +51,7 @@ func TestEmaiMessage2Maildir(t *testing.T) {
 		"Subject: Hello",
 		"Date: Thu, 14 Aug 2025 16:43:00 +0000",
 		fmt.Sprintf("Message-ID: <%d.%s@localhost>", msg.Timestamp, key),
+		"List-Unsubscribe: <https://bla.net/u>",
 		"MIME-Version: 1.0",
 		"Content-Type: text/plain; charset=\"UTF-8\"",
 		msg.Body,
@@ -63,6 +68,68 @@ func TestEmaiMessage2Maildir(t *testing.T) {
 	}
 }
 
+func TestEmaiMessage2Maildir_Multipart(t *testing.T) {
+	gen := func() (string, error) {
+		return "xxx", nil
+	}
+
+	msg, _ := NewEmailMessage("José <jose@bla.net>",
+		[]string{"other@ble.com", "Zé Ninguém <ze@ble.com>"},
+		"Olá, comentário novo", "Olá!\nTudo bem?")
+	msg.HTMLBody = "<p>Olá!</p>"
+	mformat, err := EmailMessage2Maildir(msg, gen)
+	if err != nil {
+		t.Fatalf("error EmailMessage2Maildir %s", err.Error())
+	}
+
+	m, err := mail.ReadMessage(strings.NewReader(mformat))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec := new(mime.WordDecoder)
+	subject, _ := dec.DecodeHeader(m.Header.Get("Subject"))
+	if subject != msg.Subject || !strings.Contains(mformat, "=?UTF-8?q?") {
+		t.Fatalf("bad subject %s", m.Header.Get("Subject"))
+	}
+	from, err := m.Header.AddressList("From")
+	if err != nil || from[0].Name != "José" {
+		t.Fatalf("bad from %s %v", m.Header.Get("From"), err)
+	}
+	to, err := m.Header.AddressList("To")
+	if err != nil || len(to) != 2 || to[1].Name != "Zé Ninguém" {
+		t.Fatalf("bad to %s %v", m.Header.Get("To"), err)
+	}
+
+	mediaType, params, _ := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	if mediaType != "multipart/alternative" {
+		t.Fatalf("bad content type %s", mediaType)
+	}
+	r := multipart.NewReader(m.Body, params["boundary"])
+	expected := []struct {
+		contentType string
+		body        string
+	}{
+		{"text/plain", msg.Body},
+		{"text/html", msg.HTMLBody},
+	}
+	for _, e := range expected {
+		// quoted-printable parts are decoded by the reader.
+		part, err := r.NextPart()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ct, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		body, _ := io.ReadAll(part)
+		if ct != e.contentType || string(body) != e.body {
+			t.Fatalf("bad part %s %q", ct, body)
+		}
+	}
+	_, err = r.NextPart()
+	if err != io.EOF {
+		t.Fatalf("unexpected part %v", err)
+	}
+}
+
 func TestSendEmail(t *testing.T) {
 
 	gen := func() (string, error) {
@@ -0,0 +1,345 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package parlante
+
+import (
+	"encoding/xml"
+	"html"
+	"html/template"
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// allowedTags are the html elements allowed in the rendered comments
+// and the attributes allowed in each one.
+var allowedTags = map[string][]string{
+	"p":          nil,
+	"br":         nil,
+	"em":         nil,
+	"strong":     nil,
+	"code":       nil,
+	"pre":        nil,
+	"blockquote": nil,
+	"a":          {"href"},
+}
+
+// allowedLinkSchemes are the url schemes allowed in the links
+var allowedLinkSchemes = []string{"http", "https", "mailto"}
+
+// characters that can be escaped with a backslash in markdown
+const markdownPunct = "\\`*_[]()>#"
+
+// RenderMarkdown renders the markdown subset accepted in the comments:
+// paragraphs, emphasis, inline code, fenced code blocks, quotes and
+// links. Raw html is escaped and the result passes through
+// SanitizeHTML anyway.
+func RenderMarkdown(content string) template.HTML {
+	content = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) && r != '\n' && r != '\t' {
+			return -1
+		}
+		return r
+	}, content)
+	lines := strings.Split(content, "\n")
+	return template.HTML(SanitizeHTML(renderMarkdownBlocks(lines)))
+}
+
+// RenderCommentContent returns the content of a comment as html. In
+// domains with markdown the content is markdown, otherwise it is
+// plain text.
+func RenderCommentContent(d ClientDomain, content string) template.HTML {
+	if d.Markdown {
+		return RenderMarkdown(content)
+	}
+	return template.HTML(html.EscapeString(content))
+}
+
+func renderMarkdownBlocks(lines []string) string {
+	var b strings.Builder
+	para := make([]string, 0)
+	flush := func() {
+		if len(para) == 0 {
+			return
+		}
+		b.WriteString("<p>")
+		for i, line := range para {
+			if i > 0 {
+				b.WriteString("<br>\n")
+			}
+			b.WriteString(renderMarkdownInline(strings.TrimSpace(line), true))
+		}
+		b.WriteString("</p>\n")
+		para = para[:0]
+	}
+	for i := 0; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		switch {
+		case strings.HasPrefix(trimmed, "```"):
+			flush()
+			code := make([]string, 0)
+			for i++; i < len(lines); i++ {
+				if strings.HasPrefix(strings.TrimSpace(lines[i]), "```") {
+					break
+				}
+				code = append(code, lines[i])
+			}
+			b.WriteString("<pre><code>")
+			b.WriteString(html.EscapeString(strings.Join(code, "\n")))
+			b.WriteString("</code></pre>\n")
+		case strings.HasPrefix(trimmed, ">"):
+			flush()
+			quote := make([]string, 0)
+			for ; i < len(lines); i++ {
+				line := strings.TrimSpace(lines[i])
+				if !strings.HasPrefix(line, ">") {
+					break
+				}
+				quote = append(quote, strings.TrimPrefix(line[1:], " "))
+			}
+			// the line after the quote is not consumed.
+			i--
+			b.WriteString("<blockquote>\n")
+			b.WriteString(renderMarkdownBlocks(quote))
+			b.WriteString("</blockquote>\n")
+		case trimmed == "":
+			flush()
+		default:
+			para = append(para, lines[i])
+		}
+	}
+	flush()
+	return b.String()
+}
+
+// renderMarkdownInline renders the emphasis, code and links in a line.
+// links is false inside the text of links so they are not nested.
+func renderMarkdownInline(s string, links bool) string {
+	var b strings.Builder
+	text := 0
+	for i := 0; i < len(s); {
+		out, n := markdownInlineToken(s, i, links)
+		if n == 0 {
+			i++
+			continue
+		}
+		b.WriteString(html.EscapeString(s[text:i]))
+		b.WriteString(out)
+		i += n
+		text = i
+	}
+	b.WriteString(html.EscapeString(s[text:]))
+	return b.String()
+}
+
+// markdownInlineToken returns the html for the markdown starting at i
+// and the number of bytes used. Zero bytes means there is no markdown
+// at i.
+func markdownInlineToken(s string, i int, links bool) (string, int) {
+	switch s[i] {
+	case '\\':
+		if i+1 < len(s) && strings.IndexByte(markdownPunct, s[i+1]) >= 0 {
+			return html.EscapeString(s[i+1 : i+2]), 2
+		}
+	case '`':
+		if j := strings.IndexByte(s[i+1:], '`'); j > 0 {
+			return "<code>" + html.EscapeString(s[i+1:i+1+j]) + "</code>", j + 2
+		}
+	case '*', '_':
+		return markdownEmphasis(s, i, links)
+	case '[':
+		if links {
+			return markdownLink(s[i:])
+		}
+	case 'h', 'H':
+		if links && (i == 0 || !isWordByte(s[i-1])) {
+			return markdownAutolink(s[i:])
+		}
+	}
+	return "", 0
+}
+
+func markdownEmphasis(s string, i int, links bool) (string, int) {
+	d := s[i]
+	if d == '_' && i > 0 && isWordByte(s[i-1]) {
+		return "", 0
+	}
+	marker, tag := s[i:i+1], "em"
+	if i+1 < len(s) && s[i+1] == d {
+		marker, tag = s[i:i+2], "strong"
+	}
+	start := i + len(marker)
+	if start >= len(s) || unicode.IsSpace(rune(s[start])) {
+		return "", 0
+	}
+	for off := start; off < len(s); {
+		j := strings.Index(s[off:], marker)
+		if j < 0 {
+			return "", 0
+		}
+		end := off + j
+		after := end + len(marker)
+		closes := end > start && !unicode.IsSpace(rune(s[end-1])) &&
+			(d != '_' || after >= len(s) || !isWordByte(s[after]))
+		if closes {
+			inner := renderMarkdownInline(s[start:end], links)
+			return "<" + tag + ">" + inner + "</" + tag + ">", after - i
+		}
+		off = end + 1
+	}
+	return "", 0
+}
+
+// markdownLink renders links like [text](url)
+func markdownLink(s string) (string, int) {
+	end := strings.Index(s, "](")
+	if end < 1 || strings.ContainsAny(s[1:end], "[]") {
+		return "", 0
+	}
+	closing := strings.IndexByte(s[end+2:], ')')
+	if closing < 0 {
+		return "", 0
+	}
+	href := strings.TrimSpace(s[end+2 : end+2+closing])
+	if !isSafeURL(href) {
+		return "", 0
+	}
+	text := renderMarkdownInline(s[1:end], false)
+	return markdownAnchor(href, text), end + 3 + closing
+}
+
+// markdownAutolink renders the urls in the text as links
+func markdownAutolink(s string) (string, int) {
+	lower := strings.ToLower(s[:min(len(s), 8)])
+	if !strings.HasPrefix(lower, "http://") &&
+		!strings.HasPrefix(lower, "https://") {
+		return "", 0
+	}
+	end := strings.IndexFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune(`<>"`, r)
+	})
+	if end < 0 {
+		end = len(s)
+	}
+	// punctuation after the url is not part of it
+	href := strings.TrimRight(s[:end], ".,;:!?)'")
+	if !isSafeURL(href) {
+		return "", 0
+	}
+	return markdownAnchor(href, html.EscapeString(href)), len(href)
+}
+
+func markdownAnchor(href string, text string) string {
+	return `<a href="` + html.EscapeString(href) + `" rel="nofollow ugc">` +
+		text + "</a>"
+}
+
+// isSafeURL says if the url may be used in a link
+func isSafeURL(href string) bool {
+	if href == "" || strings.ContainsFunc(href, unicode.IsSpace) {
+		return false
+	}
+	u, err := url.Parse(href)
+	if err != nil {
+		return false
+	}
+	scheme := strings.ToLower(u.Scheme)
+	if !slices.Contains(allowedLinkSchemes, scheme) {
+		return false
+	}
+	return scheme == "mailto" || u.Host != ""
+}
+
+func isWordByte(c byte) bool {
+	// bytes of multibyte runes are taken as letters.
+	return c >= 0x80 || c == '_' || ('0' <= c && c <= '9') ||
+		('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+// SanitizeHTML keeps only the allowed elements and attributes of the
+// html. Links keep only http, https and mailto urls and always get
+// rel="nofollow ugc". The text of removed elements is kept, except for
+// script and style. Html that can't be parsed is escaped.
+func SanitizeHTML(s string) string {
+	d := xml.NewDecoder(strings.NewReader("<body>" + s + "</body>"))
+	d.Strict = false
+	d.AutoClose = xml.HTMLAutoClose
+	d.Entity = xml.HTMLEntity
+
+	var b strings.Builder
+	// names of the open elements. Empty for the removed ones.
+	open := make([]string, 0)
+	// depth inside script or style elements.
+	skip := 0
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return html.EscapeString(s)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			name := strings.ToLower(t.Name.Local)
+			if skip > 0 || name == "script" || name == "style" {
+				skip++
+				open = append(open, "")
+				continue
+			}
+			attrs, ok := allowedTags[name]
+			if !ok || t.Name.Space != "" {
+				open = append(open, "")
+				continue
+			}
+			open = append(open, name)
+			b.WriteString("<" + name)
+			for _, a := range t.Attr {
+				attr := strings.ToLower(a.Name.Local)
+				if a.Name.Space != "" || !slices.Contains(attrs, attr) {
+					continue
+				}
+				if attr == "href" && !isSafeURL(a.Value) {
+					continue
+				}
+				b.WriteString(" " + attr + `="` + html.EscapeString(a.Value) + `"`)
+			}
+			if name == "a" {
+				b.WriteString(` rel="nofollow ugc"`)
+			}
+			b.WriteString(">")
+		case xml.EndElement:
+			name := open[len(open)-1]
+			open = open[:len(open)-1]
+			if skip > 0 {
+				skip--
+				continue
+			}
+			if name != "" && name != "br" {
+				b.WriteString("</" + name + ">")
+			}
+		case xml.CharData:
+			if skip == 0 {
+				b.WriteString(html.EscapeString(string(t)))
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,190 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package parlante
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	var tests = []struct {
+		testName string
+		content  string
+		expected string
+	}{
+		{
+			"plain text",
+			"some comment",
+			"<p>some comment</p>\n",
+		},
+		{
+			"paragraphs and line breaks",
+			"first line\nsecond line\n\nother paragraph",
+			"<p>first line<br>\nsecond line</p>\n<p>other paragraph</p>\n",
+		},
+		{
+			"emphasis",
+			"*em* and **strong** and _em_ and __strong__",
+			"<p><em>em</em> and <strong>strong</strong> and <em>em</em> and <strong>strong</strong></p>\n",
+		},
+		{
+			"no emphasis inside words",
+			"snake_case_name and 2 * 3 * 4",
+			"<p>snake_case_name and 2 * 3 * 4</p>\n",
+		},
+		{
+			"escaped markdown",
+			`\*not em\*`,
+			"<p>*not em*</p>\n",
+		},
+		{
+			"inline code",
+			"use `<b>*x*</b>` here",
+			"<p>use <code>&lt;b&gt;*x*&lt;/b&gt;</code> here</p>\n",
+		},
+		{
+			"code block",
+			"```go\nif a < b {\n    *x = 1\n}\n```\nafter",
+			"<pre><code>if a &lt; b {\n    *x = 1\n}</code></pre>\n<p>after</p>\n",
+		},
+		{
+			"quote",
+			"> quoted *text*\n> more\n\nanswer",
+			"<blockquote>\n<p>quoted <em>text</em><br>\nmore</p>\n</blockquote>\n<p>answer</p>\n",
+		},
+		{
+			"link",
+			"see [the *post*](https://bla.net/post?a=1&b=2)",
+			`<p>see <a href="https://bla.net/post?a=1&amp;b=2" rel="nofollow ugc">the <em>post</em></a></p>` + "\n",
+		},
+		{
+			"autolink",
+			"see http://bla.net/post.",
+			`<p>see <a href="http://bla.net/post" rel="nofollow ugc">http://bla.net/post</a>.</p>` + "\n",
+		},
+		{
+			"no nested links",
+			"[http://bla.net](http://ble.net)",
+			`<p><a href="http://ble.net" rel="nofollow ugc">http://bla.net</a></p>` + "\n",
+		},
+		{
+			"javascript link",
+			"[click](javascript:alert(1))",
+			"<p>[click](javascript:alert(1))</p>\n",
+		},
+		{
+			"raw html",
+			`<script>alert("x")</script><a href="/post" onclick="x">a</a>`,
+			"<p>&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt;&lt;a href=&#34;/post&#34; onclick=&#34;x&#34;&gt;a&lt;/a&gt;</p>\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			r := string(RenderMarkdown(test.content))
+			if r != test.expected {
+				t.Fatalf("bad markdown\n%q\n%q", r, test.expected)
+			}
+		})
+	}
+}
+
+func TestRenderCommentContent(t *testing.T) {
+	d := ClientDomain{}
+	r := RenderCommentContent(d, "*a* <b>")
+	if r != "*a* &lt;b&gt;" {
+		t.Fatalf("bad content for plain text %s", r)
+	}
+	d.Markdown = true
+	r = RenderCommentContent(d, "*a* <b>")
+	if r != "<p><em>a</em> &lt;b&gt;</p>\n" {
+		t.Fatalf("bad content for markdown %s", r)
+	}
+}
+
+func TestSanitizeHTML(t *testing.T) {
+	var tests = []struct {
+		testName string
+		html     string
+		expected string
+	}{
+		{
+			"allowed tags",
+			"<p><em>a</em><br><strong>b</strong></p>",
+			"<p><em>a</em><br><strong>b</strong></p>",
+		},
+		{
+			"removed tags keep text",
+			`<div class="x"><img src="x.png">text <b>bold</b></div>`,
+			"text bold",
+		},
+		{
+			"script and style removed",
+			"<p>a<script>alert(1)</script><style>p {}</style></p>",
+			"<p>a</p>",
+		},
+		{
+			"attributes removed",
+			`<p onclick="x" style="y">a</p>`,
+			"<p>a</p>",
+		},
+		{
+			"links get rel",
+			`<a href="https://bla.net" rel="follow" target="_blank">a</a>`,
+			`<a href="https://bla.net" rel="nofollow ugc">a</a>`,
+		},
+		{
+			"unsafe link",
+			`<a href="javascript:alert(1)">a</a>`,
+			`<a rel="nofollow ugc">a</a>`,
+		},
+		{
+			"comments removed",
+			"<p>a<!-- <script>x</script> --></p>",
+			"<p>a</p>",
+		},
+		{
+			"text escaped",
+			"<p>a &amp; &lt;b&gt;</p>",
+			"<p>a &amp; &lt;b&gt;</p>",
+		},
+		{
+			"unclosed tags",
+			"<p><em>a",
+			"<p><em>a</em></p>",
+		},
+		{
+			"invalid html",
+			"<p>a</p></div><script>x</script>",
+			"&lt;p&gt;a&lt;/p&gt;&lt;/div&gt;&lt;script&gt;x&lt;/script&gt;",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			r := SanitizeHTML(test.html)
+			if r != test.expected {
+				t.Fatalf("bad html\n%q\n%q", r, test.expected)
+			}
+			if strings.Contains(r, "<script") {
+				t.Fatalf("script not removed %s", r)
+			}
+		})
+	}
+}
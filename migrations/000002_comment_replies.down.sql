@@ -0,0 +1,2 @@
+drop index if exists comment_parent_idx;
+alter table comments drop column parent_id;
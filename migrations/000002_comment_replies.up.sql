@@ -0,0 +1,3 @@
+alter table comments add column parent_id integer default null;
+
+CREATE INDEX IF NOT EXISTS comment_parent_idx ON comments(parent_id);
@@ -0,0 +1,3 @@
+alter table client_domains drop column moderate;
+drop index if exists comment_status_idx;
+alter table comments drop column status;
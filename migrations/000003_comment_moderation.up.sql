@@ -0,0 +1,6 @@
+alter table comments add column status string not null default 'approved';
+update comments set status = 'rejected' where hidden = 1;
+
+CREATE INDEX IF NOT EXISTS comment_status_idx ON comments(status);
+
+alter table client_domains add column moderate boolean not null default false;
@@ -0,0 +1,30 @@
+create virtual table if not exists comments_fts using fts5(
+       name,
+       content,
+       page_url,
+       content='comments',
+       content_rowid='id'
+);
+
+insert into comments_fts(comments_fts) values('rebuild');
+
+create trigger if not exists comments_fts_insert after insert on comments
+begin
+       insert into comments_fts(rowid, name, content, page_url)
+       values (new.id, new.name, new.content, new.page_url);
+end;
+
+create trigger if not exists comments_fts_delete after delete on comments
+begin
+       insert into comments_fts(comments_fts, rowid, name, content, page_url)
+       values ('delete', old.id, old.name, old.content, old.page_url);
+end;
+
+create trigger if not exists comments_fts_update
+after update of name, content, page_url on comments
+begin
+       insert into comments_fts(comments_fts, rowid, name, content, page_url)
+       values ('delete', old.id, old.name, old.content, old.page_url);
+       insert into comments_fts(rowid, name, content, page_url)
+       values (new.id, new.name, new.content, new.page_url);
+end;
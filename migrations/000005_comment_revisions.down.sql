@@ -0,0 +1,3 @@
+drop index if exists comment_revision_comment_idx;
+drop table if exists comment_revisions;
+alter table comments drop column edited_at;
@@ -0,0 +1,12 @@
+alter table comments add column edited_at timestamp not null default 0;
+
+create table if not exists comment_revisions (
+       id INTEGER PRIMARY KEY AUTOINCREMENT,
+       comment_id integer not null,
+       content text not null,
+       editor string not null,
+       timestamp timestamp not null,
+       FOREIGN KEY(comment_id) REFERENCES comments(id)
+);
+
+CREATE INDEX IF NOT EXISTS comment_revision_comment_idx ON comment_revisions(comment_id);
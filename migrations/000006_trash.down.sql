@@ -0,0 +1,6 @@
+drop index if exists comment_deleted_at_idx;
+drop index if exists client_domain_deleted_at_idx;
+drop index if exists client_deleted_at_idx;
+alter table comments drop column deleted_at;
+alter table client_domains drop column deleted_at;
+alter table clients drop column deleted_at;
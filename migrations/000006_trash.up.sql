@@ -0,0 +1,7 @@
+alter table clients add column deleted_at timestamp default null;
+alter table client_domains add column deleted_at timestamp default null;
+alter table comments add column deleted_at timestamp default null;
+
+CREATE INDEX IF NOT EXISTS client_deleted_at_idx ON clients(deleted_at);
+CREATE INDEX IF NOT EXISTS client_domain_deleted_at_idx ON client_domains(deleted_at);
+CREATE INDEX IF NOT EXISTS comment_deleted_at_idx ON comments(deleted_at);
@@ -0,0 +1,93 @@
+create table client_domains_old (
+       id INTEGER PRIMARY KEY AUTOINCREMENT,
+       client_id integer not null,
+       domain string not null,
+       moderate boolean not null default false,
+       deleted_at timestamp default null,
+       FOREIGN KEY(client_id) REFERENCES clients(id),
+       Unique(client_id, domain) on conflict fail
+);
+
+insert into client_domains_old (id, client_id, domain, moderate, deleted_at)
+select id, client_id, domain, moderate, deleted_at from client_domains;
+
+drop table client_domains;
+alter table client_domains_old rename to client_domains;
+
+CREATE INDEX IF NOT EXISTS client_domain_client_idx ON client_domains(client_id);
+CREATE INDEX IF NOT EXISTS client_domain_domain_idx ON client_domains(domain);
+CREATE INDEX IF NOT EXISTS client_domain_deleted_at_idx ON client_domains(deleted_at);
+
+
+create table comments_old (
+       id INTEGER PRIMARY KEY AUTOINCREMENT,
+       client_id integer not null,
+       domain_id integer not null,
+       name STRING not null,
+       content text not null,
+       page_url string not null,
+       hidden boolean not null default false,
+       timestamp timestamp not null,
+       parent_id integer default null,
+       status string not null default 'approved',
+       edited_at timestamp not null default 0,
+       deleted_at timestamp default null,
+       FOREIGN KEY(domain_id) REFERENCES client_domains(id),
+       FOREIGN KEY(client_id) REFERENCES clients(id)
+);
+
+insert into comments_old (id, client_id, domain_id, name, content, page_url,
+                          hidden, timestamp, parent_id, status, edited_at,
+                          deleted_at)
+select id, client_id, domain_id, name, content, page_url, hidden, timestamp,
+       parent_id, status, edited_at, deleted_at
+from comments;
+
+drop table comments;
+alter table comments_old rename to comments;
+
+CREATE INDEX IF NOT EXISTS comment_client_idx ON comments(client_id);
+CREATE INDEX IF NOT EXISTS comment_domain_idx ON comments(domain_id);
+CREATE INDEX IF NOT EXISTS comment_page_url_idx ON comments(page_url);
+CREATE INDEX IF NOT EXISTS comment_parent_idx ON comments(parent_id);
+CREATE INDEX IF NOT EXISTS comment_status_idx ON comments(status);
+CREATE INDEX IF NOT EXISTS comment_deleted_at_idx ON comments(deleted_at);
+
+create trigger if not exists comments_fts_insert after insert on comments
+begin
+       insert into comments_fts(rowid, name, content, page_url)
+       values (new.id, new.name, new.content, new.page_url);
+end;
+
+create trigger if not exists comments_fts_delete after delete on comments
+begin
+       insert into comments_fts(comments_fts, rowid, name, content, page_url)
+       values ('delete', old.id, old.name, old.content, old.page_url);
+end;
+
+create trigger if not exists comments_fts_update
+after update of name, content, page_url on comments
+begin
+       insert into comments_fts(comments_fts, rowid, name, content, page_url)
+       values ('delete', old.id, old.name, old.content, old.page_url);
+       insert into comments_fts(rowid, name, content, page_url)
+       values (new.id, new.name, new.content, new.page_url);
+end;
+
+
+create table comment_revisions_old (
+       id INTEGER PRIMARY KEY AUTOINCREMENT,
+       comment_id integer not null,
+       content text not null,
+       editor string not null,
+       timestamp timestamp not null,
+       FOREIGN KEY(comment_id) REFERENCES comments(id)
+);
+
+insert into comment_revisions_old (id, comment_id, content, editor, timestamp)
+select id, comment_id, content, editor, timestamp from comment_revisions;
+
+drop table comment_revisions;
+alter table comment_revisions_old rename to comment_revisions;
+
+CREATE INDEX IF NOT EXISTS comment_revision_comment_idx ON comment_revisions(comment_id);
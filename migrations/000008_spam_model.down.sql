@@ -0,0 +1,2 @@
+drop table if exists spam_trained_comments;
+drop table if exists spam_tokens;
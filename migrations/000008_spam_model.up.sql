@@ -0,0 +1,13 @@
+create table if not exists spam_tokens (
+       token text primary key,
+       spam integer not null default 0,
+       ham integer not null default 0
+);
+
+-- The comments used to train the spam model. The tokens are kept so
+-- a comment can be untrained even after it was changed or removed.
+create table if not exists spam_trained_comments (
+       comment_id integer primary key,
+       spam boolean not null,
+       tokens text not null
+);
@@ -0,0 +1,3 @@
+drop table if exists comment_request_info;
+alter table clients drop column akismet_key;
+alter table clients drop column akismet_endpoint;
@@ -0,0 +1,12 @@
+alter table clients add column akismet_endpoint text not null default '';
+alter table clients add column akismet_key text not null default '';
+
+-- Information about the request that created a comment. Needed by
+-- external spam services.
+create table if not exists comment_request_info (
+       comment_id integer primary key,
+       user_ip text not null,
+       user_agent text not null,
+       referrer text not null,
+       FOREIGN KEY(comment_id) REFERENCES comments(id) on delete cascade
+);
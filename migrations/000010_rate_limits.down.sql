@@ -0,0 +1,2 @@
+drop table if exists domain_rate_limits;
+drop table if exists client_rate_limits;
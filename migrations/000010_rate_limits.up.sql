@@ -0,0 +1,19 @@
+-- Rate limits overriding the server limits. Domain limits take
+-- precedence over client limits. Period is in seconds.
+create table if not exists client_rate_limits (
+       client_id integer not null,
+       route text not null,
+       requests integer not null,
+       period integer not null,
+       PRIMARY KEY(client_id, route),
+       FOREIGN KEY(client_id) REFERENCES clients(id) on delete cascade
+);
+
+create table if not exists domain_rate_limits (
+       domain_id integer not null,
+       route text not null,
+       requests integer not null,
+       period integer not null,
+       PRIMARY KEY(domain_id, route),
+       FOREIGN KEY(domain_id) REFERENCES client_domains(id) on delete cascade
+);
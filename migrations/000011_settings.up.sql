@@ -0,0 +1,6 @@
+-- Values generated by the server that must survive restarts, like the
+-- secret key used to sign tokens.
+create table if not exists settings (
+       name text primary key,
+       value text not null
+);
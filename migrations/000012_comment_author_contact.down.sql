@@ -0,0 +1,2 @@
+alter table comments drop column website;
+alter table comments drop column email;
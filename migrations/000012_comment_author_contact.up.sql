@@ -0,0 +1,4 @@
+-- Optional contact of the comment author. The email is private and
+-- never shown in the comments.
+alter table comments add column email text not null default '';
+alter table comments add column website text not null default '';
@@ -0,0 +1,12 @@
+-- Commenters subscribed to the new comments of a page.
+create table if not exists subscriptions (
+       id INTEGER PRIMARY KEY AUTOINCREMENT,
+       domain_id integer not null,
+       page_url string not null,
+       email text not null,
+       timestamp timestamp not null,
+       FOREIGN KEY(domain_id) REFERENCES client_domains(id) on delete cascade,
+       Unique(domain_id, page_url, email) on conflict ignore
+);
+
+CREATE INDEX IF NOT EXISTS subscription_page_url_idx ON subscriptions(domain_id, page_url);
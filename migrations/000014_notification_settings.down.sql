@@ -0,0 +1,2 @@
+drop table if exists domain_notification_settings;
+drop table if exists client_notification_settings;
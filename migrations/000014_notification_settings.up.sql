@@ -0,0 +1,15 @@
+-- Addresses of the emails sent to the site owners. Domain settings
+-- take precedence over client settings. Recipients are comma separated.
+create table if not exists client_notification_settings (
+       client_id integer PRIMARY KEY,
+       sender text not null default '',
+       recipients text not null default '',
+       FOREIGN KEY(client_id) REFERENCES clients(id) on delete cascade
+);
+
+create table if not exists domain_notification_settings (
+       domain_id integer PRIMARY KEY,
+       sender text not null default '',
+       recipients text not null default '',
+       FOREIGN KEY(domain_id) REFERENCES client_domains(id) on delete cascade
+);
@@ -0,0 +1,15 @@
+-- Emails waiting to be sent. Sent messages are removed, messages that
+-- failed too many times stay here with the failed status until they
+-- are retried or discarded. The message is stored as json.
+create table if not exists outbox (
+       id integer PRIMARY KEY,
+       message text not null,
+       status text not null default 'pending',
+       attempts integer not null default 0,
+       next_attempt integer not null default 0,
+       last_error text not null default '',
+       timestamp integer not null
+);
+
+create index if not exists outbox_status_next_attempt_idx
+on outbox (status, next_attempt);
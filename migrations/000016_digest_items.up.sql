@@ -0,0 +1,21 @@
+-- Comments and messages waiting to be sent in the hourly and daily
+-- digests. The items are removed when the digest is sent.
+create table if not exists digest_items (
+       id integer PRIMARY KEY,
+       domain_id integer not null,
+       domain text not null,
+       recipient text not null,
+       mode text not null,
+       kind text not null,
+       sender text not null,
+       page_url text not null default '',
+       author text not null default '',
+       email text not null default '',
+       content text not null default '',
+       status text not null default '',
+       timestamp integer not null,
+       FOREIGN KEY(domain_id) REFERENCES client_domains(id) on delete cascade
+);
+
+create index if not exists digest_items_mode_timestamp_idx
+on digest_items (mode, timestamp);
@@ -0,0 +1 @@
+alter table client_domains drop column markdown;
@@ -0,0 +1 @@
+alter table client_domains add column markdown boolean not null default false;
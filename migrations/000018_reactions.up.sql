@@ -0,0 +1,13 @@
+-- Reactions of the readers to the comments. The reactor is the id
+-- of the browser.
+create table if not exists reactions (
+       id INTEGER PRIMARY KEY AUTOINCREMENT,
+       comment_id integer not null,
+       reaction varchar(32) not null,
+       reactor varchar(64) not null,
+       timestamp timestamp not null,
+       FOREIGN KEY(comment_id) REFERENCES comments(id) on delete cascade,
+       Unique(comment_id, reaction, reactor) on conflict ignore
+);
+
+CREATE INDEX IF NOT EXISTS reaction_comment_idx ON reactions(comment_id);
@@ -0,0 +1 @@
+alter table client_domains add column comments_order varchar(16) not null default '';
@@ -0,0 +1,14 @@
+-- Comments brought from other comment systems. The source is the id
+-- of the comment in the other system so it is not imported twice.
+create table if not exists comment_imports (
+       id INTEGER PRIMARY KEY AUTOINCREMENT,
+       comment_id integer not null,
+       domain_id integer not null,
+       source varchar(255) not null,
+       timestamp timestamp not null,
+       FOREIGN KEY(comment_id) REFERENCES comments(id) on delete cascade,
+       FOREIGN KEY(domain_id) REFERENCES client_domains(id) on delete cascade,
+       Unique(domain_id, source)
+);
+
+CREATE INDEX IF NOT EXISTS comment_import_comment_idx ON comment_imports(comment_id);
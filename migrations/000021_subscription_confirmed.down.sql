@@ -0,0 +1 @@
+alter table subscriptions drop column confirmed;
@@ -0,0 +1,3 @@
+alter table subscriptions add column confirmed boolean not null default 0;
+-- subscriptions made before the confirmation emails were already active.
+update subscriptions set confirmed = 1;
@@ -0,0 +1 @@
+alter table subscriptions drop column confirm_sent_at;
@@ -0,0 +1,2 @@
+-- unix timestamp of the confirmation email, 0 if not sent yet.
+alter table subscriptions add column confirm_sent_at integer not null default 0;
@@ -0,0 +1,48 @@
+-- The trashed duplicates must be purged before going back.
+
+create table clients_old (
+       id INTEGER PRIMARY KEY AUTOINCREMENT,
+       name STRING unique not null,
+       uuid string unique not null,
+       key string not null,
+       deleted_at timestamp default null,
+       akismet_endpoint text not null default '',
+       akismet_key text not null default ''
+);
+
+insert into clients_old (id, name, uuid, key, deleted_at, akismet_endpoint,
+                         akismet_key)
+select id, name, uuid, key, deleted_at, akismet_endpoint, akismet_key
+from clients;
+
+drop table clients;
+alter table clients_old rename to clients;
+
+CREATE INDEX IF NOT EXISTS client_uuid_idx ON clients(uuid);
+CREATE INDEX IF NOT EXISTS client_key_idx ON clients(key);
+CREATE INDEX IF NOT EXISTS client_deleted_at_idx ON clients(deleted_at);
+
+
+create table client_domains_old (
+       id INTEGER PRIMARY KEY AUTOINCREMENT,
+       client_id integer not null,
+       domain string not null,
+       moderate boolean not null default false,
+       deleted_at timestamp default null,
+       markdown boolean not null default false,
+       comments_order varchar(16) not null default '',
+       FOREIGN KEY(client_id) REFERENCES clients(id) on delete cascade,
+       Unique(client_id, domain) on conflict fail
+);
+
+insert into client_domains_old (id, client_id, domain, moderate, deleted_at,
+                                markdown, comments_order)
+select id, client_id, domain, moderate, deleted_at, markdown, comments_order
+from client_domains;
+
+drop table client_domains;
+alter table client_domains_old rename to client_domains;
+
+CREATE INDEX IF NOT EXISTS client_domain_client_idx ON client_domains(client_id);
+CREATE INDEX IF NOT EXISTS client_domain_domain_idx ON client_domains(domain);
+CREATE INDEX IF NOT EXISTS client_domain_deleted_at_idx ON client_domains(deleted_at);
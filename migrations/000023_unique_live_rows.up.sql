@@ -0,0 +1,53 @@
+-- The client names and the domains of a client are unique only among
+-- the items not in the trash, so a removed item may be added again.
+-- sqlite can't drop constraints so the tables are rebuilt.
+
+create table clients_new (
+       id INTEGER PRIMARY KEY AUTOINCREMENT,
+       name STRING not null,
+       uuid string unique not null,
+       key string not null,
+       deleted_at timestamp default null,
+       akismet_endpoint text not null default '',
+       akismet_key text not null default ''
+);
+
+insert into clients_new (id, name, uuid, key, deleted_at, akismet_endpoint,
+                         akismet_key)
+select id, name, uuid, key, deleted_at, akismet_endpoint, akismet_key
+from clients;
+
+drop table clients;
+alter table clients_new rename to clients;
+
+CREATE INDEX IF NOT EXISTS client_uuid_idx ON clients(uuid);
+CREATE INDEX IF NOT EXISTS client_key_idx ON clients(key);
+CREATE INDEX IF NOT EXISTS client_deleted_at_idx ON clients(deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS client_name_live_idx ON clients(name)
+where deleted_at is null;
+
+
+create table client_domains_new (
+       id INTEGER PRIMARY KEY AUTOINCREMENT,
+       client_id integer not null,
+       domain string not null,
+       moderate boolean not null default false,
+       deleted_at timestamp default null,
+       markdown boolean not null default false,
+       comments_order varchar(16) not null default '',
+       FOREIGN KEY(client_id) REFERENCES clients(id) on delete cascade
+);
+
+insert into client_domains_new (id, client_id, domain, moderate, deleted_at,
+                                markdown, comments_order)
+select id, client_id, domain, moderate, deleted_at, markdown, comments_order
+from client_domains;
+
+drop table client_domains;
+alter table client_domains_new rename to client_domains;
+
+CREATE INDEX IF NOT EXISTS client_domain_client_idx ON client_domains(client_id);
+CREATE INDEX IF NOT EXISTS client_domain_domain_idx ON client_domains(domain);
+CREATE INDEX IF NOT EXISTS client_domain_deleted_at_idx ON client_domains(deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS client_domain_live_idx
+ON client_domains(client_id, domain) where deleted_at is null;
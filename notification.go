@@ -0,0 +1,160 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package parlante
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrNoRecipients is returned when an email to the site owners is sent
+// but there is no one to receive it.
+var ErrNoRecipients = errors.New("No notification recipients")
+
+// NotificationMode says when a recipient gets the notifications.
+type NotificationMode string
+
+const (
+	// NotifyImmediate recipients get one email for each comment or
+	// message.
+	NotifyImmediate NotificationMode = "immediate"
+	// NotifyHourly recipients get one email with everything of the
+	// last hour.
+	NotifyHourly NotificationMode = "hourly"
+	// NotifyDaily recipients get one email with everything of the
+	// last day.
+	NotifyDaily NotificationMode = "daily"
+)
+
+var notificationModes = []NotificationMode{
+	NotifyImmediate, NotifyHourly, NotifyDaily}
+
+// NotificationSettings are the addresses of the emails sent to the
+// site owners about new comments and messages.
+type NotificationSettings struct {
+	// Sender is the from address of the emails. If empty the first
+	// recipient is used.
+	Sender     string
+	Recipients []string
+	// Modes of the recipients. Recipients without a mode get the
+	// notifications immediately.
+	Modes map[string]NotificationMode
+}
+
+// ParseNotificationSettings returns the settings for a sender and a
+// comma separated list of recipients. The mode of a recipient may
+// follow its address between parentheses, like me@bla.net (daily).
+func ParseNotificationSettings(sender string, recipients string) (
+	NotificationSettings, error) {
+	n := NotificationSettings{
+		Recipients: make([]string, 0),
+		Modes:      make(map[string]NotificationMode),
+	}
+	var err error
+	n.Sender, err = NormalizeEmail(sender)
+	if err != nil {
+		return NotificationSettings{}, err
+	}
+	for _, r := range strings.Split(recipients, ",") {
+		r, mode, err := parseRecipientMode(r)
+		if err != nil {
+			return NotificationSettings{}, err
+		}
+		email, err := NormalizeEmail(r)
+		if err != nil {
+			return NotificationSettings{}, err
+		}
+		if email == "" {
+			continue
+		}
+		n.Recipients = append(n.Recipients, email)
+		if mode != NotifyImmediate {
+			n.Modes[email] = mode
+		}
+	}
+	return n, nil
+}
+
+// parseRecipientMode splits a recipient in address and mode.
+func parseRecipientMode(r string) (string, NotificationMode, error) {
+	r = strings.TrimSpace(r)
+	i := strings.LastIndex(r, "(")
+	if i < 0 || !strings.HasSuffix(r, ")") {
+		return r, NotifyImmediate, nil
+	}
+	mode := strings.ToLower(strings.TrimSpace(r[i+1 : len(r)-1]))
+	if !slices.Contains(notificationModes, NotificationMode(mode)) {
+		return "", "", fmt.Errorf("Invalid notification mode %s", mode)
+	}
+	return r[:i], NotificationMode(mode), nil
+}
+
+// IsZero says if nothing is set.
+func (n NotificationSettings) IsZero() bool {
+	return n.Sender == "" && len(n.Recipients) == 0
+}
+
+// From returns the from address of the emails. Empty if there is no
+// sender nor recipients.
+func (n NotificationSettings) From() string {
+	if n.Sender != "" {
+		return n.Sender
+	}
+	if len(n.Recipients) > 0 {
+		return n.Recipients[0]
+	}
+	return ""
+}
+
+// Mode returns the notification mode of a recipient.
+func (n NotificationSettings) Mode(recipient string) NotificationMode {
+	mode, ok := n.Modes[recipient]
+	if !ok {
+		return NotifyImmediate
+	}
+	return mode
+}
+
+// RecipientsString returns the recipients separated by commas, with
+// the modes of the recipients that don't get the notifications
+// immediately.
+func (n NotificationSettings) RecipientsString() string {
+	recipients := make([]string, 0, len(n.Recipients))
+	for _, r := range n.Recipients {
+		if mode := n.Mode(r); mode != NotifyImmediate {
+			r += " (" + string(mode) + ")"
+		}
+		recipients = append(recipients, r)
+	}
+	return strings.Join(recipients, ", ")
+}
+
+// Override returns the settings with the values set in other replacing
+// its own values.
+func (n NotificationSettings) Override(other NotificationSettings) NotificationSettings {
+	if other.Sender != "" {
+		n.Sender = other.Sender
+	}
+	if len(other.Recipients) > 0 {
+		n.Recipients = other.Recipients
+		n.Modes = other.Modes
+	}
+	return n
+}
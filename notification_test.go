@@ -0,0 +1,113 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package parlante
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseNotificationSettings(t *testing.T) {
+	var tests = []struct {
+		testName   string
+		sender     string
+		recipients string
+		expected   NotificationSettings
+		err        bool
+	}{
+		{"empty", "", "", NotificationSettings{Recipients: []string{}}, false},
+		{"sender and recipients", "blog@bla.net", "me@bla.net, you@bla.net",
+			NotificationSettings{
+				Sender:     "blog@bla.net",
+				Recipients: []string{"me@bla.net", "you@bla.net"},
+			}, false},
+		{"empty recipient", "", "me@bla.net,,",
+			NotificationSettings{Recipients: []string{"me@bla.net"}}, false},
+		{"bad sender", "blog", "me@bla.net", NotificationSettings{}, true},
+		{"bad recipient", "", "me@bla.net, you", NotificationSettings{}, true},
+		{"recipient modes", "", "me@bla.net (daily), you@bla.net(Hourly)",
+			NotificationSettings{
+				Recipients: []string{"me@bla.net", "you@bla.net"},
+				Modes: map[string]NotificationMode{
+					"me@bla.net":  NotifyDaily,
+					"you@bla.net": NotifyHourly,
+				},
+			}, false},
+		{"immediate mode", "", "me@bla.net (immediate)",
+			NotificationSettings{Recipients: []string{"me@bla.net"}}, false},
+		{"bad mode", "", "me@bla.net (weekly)", NotificationSettings{}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			n, err := ParseNotificationSettings(test.sender, test.recipients)
+			if (err != nil) != test.err {
+				t.Fatalf("bad error %v", err)
+			}
+			if n.Sender != test.expected.Sender ||
+				!slices.Equal(n.Recipients, test.expected.Recipients) {
+				t.Fatalf("bad settings %+v", n)
+			}
+			for _, r := range n.Recipients {
+				if n.Mode(r) != test.expected.Mode(r) {
+					t.Fatalf("bad mode for %s %s", r, n.Mode(r))
+				}
+			}
+		})
+	}
+}
+
+func TestNotificationSettings(t *testing.T) {
+	client := NotificationSettings{
+		Sender:     "blog@bla.net",
+		Recipients: []string{"me@bla.net", "you@bla.net"},
+	}
+	domain := NotificationSettings{Recipients: []string{"other@bla.net"}}
+
+	if !(NotificationSettings{}).IsZero() || client.IsZero() {
+		t.Fatalf("bad IsZero")
+	}
+	if client.From() != "blog@bla.net" || domain.From() != "other@bla.net" ||
+		(NotificationSettings{}).From() != "" {
+		t.Fatalf("bad From")
+	}
+	if client.RecipientsString() != "me@bla.net, you@bla.net" {
+		t.Fatalf("bad recipients string %s", client.RecipientsString())
+	}
+
+	n := client.Override(domain)
+	if n.Sender != client.Sender || !slices.Equal(n.Recipients, domain.Recipients) {
+		t.Fatalf("bad override %+v", n)
+	}
+	digest := NotificationSettings{
+		Recipients: []string{"me@bla.net", "you@bla.net"},
+		Modes:      map[string]NotificationMode{"you@bla.net": NotifyDaily},
+	}
+	if digest.RecipientsString() != "me@bla.net, you@bla.net (daily)" {
+		t.Fatalf("bad recipients string with modes %s", digest.RecipientsString())
+	}
+	n = domain.Override(digest)
+	if n.Mode("you@bla.net") != NotifyDaily || n.Mode("me@bla.net") != NotifyImmediate {
+		t.Fatalf("bad modes override %+v", n)
+	}
+
+	n = client.Override(NotificationSettings{})
+	if n.Sender != client.Sender || !slices.Equal(n.Recipients, client.Recipients) {
+		t.Fatalf("bad empty override %+v", n)
+	}
+}
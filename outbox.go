@@ -0,0 +1,149 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package parlante
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	DEFAULT_OUTBOX_MAX_ATTEMPTS = 8
+	// Wait after the first failed attempt. It doubles after each
+	// attempt up to DEFAULT_OUTBOX_MAX_BACKOFF.
+	DEFAULT_OUTBOX_BACKOFF     = time.Minute
+	DEFAULT_OUTBOX_MAX_BACKOFF = 6 * time.Hour
+	// Max time between deliveries when no new message is enqueued.
+	OUTBOX_INTERVAL = time.Minute
+	outboxBatchSize = 50
+)
+
+// Outbox is an EmailSender that stores the messages and sends them
+// later using another sender. Messages that fail are sent again with
+// an exponential backoff until MaxAttempts, then they are marked as
+// failed and wait to be retried or discarded.
+type Outbox struct {
+	Storage     OutboxStorage
+	Sender      EmailSender
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+	// wake tells the worker there are new messages.
+	wake chan struct{}
+	// mu makes sure the same message is not delivered twice at the
+	// same time.
+	mu  sync.Mutex
+	now func() time.Time
+}
+
+func NewOutbox(storage OutboxStorage, sender EmailSender) *Outbox {
+	return &Outbox{
+		Storage:     storage,
+		Sender:      sender,
+		MaxAttempts: DEFAULT_OUTBOX_MAX_ATTEMPTS,
+		Backoff:     DEFAULT_OUTBOX_BACKOFF,
+		MaxBackoff:  DEFAULT_OUTBOX_MAX_BACKOFF,
+		wake:        make(chan struct{}, 1),
+		now:         time.Now,
+	}
+}
+
+// SendEmail stores the message in the outbox. It is sent by the worker.
+func (o *Outbox) SendEmail(msg EmailMessage) error {
+	_, err := o.Storage.Enqueue(msg)
+	if err != nil {
+		return err
+	}
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Deliver sends the pending messages that are due. Returns the number
+// of messages sent. Errors sending a message are saved in the message,
+// only storage errors are returned.
+func (o *Outbox) Deliver() (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	sent := 0
+	for {
+		now := o.now()
+		msgs, err := o.Storage.ListDue(now.Unix(), outboxBatchSize)
+		if err != nil {
+			return sent, err
+		}
+		if len(msgs) == 0 {
+			return sent, nil
+		}
+		for _, m := range msgs {
+			err := o.Sender.SendEmail(m.Message)
+			if err == nil {
+				sent++
+				err = o.Storage.RemoveMessage(m)
+				if err != nil {
+					return sent, err
+				}
+				continue
+			}
+			m.Attempts++
+			m.LastError = err.Error()
+			if m.Attempts >= o.MaxAttempts {
+				m.Status = OutboxFailed
+				Errorf("giving up sending email %d after %d attempts: %s",
+					m.ID, m.Attempts, m.LastError)
+			} else {
+				m.NextAttempt = now.Add(o.RetryDelay(m.Attempts)).Unix()
+				Debugf("error sending email %d: %s", m.ID, m.LastError)
+			}
+			err = o.Storage.UpdateMessage(m)
+			if err != nil {
+				return sent, err
+			}
+		}
+	}
+}
+
+// RetryDelay returns the time to wait for the next attempt after a
+// number of failed attempts.
+func (o *Outbox) RetryDelay(attempts int) time.Duration {
+	d := o.Backoff
+	for i := 1; i < attempts && d < o.MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, o.MaxBackoff)
+}
+
+// Run delivers the messages when new ones are enqueued or from time to
+// time. It never returns.
+func (o *Outbox) Run(interval time.Duration) {
+	// notest
+	for {
+		n, err := o.Deliver()
+		if err != nil {
+			Errorf("error delivering emails %s", err.Error())
+		} else if n > 0 {
+			Infof("%d emails sent", n)
+		}
+		select {
+		case <-o.wake:
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package parlante
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOutbox_Deliver(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	msg := EmailMessage{
+		From: "blog@bla.net", To: []string{"me@bla.net"}, Subject: "Hi"}
+
+	var tests = []struct {
+		testName  string
+		attempts  int
+		sendError bool
+		listError bool
+		sent      int
+		checkFn   func(OutboxStorageInMemory)
+		err       bool
+	}{
+		{"sent", 0, false, false, 1,
+			func(s OutboxStorageInMemory) {
+				if len(s.data) != 0 {
+					t.Fatalf("sent message not removed %+v", s.data)
+				}
+			}, false},
+		{"failed attempt", 0, true, false, 0,
+			func(s OutboxStorageInMemory) {
+				m := s.data[1]
+				if m.Status != OutboxPending || m.Attempts != 1 ||
+					m.LastError != "bad send email" ||
+					m.NextAttempt != now.Add(DEFAULT_OUTBOX_BACKOFF).Unix() {
+					t.Fatalf("bad message after failure %+v", m)
+				}
+			}, false},
+		{"gives up", DEFAULT_OUTBOX_MAX_ATTEMPTS - 1, true, false, 0,
+			func(s OutboxStorageInMemory) {
+				m := s.data[1]
+				if m.Status != OutboxFailed ||
+					m.Attempts != DEFAULT_OUTBOX_MAX_ATTEMPTS {
+					t.Fatalf("bad message after giving up %+v", m)
+				}
+			}, false},
+		{"list error", 0, false, true, 0,
+			func(s OutboxStorageInMemory) {
+				if len(s.data) != 1 {
+					t.Fatalf("bad messages after error %+v", s.data)
+				}
+			}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			storage := NewOutboxStorageInMemory()
+			sender := TestMailSender{}
+			sender.ForceError(test.sendError)
+			o := NewOutbox(storage, sender)
+			o.now = func() time.Time { return now }
+
+			m, _ := storage.Enqueue(msg)
+			m.Attempts = test.attempts
+			m.NextAttempt = now.Unix()
+			storage.UpdateMessage(m)
+			storage.ForceListError(test.listError)
+			o.Storage = storage
+
+			sent, err := o.Deliver()
+			if (err != nil) != test.err {
+				t.Fatalf("bad error %v", err)
+			}
+			if sent != test.sent {
+				t.Fatalf("bad sent count %d", sent)
+			}
+			test.checkFn(storage)
+		})
+	}
+}
+
+func TestOutbox_RetryDelay(t *testing.T) {
+	o := NewOutbox(NewOutboxStorageInMemory(), TestMailSender{})
+	var tests = []struct {
+		attempts int
+		expected time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{4, 8 * time.Minute},
+		{20, DEFAULT_OUTBOX_MAX_BACKOFF},
+	}
+
+	for _, test := range tests {
+		d := o.RetryDelay(test.attempts)
+		if d != test.expected {
+			t.Fatalf("bad delay for %d attempts %s", test.attempts, d)
+		}
+	}
+}
+
+func TestOutbox_SendEmail(t *testing.T) {
+	storage := NewOutboxStorageInMemory()
+	o := NewOutbox(storage, TestMailSender{})
+	msg := EmailMessage{
+		From: "blog@bla.net", To: []string{"me@bla.net"}, Subject: "Hi"}
+
+	// the second message must not block waiting for the worker.
+	for range 2 {
+		err := o.SendEmail(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(storage.data) != 2 {
+		t.Fatalf("bad messages in outbox %+v", storage.data)
+	}
+	select {
+	case <-o.wake:
+	default:
+		t.Fatalf("worker not woken")
+	}
+}
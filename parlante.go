@@ -18,11 +18,18 @@
 package parlante
 
 import (
+	"cmp"
 	"crypto/rand"
 	"crypto/sha512"
+	"encoding/base64"
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"html/template"
+	"net/mail"
+	"net/url"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -35,6 +42,16 @@ type Client struct {
 	// The key is used to authenticate the client. It is always stored
 	// as a hashed value.
 	Key string
+	// Akismet compatible service used to check the comments of the
+	// client for spam.
+	AkismetEndpoint string
+	AkismetKey      string
+}
+
+// UsesAkismet says if the comments of the client are checked by an
+// akismet compatible service.
+func (c Client) UsesAkismet() bool {
+	return c.AkismetEndpoint != "" && c.AkismetKey != ""
 }
 
 // UpdateKey creates a new key to the client. Returns the plain text
@@ -83,8 +100,12 @@ func NewClient(name string) (Client, string, error) {
 type ClientStorage interface {
 	CreateClient(name string) (Client, string, error)
 	GetClientByUUID(uuid string) (Client, error)
+	GetClientByID(id int64) (Client, error)
 	ListClients() ([]Client, error)
 	RemoveClient(uuid string) error
+	// SetClientAkismet changes the akismet service used by the client.
+	// Empty values disable the service.
+	SetClientAkismet(c Client, endpoint string, key string) error
 }
 
 // ClientDomain is a domain allowed by a client to have comments
@@ -93,6 +114,15 @@ type ClientDomain struct {
 	ClientID int64
 	Domain   string
 	Client   *Client
+	// When Moderate is true new comments for the domain are
+	// pending until a moderator approves them.
+	Moderate bool
+	// When Markdown is true the comments content is rendered as
+	// markdown.
+	Markdown bool
+	// Order is the default order of the comments in the pages. Empty
+	// means OrderOldest.
+	Order CommentOrder
 }
 
 // NewClientDomain instantiate a new  ClientDomain
@@ -112,6 +142,33 @@ type ClientDomainStorage interface {
 	RemoveClientDomain(c Client, domain string) error
 	GetClientDomain(c Client, domain string) (ClientDomain, error)
 	ListDomains() ([]ClientDomain, error)
+	SetDomainModeration(d ClientDomain, moderate bool) error
+	SetDomainMarkdown(d ClientDomain, markdown bool) error
+	SetDomainOrder(d ClientDomain, order CommentOrder) error
+	// GetClientRateLimit returns the rate limit of a route for all the
+	// domains of a client. Zero if not set.
+	GetClientRateLimit(c Client, route RateLimitRoute) (RateLimit, error)
+	// SetClientRateLimit changes the rate limit of a route for all the
+	// domains of a client. A zero rate limit removes it.
+	SetClientRateLimit(c Client, route RateLimitRoute, limit RateLimit) error
+	// GetDomainRateLimit returns the rate limit of a route for a
+	// domain. Zero if not set.
+	GetDomainRateLimit(d ClientDomain, route RateLimitRoute) (RateLimit, error)
+	// SetDomainRateLimit changes the rate limit of a route for a
+	// domain. A zero rate limit removes it.
+	SetDomainRateLimit(d ClientDomain, route RateLimitRoute, limit RateLimit) error
+	// GetClientNotifications returns the notification settings for
+	// all the domains of a client.
+	GetClientNotifications(c Client) (NotificationSettings, error)
+	// SetClientNotifications changes the notification settings for
+	// all the domains of a client. Empty settings are removed.
+	SetClientNotifications(c Client, n NotificationSettings) error
+	// GetDomainNotifications returns the notification settings of
+	// a domain.
+	GetDomainNotifications(d ClientDomain) (NotificationSettings, error)
+	// SetDomainNotifications changes the notification settings of a
+	// domain. Empty settings are removed.
+	SetDomainNotifications(d ClientDomain, n NotificationSettings) error
 }
 
 // CommentsFilter contains the fields used to filter a query for
@@ -121,6 +178,190 @@ type CommentsFilter struct {
 	DomainID *int64
 	PageURL  *string
 	Hidden   *bool
+	// ParentID filters the direct replies to a comment
+	ParentID *int64
+	// ThreadID filters a comment and all its replies, recursively
+	ThreadID *int64
+	Status   *CommentStatus
+	// Limit is the max number of comments returned. Zero means no limit.
+	Limit int
+	// Cursor filters the comments after the cursor position.
+	Cursor *CommentCursor
+	// Order of the comments. Empty means OrderOldest.
+	Order CommentOrder
+}
+
+// CommentOrder is the order of the comments in a listing
+type CommentOrder string
+
+const (
+	// OrderOldest lists the oldest comments first.
+	OrderOldest CommentOrder = "oldest"
+	// OrderNewest lists the newest comments first.
+	OrderNewest CommentOrder = "newest"
+	// OrderScore lists the comments with more reactions first.
+	OrderScore CommentOrder = "score"
+	// OrderAuthor lists the comments by author name.
+	OrderAuthor CommentOrder = "author"
+	// OrderPage lists the comments by page url.
+	OrderPage CommentOrder = "page"
+	// OrderHidden lists the visible comments first.
+	OrderHidden CommentOrder = "hidden"
+)
+
+// CommentOrders are the valid orders of comments
+var CommentOrders = []CommentOrder{
+	OrderOldest, OrderNewest, OrderScore, OrderAuthor, OrderPage, OrderHidden}
+
+// PageCommentOrders are the orders that make sense for the readers
+// of a page.
+var PageCommentOrders = []CommentOrder{
+	OrderOldest, OrderNewest, OrderScore, OrderAuthor}
+
+// ParseCommentOrder returns the CommentOrder for a string. An empty
+// string is OrderOldest.
+func ParseCommentOrder(s string) (CommentOrder, error) {
+	if s == "" {
+		return OrderOldest, nil
+	}
+	o := CommentOrder(s)
+	if !slices.Contains(CommentOrders, o) {
+		return "", errors.New("Invalid order")
+	}
+	return o, nil
+}
+
+// CommentCursor is the position of a comment in a listing. Comments
+// are ordered by timestamp and id, newest first in OrderNewest. The
+// other orders use the Key of the comments before the timestamp.
+type CommentCursor struct {
+	Timestamp int64
+	ID        int64
+	// Key is the value used to order the comments before the
+	// timestamp. Empty in OrderOldest and OrderNewest.
+	Key string
+}
+
+// NewCommentCursor returns the cursor pointing to a comment in a
+// listing with the given order.
+func NewCommentCursor(c Comment, order CommentOrder) CommentCursor {
+	return CommentCursor{Timestamp: c.Timestamp, ID: c.ID, Key: c.orderKey(order)}
+}
+
+// String returns the opaque string representation of the cursor
+func (c CommentCursor) String() string {
+	raw := fmt.Sprintf("%d.%d", c.Timestamp, c.ID)
+	if c.Key != "" {
+		raw += "." + c.Key
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// IsBefore says if the comment is before the cursor position
+func (c CommentCursor) IsBefore(comment Comment) bool {
+	return c.IsBeforeInOrder(comment, OrderOldest)
+}
+
+// IsBeforeInOrder says if the comment is before the cursor position
+// in a listing with the given order.
+func (c CommentCursor) IsBeforeInOrder(comment Comment, order CommentOrder) bool {
+	return NewCommentCursor(comment, order).Compare(c, order) <= 0
+}
+
+// Compare returns -1 if the cursor is before the other in a listing
+// with the given order, 1 if it is after the other and 0 if they are
+// in the same position.
+func (c CommentCursor) Compare(other CommentCursor, order CommentOrder) int {
+	switch order {
+	case OrderNewest:
+		return cmp.Or(cmp.Compare(other.Timestamp, c.Timestamp),
+			cmp.Compare(other.ID, c.ID))
+	case OrderScore:
+		// scores that can't be parsed are zero.
+		a, _ := strconv.ParseInt(c.Key, 10, 64)
+		b, _ := strconv.ParseInt(other.Key, 10, 64)
+		if r := cmp.Compare(b, a); r != 0 {
+			return r
+		}
+	case OrderAuthor:
+		// case is ignored, like in the database.
+		a, b := asciiLower(c.Key), asciiLower(other.Key)
+		if r := cmp.Compare(a, b); r != 0 {
+			return r
+		}
+	case OrderPage, OrderHidden:
+		if r := cmp.Compare(c.Key, other.Key); r != 0 {
+			return r
+		}
+	}
+	return cmp.Or(cmp.Compare(c.Timestamp, other.Timestamp),
+		cmp.Compare(c.ID, other.ID))
+}
+
+// asciiLower lowers only the ascii letters, like sqlite nocase.
+func asciiLower(s string) string {
+	return strings.Map(func(r rune) rune {
+		if 'A' <= r && r <= 'Z' {
+			return r + 'a' - 'A'
+		}
+		return r
+	}, s)
+}
+
+// ParseCommentCursor returns a CommentCursor from its string
+// representation.
+func ParseCommentCursor(s string) (CommentCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return CommentCursor{}, errors.New("Invalid cursor")
+	}
+	// the key may have dots.
+	parts := strings.SplitN(string(raw), ".", 3)
+	if len(parts) < 2 {
+		return CommentCursor{}, errors.New("Invalid cursor")
+	}
+	ts, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return CommentCursor{}, errors.New("Invalid cursor")
+	}
+	id, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return CommentCursor{}, errors.New("Invalid cursor")
+	}
+	c := CommentCursor{Timestamp: ts, ID: id}
+	if len(parts) == 3 {
+		c.Key = parts[2]
+	}
+	return c, nil
+}
+
+// CommentStatus is the moderation state of a comment
+type CommentStatus string
+
+const (
+	// CommentPending is the status of a comment waiting for moderation.
+	CommentPending CommentStatus = "pending"
+	// CommentApproved is the status of a comment that is publicly visible.
+	CommentApproved CommentStatus = "approved"
+	// CommentSpam is the status of a comment marked as spam.
+	CommentSpam CommentStatus = "spam"
+	// CommentRejected is the status of a comment rejected by a moderator.
+	CommentRejected CommentStatus = "rejected"
+)
+
+// commentStatusTransitions has the valid status changes for a comment.
+// Once moderated a comment never goes back to pending.
+var commentStatusTransitions = map[CommentStatus][]CommentStatus{
+	CommentPending:  {CommentApproved, CommentRejected, CommentSpam},
+	CommentApproved: {CommentRejected, CommentSpam},
+	CommentRejected: {CommentApproved, CommentSpam},
+	CommentSpam:     {CommentApproved, CommentRejected},
+}
+
+// CanChangeTo says if a comment with the status s can change to the
+// status to.
+func (s CommentStatus) CanChangeTo(to CommentStatus) bool {
+	return slices.Contains(commentStatusTransitions[s], to)
 }
 
 // Comment is a comment made by an user in a web page.
@@ -136,6 +377,114 @@ type Comment struct {
 	Domain   *ClientDomain
 	// unix timestamp for comment creating. It must be in UTC timezone
 	Timestamp int64
+	// The id of the comment this one replies to. Zero for top level
+	// comments.
+	ParentID int64
+	// The moderation status of the comment. Hidden is true for
+	// every status other than approved.
+	Status CommentStatus
+	// unix timestamp for the last edition of the comment. Zero if the
+	// comment was never edited.
+	EditedAt int64
+	// Score is the number of reactions to the comment.
+	Score int64
+	// Optional contact of the author. The email is private, it must
+	// never be shown with the comment.
+	Email   string
+	Website string
+	// Information about the request that created the comment. It is
+	// not loaded with the comment, use
+	// CommentStorage.GetCommentRequestInfo to get it.
+	RequestInfo *CommentRequestInfo
+}
+
+// CommentRequestInfo is information about the http request that
+// created a comment.
+type CommentRequestInfo struct {
+	UserIP    string
+	UserAgent string
+	Referrer  string
+}
+
+// Edited returns true if the comment content was changed after its
+// creation
+func (c Comment) Edited() bool {
+	return c.EditedAt != 0
+}
+
+// orderKey returns the value of the comment used to order a listing
+// before the timestamp.
+func (c Comment) orderKey(order CommentOrder) string {
+	switch order {
+	case OrderScore:
+		return strconv.FormatInt(c.Score, 10)
+	case OrderAuthor:
+		return c.Author
+	case OrderPage:
+		return c.PageURL
+	case OrderHidden:
+		if c.Hidden {
+			return "1"
+		}
+		return "0"
+	}
+	return ""
+}
+
+// Edit changes the content of the comment. Returns a revision with
+// the previous content.
+func (c *Comment) Edit(content string, editor string) (CommentRevision, error) {
+	if content == "" || editor == "" {
+		return CommentRevision{}, errors.New("Missing required field")
+	}
+	if content == c.Content {
+		return CommentRevision{}, errors.New("Comment content not changed")
+	}
+	now := time.Now().Unix()
+	rev := CommentRevision{
+		CommentID: c.ID,
+		Content:   c.Content,
+		Editor:    editor,
+		Timestamp: now,
+	}
+	c.Content = content
+	c.EditedAt = now
+	return rev, nil
+}
+
+// CommentRevision is a previous version of the content of a comment.
+type CommentRevision struct {
+	ID        int64
+	CommentID int64
+	// The content of the comment before the edition
+	Content string
+	// Who changed the comment
+	Editor string
+	// unix timestamp for the edition. It must be in UTC timezone
+	Timestamp int64
+}
+
+// SetStatus changes the moderation status of a comment. Returns an
+// error if the status change is not allowed.
+func (c *Comment) SetStatus(status CommentStatus) error {
+	if !c.Status.CanChangeTo(status) {
+		return fmt.Errorf("Can't change comment from %s to %s",
+			c.Status, status)
+	}
+	c.Status = status
+	c.Hidden = status != CommentApproved
+	return nil
+}
+
+// CommentTree is a comment with its replies
+type CommentTree struct {
+	Comment
+	Replies []CommentTree
+	// Avatar is the key used to draw the author avatar.
+	Avatar string
+	// ContentHTML is the rendered content in domains with markdown.
+	ContentHTML template.HTML
+	Reactions   ReactionCounts
 }
 
 // CommentCount has the count of comments made in a web page.
@@ -145,7 +494,8 @@ type CommentCount struct {
 }
 
 // NewComment returns a new instance of Comment. Checks for blank strings
-// for author, content e page_url. If missing returns an error.
+// for author, content e page_url. If missing returns an error. Comments
+// for domains with moderation are created as pending.
 func NewComment(c Client, d ClientDomain, author string, content string,
 	page_url string) (Comment, error) {
 	if author == "" || content == "" || page_url == "" {
@@ -160,10 +510,95 @@ func NewComment(c Client, d ClientDomain, author string, content string,
 		Client:    &c,
 		Domain:    &d,
 		Timestamp: time.Now().Unix(),
+		Status:    CommentApproved,
+	}
+	if d.Moderate {
+		comment.Status = CommentPending
+		comment.Hidden = true
+	}
+	return comment, nil
+}
+
+// NormalizeEmail validates an email given by a commenter. An empty
+// email is valid.
+func NormalizeEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return "", nil
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || len(email) > 254 {
+		return "", errors.New("Invalid email")
+	}
+	return email, nil
+}
+
+// NormalizeWebsite validates a website given by a commenter. Only
+// http(s) urls are valid. An empty website is valid.
+func NormalizeWebsite(website string) (string, error) {
+	website = strings.TrimSpace(website)
+	if website == "" {
+		return "", nil
+	}
+	u, err := url.Parse(website)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") ||
+		u.Host == "" || len(website) > 2048 {
+		return "", errors.New("Invalid website")
+	}
+	return u.String(), nil
+}
+
+// NewReply returns a new instance of Comment that is a reply to
+// the parent comment. The reply must be in the same page and domain
+// of the parent comment.
+func NewReply(c Client, d ClientDomain, parent Comment, author string,
+	content string, page_url string) (Comment, error) {
+	if parent.ID == 0 {
+		return Comment{}, errors.New("Invalid parent comment")
+	}
+	if parent.PageURL != page_url || parent.DomainID != d.ID {
+		return Comment{}, errors.New("Parent comment is from other page")
 	}
+	comment, err := NewComment(c, d, author, content, page_url)
+	if err != nil {
+		return Comment{}, err
+	}
+	comment.ParentID = parent.ID
 	return comment, nil
 }
 
+// BuildCommentTree nests the replies under its parent comments. The
+// order of the comments is kept. Replies whose parent is not in
+// the comments list are treated as top level comments.
+func BuildCommentTree(comments []Comment) []CommentTree {
+	ids := make(map[int64]bool)
+	children := make(map[int64][]Comment)
+	for _, c := range comments {
+		ids[c.ID] = true
+	}
+	roots := make([]Comment, 0)
+	for _, c := range comments {
+		if c.ParentID != 0 && ids[c.ParentID] {
+			children[c.ParentID] = append(children[c.ParentID], c)
+			continue
+		}
+		roots = append(roots, c)
+	}
+	var build func(cs []Comment) []CommentTree
+	build = func(cs []Comment) []CommentTree {
+		tree := make([]CommentTree, 0)
+		for _, c := range cs {
+			node := CommentTree{
+				Comment: c,
+				Replies: build(children[c.ID]),
+			}
+			tree = append(tree, node)
+		}
+		return tree
+	}
+	return build(roots)
+}
+
 // CommentStorage is an interface to save/retrive information
 // from comments
 type CommentStorage interface {
@@ -174,20 +609,215 @@ type CommentStorage interface {
 		content string,
 		page_url string) (Comment, error)
 
+	CreateReply(
+		c Client,
+		d ClientDomain,
+		parent Comment,
+		name string,
+		content string,
+		page_url string) (Comment, error)
+
+	GetCommentByID(id int64) (Comment, error)
 	ListComments(filter CommentsFilter) ([]Comment, error)
+	// SearchComments returns the comments matching the filter that
+	// have all the terms in the query in the author, content or url.
+	SearchComments(query string, filter CommentsFilter) ([]Comment, error)
 	RemoveComment(comment Comment) error
+	SetCommentStatus(comment Comment, status CommentStatus) error
+	// UpdateComment changes the content of a comment and keeps the
+	// previous content as a revision.
+	UpdateComment(comment Comment, content string, editor string) (
+		Comment, error)
+	// ListCommentRevisions returns the previous versions of a comment,
+	// the newest first.
+	ListCommentRevisions(comment Comment) ([]CommentRevision, error)
 	CountComments(urls ...string) ([]CommentCount, error)
+	// TrainSpamModel adds the tokens of a comment to the spam model as
+	// spam or ham. A comment trained before is untrained first.
+	TrainSpamModel(comment Comment, spam bool) error
+	// ResetSpamModel discards everything learned by the spam model.
+	ResetSpamModel() error
+	// GetSpamModel returns the spam model with the counts of the tokens.
+	GetSpamModel(tokens []string) (SpamModel, error)
+	SetCommentRequestInfo(comment Comment, info CommentRequestInfo) error
+	// SetCommentAuthorContact changes the email and website of the
+	// comment author.
+	SetCommentAuthorContact(comment Comment, email string, website string) error
+	// GetCommentRequestInfo returns the request info of a comment. The
+	// info is empty if it was not saved.
+	GetCommentRequestInfo(comment Comment) (CommentRequestInfo, error)
+	// ToggleReaction adds the reaction of a reactor to a comment or
+	// removes it if the reactor already reacted the same way. Returns
+	// true if the reaction was added.
+	ToggleReaction(comment Comment, reaction string, reactor string) (
+		bool, error)
+	// CountReactions returns the count of each reaction to the
+	// comments, by comment id. Comments without reactions have
+	// empty counts.
+	CountReactions(ids ...int64) (map[int64]ReactionCounts, error)
+	// ImportComment stores a comment brought from other comment system
+	// keeping its timestamp, status and parent. The source identifies
+	// the comment in the other system.
+	ImportComment(comment Comment, source string) (Comment, error)
+	// GetImportedComment returns the comment imported from a source to
+	// a domain. Removed comments are returned too so they are not
+	// imported again. The comment is empty if nothing was imported.
+	GetImportedComment(d ClientDomain, source string) (Comment, error)
+}
+
+// TrashItemType is the kind of a removed item
+type TrashItemType string
+
+const (
+	TrashClient  TrashItemType = "client"
+	TrashDomain  TrashItemType = "domain"
+	TrashComment TrashItemType = "comment"
+)
+
+// TrashItem is a removed client, domain or comment. Items in the trash
+// can be restored or purged for good.
+type TrashItem struct {
+	Type TrashItemType
+	ID   int64
+	// A human readable name for the item
+	Name string
+	// unix timestamp for the removal of the item
+	DeletedAt int64
+}
+
+type TrashStorage interface {
+	ListTrash() ([]TrashItem, error)
+	RestoreItem(item TrashItem) error
+	PurgeItem(item TrashItem) error
+	// PurgeOlderThan removes for good the items removed before the
+	// timestamp. Returns the number of items purged.
+	PurgeOlderThan(timestamp int64) (int64, error)
+}
+
+// Subscription is a commenter that wants to be notified about the new
+// comments in a page.
+type Subscription struct {
+	ID       int64
+	DomainID int64
+	PageURL  string
+	Email    string
+	// unix timestamp for the subscription
+	Timestamp int64
+	// Confirmed says if the subscriber confirmed the email. Only
+	// confirmed subscriptions get notifications.
+	Confirmed bool
+	// unix timestamp of the confirmation email, zero if not sent.
+	ConfirmSentAt int64
+}
+
+type SubscriptionStorage interface {
+	// Subscribe adds a subscription to the page. Subscribing twice to
+	// the same page returns the existing subscription.
+	Subscribe(cd ClientDomain, pageURL string, email string) (
+		Subscription, error)
+	GetSubscription(id int64) (Subscription, error)
+	// ListSubscriptions returns the confirmed subscriptions to a page.
+	ListSubscriptions(cd ClientDomain, pageURL string) ([]Subscription, error)
+	ConfirmSubscription(sub Subscription) error
+	// MarkConfirmSent records that the confirmation email was sent.
+	// Returns false if it was already sent before.
+	MarkConfirmSent(sub Subscription) (bool, error)
+	Unsubscribe(sub Subscription) error
+}
+
+// OutboxStatus is the delivery status of a message in the outbox
+type OutboxStatus string
+
+const (
+	// OutboxPending messages are sent in the next attempt.
+	OutboxPending OutboxStatus = "pending"
+	// OutboxFailed messages failed too many times and are only sent
+	// again if retried.
+	OutboxFailed OutboxStatus = "failed"
+)
+
+// OutboxMessage is an email waiting in the outbox to be sent.
+type OutboxMessage struct {
+	ID       int64
+	Message  EmailMessage
+	Status   OutboxStatus
+	Attempts int
+	// unix timestamp for the next delivery attempt
+	NextAttempt int64
+	// error of the last failed attempt
+	LastError string
+	// unix timestamp for the creation of the message
+	Timestamp int64
+}
+
+type OutboxStorage interface {
+	// Enqueue stores a message to be sent as soon as possible.
+	Enqueue(msg EmailMessage) (OutboxMessage, error)
+	// ListDue returns up to limit pending messages with the next
+	// attempt before or at the timestamp, the oldest first.
+	ListDue(timestamp int64, limit int) ([]OutboxMessage, error)
+	// ListFailed returns the messages that gave up being sent, the
+	// newest first.
+	ListFailed() ([]OutboxMessage, error)
+	// UpdateMessage saves the delivery status of a message.
+	UpdateMessage(msg OutboxMessage) error
+	// RemoveMessage removes a message sent or discarded.
+	RemoveMessage(msg OutboxMessage) error
+	// RetryMessage puts a failed message back in the queue to be
+	// sent as soon as possible.
+	RetryMessage(msg OutboxMessage) error
+}
+
+// DigestItemKind is the kind of a notification in a digest
+type DigestItemKind string
+
+const (
+	DigestComment DigestItemKind = "comment"
+	// DigestMessage is a message sent using the contact form
+	DigestMessage DigestItemKind = "message"
+)
+
+// DigestItem is a notification waiting to be sent to a recipient in
+// the next digest.
+type DigestItem struct {
+	ID        int64
+	DomainID  int64
+	Domain    string
+	Recipient string
+	Mode      NotificationMode
+	Kind      DigestItemKind
+	// Sender is the from address of the digest.
+	Sender  string
+	PageURL string
+	Author  string
+	Email   string
+	Content string
+	// Status of the comment. Empty for messages.
+	Status CommentStatus
+	// unix timestamp for the creation of the item
+	Timestamp int64
+}
+
+type DigestStorage interface {
+	AddDigestItem(item DigestItem) (DigestItem, error)
+	// ListDigestItems returns the items of a mode created before the
+	// timestamp ordered by recipient, domain, page and timestamp.
+	ListDigestItems(mode NotificationMode, before int64) ([]DigestItem, error)
+	RemoveDigestItems(items []DigestItem) error
 }
 
-// EmailMessage represents an email to be sent. Note that as this have
-// no content type and the body is a string, only text/plain bodies are
-// supported.
+// EmailMessage represents an email to be sent. Body is the text/plain
+// version of the message. If HTMLBody is set the message is sent as
+// multipart/alternative with both versions.
 type EmailMessage struct {
 	From      string
 	To        []string
 	Subject   string
 	Body      string
+	HTMLBody  string
 	Timestamp int64
+	// Extra headers of the message, like List-Unsubscribe.
+	Headers map[string]string
 }
 
 // NewEmailMessage checks for missing from or to.
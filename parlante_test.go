@@ -76,6 +76,279 @@ func TestNewComment(t *testing.T) {
 	}
 }
 
+func TestNewComment_Moderated(t *testing.T) {
+	c, _, _ := NewClient("the test client")
+	d := NewClientDomain(c, "bla.net")
+	d.Moderate = true
+
+	comment, err := NewComment(c, d, "zé", "blabla", "https://bla.net")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if comment.Status != CommentPending || !comment.Hidden {
+		t.Fatalf("bad status for moderated comment %s", comment.Status)
+	}
+}
+
+func TestCommentSetStatus(t *testing.T) {
+	var tests = []struct {
+		testName string
+		from     CommentStatus
+		to       CommentStatus
+		hasError bool
+		hidden   bool
+	}{
+		{"pending to approved", CommentPending, CommentApproved, false, false},
+		{"pending to spam", CommentPending, CommentSpam, false, true},
+		{"approved to rejected", CommentApproved, CommentRejected, false, true},
+		{"spam to approved", CommentSpam, CommentApproved, false, false},
+		{"approved to pending", CommentApproved, CommentPending, true, false},
+		{"approved to approved", CommentApproved, CommentApproved, true, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			c := Comment{Status: test.from}
+			err := c.SetStatus(test.to)
+			if err == nil && test.hasError {
+				t.Fatalf("No error")
+			}
+
+			if err != nil && !test.hasError {
+				t.Fatalf("Error!")
+			}
+			if !test.hasError && c.Hidden != test.hidden {
+				t.Fatalf("bad hidden for %s", c.Status)
+			}
+		})
+	}
+}
+
+func TestCommentCursor(t *testing.T) {
+	c := NewCommentCursor(Comment{ID: 10, Timestamp: 1234}, OrderOldest)
+	parsed, err := ParseCommentCursor(c.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed != c {
+		t.Fatalf("bad parsed cursor %v", parsed)
+	}
+
+	if !c.IsBefore(Comment{ID: 9, Timestamp: 1234}) ||
+		!c.IsBefore(Comment{ID: 11, Timestamp: 1000}) {
+		t.Fatalf("comment should be before cursor")
+	}
+
+	if c.IsBefore(Comment{ID: 11, Timestamp: 1234}) ||
+		c.IsBefore(Comment{ID: 1, Timestamp: 2000}) {
+		t.Fatalf("comment should be after cursor")
+	}
+
+	for _, bad := range []string{"!!", "MTIz", "YS4x", "MS5h"} {
+		_, err := ParseCommentCursor(bad)
+		if err == nil {
+			t.Fatalf("no error for bad cursor %s", bad)
+		}
+	}
+}
+
+func TestCommentCursor_Orders(t *testing.T) {
+	comment := Comment{
+		ID: 10, Timestamp: 1234, Score: 3, Author: "Maria",
+		PageURL: "http://bla.net/b", Hidden: true}
+
+	var tests = []struct {
+		order  CommentOrder
+		before []Comment
+		after  []Comment
+	}{
+		{
+			OrderNewest,
+			[]Comment{{ID: 1, Timestamp: 2000}, {ID: 11, Timestamp: 1234}},
+			[]Comment{{ID: 9, Timestamp: 1234}, {ID: 11, Timestamp: 1000}},
+		},
+		{
+			OrderScore,
+			[]Comment{{ID: 11, Timestamp: 2000, Score: 4},
+				{ID: 9, Timestamp: 1234, Score: 3}},
+			[]Comment{{ID: 1, Timestamp: 1000, Score: 2},
+				{ID: 11, Timestamp: 1234, Score: 3}},
+		},
+		{
+			OrderAuthor,
+			[]Comment{{ID: 11, Timestamp: 2000, Author: "ana"},
+				{ID: 1, Timestamp: 1000, Author: "maria"}},
+			[]Comment{{ID: 1, Timestamp: 1000, Author: "Zé"},
+				{ID: 11, Timestamp: 2000, Author: "Maria"}},
+		},
+		{
+			OrderPage,
+			[]Comment{{ID: 11, Timestamp: 2000, PageURL: "http://bla.net/a"}},
+			[]Comment{{ID: 1, Timestamp: 1000, PageURL: "http://bla.net/c"}},
+		},
+		{
+			OrderHidden,
+			[]Comment{{ID: 11, Timestamp: 2000, Hidden: false},
+				{ID: 1, Timestamp: 1000, Hidden: true}},
+			[]Comment{{ID: 11, Timestamp: 2000, Hidden: true}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.order), func(t *testing.T) {
+			c := NewCommentCursor(comment, test.order)
+			parsed, err := ParseCommentCursor(c.String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if parsed != c {
+				t.Fatalf("bad parsed cursor %v", parsed)
+			}
+			for _, other := range test.before {
+				if !c.IsBeforeInOrder(other, test.order) {
+					t.Fatalf("comment should be before cursor %+v", other)
+				}
+			}
+			for _, other := range test.after {
+				if c.IsBeforeInOrder(other, test.order) {
+					t.Fatalf("comment should be after cursor %+v", other)
+				}
+			}
+		})
+	}
+}
+
+func TestParseCommentOrder(t *testing.T) {
+	var tests = []struct {
+		order    string
+		expected CommentOrder
+		hasError bool
+	}{
+		{"", OrderOldest, false},
+		{"oldest", OrderOldest, false},
+		{"newest", OrderNewest, false},
+		{"score", OrderScore, false},
+		{"author", OrderAuthor, false},
+		{"page", OrderPage, false},
+		{"hidden", OrderHidden, false},
+		{"bad", "", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.order, func(t *testing.T) {
+			o, err := ParseCommentOrder(test.order)
+			if (err != nil) != test.hasError {
+				t.Fatalf("bad error %v", err)
+			}
+			if o != test.expected {
+				t.Fatalf("bad order %s", o)
+			}
+		})
+	}
+}
+
+func TestNewReply(t *testing.T) {
+	c, _, _ := NewClient("the test client")
+	d := NewClientDomain(c, "bla.net")
+	parent, _ := NewComment(c, d, "zé", "blabla", "https://bla.net/post")
+	parent.ID = 1
+
+	var tests = []struct {
+		testName string
+		parent   Comment
+		page_url string
+		hasError bool
+	}{
+		{"reply without parent id", Comment{}, "https://bla.net/post", true},
+		{"reply other page", parent, "https://bla.net/other", true},
+		{"reply ok", parent, "https://bla.net/post", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			r, err := NewReply(c, d, test.parent, "jão", "ble", test.page_url)
+			if err == nil && test.hasError {
+				t.Fatalf("No error")
+			}
+
+			if err != nil && !test.hasError {
+				t.Fatalf("Error!")
+			}
+			if !test.hasError && r.ParentID != parent.ID {
+				t.Fatalf("bad parent id %d", r.ParentID)
+			}
+		})
+	}
+}
+
+func TestCommentEdit(t *testing.T) {
+	var tests = []struct {
+		testName string
+		content  string
+		editor   string
+		hasError bool
+	}{
+		{"edit without content", "", "zé", true},
+		{"edit without editor", "new content", "", true},
+		{"edit same content", "the content", "zé", true},
+		{"edit ok", "new content", "zé", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			c := Comment{ID: 1, Content: "the content"}
+			rev, err := c.Edit(test.content, test.editor)
+			if err == nil && test.hasError {
+				t.Fatalf("No error")
+			}
+
+			if err != nil && !test.hasError {
+				t.Fatalf("Error!")
+			}
+			if test.hasError {
+				if c.Edited() {
+					t.Fatalf("comment edited with error")
+				}
+				return
+			}
+			if !c.Edited() || c.Content != test.content {
+				t.Fatalf("comment not edited %v", c)
+			}
+			if rev.Content != "the content" || rev.CommentID != c.ID {
+				t.Fatalf("bad revision %v", rev)
+			}
+		})
+	}
+}
+
+func TestBuildCommentTree(t *testing.T) {
+	comments := []Comment{
+		{ID: 1, Author: "zé"},
+		{ID: 2, Author: "jão", ParentID: 1},
+		{ID: 3, Author: "tião"},
+		{ID: 4, Author: "zé", ParentID: 2},
+		{ID: 5, Author: "ble", ParentID: 99},
+	}
+
+	tree := BuildCommentTree(comments)
+
+	if len(tree) != 3 {
+		t.Fatalf("bad len for tree %d", len(tree))
+	}
+
+	if len(tree[0].Replies) != 1 || tree[0].Replies[0].ID != 2 {
+		t.Fatalf("bad replies for first comment %v", tree[0].Replies)
+	}
+
+	if len(tree[0].Replies[0].Replies) != 1 {
+		t.Fatalf("bad nested replies %v", tree[0].Replies[0].Replies)
+	}
+
+	if tree[2].ID != 5 {
+		t.Fatalf("orphan reply not in top level %d", tree[2].ID)
+	}
+}
+
 func TestNewEmailMessage(t *testing.T) {
 
 	var tests = []struct {
@@ -105,3 +378,54 @@ func TestNewEmailMessage(t *testing.T) {
 		})
 	}
 }
+
+func TestNormalizeEmail(t *testing.T) {
+	var tests = []struct {
+		email    string
+		expected string
+		hasErr   bool
+	}{
+		{"", "", false},
+		{" ze@bla.net ", "ze@bla.net", false},
+		{"ze", "", true},
+		{"Zé <ze@bla.net>", "", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.email, func(t *testing.T) {
+			email, err := NormalizeEmail(test.email)
+			if (err != nil) != test.hasErr {
+				t.Fatalf("bad error %v", err)
+			}
+			if email != test.expected {
+				t.Fatalf("bad email %s", email)
+			}
+		})
+	}
+}
+
+func TestNormalizeWebsite(t *testing.T) {
+	var tests = []struct {
+		website  string
+		expected string
+		hasErr   bool
+	}{
+		{"", "", false},
+		{" https://ze.net/blog ", "https://ze.net/blog", false},
+		{"javascript:alert(1)", "", true},
+		{"ze.net", "", true},
+		{"http://", "", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.website, func(t *testing.T) {
+			website, err := NormalizeWebsite(test.website)
+			if (err != nil) != test.hasErr {
+				t.Fatalf("bad error %v", err)
+			}
+			if website != test.expected {
+				t.Fatalf("bad website %s", website)
+			}
+		})
+	}
+}
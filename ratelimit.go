@@ -0,0 +1,186 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package parlante
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimitRoute is a route with its own rate limit.
+type RateLimitRoute string
+
+const (
+	RateLimitComment RateLimitRoute = "comment"
+	RateLimitPingMe  RateLimitRoute = "pingme"
+	RateLimitReact   RateLimitRoute = "react"
+)
+
+// DefaultRateLimit returns the rate limit used for the route when
+// none is configured.
+func (r RateLimitRoute) DefaultRateLimit() string {
+	switch r {
+	case RateLimitComment:
+		return DEFAULT_COMMENT_RATE_LIMIT
+	case RateLimitPingMe:
+		return DEFAULT_PINGME_RATE_LIMIT
+	case RateLimitReact:
+		return DEFAULT_REACT_RATE_LIMIT
+	}
+	return ""
+}
+
+// RateLimitRoutes are all the routes with rate limits.
+var RateLimitRoutes = []RateLimitRoute{
+	RateLimitComment, RateLimitPingMe, RateLimitReact}
+
+const (
+	DEFAULT_COMMENT_RATE_LIMIT = "5/1m"
+	DEFAULT_PINGME_RATE_LIMIT  = "2/1m"
+	DEFAULT_REACT_RATE_LIMIT   = "20/1m"
+	// Each reactor token counts as a new reader, so the tokens given
+	// to an ip are limited apart from the reactions.
+	DEFAULT_REACTOR_TOKEN_RATE_LIMIT = "5/1h"
+	// The requests from an ip to a client, whatever the page, are
+	// limited to RATE_LIMIT_PAGES times the limit of a page.
+	RATE_LIMIT_PAGES = 3
+	// Number of requests between removals of idle buckets.
+	rateLimiterSweepInterval = 1000
+)
+
+// RateLimit is the max number of requests in a period. The requests
+// may be done all at once, after that they are allowed at the rate of
+// Requests per Period.
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// IsZero says if the rate limit is not set.
+func (l RateLimit) IsZero() bool {
+	return l.Requests <= 0 || l.Period <= 0
+}
+
+// String returns the rate limit in the format parsed by ParseRateLimit
+func (l RateLimit) String() string {
+	if l.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf("%d/%s", l.Requests, l.Period)
+}
+
+// ParseRateLimit parses a rate limit in the format requests/period,
+// like 5/1m. An empty string is a zero rate limit.
+func ParseRateLimit(s string) (RateLimit, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return RateLimit{}, nil
+	}
+	rawRequests, rawPeriod, ok := strings.Cut(s, "/")
+	if !ok {
+		return RateLimit{}, errors.New("Invalid rate limit")
+	}
+	requests, err := strconv.Atoi(strings.TrimSpace(rawRequests))
+	if err != nil || requests <= 0 {
+		return RateLimit{}, errors.New("Invalid rate limit requests")
+	}
+	period, err := time.ParseDuration(strings.TrimSpace(rawPeriod))
+	if err != nil || period < time.Second {
+		return RateLimit{}, errors.New("Invalid rate limit period")
+	}
+	return RateLimit{Requests: requests, Period: period}, nil
+}
+
+type tokenBucket struct {
+	limit  RateLimit
+	tokens float64
+	last   time.Time
+}
+
+// refill adds the tokens earned since the last time the bucket was used.
+func (b *tokenBucket) refill(now time.Time) {
+	rate := float64(b.limit.Requests) / b.limit.Period.Seconds()
+	elapsed := now.Sub(b.last).Seconds()
+	b.tokens = math.Min(float64(b.limit.Requests), b.tokens+elapsed*rate)
+	b.last = now
+}
+
+// RateLimiter is a token bucket rate limiter. Each key has its own
+// bucket.
+type RateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	calls   int
+	now     func() time.Time
+}
+
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+	}
+}
+
+// Allow says if a request for the key is allowed. When it is not,
+// returns how long until the next request is allowed.
+func (l *RateLimiter) Allow(key string, limit RateLimit) (bool, time.Duration) {
+	if limit.IsZero() {
+		return true, 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.calls++
+	if l.calls%rateLimiterSweepInterval == 0 {
+		l.sweep(now)
+	}
+	b, ok := l.buckets[key]
+	if !ok || b.limit != limit {
+		b = &tokenBucket{
+			limit:  limit,
+			tokens: float64(limit.Requests),
+			last:   now,
+		}
+		l.buckets[key] = b
+	}
+	b.refill(now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	rate := float64(limit.Requests) / limit.Period.Seconds()
+	wait := time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	return false, wait
+}
+
+// sweep removes the buckets that are full again, as they are the same
+// as new buckets.
+func (l *RateLimiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		b.refill(now)
+		if b.tokens >= float64(b.limit.Requests) {
+			delete(l.buckets, key)
+		}
+	}
+}
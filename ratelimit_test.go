@@ -0,0 +1,128 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package parlante
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	var tests = []struct {
+		testName string
+		raw      string
+		limit    RateLimit
+		hasErr   bool
+	}{
+		{"empty", "", RateLimit{}, false},
+		{"ok", "5/1m", RateLimit{Requests: 5, Period: time.Minute}, false},
+		{"with spaces", " 2 / 30s ", RateLimit{Requests: 2, Period: 30 * time.Second}, false},
+		{"no period", "5", RateLimit{}, true},
+		{"bad requests", "x/1m", RateLimit{}, true},
+		{"zero requests", "0/1m", RateLimit{}, true},
+		{"bad period", "5/bla", RateLimit{}, true},
+		{"short period", "5/1ms", RateLimit{}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			limit, err := ParseRateLimit(test.raw)
+			if (err != nil) != test.hasErr {
+				t.Fatalf("bad error for parse %v", err)
+			}
+			if limit != test.limit {
+				t.Fatalf("bad limit %v", limit)
+			}
+		})
+	}
+}
+
+func TestRateLimit_String(t *testing.T) {
+	limit := RateLimit{Requests: 5, Period: time.Minute}
+	parsed, _ := ParseRateLimit(limit.String())
+	if parsed != limit {
+		t.Fatalf("bad string for limit %s", limit.String())
+	}
+	if (RateLimit{}).String() != "" {
+		t.Fatalf("bad string for zero limit")
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewRateLimiter()
+	l.now = func() time.Time { return now }
+	limit := RateLimit{Requests: 2, Period: time.Minute}
+
+	for i := 0; i < 2; i++ {
+		ok, _ := l.Allow("key", limit)
+		if !ok {
+			t.Fatalf("request %d not allowed", i)
+		}
+	}
+	ok, wait := l.Allow("key", limit)
+	if ok {
+		t.Fatalf("request over the limit allowed")
+	}
+	if wait != 30*time.Second {
+		t.Fatalf("bad wait %s", wait)
+	}
+
+	ok, _ = l.Allow("other", limit)
+	if !ok {
+		t.Fatalf("request for other key not allowed")
+	}
+
+	now = now.Add(30 * time.Second)
+	ok, _ = l.Allow("key", limit)
+	if !ok {
+		t.Fatalf("request not allowed after refill")
+	}
+	ok, _ = l.Allow("key", limit)
+	if ok {
+		t.Fatalf("request allowed before refill")
+	}
+
+	ok, _ = l.Allow("key", RateLimit{Requests: 10, Period: time.Minute})
+	if !ok {
+		t.Fatalf("request not allowed after limit change")
+	}
+
+	ok, _ = l.Allow("key", RateLimit{})
+	if !ok {
+		t.Fatalf("request not allowed without limit")
+	}
+}
+
+func TestRateLimiter_Sweep(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewRateLimiter()
+	l.now = func() time.Time { return now }
+	limit := RateLimit{Requests: 1, Period: time.Minute}
+	l.Allow("key", limit)
+	now = now.Add(time.Minute)
+	l.calls = rateLimiterSweepInterval - 1
+	l.Allow("other", limit)
+
+	if _, ok := l.buckets["key"]; ok {
+		t.Fatalf("full bucket not removed")
+	}
+	if _, ok := l.buckets["other"]; !ok {
+		t.Fatalf("used bucket removed")
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package parlante
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// DEFAULT_REACTIONS are the reactions used when none is configured:
+// a simple upvote.
+var DEFAULT_REACTIONS = []string{"👍"}
+
+var ErrInvalidReactorToken = errors.New("Invalid reactor token")
+
+// ReactionCounts is the number of times each reaction was given to
+// a comment.
+type ReactionCounts map[string]int64
+
+// NewReactorToken returns a token that identifies a browser when it
+// reacts to comments. The token is anonymous, it is only a random id
+// signed by the server.
+func NewReactorToken(signer Signer) string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	id := hex.EncodeToString(b)
+	return id + "." + signer.Sign("reactor", id)
+}
+
+// ReactorID returns the id of the reactor in a token.
+func ReactorID(signer Signer, token string) (string, error) {
+	id, signature, ok := strings.Cut(token, ".")
+	if !ok || id == "" || !signer.Verify(signature, "reactor", id) {
+		return "", ErrInvalidReactorToken
+	}
+	return id, nil
+}
@@ -0,0 +1,45 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package parlante
+
+import (
+	"testing"
+)
+
+func TestReactorToken(t *testing.T) {
+	signer := NewSigner("secret")
+	token := NewReactorToken(signer)
+	id, err := ReactorID(signer, token)
+	if err != nil || id == "" {
+		t.Fatalf("bad reactor id %s %v", id, err)
+	}
+	if other := NewReactorToken(signer); other == token {
+		t.Fatalf("same token twice")
+	}
+
+	for _, bad := range []string{"", "abc", "abc.def", "." + signer.Sign("reactor", "")} {
+		_, err := ReactorID(signer, bad)
+		if err != ErrInvalidReactorToken {
+			t.Fatalf("bad error for token %s: %v", bad, err)
+		}
+	}
+	_, err = ReactorID(NewSigner("other"), token)
+	if err != ErrInvalidReactorToken {
+		t.Fatalf("token valid for other signer")
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package parlante
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// Signer signs data sent to the browsers so the server knows it was
+// not changed when it comes back.
+type Signer struct {
+	key []byte
+}
+
+func NewSigner(secret string) Signer {
+	return Signer{key: []byte(secret)}
+}
+
+// NewRandomSigner returns a signer with a random key. Signatures made
+// with it are not valid after the server restarts.
+func NewRandomSigner() Signer {
+	key := make([]byte, 32)
+	rand.Read(key)
+	return Signer{key: key}
+}
+
+// Sign returns the signature for a list of values.
+func (s Signer) Sign(values ...string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(strings.Join(values, "\x00")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify says if the signature is valid for the values.
+func (s Signer) Verify(signature string, values ...string) bool {
+	expected := s.Sign(values...)
+	return hmac.Equal([]byte(signature), []byte(expected))
+}
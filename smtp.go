@@ -0,0 +1,320 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package parlante
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	DEFAULT_SMTP_PORT         = 587
+	DEFAULT_SMTP_TIMEOUT      = 30 * time.Second
+	DEFAULT_SMTP_IDLE_TIMEOUT = time.Minute
+)
+
+// SMTPSecurity is how the connection to the smtp server is encrypted.
+type SMTPSecurity string
+
+const (
+	// SMTPStartTLS upgrades a plain connection using the STARTTLS
+	// command. The server must support it.
+	SMTPStartTLS SMTPSecurity = "starttls"
+	// SMTPTLS uses a tls connection from the start, usually in the
+	// port 465.
+	SMTPTLS SMTPSecurity = "tls"
+	// SMTPNoSecurity does not encrypt the connection. Use only with
+	// local servers.
+	SMTPNoSecurity SMTPSecurity = "none"
+)
+
+// SMTPAuthMechanism is the mechanism used to authenticate in the smtp
+// server.
+type SMTPAuthMechanism string
+
+const (
+	SMTPAuthPlain SMTPAuthMechanism = "plain"
+	SMTPAuthLogin SMTPAuthMechanism = "login"
+)
+
+// SMTPSender sends emails using a smtp server. The connection is kept
+// open and reused to send the next emails until it is idle for
+// IdleTimeout.
+type SMTPSender struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	// Auth is only used when Username is set.
+	Auth     SMTPAuthMechanism
+	Security SMTPSecurity
+	// Timeout for connecting and for each email sent.
+	Timeout     time.Duration
+	IdleTimeout time.Duration
+	// TLSConfig is used for tls connections. If nil the certificate
+	// of the server is verified against the system roots.
+	TLSConfig *tls.Config
+	keyGen    keyGen
+	mu        sync.Mutex
+	conn      net.Conn
+	client    *smtp.Client
+	lastUsed  time.Time
+}
+
+// NewSMTPSender returns a sender for a smtp server using STARTTLS
+// and PLAIN auth.
+func NewSMTPSender(host string, port int, username string,
+	password string) *SMTPSender {
+	if port <= 0 {
+		port = DEFAULT_SMTP_PORT
+	}
+	return &SMTPSender{
+		Host:        host,
+		Port:        port,
+		Username:    username,
+		Password:    password,
+		Auth:        SMTPAuthPlain,
+		Security:    SMTPStartTLS,
+		Timeout:     DEFAULT_SMTP_TIMEOUT,
+		IdleTimeout: DEFAULT_SMTP_IDLE_TIMEOUT,
+		keyGen:      GenKey,
+	}
+}
+
+// SendEmail sends an EmailMessage using the smtp server. If the kept
+// connection was closed by the server a new one is opened.
+func (s *SMTPSender) SendEmail(msg EmailMessage) error {
+	data, err := EmailMessage2Maildir(msg, s.keyGen)
+	if err != nil {
+		return err
+	}
+	from, err := envelopeAddress(msg.From)
+	if err != nil {
+		return err
+	}
+	to := make([]string, 0, len(msg.To))
+	for _, addr := range msg.To {
+		rcpt, err := envelopeAddress(addr)
+		if err != nil {
+			return err
+		}
+		to = append(to, rcpt)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.send(from, to, []byte(data))
+}
+
+// Close closes the connection to the server, if any.
+func (s *SMTPSender) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.client == nil {
+		return nil
+	}
+	s.conn.SetDeadline(time.Now().Add(s.timeout()))
+	err := s.client.Quit()
+	s.drop()
+	return err
+}
+
+func (s *SMTPSender) send(from string, to []string, data []byte) error {
+	err := s.connect()
+	if err != nil {
+		return err
+	}
+	s.conn.SetDeadline(time.Now().Add(s.timeout()))
+	err = s.transaction(from, to, data)
+	if err != nil {
+		s.drop()
+		return err
+	}
+	s.lastUsed = time.Now()
+	return nil
+}
+
+func (s *SMTPSender) transaction(from string, to []string, data []byte) error {
+	err := s.client.Mail(from)
+	if err != nil {
+		return err
+	}
+	for _, addr := range to {
+		err = s.client.Rcpt(addr)
+		if err != nil {
+			return err
+		}
+	}
+	w, err := s.client.Data()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	if err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// connect opens a new connection if there is no open connection or
+// the open one was idle for too long. Reused connections are reset so
+// an aborted transaction doesn't affect the next one.
+func (s *SMTPSender) connect() error {
+	if s.client != nil {
+		idle := s.IdleTimeout > 0 && time.Since(s.lastUsed) > s.IdleTimeout
+		if !idle {
+			s.conn.SetDeadline(time.Now().Add(s.timeout()))
+			if s.client.Reset() == nil {
+				return nil
+			}
+		}
+		s.drop()
+	}
+	switch s.Security {
+	case SMTPStartTLS, SMTPTLS, SMTPNoSecurity:
+	default:
+		return fmt.Errorf("Invalid smtp security %s", s.Security)
+	}
+	addr := net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+	dialer := &net.Dialer{Timeout: s.timeout()}
+	var conn net.Conn
+	var err error
+	if s.Security == SMTPTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, s.tlsConfig())
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(s.timeout()))
+	client, err := smtp.NewClient(conn, s.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	err = s.setupClient(client)
+	if err != nil {
+		client.Close()
+		return err
+	}
+	s.conn = conn
+	s.client = client
+	return nil
+}
+
+func (s *SMTPSender) setupClient(client *smtp.Client) error {
+	if s.Security == SMTPStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("smtp server does not support STARTTLS")
+		}
+		err := client.StartTLS(s.tlsConfig())
+		if err != nil {
+			return err
+		}
+	}
+	if s.Username == "" {
+		return nil
+	}
+	var auth smtp.Auth
+	switch s.Auth {
+	case SMTPAuthPlain, "":
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	case SMTPAuthLogin:
+		auth = &loginAuth{s.Username, s.Password, s.Host}
+	default:
+		return fmt.Errorf("Invalid smtp auth mechanism %s", s.Auth)
+	}
+	return client.Auth(auth)
+}
+
+// drop closes the connection without saying goodbye to the server.
+func (s *SMTPSender) drop() {
+	if s.client != nil {
+		s.client.Close()
+	}
+	s.client = nil
+	s.conn = nil
+}
+
+func (s *SMTPSender) timeout() time.Duration {
+	if s.Timeout <= 0 {
+		return DEFAULT_SMTP_TIMEOUT
+	}
+	return s.Timeout
+}
+
+func (s *SMTPSender) tlsConfig() *tls.Config {
+	if s.TLSConfig != nil {
+		return s.TLSConfig
+	}
+	return &tls.Config{ServerName: s.Host}
+}
+
+// envelopeAddress returns the address used in the smtp commands,
+// without the name.
+func envelopeAddress(addr string) (string, error) {
+	a, err := mail.ParseAddress(addr)
+	if err != nil {
+		return "", err
+	}
+	return a.Address, nil
+}
+
+// loginAuth implements the LOGIN auth mechanism. As the PLAIN auth of
+// net/smtp it refuses to send the password without tls, except to
+// localhost.
+type loginAuth struct {
+	username string
+	password string
+	host     string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	switch string(fromServer) {
+	case "Username:", "User Name\x00":
+		return []byte(a.username), nil
+	case "Password:", "Password\x00":
+		return []byte(a.password), nil
+	}
+	return nil, fmt.Errorf("unexpected server challenge %s", fromServer)
+}
+
+func isLocalhost(name string) bool {
+	return name == "localhost" || name == "127.0.0.1" || name == "::1"
+}
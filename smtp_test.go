@@ -0,0 +1,370 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package parlante
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeSMTPMessage struct {
+	From string
+	To   []string
+	Data string
+}
+
+// fakeSMTPServer is a smtp server that keeps the messages in memory.
+type fakeSMTPServer struct {
+	listener    net.Listener
+	tlsConfig   *tls.Config
+	implicitTLS bool
+	startTLS    bool
+	// silent servers never greet the clients
+	silent   bool
+	username string
+	password string
+
+	mu          sync.Mutex
+	connections int
+	conns       []net.Conn
+	messages    []fakeSMTPMessage
+	commands    []string
+}
+
+func newFakeSMTPServer(t *testing.T, tlsConfig *tls.Config) *fakeSMTPServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeSMTPServer{
+		listener:  l,
+		tlsConfig: tlsConfig,
+		username:  "me",
+		password:  "secret",
+	}
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Start accepts connections. The server must not be changed after it.
+func (s *fakeSMTPServer) Start() {
+	go func() {
+		for {
+			conn, err := s.listener.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.connections++
+			s.conns = append(s.conns, conn)
+			s.mu.Unlock()
+			go s.handle(conn)
+		}
+	}()
+}
+
+func (s *fakeSMTPServer) Port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeSMTPServer) Close() {
+	s.listener.Close()
+	s.DropConnections()
+}
+
+// DropConnections closes the connections without telling the clients.
+func (s *fakeSMTPServer) DropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.conns {
+		c.Close()
+	}
+	s.conns = nil
+}
+
+func (s *fakeSMTPServer) Stats() (int, []fakeSMTPMessage, []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.connections, s.messages, s.commands
+}
+
+func (s *fakeSMTPServer) handle(conn net.Conn) {
+	defer conn.Close()
+	isTLS := s.implicitTLS
+	if isTLS {
+		conn = tls.Server(conn, s.tlsConfig)
+	}
+	if s.silent {
+		io.Copy(io.Discard, conn)
+		return
+	}
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 fake ESMTP")
+	var msg fakeSMTPMessage
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd, arg, _ := strings.Cut(line, " ")
+		cmd = strings.ToUpper(cmd)
+		s.mu.Lock()
+		s.commands = append(s.commands, cmd)
+		s.mu.Unlock()
+		switch cmd {
+		case "EHLO", "HELO":
+			tp.PrintfLine("250-fake")
+			if s.startTLS && !isTLS {
+				tp.PrintfLine("250-STARTTLS")
+			}
+			tp.PrintfLine("250 AUTH PLAIN LOGIN")
+		case "STARTTLS":
+			tp.PrintfLine("220 go ahead")
+			conn = tls.Server(conn, s.tlsConfig)
+			tp = textproto.NewConn(conn)
+			isTLS = true
+		case "AUTH":
+			mech, resp, _ := strings.Cut(arg, " ")
+			var user, pass string
+			if mech == "PLAIN" {
+				b, _ := base64.StdEncoding.DecodeString(resp)
+				parts := strings.Split(string(b), "\x00")
+				if len(parts) == 3 {
+					user, pass = parts[1], parts[2]
+				}
+			} else {
+				tp.PrintfLine("334 %s",
+					base64.StdEncoding.EncodeToString([]byte("Username:")))
+				l, _ := tp.ReadLine()
+				b, _ := base64.StdEncoding.DecodeString(l)
+				user = string(b)
+				tp.PrintfLine("334 %s",
+					base64.StdEncoding.EncodeToString([]byte("Password:")))
+				l, _ = tp.ReadLine()
+				b, _ = base64.StdEncoding.DecodeString(l)
+				pass = string(b)
+			}
+			if user == s.username && pass == s.password {
+				tp.PrintfLine("235 ok")
+			} else {
+				tp.PrintfLine("535 bad credentials")
+			}
+		case "MAIL":
+			msg = fakeSMTPMessage{From: strings.Trim(arg[5:], "<>")}
+			tp.PrintfLine("250 ok")
+		case "RCPT":
+			msg.To = append(msg.To, strings.Trim(arg[3:], "<>"))
+			tp.PrintfLine("250 ok")
+		case "DATA":
+			tp.PrintfLine("354 go ahead")
+			b, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			msg.Data = string(b)
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			tp.PrintfLine("250 ok")
+		case "RSET", "NOOP":
+			tp.PrintfLine("250 ok")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+// testTLSConfigs returns the config for the fake server and the config
+// for the clients trusting it.
+func testTLSConfigs(t *testing.T) (*tls.Config, *tls.Config) {
+	ts := httptest.NewUnstartedServer(nil)
+	ts.StartTLS()
+	t.Cleanup(ts.Close)
+	serverConfig := ts.TLS
+	clientConfig := ts.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+	clientConfig.ServerName = "127.0.0.1"
+	return serverConfig, clientConfig
+}
+
+func TestSMTPSender(t *testing.T) {
+	serverTLS, clientTLS := testTLSConfigs(t)
+	var tests = []struct {
+		testName    string
+		implicitTLS bool
+		startTLS    bool
+		security    SMTPSecurity
+		auth        SMTPAuthMechanism
+		username    string
+		password    string
+		err         bool
+	}{
+		{"starttls with plain auth", false, true, SMTPStartTLS, SMTPAuthPlain,
+			"me", "secret", false},
+		{"implicit tls with login auth", true, false, SMTPTLS, SMTPAuthLogin,
+			"me", "secret", false},
+		{"without security nor auth", false, false, SMTPNoSecurity, "",
+			"", "", false},
+		{"bad password", false, true, SMTPStartTLS, SMTPAuthLogin,
+			"me", "bad", true},
+		{"starttls not supported", false, false, SMTPStartTLS, SMTPAuthPlain,
+			"me", "secret", true},
+		{"invalid auth", false, true, SMTPStartTLS, "bad",
+			"me", "secret", true},
+		{"invalid security", false, false, "bad", SMTPAuthPlain,
+			"me", "secret", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			server := newFakeSMTPServer(t, serverTLS)
+			server.implicitTLS = test.implicitTLS
+			server.startTLS = test.startTLS
+			server.Start()
+
+			sender := NewSMTPSender(
+				"127.0.0.1", server.Port(), test.username, test.password)
+			sender.Security = test.security
+			sender.Auth = test.auth
+			sender.TLSConfig = clientTLS
+			defer sender.Close()
+
+			msg, _ := NewEmailMessage("Blog <blog@bla.net>",
+				[]string{"me@bla.net", "you@bla.net"}, "Hello", "How are you?")
+			err := sender.SendEmail(msg)
+			if (err != nil) != test.err {
+				t.Fatalf("bad error %v", err)
+			}
+			_, msgs, _ := server.Stats()
+			if test.err {
+				if len(msgs) != 0 {
+					t.Fatalf("message sent with error")
+				}
+				return
+			}
+			if len(msgs) != 1 {
+				t.Fatalf("bad number of messages %d", len(msgs))
+			}
+			m := msgs[0]
+			if m.From != "blog@bla.net" || len(m.To) != 2 ||
+				m.To[1] != "you@bla.net" {
+				t.Fatalf("bad envelope %+v", m)
+			}
+			if !strings.Contains(m.Data, "Subject: Hello\n") ||
+				!strings.Contains(m.Data, "How are you?") {
+				t.Fatalf("bad data %s", m.Data)
+			}
+		})
+	}
+}
+
+func TestSMTPSender_ConnectionReuse(t *testing.T) {
+	server := newFakeSMTPServer(t, nil)
+	server.Start()
+	sender := NewSMTPSender("127.0.0.1", server.Port(), "", "")
+	sender.Security = SMTPNoSecurity
+	msg, _ := NewEmailMessage("blog@bla.net", []string{"me@bla.net"}, "Hi", "Hi")
+
+	send := func() {
+		err := sender.SendEmail(msg)
+		if err != nil {
+			t.Fatalf("error sending %s", err.Error())
+		}
+	}
+
+	send()
+	send()
+	n, msgs, _ := server.Stats()
+	if n != 1 || len(msgs) != 2 {
+		t.Fatalf("connection not reused %d %d", n, len(msgs))
+	}
+
+	// connections closed by the server are opened again
+	server.DropConnections()
+	send()
+	n, msgs, _ = server.Stats()
+	if n != 2 || len(msgs) != 3 {
+		t.Fatalf("connection not reopened %d %d", n, len(msgs))
+	}
+
+	// idle connections are not reused
+	sender.IdleTimeout = time.Millisecond
+	time.Sleep(10 * time.Millisecond)
+	send()
+	n, _, _ = server.Stats()
+	if n != 3 {
+		t.Fatalf("idle connection reused %d", n)
+	}
+
+	err := sender.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, cmds := server.Stats()
+	if cmds[len(cmds)-1] != "QUIT" {
+		t.Fatalf("connection not closed %s", cmds)
+	}
+	err = sender.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSMTPSender_Timeout(t *testing.T) {
+	server := newFakeSMTPServer(t, nil)
+	server.silent = true
+	server.Start()
+	sender := NewSMTPSender("127.0.0.1", server.Port(), "", "")
+	sender.Security = SMTPNoSecurity
+	sender.Timeout = 100 * time.Millisecond
+	msg, _ := NewEmailMessage("blog@bla.net", []string{"me@bla.net"}, "Hi", "Hi")
+
+	start := time.Now()
+	err := sender.SendEmail(msg)
+	if err == nil {
+		t.Fatalf("no error for silent server")
+	}
+	if time.Since(start) > time.Second {
+		t.Fatalf("timeout not respected %s", time.Since(start))
+	}
+}
+
+func TestSMTPSender_BadAddress(t *testing.T) {
+	sender := NewSMTPSender("127.0.0.1", 1, "", "")
+	msg, _ := NewEmailMessage("blog", []string{"me@bla.net"}, "Hi", "Hi")
+	err := sender.SendEmail(msg)
+	if err == nil {
+		t.Fatalf("no error for bad from")
+	}
+	msg, _ = NewEmailMessage("blog@bla.net", []string{"me"}, "Hi", "Hi")
+	err = sender.SendEmail(msg)
+	if err == nil {
+		t.Fatalf("no error for bad to")
+	}
+}
@@ -0,0 +1,280 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package parlante
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	DEFAULT_SPAM_MAX_LINKS      = 2
+	DEFAULT_COMMENT_MIN_LENGTH  = 2
+	DEFAULT_COMMENT_MAX_LENGTH  = 5000
+	DEFAULT_SPAM_SUSPECT_SCORE  = 0.5
+	DEFAULT_SPAM_SPAM_SCORE     = 1.0
+	DEFAULT_SPAM_CHECKER_WEIGHT = 0.5
+)
+
+// SpamVerdict is the conclusion of a spam check.
+type SpamVerdict string
+
+const (
+	// SpamHam is the verdict for a comment that looks legit.
+	SpamHam SpamVerdict = "ham"
+	// SpamSuspect is the verdict for a comment that must be reviewed
+	// by a moderator before being published.
+	SpamSuspect SpamVerdict = "suspect"
+	// SpamSpam is the verdict for a comment that looks like spam.
+	SpamSpam SpamVerdict = "spam"
+)
+
+// CommentStatus returns the status a new comment gets because of the
+// verdict. An empty status means the verdict does not change the
+// comment status.
+func (v SpamVerdict) CommentStatus() CommentStatus {
+	switch v {
+	case SpamSuspect:
+		return CommentPending
+	case SpamSpam:
+		return CommentSpam
+	}
+	return ""
+}
+
+// SpamCheckResult is the result of a spam check.
+type SpamCheckResult struct {
+	Verdict SpamVerdict
+	// Score is how spammy the comment looks. Zero means not spammy at all.
+	Score float64
+	// Reasons explains why the comment got its score.
+	Reasons []string
+}
+
+// SpamThresholds are the scores from which a comment is considered
+// suspect or spam.
+type SpamThresholds struct {
+	Suspect float64
+	Spam    float64
+}
+
+// DefaultSpamThresholds returns the thresholds used when none is
+// configured.
+func DefaultSpamThresholds() SpamThresholds {
+	return SpamThresholds{
+		Suspect: DEFAULT_SPAM_SUSPECT_SCORE,
+		Spam:    DEFAULT_SPAM_SPAM_SCORE,
+	}
+}
+
+// Verdict returns the verdict for a given score.
+func (t SpamThresholds) Verdict(score float64) SpamVerdict {
+	if score >= t.Spam {
+		return SpamSpam
+	}
+	if score >= t.Suspect {
+		return SpamSuspect
+	}
+	return SpamHam
+}
+
+// NewSpamCheckResult returns a SpamCheckResult with the verdict for
+// the score using the default thresholds.
+func NewSpamCheckResult(score float64, reasons ...string) SpamCheckResult {
+	return SpamCheckResult{
+		Verdict: DefaultSpamThresholds().Verdict(score),
+		Score:   score,
+		Reasons: reasons,
+	}
+}
+
+// SpamChecker checks if a comment is spam before it is stored.
+type SpamChecker interface {
+	CheckComment(comment Comment) (SpamCheckResult, error)
+}
+
+// SpamReporter tells a spam checker about its mistakes: spam comments
+// it missed and legit comments it flagged as spam.
+type SpamReporter interface {
+	ReportSpam(comment Comment) error
+	ReportHam(comment Comment) error
+}
+
+// SpamCheckers runs a list of checkers and sums their scores. The
+// verdict is given by the thresholds using the sum of scores. Checkers
+// that fail are logged and ignored so an unavailable service does not
+// disable the other checks.
+type SpamCheckers struct {
+	Checkers   []SpamChecker
+	Thresholds SpamThresholds
+}
+
+func (s SpamCheckers) CheckComment(comment Comment) (SpamCheckResult, error) {
+	var score float64
+	reasons := make([]string, 0)
+	for _, checker := range s.Checkers {
+		r, err := checker.CheckComment(comment)
+		if err != nil {
+			Errorf("error checking spam with %T: %s", checker, err.Error())
+			continue
+		}
+		score += r.Score
+		reasons = append(reasons, r.Reasons...)
+	}
+	result := SpamCheckResult{
+		Verdict: s.Thresholds.Verdict(score),
+		Score:   score,
+		Reasons: reasons,
+	}
+	return result, nil
+}
+
+var linkRegex = regexp.MustCompile(`(?i)(https?://|www\.)`)
+
+// LinkCountChecker scores comments with too many links.
+type LinkCountChecker struct {
+	MaxLinks int
+	// Score given for each link above MaxLinks.
+	Weight float64
+}
+
+func (c LinkCountChecker) CheckComment(comment Comment) (SpamCheckResult, error) {
+	n := len(linkRegex.FindAllString(comment.Content, -1))
+	if n <= c.MaxLinks {
+		return NewSpamCheckResult(0), nil
+	}
+	score := float64(n-c.MaxLinks) * c.Weight
+	reason := fmt.Sprintf("%d links", n)
+	return NewSpamCheckResult(score, reason), nil
+}
+
+// BlockedWordsChecker scores comments containing blocked words
+// either in the content or in the author name.
+type BlockedWordsChecker struct {
+	Words []string
+	// Score given for each blocked word found.
+	Weight float64
+}
+
+func (c BlockedWordsChecker) CheckComment(comment Comment) (SpamCheckResult, error) {
+	text := strings.ToLower(comment.Author + " " + comment.Content)
+	var score float64
+	reasons := make([]string, 0)
+	for _, word := range c.Words {
+		word = strings.ToLower(strings.TrimSpace(word))
+		if word == "" {
+			continue
+		}
+		if strings.Contains(text, word) {
+			score += c.Weight
+			reasons = append(reasons, fmt.Sprintf("blocked word %s", word))
+		}
+	}
+	return NewSpamCheckResult(score, reasons...), nil
+}
+
+// ContentLengthChecker scores comments that are too short or too long.
+type ContentLengthChecker struct {
+	MinLength int
+	// MaxLength zero means no max length.
+	MaxLength int
+	Weight    float64
+}
+
+func (c ContentLengthChecker) CheckComment(comment Comment) (SpamCheckResult, error) {
+	n := utf8.RuneCountInString(strings.TrimSpace(comment.Content))
+	if n < c.MinLength {
+		return NewSpamCheckResult(c.Weight, "content too short"), nil
+	}
+	if c.MaxLength > 0 && n > c.MaxLength {
+		return NewSpamCheckResult(c.Weight, "content too long"), nil
+	}
+	return NewSpamCheckResult(0), nil
+}
+
+// DuplicateContentChecker scores comments with the same content of an
+// existing comment in the same domain.
+type DuplicateContentChecker struct {
+	Storage CommentStorage
+	Weight  float64
+}
+
+func (c DuplicateContentChecker) CheckComment(comment Comment) (SpamCheckResult, error) {
+	content := normalizeContent(comment.Content)
+	if content == "" {
+		return NewSpamCheckResult(0), nil
+	}
+	filter := CommentsFilter{
+		ClientID: &comment.ClientID,
+		DomainID: &comment.DomainID,
+	}
+	comments, err := c.Storage.SearchComments(content, filter)
+	if err != nil {
+		return SpamCheckResult{}, err
+	}
+	for _, other := range comments {
+		if normalizeContent(other.Content) == content {
+			return NewSpamCheckResult(c.Weight, "duplicate content"), nil
+		}
+	}
+	return NewSpamCheckResult(0), nil
+}
+
+// normalizeContent lowers the content and collapses whitespaces so
+// small changes don't avoid duplicate detection.
+func normalizeContent(content string) string {
+	return strings.Join(strings.Fields(strings.ToLower(content)), " ")
+}
+
+// NewDefaultSpamChecker returns the checkers configured for the server.
+func NewDefaultSpamChecker(c Config, storage CommentStorage) SpamChecker {
+	maxLinks := c.SpamMaxLinks
+	if maxLinks <= 0 {
+		maxLinks = DEFAULT_SPAM_MAX_LINKS
+	}
+	minLength := c.CommentMinLength
+	if minLength <= 0 {
+		minLength = DEFAULT_COMMENT_MIN_LENGTH
+	}
+	maxLength := c.CommentMaxLength
+	if maxLength <= 0 {
+		maxLength = DEFAULT_COMMENT_MAX_LENGTH
+	}
+	thresholds := DefaultSpamThresholds()
+	if c.SpamSuspectScore > 0 {
+		thresholds.Suspect = c.SpamSuspectScore
+	}
+	if c.SpamScore > 0 {
+		thresholds.Spam = c.SpamScore
+	}
+	w := DEFAULT_SPAM_CHECKER_WEIGHT
+	return SpamCheckers{
+		Checkers: []SpamChecker{
+			LinkCountChecker{MaxLinks: maxLinks, Weight: w},
+			BlockedWordsChecker{Words: c.SpamBlockedWords, Weight: w * 2},
+			ContentLengthChecker{
+				MinLength: minLength, MaxLength: maxLength, Weight: w},
+			DuplicateContentChecker{Storage: storage, Weight: w},
+			NewBayesSpamChecker(storage),
+			NewAkismetClient(),
+		},
+		Thresholds: thresholds,
+	}
+}
@@ -0,0 +1,240 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package parlante
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+type errorSpamChecker struct{}
+
+func (c errorSpamChecker) CheckComment(comment Comment) (SpamCheckResult, error) {
+	return SpamCheckResult{}, errors.New("bad checker")
+}
+
+func TestSpamCheckers(t *testing.T) {
+	storage := NewCommentStorageInMemory()
+	client := Client{Name: "client"}
+	domain := ClientDomain{Domain: "bla.net"}
+	storage.CreateComment(client, domain, "zé", "The   same comment",
+		"https://bla.net/post")
+
+	var tests = []struct {
+		testName string
+		checker  SpamChecker
+		content  string
+		verdict  SpamVerdict
+		score    float64
+		err      bool
+	}{
+		{
+			"link count ok",
+			LinkCountChecker{MaxLinks: 1, Weight: 0.5},
+			"see https://bla.net",
+			SpamHam,
+			0,
+			false,
+		},
+		{
+			"link count suspect",
+			LinkCountChecker{MaxLinks: 1, Weight: 0.5},
+			"see https://bla.net and www.ble.net",
+			SpamSuspect,
+			0.5,
+			false,
+		},
+		{
+			"link count spam",
+			LinkCountChecker{MaxLinks: 1, Weight: 0.5},
+			"see https://bla.net, www.ble.net and http://bli.net",
+			SpamSpam,
+			1,
+			false,
+		},
+		{
+			"blocked words ok",
+			BlockedWordsChecker{Words: []string{"casino"}, Weight: 1},
+			"a nice comment",
+			SpamHam,
+			0,
+			false,
+		},
+		{
+			"blocked words spam",
+			BlockedWordsChecker{Words: []string{"casino", " "}, Weight: 1},
+			"Best CASINO ever",
+			SpamSpam,
+			1,
+			false,
+		},
+		{
+			"content length ok",
+			ContentLengthChecker{MinLength: 2, MaxLength: 10, Weight: 0.5},
+			"ótimo",
+			SpamHam,
+			0,
+			false,
+		},
+		{
+			"content too short",
+			ContentLengthChecker{MinLength: 2, MaxLength: 10, Weight: 0.5},
+			" a ",
+			SpamSuspect,
+			0.5,
+			false,
+		},
+		{
+			"content too long",
+			ContentLengthChecker{MinLength: 2, MaxLength: 10, Weight: 0.5},
+			"a comment too long",
+			SpamSuspect,
+			0.5,
+			false,
+		},
+		{
+			"duplicate content ok",
+			DuplicateContentChecker{Storage: &storage, Weight: 0.5},
+			"the same comment, but not really",
+			SpamHam,
+			0,
+			false,
+		},
+		{
+			"duplicate content",
+			DuplicateContentChecker{Storage: &storage, Weight: 0.5},
+			"the same\ncomment",
+			SpamSuspect,
+			0.5,
+			false,
+		},
+		{
+			"checkers sum scores",
+			SpamCheckers{
+				Checkers: []SpamChecker{
+					ContentLengthChecker{MinLength: 2, MaxLength: 5, Weight: 0.5},
+					DuplicateContentChecker{Storage: &storage, Weight: 0.5},
+				},
+				Thresholds: DefaultSpamThresholds(),
+			},
+			"The same comment",
+			SpamSpam,
+			1,
+			false,
+		},
+		{
+			"checkers with error",
+			SpamCheckers{
+				Checkers: []SpamChecker{
+					errorSpamChecker{},
+					ContentLengthChecker{MinLength: 20, Weight: 0.5},
+				},
+				Thresholds: DefaultSpamThresholds(),
+			},
+			"a comment",
+			SpamSuspect,
+			0.5,
+			false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			comment := Comment{Author: "jão", Content: test.content}
+			r, err := test.checker.CheckComment(comment)
+			if (err != nil) != test.err {
+				t.Fatalf("bad err %s", err)
+			}
+			if r.Verdict != test.verdict {
+				t.Fatalf("bad verdict %s", r.Verdict)
+			}
+			if r.Score != test.score {
+				t.Fatalf("bad score %f", r.Score)
+			}
+			if r.Score > 0 && len(r.Reasons) == 0 {
+				t.Fatalf("no reasons for score")
+			}
+		})
+	}
+}
+
+func TestDuplicateContentChecker_SQLite(t *testing.T) {
+	setupTestDB()
+	defer os.Remove(DBFILE)
+
+	cs := ClientStorageSQLite{}
+	ds := ClientDomainStorageSQLite{}
+	storage := CommentStorageSQLite{}
+	client, _, _ := cs.CreateClient("client")
+	domain, _ := ds.AddClientDomain(client, "bla.net")
+	other, _, _ := cs.CreateClient("other")
+	otherDomain, _ := ds.AddClientDomain(other, "ble.net")
+	storage.CreateComment(client, domain, "zé", "Buy cheap stuff",
+		"https://bla.net/post")
+
+	checker := DuplicateContentChecker{Storage: storage, Weight: 0.5}
+
+	r, err := checker.CheckComment(Comment{
+		ClientID: client.ID, DomainID: domain.ID,
+		Content: "buy  cheap stuff"})
+	if err != nil || r.Verdict != SpamSuspect {
+		t.Fatalf("bad verdict for duplicate %s %s", r.Verdict, err)
+	}
+
+	r, err = checker.CheckComment(Comment{
+		ClientID: other.ID, DomainID: otherDomain.ID,
+		Content: "buy cheap stuff"})
+	if err != nil || r.Verdict != SpamHam {
+		t.Fatalf("bad verdict for other domain %s %s", r.Verdict, err)
+	}
+}
+
+func TestNewDefaultSpamChecker(t *testing.T) {
+	storage := NewCommentStorageInMemory()
+	c := Config{SpamBlockedWords: []string{"casino"}}
+	checker := NewDefaultSpamChecker(c, &storage)
+
+	var tests = []struct {
+		content string
+		verdict SpamVerdict
+	}{
+		{"a nice comment", SpamHam},
+		{"a", SpamSuspect},
+		{"go to the casino", SpamSpam},
+		{strings.Repeat("https://bla.net ", 4), SpamSpam},
+	}
+	for _, test := range tests {
+		r, err := checker.CheckComment(Comment{Author: "zé", Content: test.content})
+		if err != nil {
+			t.Fatalf("error checking %s", err.Error())
+		}
+		if r.Verdict != test.verdict {
+			t.Fatalf("bad verdict for %s: %s", test.content, r.Verdict)
+		}
+	}
+}
+
+func TestSpamVerdictCommentStatus(t *testing.T) {
+	if SpamHam.CommentStatus() != "" ||
+		SpamSuspect.CommentStatus() != CommentPending ||
+		SpamSpam.CommentStatus() != CommentSpam {
+		t.Fatalf("bad comment status for verdict")
+	}
+}
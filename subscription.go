@@ -0,0 +1,127 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package parlante
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/leonelquinteros/gotext"
+)
+
+// UnsubscribeToken returns the token that allows a subscriber to
+// unsubscribe using the link in the notification emails.
+func UnsubscribeToken(signer Signer, sub Subscription) string {
+	id := strconv.FormatInt(sub.ID, 10)
+	return signer.Sign("unsubscribe", id, sub.Email)
+}
+
+// VerifyUnsubscribeToken says if the token is valid for the
+// subscription.
+func VerifyUnsubscribeToken(signer Signer, sub Subscription, token string) bool {
+	id := strconv.FormatInt(sub.ID, 10)
+	return signer.Verify(token, "unsubscribe", id, sub.Email)
+}
+
+// UnsubscribeURL returns the url that removes a subscription.
+func UnsubscribeURL(baseURL string, signer Signer, sub Subscription) string {
+	q := url.Values{}
+	q.Set("id", strconv.FormatInt(sub.ID, 10))
+	q.Set("token", UnsubscribeToken(signer, sub))
+	return strings.TrimRight(baseURL, "/") + "/unsubscribe/?" + q.Encode()
+}
+
+// ConfirmSubscriptionToken returns the token that allows a subscriber
+// to confirm the email using the link sent when subscribing.
+func ConfirmSubscriptionToken(signer Signer, sub Subscription) string {
+	id := strconv.FormatInt(sub.ID, 10)
+	return signer.Sign("confirm-subscription", id, sub.Email)
+}
+
+// VerifyConfirmSubscriptionToken says if the confirmation token is
+// valid for the subscription.
+func VerifyConfirmSubscriptionToken(
+	signer Signer, sub Subscription, token string) bool {
+	id := strconv.FormatInt(sub.ID, 10)
+	return signer.Verify(token, "confirm-subscription", id, sub.Email)
+}
+
+// ConfirmSubscriptionURL returns the url that confirms a subscription.
+func ConfirmSubscriptionURL(
+	baseURL string, signer Signer, sub Subscription) string {
+	q := url.Values{}
+	q.Set("id", strconv.FormatInt(sub.ID, 10))
+	q.Set("token", ConfirmSubscriptionToken(signer, sub))
+	return strings.TrimRight(baseURL, "/") + "/subscribe/confirm/?" + q.Encode()
+}
+
+// NewSubscriptionConfirmEmail returns the email asking a subscriber to
+// confirm the subscription to a page.
+func NewSubscriptionConfirmEmail(
+	from string,
+	sub Subscription,
+	confirmURL string,
+	loc *gotext.Locale) (EmailMessage, error) {
+
+	data := make(map[string]any)
+	data["url"] = sub.PageURL
+	subject := Tprintf(loc.Get("Confirm your subscription to {{.url}}"), data)
+	tmplCtx := make(map[string]any)
+	tmplCtx["intro"] = Tprintf(loc.Get(
+		"To receive emails about new comments at {{.url}} use the link below:"),
+		data)
+	tmplCtx["confirmURL"] = confirmURL
+	tmplCtx["ignoreLabel"] = loc.Get(
+		"If you did not subscribe you can ignore this email.")
+	return NewTemplatedEmail(from, []string{sub.Email}, subject,
+		"email_subscription_confirm", loc, tmplCtx)
+}
+
+// NewSubscriptionEmail returns the email telling a subscriber about a
+// new comment in the page.
+func NewSubscriptionEmail(
+	from string,
+	sub Subscription,
+	comment Comment,
+	unsubscribeURL string,
+	loc *gotext.Locale) (EmailMessage, error) {
+
+	data := make(map[string]any)
+	data["name"] = comment.Author
+	data["url"] = comment.PageURL
+	subject := Tprintf(loc.Get("New comment from {{.name}} at {{.url}}"), data)
+	tmplCtx := make(map[string]any)
+	tmplCtx["intro"] = Tprintf(loc.Get("{{.name}} commented at {{.url}}:"), data)
+	tmplCtx["url"] = comment.PageURL
+	tmplCtx["content"] = comment.Content
+	tmplCtx["unsubscribeLabel"] = loc.Get(
+		"To stop receiving emails about this page use the link below:")
+	tmplCtx["unsubscribeURL"] = unsubscribeURL
+	msg, err := NewTemplatedEmail(from, []string{sub.Email}, subject,
+		"email_subscription", loc, tmplCtx)
+	if err != nil {
+		return EmailMessage{}, err
+	}
+	// one click unsubscribe as in rfc 8058
+	msg.Headers = map[string]string{
+		"List-Unsubscribe":      "<" + unsubscribeURL + ">",
+		"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+	}
+	return msg, nil
+}
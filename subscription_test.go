@@ -0,0 +1,176 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package parlante
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestUnsubscribeURL(t *testing.T) {
+	signer := NewSigner("secret")
+	sub := Subscription{ID: 1, Email: "ze@bla.net"}
+
+	link := UnsubscribeURL("https://parlante.net/", signer, sub)
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Host != "parlante.net" || u.Path != "/unsubscribe/" {
+		t.Fatalf("bad unsubscribe url %s", link)
+	}
+	token := u.Query().Get("token")
+	if u.Query().Get("id") != "1" || token != UnsubscribeToken(signer, sub) {
+		t.Fatalf("bad unsubscribe params %s", link)
+	}
+
+	var tests = []struct {
+		testName string
+		signer   Signer
+		sub      Subscription
+		token    string
+		valid    bool
+	}{
+		{"valid token", signer, sub, token, true},
+		{"other subscription", signer, Subscription{ID: 2, Email: sub.Email},
+			token, false},
+		{"other email", signer, Subscription{ID: 1, Email: "x@bla.net"},
+			token, false},
+		{"other key", NewSigner("other"), sub, token, false},
+		{"empty token", signer, sub, "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			valid := VerifyUnsubscribeToken(test.signer, test.sub, test.token)
+			if valid != test.valid {
+				t.Fatalf("bad token verification %t", valid)
+			}
+		})
+	}
+}
+
+func TestNewSubscriptionEmail(t *testing.T) {
+	sub := Subscription{ID: 1, Email: "ze@bla.net"}
+	comment := Comment{
+		Author:  "Maria",
+		Content: "A new comment",
+		PageURL: "https://bla.net/post",
+	}
+	link := "https://parlante.net/unsubscribe/?id=1"
+
+	msg, err := NewSubscriptionEmail(
+		"me@bla.net", sub, comment, link, GetLocale("en"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msg.To) != 1 || msg.To[0] != sub.Email {
+		t.Fatalf("bad to %s", msg.To)
+	}
+	if !strings.Contains(msg.Subject, "Maria") {
+		t.Fatalf("bad subject %s", msg.Subject)
+	}
+	if !strings.Contains(msg.Body, comment.Content) ||
+		!strings.Contains(msg.Body, link) {
+		t.Fatalf("bad body %s", msg.Body)
+	}
+	if !strings.Contains(msg.HTMLBody, comment.Content) ||
+		!strings.Contains(msg.HTMLBody, `<a href="`+link+`">`) {
+		t.Fatalf("bad html body %s", msg.HTMLBody)
+	}
+	if msg.Headers["List-Unsubscribe"] != "<"+link+">" ||
+		msg.Headers["List-Unsubscribe-Post"] != "List-Unsubscribe=One-Click" {
+		t.Fatalf("bad headers %s", msg.Headers)
+	}
+
+	_, err = NewSubscriptionEmail("", sub, comment, link, GetLocale("en"))
+	if err == nil {
+		t.Fatalf("no error without from")
+	}
+}
+
+func TestConfirmSubscriptionURL(t *testing.T) {
+	signer := NewSigner("secret")
+	sub := Subscription{ID: 1, Email: "ze@bla.net"}
+
+	link := ConfirmSubscriptionURL("https://parlante.net/", signer, sub)
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Host != "parlante.net" || u.Path != "/subscribe/confirm/" {
+		t.Fatalf("bad confirm url %s", link)
+	}
+	token := u.Query().Get("token")
+	if u.Query().Get("id") != "1" ||
+		token != ConfirmSubscriptionToken(signer, sub) {
+		t.Fatalf("bad confirm params %s", link)
+	}
+
+	var tests = []struct {
+		testName string
+		signer   Signer
+		sub      Subscription
+		token    string
+		valid    bool
+	}{
+		{"valid token", signer, sub, token, true},
+		{"other subscription", signer, Subscription{ID: 2, Email: sub.Email},
+			token, false},
+		{"other email", signer, Subscription{ID: 1, Email: "x@bla.net"},
+			token, false},
+		{"unsubscribe token", signer, sub, UnsubscribeToken(signer, sub),
+			false},
+		{"empty token", signer, sub, "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			valid := VerifyConfirmSubscriptionToken(
+				test.signer, test.sub, test.token)
+			if valid != test.valid {
+				t.Fatalf("bad token verification %t", valid)
+			}
+		})
+	}
+}
+
+func TestNewSubscriptionConfirmEmail(t *testing.T) {
+	sub := Subscription{ID: 1, Email: "ze@bla.net",
+		PageURL: "https://bla.net/post"}
+	link := "https://parlante.net/subscribe/confirm/?id=1"
+
+	msg, err := NewSubscriptionConfirmEmail(
+		"me@bla.net", sub, link, GetLocale("en"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msg.To) != 1 || msg.To[0] != sub.Email {
+		t.Fatalf("bad to %s", msg.To)
+	}
+	if !strings.Contains(msg.Subject, sub.PageURL) {
+		t.Fatalf("bad subject %s", msg.Subject)
+	}
+	if !strings.Contains(msg.Body, link) {
+		t.Fatalf("bad body %s", msg.Body)
+	}
+	if !strings.Contains(msg.HTMLBody, `<a href="`+link+`">`) {
+		t.Fatalf("bad html body %s", msg.HTMLBody)
+	}
+}
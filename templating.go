@@ -20,7 +20,10 @@ package parlante
 import (
 	"bytes"
 	"embed"
+	"errors"
 	"html/template"
+	"strings"
+	texttemplate "text/template"
 )
 
 //go:embed templates
@@ -33,21 +36,41 @@ func LoadTemplates(funcMap template.FuncMap) (*template.Template, error) {
 	return tmpl.ParseFS(embeddedTemplates, "templates/*.html")
 }
 
+// LoadTextTemplates returns the plain text templates, like the ones
+// used in emails. Note that the templates are embedded.
+func LoadTextTemplates(funcMap texttemplate.FuncMap) (
+	*texttemplate.Template, error) {
+	tmpl := texttemplate.New("").Funcs(funcMap)
+	return tmpl.ParseFS(embeddedTemplates, "templates/*.txt")
+}
+
 func RenderTemplate(
 	path string,
 	lang string,
 	timezone string,
 	data map[string]any) ([]byte, error) {
 
-	funcMap := template.FuncMap{
-		"fmtTimestap": func(ts int64) string {
-			fmt := GetDateTimeFmt(lang)
-			dtstr, _ := LocalizeTimestamp(ts, timezone, fmt)
-			return dtstr
-		},
+	tmpl, err := LoadTemplates(templateFuncs(lang, timezone))
+	if err != nil {
+		return nil, err
 	}
+	var buff bytes.Buffer
+	err = tmpl.ExecuteTemplate(&buff, path, data)
+	if err != nil {
+		return nil, err
+	}
+	return buff.Bytes(), nil
+}
+
+// RenderTextTemplate is like RenderTemplate but for the plain text
+// templates. Nothing is escaped.
+func RenderTextTemplate(
+	path string,
+	lang string,
+	timezone string,
+	data map[string]any) ([]byte, error) {
 
-	tmpl, err := LoadTemplates(funcMap)
+	tmpl, err := LoadTextTemplates(templateFuncs(lang, timezone))
 	if err != nil {
 		return nil, err
 	}
@@ -58,3 +81,35 @@ func RenderTemplate(
 	}
 	return buff.Bytes(), nil
 }
+
+// templateFuncs returns the functions available in the templates.
+func templateFuncs(lang string, timezone string) map[string]any {
+	return map[string]any{
+		"fmtTimestap": func(ts int64) string {
+			fmt := GetDateTimeFmt(lang)
+			dtstr, _ := LocalizeTimestamp(ts, timezone, fmt)
+			return dtstr
+		},
+		"dict": dict,
+		"indent": func(n int, s string) string {
+			return strings.ReplaceAll(s, "\n", "\n"+strings.Repeat(" ", n))
+		},
+	}
+}
+
+// dict creates a map from a list of key/value pairs. It is used to
+// pass more than one value to a nested template.
+func dict(values ...any) (map[string]any, error) {
+	if len(values)%2 != 0 {
+		return nil, errors.New("dict needs an even number of args")
+	}
+	d := make(map[string]any)
+	for i := 0; i < len(values); i += 2 {
+		k, ok := values[i].(string)
+		if !ok {
+			return nil, errors.New("dict keys must be strings")
+		}
+		d[k] = values[i+1]
+	}
+	return d, nil
+}
@@ -39,3 +39,45 @@ func TestTemplating(t *testing.T) {
 	}
 
 }
+
+func TestTemplating_Replies(t *testing.T) {
+	tmpl := "comments.html"
+	comments := []Comment{
+		{ID: 1, Author: "zé", Content: "the comment"},
+		{ID: 2, Author: "jão", Content: "the reply", ParentID: 1},
+	}
+	data := make(map[string]any)
+	data["header"] = "bla"
+	data["replyLabel"] = "reply"
+	data["comments"] = BuildCommentTree(comments)
+	b, err := RenderTemplate(tmpl, "pt_br", "UTC", data)
+	if err != nil {
+		t.Fatalf("Error rendering template: %s", err.Error())
+	}
+
+	s := string(b)
+	if !strings.Contains(s, "parlante-comment-replies") ||
+		!strings.Contains(s, "the reply") {
+		t.Fatalf("bad render for replies: %s", s)
+	}
+}
+
+func TestTemplating_Edited(t *testing.T) {
+	tmpl := "comments.html"
+	comments := []Comment{
+		{ID: 1, Author: "zé", Content: "the comment", EditedAt: 1234},
+		{ID: 2, Author: "jão", Content: "the reply", ParentID: 1},
+	}
+	data := make(map[string]any)
+	data["editedLabel"] = "edited"
+	data["comments"] = BuildCommentTree(comments)
+	b, err := RenderTemplate(tmpl, "pt_br", "UTC", data)
+	if err != nil {
+		t.Fatalf("Error rendering template: %s", err.Error())
+	}
+
+	s := string(b)
+	if strings.Count(s, "parlante-comment-edited") != 1 {
+		t.Fatalf("bad render for edited comment: %s", s)
+	}
+}
@@ -19,7 +19,14 @@ package parlante
 
 // notest
 
-import "errors"
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
 
 // A in memory database for tests
 type ClientStorageInMemory struct {
@@ -46,6 +53,27 @@ func (s ClientStorageInMemory) GetClientByUUID(uuid string) (Client, error) {
 	return c, nil
 }
 
+func (s ClientStorageInMemory) GetClientByID(id int64) (Client, error) {
+	for _, c := range s.data {
+		if c.ID == id {
+			return c, nil
+		}
+	}
+	return Client{}, errors.New("client not found")
+}
+
+func (s ClientStorageInMemory) SetClientAkismet(
+	c Client, endpoint string, key string) error {
+	current, ok := s.data[c.UUID]
+	if !ok {
+		return errors.New("client not found")
+	}
+	current.AkismetEndpoint = endpoint
+	current.AkismetKey = key
+	s.data[c.UUID] = current
+	return nil
+}
+
 func (s ClientStorageInMemory) ListClients() ([]Client, error) {
 	if s.listError {
 		return nil, errors.New("error list client")
@@ -83,6 +111,8 @@ func NewClientStorageInMemory() ClientStorageInMemory {
 
 type ClientDomainStorageInMemory struct {
 	data        map[string]ClientDomain
+	rateLimits  map[string]RateLimit
+	notifs      map[string]NotificationSettings
 	BadDomain   string
 	listError   bool
 	removeError bool
@@ -129,6 +159,112 @@ func (s ClientDomainStorageInMemory) ListDomains() ([]ClientDomain, error) {
 	return domains, nil
 
 }
+func (s ClientDomainStorageInMemory) SetDomainModeration(
+	d ClientDomain, moderate bool) error {
+	for k, v := range s.data {
+		if v.Domain == d.Domain && v.ClientID == d.ClientID {
+			v.Moderate = moderate
+			s.data[k] = v
+		}
+	}
+	return nil
+}
+
+func (s ClientDomainStorageInMemory) SetDomainMarkdown(
+	d ClientDomain, markdown bool) error {
+	for k, v := range s.data {
+		if v.Domain == d.Domain && v.ClientID == d.ClientID {
+			v.Markdown = markdown
+			s.data[k] = v
+		}
+	}
+	return nil
+}
+
+func (s ClientDomainStorageInMemory) SetDomainOrder(
+	d ClientDomain, order CommentOrder) error {
+	for k, v := range s.data {
+		if v.Domain == d.Domain && v.ClientID == d.ClientID {
+			v.Order = order
+			s.data[k] = v
+		}
+	}
+	return nil
+}
+
+func (s ClientDomainStorageInMemory) GetClientRateLimit(
+	c Client, route RateLimitRoute) (RateLimit, error) {
+	if s.listError {
+		return RateLimit{}, errors.New("error get rate limit")
+	}
+	return s.rateLimits["client-"+c.UUID+"-"+string(route)], nil
+}
+
+func (s ClientDomainStorageInMemory) SetClientRateLimit(
+	c Client, route RateLimitRoute, limit RateLimit) error {
+	s.setRateLimit("client-"+c.UUID+"-"+string(route), limit)
+	return nil
+}
+
+func (s ClientDomainStorageInMemory) GetDomainRateLimit(
+	d ClientDomain, route RateLimitRoute) (RateLimit, error) {
+	if s.listError {
+		return RateLimit{}, errors.New("error get rate limit")
+	}
+	return s.rateLimits["domain-"+d.Domain+"-"+string(route)], nil
+}
+
+func (s ClientDomainStorageInMemory) SetDomainRateLimit(
+	d ClientDomain, route RateLimitRoute, limit RateLimit) error {
+	s.setRateLimit("domain-"+d.Domain+"-"+string(route), limit)
+	return nil
+}
+
+func (s ClientDomainStorageInMemory) setRateLimit(key string, limit RateLimit) {
+	if limit.IsZero() {
+		delete(s.rateLimits, key)
+		return
+	}
+	s.rateLimits[key] = limit
+}
+
+func (s ClientDomainStorageInMemory) GetClientNotifications(c Client) (
+	NotificationSettings, error) {
+	if s.listError {
+		return NotificationSettings{}, errors.New("error get notifications")
+	}
+	return s.notifs["client-"+c.UUID], nil
+}
+
+func (s ClientDomainStorageInMemory) SetClientNotifications(
+	c Client, n NotificationSettings) error {
+	s.setNotifications("client-"+c.UUID, n)
+	return nil
+}
+
+func (s ClientDomainStorageInMemory) GetDomainNotifications(d ClientDomain) (
+	NotificationSettings, error) {
+	if s.listError {
+		return NotificationSettings{}, errors.New("error get notifications")
+	}
+	return s.notifs["domain-"+d.Domain], nil
+}
+
+func (s ClientDomainStorageInMemory) SetDomainNotifications(
+	d ClientDomain, n NotificationSettings) error {
+	s.setNotifications("domain-"+d.Domain, n)
+	return nil
+}
+
+func (s ClientDomainStorageInMemory) setNotifications(
+	key string, n NotificationSettings) {
+	if n.IsZero() {
+		delete(s.notifs, key)
+		return
+	}
+	s.notifs[key] = n
+}
+
 func (s *ClientDomainStorageInMemory) ForceListError(f bool) {
 	s.listError = f
 }
@@ -140,6 +276,8 @@ func (s *ClientDomainStorageInMemory) ForceRemoveError(f bool) {
 func NewClientDomainStorageInMemory() ClientDomainStorageInMemory {
 	d := ClientDomainStorageInMemory{}
 	d.data = make(map[string]ClientDomain)
+	d.rateLimits = make(map[string]RateLimit)
+	d.notifs = make(map[string]NotificationSettings)
 	d.BadDomain = "bad.net"
 	return d
 }
@@ -149,6 +287,13 @@ type CommentStorageInMemory struct {
 	clientComments map[int64][]Comment
 	domainComments map[int64][]Comment
 	pageComments   map[string][]Comment
+	byID           map[int64]Comment
+	revisions      map[int64][]CommentRevision
+	spamTokens     map[string]SpamTokenCount
+	spamTrained    map[int64]trainedComment
+	requestInfo    map[int64]CommentRequestInfo
+	reactions      map[int64]map[string]map[string]bool
+	imports        map[string]int64
 	BadCommenter   string
 	BadPage        string
 	listError      bool
@@ -165,15 +310,45 @@ func (s CommentStorageInMemory) CreateComment(c Client, d ClientDomain,
 	if err != nil {
 		return Comment{}, err
 	}
-	s.data["all"] = append(s.data["all"], comment)
-	s.clientComments[c.ID] = append(s.clientComments[c.ID], comment)
-	s.domainComments[d.ID] = append(s.domainComments[d.ID], comment)
-	s.pageComments[comment.PageURL] = append(
-		s.pageComments[comment.PageURL], comment)
+	s.addComment(&comment)
+	return comment, nil
+}
+
+func (s CommentStorageInMemory) CreateReply(c Client, d ClientDomain,
+	parent Comment, name string, content string, page_url string) (
+	Comment, error) {
+	if name == s.BadCommenter {
+		return Comment{}, errors.New("bad")
+	}
 
+	comment, err := NewReply(c, d, parent, name, content, page_url)
+	if err != nil {
+		return Comment{}, err
+	}
+	s.addComment(&comment)
 	return comment, nil
 }
 
+func (s CommentStorageInMemory) GetCommentByID(id int64) (Comment, error) {
+	c, ok := s.byID[id]
+	if !ok {
+		return Comment{}, errors.New("comment not found")
+	}
+	return c, nil
+}
+
+func (s CommentStorageInMemory) addComment(comment *Comment) {
+	comment.ID = int64(len(s.byID) + 1)
+	s.byID[comment.ID] = *comment
+	s.data["all"] = append(s.data["all"], *comment)
+	s.clientComments[comment.ClientID] = append(
+		s.clientComments[comment.ClientID], *comment)
+	s.domainComments[comment.DomainID] = append(
+		s.domainComments[comment.DomainID], *comment)
+	s.pageComments[comment.PageURL] = append(
+		s.pageComments[comment.PageURL], *comment)
+}
+
 func (s CommentStorageInMemory) ListComments(filter CommentsFilter) (
 	[]Comment, error) {
 
@@ -185,17 +360,67 @@ func (s CommentStorageInMemory) ListComments(filter CommentsFilter) (
 		return []Comment{}, errors.New("bad")
 	}
 
-	if filter.ClientID != nil {
-		return s.clientComments[*filter.ClientID], nil
+	var comments []Comment
+	switch {
+	case filter.ClientID != nil:
+		comments = s.clientComments[*filter.ClientID]
+	case filter.DomainID != nil:
+		comments = s.domainComments[*filter.DomainID]
+	case filter.PageURL != nil:
+		comments = s.pageComments[*filter.PageURL]
+	default:
+		comments = s.data["all"]
+	}
+	filtered := make([]Comment, 0)
+	for _, c := range comments {
+		c.Score = s.score(c.ID)
+		if filter.PageURL != nil && c.PageURL != *filter.PageURL {
+			continue
+		}
+		if filter.Status != nil && c.Status != *filter.Status {
+			continue
+		}
+		if filter.Cursor != nil &&
+			filter.Cursor.IsBeforeInOrder(c, filter.Order) {
+			continue
+		}
+		filtered = append(filtered, c)
 	}
-	if filter.DomainID != nil {
-		return s.domainComments[*filter.DomainID], nil
+	slices.SortStableFunc(filtered, func(a, b Comment) int {
+		ca := NewCommentCursor(a, filter.Order)
+		return ca.Compare(NewCommentCursor(b, filter.Order), filter.Order)
+	})
+	if filter.Limit > 0 && len(filtered) > filter.Limit {
+		filtered = filtered[:filter.Limit]
 	}
+	return filtered, nil
+}
 
-	if filter.PageURL != nil {
-		return s.pageComments[*filter.PageURL], nil
+func (s CommentStorageInMemory) SearchComments(
+	query string, filter CommentsFilter) ([]Comment, error) {
+	terms := strings.Fields(strings.ToLower(query))
+	if len(terms) == 0 {
+		return nil, errors.New("Empty search query")
 	}
-	return s.data["all"], nil
+	comments, err := s.ListComments(filter)
+	if err != nil {
+		return nil, err
+	}
+	found := make([]Comment, 0)
+	for _, c := range comments {
+		text := strings.ToLower(c.Author + " " + c.Content + " " + c.PageURL)
+		matches := true
+		for _, term := range terms {
+			if !strings.Contains(text, strings.TrimRight(term, "*")) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			found = append(found, c)
+		}
+	}
+	return found, nil
 }
 
 func (s CommentStorageInMemory) CountComments(urls ...string) ([]CommentCount, error) {
@@ -204,8 +429,14 @@ func (s CommentStorageInMemory) CountComments(urls ...string) ([]CommentCount, e
 		if url == s.BadPage {
 			return nil, errors.New("Bad")
 		}
+		var count int64
+		for _, comment := range s.pageComments[url] {
+			if comment.Status == CommentApproved {
+				count++
+			}
+		}
 		c := CommentCount{
-			Count:   int64(len(s.data[url])),
+			Count:   count,
 			PageURL: url}
 		r = append(r, c)
 	}
@@ -223,6 +454,154 @@ func (s CommentStorageInMemory) RemoveComment(comment Comment) error {
 	return nil
 }
 
+func (s CommentStorageInMemory) SetCommentStatus(
+	comment Comment, status CommentStatus) error {
+	current, err := s.GetCommentByID(comment.ID)
+	if err != nil {
+		return err
+	}
+	err = current.SetStatus(status)
+	if err != nil {
+		return err
+	}
+	s.updateComment(current)
+	return nil
+}
+
+func (s CommentStorageInMemory) UpdateComment(
+	comment Comment, content string, editor string) (Comment, error) {
+	current, err := s.GetCommentByID(comment.ID)
+	if err != nil {
+		return Comment{}, err
+	}
+	rev, err := current.Edit(content, editor)
+	if err != nil {
+		return Comment{}, err
+	}
+	rev.ID = int64(len(s.revisions[current.ID]) + 1)
+	s.revisions[current.ID] = append(
+		[]CommentRevision{rev}, s.revisions[current.ID]...)
+	s.updateComment(current)
+	return current, nil
+}
+
+func (s CommentStorageInMemory) ListCommentRevisions(comment Comment) (
+	[]CommentRevision, error) {
+	if s.listError {
+		return nil, errors.New("bad")
+	}
+	return s.revisions[comment.ID], nil
+}
+
+type trainedComment struct {
+	spam   bool
+	tokens []string
+}
+
+func (s CommentStorageInMemory) TrainSpamModel(comment Comment, spam bool) error {
+	if s.listError {
+		return errors.New("bad")
+	}
+	update := func(tokens []string, spam bool, n int64) {
+		for _, t := range tokens {
+			count := s.spamTokens[t]
+			if spam {
+				count.Spam += n
+			} else {
+				count.Ham += n
+			}
+			s.spamTokens[t] = count
+		}
+	}
+	if old, ok := s.spamTrained[comment.ID]; ok {
+		update(old.tokens, old.spam, -1)
+	}
+	tokens := SpamTokens(comment)
+	update(tokens, spam, 1)
+	s.spamTrained[comment.ID] = trainedComment{spam: spam, tokens: tokens}
+	return nil
+}
+
+func (s CommentStorageInMemory) ResetSpamModel() error {
+	if s.listError {
+		return errors.New("bad")
+	}
+	clear(s.spamTokens)
+	clear(s.spamTrained)
+	return nil
+}
+
+func (s CommentStorageInMemory) GetSpamModel(tokens []string) (SpamModel, error) {
+	if s.listError {
+		return SpamModel{}, errors.New("bad")
+	}
+	model := SpamModel{Tokens: make(map[string]SpamTokenCount)}
+	for _, c := range s.spamTrained {
+		if c.spam {
+			model.SpamComments++
+		} else {
+			model.HamComments++
+		}
+	}
+	for _, t := range tokens {
+		if count, ok := s.spamTokens[t]; ok {
+			model.Tokens[t] = count
+		}
+	}
+	return model, nil
+}
+
+func (s CommentStorageInMemory) SetCommentRequestInfo(
+	comment Comment, info CommentRequestInfo) error {
+	s.requestInfo[comment.ID] = info
+	return nil
+}
+
+func (s CommentStorageInMemory) SetCommentAuthorContact(
+	comment Comment, email string, website string) error {
+	c, ok := s.byID[comment.ID]
+	if !ok {
+		return errors.New("comment not found")
+	}
+	c.Email = email
+	c.Website = website
+	s.updateComment(c)
+	return nil
+}
+
+func (s CommentStorageInMemory) GetCommentRequestInfo(comment Comment) (
+	CommentRequestInfo, error) {
+	if s.listError {
+		return CommentRequestInfo{}, errors.New("bad")
+	}
+	return s.requestInfo[comment.ID], nil
+}
+
+// updateComment replaces a comment in all the indexes
+func (s CommentStorageInMemory) updateComment(comment Comment) {
+	s.byID[comment.ID] = comment
+	indexes := []map[string][]Comment{s.data, s.pageComments}
+	for _, idx := range indexes {
+		for _, comments := range idx {
+			replaceComment(comments, comment)
+		}
+	}
+	for _, idx := range []map[int64][]Comment{
+		s.clientComments, s.domainComments} {
+		for _, comments := range idx {
+			replaceComment(comments, comment)
+		}
+	}
+}
+
+func replaceComment(comments []Comment, comment Comment) {
+	for i, c := range comments {
+		if c.ID == comment.ID {
+			comments[i] = comment
+		}
+	}
+}
+
 func (s CommentStorageInMemory) GetComment() Comment {
 	if len(s.data["all"]) > 0 {
 		return s.data["all"][0]
@@ -238,17 +617,383 @@ func (s *CommentStorageInMemory) ForceRemoveError(force bool) {
 	s.removeError = force
 }
 
+func (s CommentStorageInMemory) ToggleReaction(
+	comment Comment, reaction string, reactor string) (bool, error) {
+	if s.reactions[comment.ID] == nil {
+		s.reactions[comment.ID] = make(map[string]map[string]bool)
+	}
+	reactors := s.reactions[comment.ID][reaction]
+	if reactors == nil {
+		reactors = make(map[string]bool)
+		s.reactions[comment.ID][reaction] = reactors
+	}
+	if reactors[reactor] {
+		delete(reactors, reactor)
+		return false, nil
+	}
+	reactors[reactor] = true
+	return true, nil
+}
+
+func (s CommentStorageInMemory) CountReactions(ids ...int64) (
+	map[int64]ReactionCounts, error) {
+	if s.listError {
+		return nil, errors.New("bad")
+	}
+	counts := make(map[int64]ReactionCounts)
+	for _, id := range ids {
+		counts[id] = make(ReactionCounts)
+		for reaction, reactors := range s.reactions[id] {
+			if len(reactors) == 0 {
+				continue
+			}
+			counts[id][reaction] = int64(len(reactors))
+		}
+	}
+	return counts, nil
+}
+
+func (s CommentStorageInMemory) score(id int64) int64 {
+	var score int64
+	for _, reactors := range s.reactions[id] {
+		score += int64(len(reactors))
+	}
+	return score
+}
+
+func (s CommentStorageInMemory) ImportComment(comment Comment, source string) (
+	Comment, error) {
+	key := fmt.Sprintf("%d:%s", comment.DomainID, source)
+	if _, ok := s.imports[key]; ok {
+		return Comment{}, errors.New("Comment already imported")
+	}
+	s.addComment(&comment)
+	s.imports[key] = comment.ID
+	return comment, nil
+}
+
+func (s CommentStorageInMemory) GetImportedComment(
+	d ClientDomain, source string) (Comment, error) {
+	id, ok := s.imports[fmt.Sprintf("%d:%s", d.ID, source)]
+	if !ok {
+		return Comment{}, nil
+	}
+	return s.byID[id], nil
+}
+
 func NewCommentStorageInMemory() CommentStorageInMemory {
 	c := CommentStorageInMemory{}
 	c.data = make(map[string][]Comment, 0)
 	c.clientComments = make(map[int64][]Comment)
 	c.domainComments = make(map[int64][]Comment)
 	c.pageComments = make(map[string][]Comment)
+	c.byID = make(map[int64]Comment)
+	c.revisions = make(map[int64][]CommentRevision)
+	c.spamTokens = make(map[string]SpamTokenCount)
+	c.spamTrained = make(map[int64]trainedComment)
+	c.requestInfo = make(map[int64]CommentRequestInfo)
+	c.reactions = make(map[int64]map[string]map[string]bool)
+	c.imports = make(map[string]int64)
 	c.BadCommenter = "bad"
 	c.BadPage = "http://bla.net/bad"
 	return c
 }
 
+type TrashStorageInMemory struct {
+	data      map[string]TrashItem
+	listError bool
+}
+
+func (s TrashStorageInMemory) key(item TrashItem) string {
+	return fmt.Sprintf("%s-%d", item.Type, item.ID)
+}
+
+// AddItem puts an item in the trash
+func (s TrashStorageInMemory) AddItem(item TrashItem) {
+	s.data[s.key(item)] = item
+}
+
+func (s TrashStorageInMemory) ListTrash() ([]TrashItem, error) {
+	if s.listError {
+		return nil, errors.New("bad")
+	}
+	items := make([]TrashItem, 0)
+	for _, item := range s.data {
+		items = append(items, item)
+	}
+	slices.SortFunc(items, func(a, b TrashItem) int {
+		return cmp.Compare(b.DeletedAt, a.DeletedAt)
+	})
+	return items, nil
+}
+
+func (s TrashStorageInMemory) RestoreItem(item TrashItem) error {
+	if _, ok := s.data[s.key(item)]; !ok {
+		return errors.New("item not in trash")
+	}
+	delete(s.data, s.key(item))
+	return nil
+}
+
+func (s TrashStorageInMemory) PurgeItem(item TrashItem) error {
+	return s.RestoreItem(item)
+}
+
+func (s TrashStorageInMemory) PurgeOlderThan(timestamp int64) (int64, error) {
+	var n int64
+	for k, item := range s.data {
+		if item.DeletedAt < timestamp {
+			delete(s.data, k)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *TrashStorageInMemory) ForceListError(f bool) {
+	s.listError = f
+}
+
+func NewTrashStorageInMemory() TrashStorageInMemory {
+	s := TrashStorageInMemory{}
+	s.data = make(map[string]TrashItem)
+	return s
+}
+
+type SubscriptionStorageInMemory struct {
+	data map[int64]Subscription
+}
+
+func (s SubscriptionStorageInMemory) Subscribe(
+	cd ClientDomain, pageURL string, email string) (Subscription, error) {
+	for _, sub := range s.data {
+		if sub.DomainID == cd.ID && sub.PageURL == pageURL &&
+			sub.Email == email {
+			return sub, nil
+		}
+	}
+	var id int64
+	for _, sub := range s.data {
+		id = max(id, sub.ID)
+	}
+	sub := Subscription{
+		ID:        id + 1,
+		DomainID:  cd.ID,
+		PageURL:   pageURL,
+		Email:     email,
+		Timestamp: time.Now().Unix(),
+	}
+	s.data[sub.ID] = sub
+	return sub, nil
+}
+
+func (s SubscriptionStorageInMemory) GetSubscription(id int64) (
+	Subscription, error) {
+	sub, ok := s.data[id]
+	if !ok {
+		return Subscription{}, errors.New("subscription not found")
+	}
+	return sub, nil
+}
+
+func (s SubscriptionStorageInMemory) ListSubscriptions(
+	cd ClientDomain, pageURL string) ([]Subscription, error) {
+	subs := make([]Subscription, 0)
+	for _, sub := range s.data {
+		if sub.DomainID == cd.ID && sub.PageURL == pageURL && sub.Confirmed {
+			subs = append(subs, sub)
+		}
+	}
+	slices.SortFunc(subs, func(a, b Subscription) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return subs, nil
+}
+
+func (s SubscriptionStorageInMemory) ConfirmSubscription(
+	sub Subscription) error {
+	stored, ok := s.data[sub.ID]
+	if !ok {
+		return errors.New("subscription not found")
+	}
+	stored.Confirmed = true
+	s.data[sub.ID] = stored
+	return nil
+}
+
+func (s SubscriptionStorageInMemory) MarkConfirmSent(
+	sub Subscription) (bool, error) {
+	stored, ok := s.data[sub.ID]
+	if !ok || stored.ConfirmSentAt != 0 {
+		return false, nil
+	}
+	stored.ConfirmSentAt = time.Now().Unix()
+	s.data[sub.ID] = stored
+	return true, nil
+}
+
+func (s SubscriptionStorageInMemory) Unsubscribe(sub Subscription) error {
+	delete(s.data, sub.ID)
+	return nil
+}
+
+func NewSubscriptionStorageInMemory() SubscriptionStorageInMemory {
+	s := SubscriptionStorageInMemory{}
+	s.data = make(map[int64]Subscription)
+	return s
+}
+
+type OutboxStorageInMemory struct {
+	data      map[int64]OutboxMessage
+	listError bool
+}
+
+func (s OutboxStorageInMemory) Enqueue(msg EmailMessage) (
+	OutboxMessage, error) {
+	var id int64
+	for _, m := range s.data {
+		id = max(id, m.ID)
+	}
+	m := OutboxMessage{
+		ID:        id + 1,
+		Message:   msg,
+		Status:    OutboxPending,
+		Timestamp: time.Now().Unix(),
+	}
+	m.NextAttempt = m.Timestamp
+	s.data[m.ID] = m
+	return m, nil
+}
+
+func (s OutboxStorageInMemory) ListDue(timestamp int64, limit int) (
+	[]OutboxMessage, error) {
+	if s.listError {
+		return nil, errors.New("bad")
+	}
+	msgs := make([]OutboxMessage, 0)
+	for _, m := range s.data {
+		if m.Status == OutboxPending && m.NextAttempt <= timestamp {
+			msgs = append(msgs, m)
+		}
+	}
+	slices.SortFunc(msgs, func(a, b OutboxMessage) int {
+		return cmp.Or(cmp.Compare(a.NextAttempt, b.NextAttempt),
+			cmp.Compare(a.ID, b.ID))
+	})
+	if len(msgs) > limit {
+		msgs = msgs[:limit]
+	}
+	return msgs, nil
+}
+
+func (s OutboxStorageInMemory) ListFailed() ([]OutboxMessage, error) {
+	if s.listError {
+		return nil, errors.New("bad")
+	}
+	msgs := make([]OutboxMessage, 0)
+	for _, m := range s.data {
+		if m.Status == OutboxFailed {
+			msgs = append(msgs, m)
+		}
+	}
+	slices.SortFunc(msgs, func(a, b OutboxMessage) int {
+		return cmp.Compare(b.ID, a.ID)
+	})
+	return msgs, nil
+}
+
+func (s OutboxStorageInMemory) UpdateMessage(msg OutboxMessage) error {
+	if _, ok := s.data[msg.ID]; !ok {
+		return errors.New("message not in outbox")
+	}
+	s.data[msg.ID] = msg
+	return nil
+}
+
+func (s OutboxStorageInMemory) RemoveMessage(msg OutboxMessage) error {
+	if _, ok := s.data[msg.ID]; !ok {
+		return errors.New("message not in outbox")
+	}
+	delete(s.data, msg.ID)
+	return nil
+}
+
+func (s OutboxStorageInMemory) RetryMessage(msg OutboxMessage) error {
+	m, ok := s.data[msg.ID]
+	if !ok {
+		return errors.New("message not in outbox")
+	}
+	m.Status = OutboxPending
+	m.Attempts = 0
+	m.NextAttempt = time.Now().Unix()
+	s.data[m.ID] = m
+	return nil
+}
+
+func (s *OutboxStorageInMemory) ForceListError(f bool) {
+	s.listError = f
+}
+
+func NewOutboxStorageInMemory() OutboxStorageInMemory {
+	s := OutboxStorageInMemory{}
+	s.data = make(map[int64]OutboxMessage)
+	return s
+}
+
+type DigestStorageInMemory struct {
+	data      map[int64]DigestItem
+	listError bool
+}
+
+func (s DigestStorageInMemory) AddDigestItem(item DigestItem) (
+	DigestItem, error) {
+	for _, i := range s.data {
+		item.ID = max(item.ID, i.ID)
+	}
+	item.ID++
+	s.data[item.ID] = item
+	return item, nil
+}
+
+func (s DigestStorageInMemory) ListDigestItems(
+	mode NotificationMode, before int64) ([]DigestItem, error) {
+	if s.listError {
+		return nil, errors.New("bad")
+	}
+	items := make([]DigestItem, 0)
+	for _, item := range s.data {
+		if item.Mode == mode && item.Timestamp < before {
+			items = append(items, item)
+		}
+	}
+	slices.SortFunc(items, func(a, b DigestItem) int {
+		return cmp.Or(
+			cmp.Compare(a.Recipient, b.Recipient),
+			cmp.Compare(a.Domain, b.Domain),
+			cmp.Compare(a.PageURL, b.PageURL),
+			cmp.Compare(a.Timestamp, b.Timestamp),
+			cmp.Compare(a.ID, b.ID))
+	})
+	return items, nil
+}
+
+func (s DigestStorageInMemory) RemoveDigestItems(items []DigestItem) error {
+	for _, item := range items {
+		delete(s.data, item.ID)
+	}
+	return nil
+}
+
+func (s *DigestStorageInMemory) ForceListError(f bool) {
+	s.listError = f
+}
+
+func NewDigestStorageInMemory() DigestStorageInMemory {
+	s := DigestStorageInMemory{}
+	s.data = make(map[int64]DigestItem)
+	return s
+}
+
 // test mail sender
 
 type TestMailSender struct {
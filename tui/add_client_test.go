@@ -29,7 +29,7 @@ import (
 func TestAddClientScreen(t *testing.T) {
 
 	cs := parlante.NewClientStorageInMemory()
-	main := newMainScreen(cs, nil, nil)
+	main := newMainScreen(cs, nil, nil, nil, nil)
 
 	var tests = []struct {
 		testName string
@@ -152,7 +152,7 @@ func TestClientAddedScreenInfo(t *testing.T) {
 
 	cs := parlante.NewClientStorageInMemory()
 	client, key, _ := cs.CreateClient("some client")
-	main := newMainScreen(cs, nil, nil)
+	main := newMainScreen(cs, nil, nil, nil, nil)
 
 	var tests = []struct {
 		testName string
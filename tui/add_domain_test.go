@@ -101,7 +101,7 @@ func TestAddDomainScreen(t *testing.T) {
 
 	c := parlante.NewClientStorageInMemory()
 	ds := parlante.NewClientDomainStorageInMemory()
-	main := newMainScreen(&c, &ds, nil)
+	main := newMainScreen(&c, &ds, nil, nil, nil)
 
 	c1, _, _ := c.CreateClient("a client")
 	c2, _, _ := c.CreateClient("another client")
@@ -0,0 +1,143 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package tui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jucacrispim/parlante"
+)
+
+type clientAkismetMsg struct {
+	err error
+}
+
+// clientAkismetScreen changes the akismet service used to check the
+// comments of a client. An empty key disables the service.
+type clientAkismetScreen struct {
+	mainScreen    mainScreen
+	ClientStorage parlante.ClientStorage
+	Client        parlante.Client
+	inputs        []textinput.Model
+	focused       int
+	keys          ConfirmCancelKeyMap
+	help          help.Model
+	err           error
+}
+
+func (m clientAkismetScreen) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (m clientAkismetScreen) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	m.mainScreen.header.Update(msg)
+	switch msg := msg.(type) {
+	case clientAkismetMsg:
+		m.err = msg.err
+		if m.err != nil {
+			return m, nil
+		}
+		model := newClientListScreen(m.mainScreen)
+		return model, model.Init()
+
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, m.keys.Confirm):
+			if m.focused < len(m.inputs)-1 {
+				m.inputs[m.focused].Blur()
+				m.focused++
+				m.inputs[m.focused].Focus()
+				return m, textinput.Blink
+			}
+			return m, m.save()
+
+		case key.Matches(msg, m.keys.Cancel):
+			model := newClientListScreen(m.mainScreen)
+			return model, model.Init()
+		}
+	}
+	m.inputs[m.focused], cmd = m.inputs[m.focused].Update(msg)
+	return m, cmd
+}
+
+func (m clientAkismetScreen) View() string {
+	s := m.mainScreen.header.View()
+	data := make(map[string]any)
+	data["clientName"] = highlightTitleStyle.Render(m.Client.Name)
+	title := "  " + titleStyle.Render(
+		parlante.Tprintf(MESSAGE_CLIENT_AKISMET, data))
+	s += title + "\n\n"
+	if m.err != nil {
+		s += defaultTextStyle.Render(m.err.Error()) + "\n\n"
+	}
+	for _, input := range m.inputs {
+		s += input.View() + "\n"
+	}
+	lines := strings.Split(s, "\n")
+	rest := m.mainScreen.list.Height() - len(lines) + 2
+
+	helpView := m.help.View(m.keys)
+	s += strings.Repeat("\n", rest) + helpViewStyle.Render(helpView)
+	return s
+}
+
+func (m clientAkismetScreen) save() tea.Cmd {
+	endpoint := strings.TrimSpace(m.inputs[0].Value())
+	apiKey := strings.TrimSpace(m.inputs[1].Value())
+	switch {
+	case apiKey == "":
+		endpoint = ""
+	case endpoint == "":
+		endpoint = parlante.DEFAULT_AKISMET_ENDPOINT
+	}
+	return func() tea.Msg {
+		err := m.ClientStorage.SetClientAkismet(m.Client, endpoint, apiKey)
+		return clientAkismetMsg{err: err}
+	}
+}
+
+func newClientAkismetScreen(
+	mainScreen mainScreen, client parlante.Client) clientAkismetScreen {
+	endpoint := textinput.New()
+	endpoint.Placeholder = parlante.DEFAULT_AKISMET_ENDPOINT
+	endpoint.SetValue(client.AkismetEndpoint)
+	apiKey := textinput.New()
+	apiKey.Placeholder = MESSAGE_AKISMET_KEY
+	apiKey.SetValue(client.AkismetKey)
+	inputs := []textinput.Model{endpoint, apiKey}
+	for i := range inputs {
+		inputs[i].Width = 40
+		inputs[i].TextStyle = defaultTextStyle
+		inputs[i].PromptStyle = defaultTextStyle
+	}
+	inputs[0].Focus()
+	m := clientAkismetScreen{
+		mainScreen:    mainScreen,
+		ClientStorage: mainScreen.clientStorage,
+		Client:        client,
+		inputs:        inputs,
+		keys:          NewConfirmCancelKeyMap(),
+		help:          createHelp(),
+	}
+	return m
+}
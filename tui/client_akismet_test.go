@@ -0,0 +1,155 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package tui
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jucacrispim/parlante"
+)
+
+func TestClientAkismetScreen(t *testing.T) {
+
+	c := parlante.NewClientStorageInMemory()
+	cd := parlante.NewClientDomainStorageInMemory()
+	comm := parlante.NewCommentStorageInMemory()
+	main := newMainScreen(&c, &cd, &comm, nil, nil)
+
+	c1, _, _ := c.CreateClient("a client")
+
+	var tests = []struct {
+		testName string
+		screenFn func() clientAkismetScreen
+		msgFn    func(clientAkismetScreen) tea.Msg
+		checkFn  func(tea.Model, tea.Cmd)
+	}{
+		{
+			"test view",
+			func() clientAkismetScreen {
+				return newClientAkismetScreen(main, c1)
+			},
+			func(m clientAkismetScreen) tea.Msg {
+				return nil
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				view := m.View()
+				if !strings.Contains(view, c1.Name) ||
+					!strings.Contains(view, parlante.DEFAULT_AKISMET_ENDPOINT) {
+					t.Fatalf("bad view %s", view)
+				}
+			},
+		},
+		{
+			"test enter goes to next field",
+			func() clientAkismetScreen {
+				return newClientAkismetScreen(main, c1)
+			},
+			func(m clientAkismetScreen) tea.Msg {
+				return tea.KeyMsg{Type: tea.KeyEnter}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				nm := m.(clientAkismetScreen)
+				if nm.focused != 1 || !nm.inputs[1].Focused() ||
+					nm.inputs[0].Focused() {
+					t.Fatalf("bad focus %d", nm.focused)
+				}
+			},
+		},
+		{
+			"test save with default endpoint",
+			func() clientAkismetScreen {
+				s := newClientAkismetScreen(main, c1)
+				s.inputs[1].SetValue("the-key")
+				return s
+			},
+			func(m clientAkismetScreen) tea.Msg {
+				return m.save()()
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				_, ok := m.(AddRemoveItemScreen)
+				if !ok {
+					t.Fatalf("bad model after save %T", m)
+				}
+				client, _ := c.GetClientByUUID(c1.UUID)
+				if client.AkismetEndpoint != parlante.DEFAULT_AKISMET_ENDPOINT ||
+					client.AkismetKey != "the-key" {
+					t.Fatalf("bad akismet config %v", client)
+				}
+			},
+		},
+		{
+			"test save without key disables akismet",
+			func() clientAkismetScreen {
+				s := newClientAkismetScreen(main, c1)
+				s.inputs[0].SetValue("http://akismet.bla")
+				return s
+			},
+			func(m clientAkismetScreen) tea.Msg {
+				return m.save()()
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				client, _ := c.GetClientByUUID(c1.UUID)
+				if client.UsesAkismet() || client.AkismetEndpoint != "" {
+					t.Fatalf("akismet not disabled %v", client)
+				}
+			},
+		},
+		{
+			"test save with error",
+			func() clientAkismetScreen {
+				return newClientAkismetScreen(main, c1)
+			},
+			func(m clientAkismetScreen) tea.Msg {
+				return clientAkismetMsg{err: errors.New("bad save")}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				nm, ok := m.(clientAkismetScreen)
+				if !ok || !strings.Contains(nm.View(), "bad save") {
+					t.Fatalf("error not shown")
+				}
+			},
+		},
+		{
+			"test cancel",
+			func() clientAkismetScreen {
+				return newClientAkismetScreen(main, c1)
+			},
+			func(m clientAkismetScreen) tea.Msg {
+				return tea.KeyMsg{Type: tea.KeyEsc}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				_, ok := m.(AddRemoveItemScreen)
+				if !ok {
+					t.Fatalf("bad model for cancel")
+				}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			screen := test.screenFn()
+			msg := test.msgFn(screen)
+			m, cmd := screen.Update(msg)
+			test.checkFn(m, cmd)
+		})
+	}
+}
@@ -20,6 +20,7 @@ package tui
 import (
 	"fmt"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/jucacrispim/parlante"
@@ -31,7 +32,11 @@ type clientItem struct {
 
 func (i clientItem) Title() string { return i.client.Name }
 func (i clientItem) Description() string {
-	return fmt.Sprintf("uuid: %s", i.client.UUID)
+	descr := fmt.Sprintf("uuid: %s", i.client.UUID)
+	if i.client.UsesAkismet() {
+		descr += " | akismet"
+	}
+	return descr
 }
 func (i clientItem) FilterValue() string { return i.client.Name }
 
@@ -100,5 +105,40 @@ func newClientListScreen(mainScreen mainScreen) AddRemoveItemScreen {
 		ShowHelp:        true,
 	}
 	s := NewAddRemoveItemScreen(&h, opts, nav, l.Load)
+	s.ScreenActions = []ScreenAction{
+		{
+			Key: key.NewBinding(
+				key.WithKeys("A"),
+				key.WithHelp("A", MESSAGE_KEY_HELP_AKISMET),
+			),
+			Screen: func(item list.Item) tea.Model {
+				i := item.(clientItem)
+				return newClientAkismetScreen(mainScreen, i.client)
+			},
+			ItemRequired: true,
+		},
+		{
+			Key: key.NewBinding(
+				key.WithKeys("t"),
+				key.WithHelp("t", MESSAGE_KEY_HELP_RATE_LIMITS),
+			),
+			Screen: func(item list.Item) tea.Model {
+				i := item.(clientItem)
+				return newClientRateLimitsScreen(mainScreen, i.client)
+			},
+			ItemRequired: true,
+		},
+		{
+			Key: key.NewBinding(
+				key.WithKeys("n"),
+				key.WithHelp("n", MESSAGE_KEY_HELP_NOTIFICATIONS),
+			),
+			Screen: func(item list.Item) tea.Model {
+				i := item.(clientItem)
+				return newClientNotificationsScreen(mainScreen, i.client)
+			},
+			ItemRequired: true,
+		},
+	}
 	return s
 }
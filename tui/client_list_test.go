@@ -46,6 +46,13 @@ func TestClientItem(t *testing.T) {
 	if item.FilterValue() != client.Name {
 		t.Fatalf("Bad filter value for item %s", item.FilterValue())
 	}
+
+	client.AkismetEndpoint = "http://akismet.bla"
+	client.AkismetKey = "key"
+	item = clientItem{client: client}
+	if !strings.HasSuffix(item.Description(), "| akismet") {
+		t.Fatalf("Bad description for item with akismet %s", item.Description())
+	}
 }
 
 func TestClientListScreen(t *testing.T) {
@@ -53,7 +60,7 @@ func TestClientListScreen(t *testing.T) {
 	c := parlante.NewClientStorageInMemory()
 	cd := parlante.NewClientDomainStorageInMemory()
 	comm := parlante.NewCommentStorageInMemory()
-	main := newMainScreen(&c, &cd, &comm)
+	main := newMainScreen(&c, &cd, &comm, nil, nil)
 
 	c1, _, _ := c.CreateClient("a client")
 	c2, _, _ := c.CreateClient("another client")
@@ -126,6 +133,86 @@ func TestClientListScreen(t *testing.T) {
 
 			},
 		},
+		{
+			"test GetAkismetScreen",
+			func() AddRemoveItemScreen {
+				s := newClientListScreen(main)
+				items := s.Init()()
+				i := items.(ItemListMsg)
+				s.List.SetItems(i.Items)
+				return s
+			},
+			func(m AddRemoveItemScreen) tea.Msg {
+				return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'A'}}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				_, ok := m.(clientAkismetScreen)
+				if !ok {
+					t.Fatalf("bad model for akismet screen")
+				}
+			},
+		},
+		{
+			"test k moves the cursor up",
+			func() AddRemoveItemScreen {
+				s := newClientListScreen(main)
+				items := s.Init()()
+				i := items.(ItemListMsg)
+				s.List.SetItems(i.Items)
+				s.List.CursorDown()
+				return s
+			},
+			func(m AddRemoveItemScreen) tea.Msg {
+				return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'k'}}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				s, ok := m.(AddRemoveItemScreen)
+				if !ok {
+					t.Fatalf("bad model for cursor up")
+				}
+				if s.List.Index() != 0 {
+					t.Fatalf("bad index for cursor up %d", s.List.Index())
+				}
+			},
+		},
+		{
+			"test GetRateLimitsScreen",
+			func() AddRemoveItemScreen {
+				s := newClientListScreen(main)
+				items := s.Init()()
+				i := items.(ItemListMsg)
+				s.List.SetItems(i.Items)
+				return s
+			},
+			func(m AddRemoveItemScreen) tea.Msg {
+				return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'t'}}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				_, ok := m.(rateLimitsScreen)
+				if !ok {
+					t.Fatalf("bad model for rate limits screen")
+				}
+			},
+		},
+		{
+			"test GetNotificationsScreen",
+			func() AddRemoveItemScreen {
+				s := newClientListScreen(main)
+				items := s.Init()()
+				i := items.(ItemListMsg)
+				s.List.SetItems(i.Items)
+				return s
+			},
+			func(m AddRemoveItemScreen) tea.Msg {
+				return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'n'}}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				_, ok := m.(notificationsScreen)
+				if !ok {
+					t.Fatalf("bad model for notifications screen")
+				}
+			},
+		},
 		{
 			"test GetRemoveScreen",
 			func() AddRemoveItemScreen {
@@ -18,8 +18,7 @@
 package tui
 
 import (
-	"fmt"
-
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/jucacrispim/parlante"
@@ -29,9 +28,21 @@ type CommentItem struct {
 	Comment parlante.Comment
 }
 
-func (i CommentItem) Title() string { return i.Comment.Author }
+func (i CommentItem) Title() string {
+	if i.Comment.Email == "" {
+		return i.Comment.Author
+	}
+	return i.Comment.Author + " <" + i.Comment.Email + ">"
+}
 func (i CommentItem) Description() string {
-	return fmt.Sprintf("url: %s", i.Comment.PageURL)
+	data := make(map[string]any)
+	data["url"] = i.Comment.PageURL
+	data["status"] = MESSAGE_COMMENT_STATUS[i.Comment.Status]
+	descr := parlante.Tprintf(MESSAGE_COMMENT_DESCRIPTION, data)
+	if i.Comment.Edited() {
+		descr += " | " + MESSAGE_COMMENT_EDITED
+	}
+	return descr
 }
 func (i CommentItem) FilterValue() string { return i.Comment.PageURL }
 
@@ -56,13 +67,23 @@ func (n CommentListNavigation) GetPreviousScreen() tea.Model {
 	return *n.MainScreen
 }
 
+// CommentLoader loads the comments for the list. If Query is not
+// empty only the comments matching the query are loaded.
 type CommentLoader struct {
 	Storage parlante.CommentStorage
+	Query   string
 }
 
 func (l CommentLoader) Load() tea.Cmd {
 	return func() tea.Msg {
-		Comments, err := l.Storage.ListComments(parlante.CommentsFilter{})
+		var Comments []parlante.Comment
+		var err error
+		if l.Query != "" {
+			Comments, err = l.Storage.SearchComments(
+				l.Query, parlante.CommentsFilter{})
+		} else {
+			Comments, err = l.Storage.ListComments(parlante.CommentsFilter{})
+		}
 
 		if err != nil {
 			msg := ItemListMsg{
@@ -86,21 +107,194 @@ func (l CommentLoader) Load() tea.Cmd {
 	}
 }
 
+// CommentModerator changes the moderation status of the
+// selected comment. Approved comments are used to train the spam
+// model as ham and spam comments as spam. The mistakes of the spam
+// service of the client are reported to Reporter.
+type CommentModerator struct {
+	Storage       parlante.CommentStorage
+	ClientStorage parlante.ClientStorage
+	Reporter      parlante.SpamReporter
+	Status        parlante.CommentStatus
+}
+
+func (m CommentModerator) Run(item list.Item) tea.Cmd {
+	return func() tea.Msg {
+		i := item.(CommentItem)
+		err := m.Storage.SetCommentStatus(i.Comment, m.Status)
+		if err != nil {
+			return ItemActionDoneMsg{Err: err}
+		}
+		switch m.Status {
+		case parlante.CommentApproved, parlante.CommentSpam:
+			spam := m.Status == parlante.CommentSpam
+			err = m.Storage.TrainSpamModel(i.Comment, spam)
+			if err != nil {
+				return ItemActionDoneMsg{Err: err}
+			}
+		}
+		err = m.report(i.Comment)
+		return ItemActionDoneMsg{Err: err}
+	}
+}
+
+// report tells the spam service about comments marked as spam by the
+// operator and spam comments approved by the operator.
+func (m CommentModerator) report(comment parlante.Comment) error {
+	if m.Reporter == nil {
+		return nil
+	}
+	var reportFn func(parlante.Comment) error
+	switch {
+	case m.Status == parlante.CommentSpam &&
+		comment.Status != parlante.CommentSpam:
+		reportFn = m.Reporter.ReportSpam
+	case m.Status == parlante.CommentApproved &&
+		comment.Status == parlante.CommentSpam:
+		reportFn = m.Reporter.ReportHam
+	default:
+		return nil
+	}
+	client, err := m.ClientStorage.GetClientByID(comment.ClientID)
+	if err != nil {
+		return err
+	}
+	if !client.UsesAkismet() {
+		return nil
+	}
+	info, err := m.Storage.GetCommentRequestInfo(comment)
+	if err != nil {
+		return err
+	}
+	comment.Client = &client
+	comment.RequestInfo = &info
+	return reportFn(comment)
+}
+
+func newCommentModerationActions(
+	storage parlante.CommentStorage,
+	clientStorage parlante.ClientStorage,
+	reporter parlante.SpamReporter) []ItemAction {
+	moderator := func(status parlante.CommentStatus) CommentModerator {
+		return CommentModerator{
+			Storage:       storage,
+			ClientStorage: clientStorage,
+			Reporter:      reporter,
+			Status:        status,
+		}
+	}
+	approve := moderator(parlante.CommentApproved)
+	reject := moderator(parlante.CommentRejected)
+	spam := moderator(parlante.CommentSpam)
+	return []ItemAction{
+		{
+			Key: key.NewBinding(
+				key.WithKeys("o"),
+				key.WithHelp("o", MESSAGE_KEY_HELP_APPROVE),
+			),
+			Run: approve.Run,
+		},
+		{
+			Key: key.NewBinding(
+				key.WithKeys("r"),
+				key.WithHelp("r", MESSAGE_KEY_HELP_REJECT),
+			),
+			Run: reject.Run,
+		},
+		{
+			Key: key.NewBinding(
+				key.WithKeys("s"),
+				key.WithHelp("s", MESSAGE_KEY_HELP_SPAM),
+			),
+			Run: spam.Run,
+		},
+	}
+}
+
 func newCommentListScreen(mainScreen *mainScreen) AddRemoveItemScreen {
+	return newCommentSearchResultScreen(mainScreen, "")
+}
+
+// newCommentSearchResultScreen returns a comment list screen with the
+// comments matching query. An empty query lists all comments.
+func newCommentSearchResultScreen(
+	mainScreen *mainScreen, query string) AddRemoveItemScreen {
 
 	nav := CommentListNavigation{
 		MainScreen: mainScreen,
 	}
 	l := CommentLoader{
 		Storage: mainScreen.CommentStorage,
+		Query:   query,
 	}
 	h := mainScreen.header
+	title := MESSAGE_COMMENTS
+	if query != "" {
+		data := make(map[string]any)
+		data["query"] = query
+		title = parlante.Tprintf(MESSAGE_SEARCH_RESULTS_FOR, data)
+	}
 	opts := ListOpts{
-		Title:           MESSAGE_COMMENTS,
+		Title:           title,
 		ShowDescription: true,
 		ShowStatusBar:   true,
 		ShowHelp:        true,
 	}
 	s := NewAddRemoveItemScreen(&h, opts, nav, l.Load)
+	s.Actions = newCommentModerationActions(mainScreen.CommentStorage,
+		mainScreen.clientStorage, mainScreen.spamReporter)
+	s.ScreenActions = []ScreenAction{
+		{
+			Key: key.NewBinding(
+				key.WithKeys("S"),
+				key.WithHelp("S", MESSAGE_KEY_HELP_SEARCH),
+			),
+			Screen: func(list.Item) tea.Model {
+				return newSearchCommentsScreen(*mainScreen)
+			},
+		},
+		{
+			Key: key.NewBinding(
+				key.WithKeys("e"),
+				key.WithHelp("e", MESSAGE_KEY_HELP_EDIT),
+			),
+			Screen: func(item list.Item) tea.Model {
+				i := item.(CommentItem)
+				return newEditCommentScreen(*mainScreen, i.Comment)
+			},
+			ItemRequired: true,
+		},
+		{
+			Key: key.NewBinding(
+				key.WithKeys("v"),
+				key.WithHelp("v", MESSAGE_KEY_HELP_REVISIONS),
+			),
+			Screen: func(item list.Item) tea.Model {
+				i := item.(CommentItem)
+				return newCommentRevisionsScreen(*mainScreen, i.Comment)
+			},
+			ItemRequired: true,
+		},
+		{
+			Key: key.NewBinding(
+				key.WithKeys("x"),
+				key.WithHelp("x", MESSAGE_KEY_HELP_EXPLAIN_SPAM),
+			),
+			Screen: func(item list.Item) tea.Model {
+				i := item.(CommentItem)
+				return newSpamExplanationScreen(*mainScreen, i.Comment)
+			},
+			ItemRequired: true,
+		},
+		{
+			Key: key.NewBinding(
+				key.WithKeys("T"),
+				key.WithHelp("T", MESSAGE_KEY_HELP_RETRAIN_SPAM),
+			),
+			Screen: func(list.Item) tea.Model {
+				return newRetrainSpamModelScreen(*mainScreen)
+			},
+		},
+	}
 	return s
 }
@@ -18,7 +18,7 @@
 package tui
 
 import (
-	"fmt"
+	"errors"
 	"strings"
 	"testing"
 
@@ -44,13 +44,22 @@ func TestCommentItem(t *testing.T) {
 		t.Fatalf("Bad title for item %s", item.Title())
 	}
 
-	if item.Description() != fmt.Sprintf("url: %s", comment.PageURL) {
+	data := make(map[string]any)
+	data["url"] = comment.PageURL
+	data["status"] = MESSAGE_COMMENT_STATUS[comment.Status]
+	if item.Description() != parlante.Tprintf(MESSAGE_COMMENT_DESCRIPTION, data) {
 		t.Fatalf("Bad description for item %s", item.Description())
 	}
 
 	if item.FilterValue() != comment.PageURL {
 		t.Fatalf("Bad filter value for item %s", item.FilterValue())
 	}
+
+	comment.Email = "ze@bla.net"
+	item = CommentItem{Comment: comment}
+	if item.Title() != "zé <ze@bla.net>" {
+		t.Fatalf("Bad title for item with email %s", item.Title())
+	}
 }
 
 func TestCommentListScreen(t *testing.T) {
@@ -58,7 +67,7 @@ func TestCommentListScreen(t *testing.T) {
 	c := parlante.NewClientStorageInMemory()
 	cd := parlante.NewClientDomainStorageInMemory()
 	comm := parlante.NewCommentStorageInMemory()
-	main := newMainScreen(&c, &cd, &comm)
+	main := newMainScreen(&c, &cd, &comm, nil, nil)
 
 	c1, _, _ := c.CreateClient("a client")
 	d1, _ := cd.AddClientDomain(c1, "domain.net")
@@ -168,6 +177,178 @@ func TestCommentListScreen(t *testing.T) {
 
 			},
 		},
+		{
+			"test mark comment as spam",
+			func() AddRemoveItemScreen {
+				s := newCommentListScreen(&main)
+				items := s.Init()()
+				i := items.(ItemListMsg)
+				s.List.SetItems(i.Items)
+				return s
+			},
+			func(m AddRemoveItemScreen) tea.Msg {
+				return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'s'}}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				msg, ok := cmd().(ItemActionDoneMsg)
+				if !ok || msg.Err != nil {
+					t.Fatalf("bad msg for spam action %v", msg)
+				}
+				c, _ := comm.GetCommentByID(comm1.ID)
+				if c.Status != parlante.CommentSpam {
+					t.Fatalf("bad status after spam action %s", c.Status)
+				}
+				model, _ := comm.GetSpamModel(nil)
+				if model.SpamComments != 1 {
+					t.Fatalf("spam model not trained %d", model.SpamComments)
+				}
+			},
+		},
+		{
+			"test invalid moderation",
+			func() AddRemoveItemScreen {
+				s := newCommentListScreen(&main)
+				items := s.Init()()
+				i := items.(ItemListMsg)
+				s.List.SetItems(i.Items)
+				s.List.CursorDown()
+				return s
+			},
+			func(m AddRemoveItemScreen) tea.Msg {
+				return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'o'}}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				msg := cmd()
+				nm, _ := m.Update(msg)
+				if nm.(AddRemoveItemScreen).err == nil {
+					t.Fatalf("no error approving approved comment")
+				}
+			},
+		},
+		{
+			"test GetSearchScreen",
+			func() AddRemoveItemScreen {
+				return newCommentListScreen(&main)
+			},
+			func(m AddRemoveItemScreen) tea.Msg {
+				return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'S'}}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				_, ok := m.(searchCommentsScreen)
+				if !ok {
+					t.Fatalf("bad model for search screen")
+				}
+			},
+		},
+		{
+			"test GetEditScreen",
+			func() AddRemoveItemScreen {
+				s := newCommentListScreen(&main)
+				items := s.Init()()
+				i := items.(ItemListMsg)
+				s.List.SetItems(i.Items)
+				return s
+			},
+			func(m AddRemoveItemScreen) tea.Msg {
+				return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'e'}}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				nm, ok := m.(editCommentScreen)
+				if !ok {
+					t.Fatalf("bad model for edit screen")
+				}
+				if nm.Comment.ID != comm1.ID {
+					t.Fatalf("bad comment for edit screen")
+				}
+			},
+		},
+		{
+			"test GetRevisionsScreen",
+			func() AddRemoveItemScreen {
+				s := newCommentListScreen(&main)
+				items := s.Init()()
+				i := items.(ItemListMsg)
+				s.List.SetItems(i.Items)
+				return s
+			},
+			func(m AddRemoveItemScreen) tea.Msg {
+				return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'v'}}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				_, ok := m.(commentRevisionsScreen)
+				if !ok {
+					t.Fatalf("bad model for revisions screen")
+				}
+			},
+		},
+		{
+			"test GetSpamExplanationScreen",
+			func() AddRemoveItemScreen {
+				s := newCommentListScreen(&main)
+				items := s.Init()()
+				i := items.(ItemListMsg)
+				s.List.SetItems(i.Items)
+				return s
+			},
+			func(m AddRemoveItemScreen) tea.Msg {
+				return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'x'}}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				_, ok := m.(spamExplanationScreen)
+				if !ok {
+					t.Fatalf("bad model for spam explanation screen")
+				}
+			},
+		},
+		{
+			"test GetRetrainSpamModelScreen",
+			func() AddRemoveItemScreen {
+				return newCommentListScreen(&main)
+			},
+			func(m AddRemoveItemScreen) tea.Msg {
+				return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'T'}}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				_, ok := m.(retrainSpamModelScreen)
+				if !ok {
+					t.Fatalf("bad model for retrain spam model screen")
+				}
+			},
+		},
+		{
+			"test edit screen without items",
+			func() AddRemoveItemScreen {
+				return newCommentListScreen(&main)
+			},
+			func(m AddRemoveItemScreen) tea.Msg {
+				return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'e'}}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				_, ok := m.(AddRemoveItemScreen)
+				if !ok {
+					t.Fatalf("bad model for edit without items")
+				}
+			},
+		},
+		{
+			"test load search results",
+			func() AddRemoveItemScreen {
+				return newCommentSearchResultScreen(&main, "other")
+			},
+			func(m AddRemoveItemScreen) tea.Msg {
+				return m.Init()()
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				nm := m.(AddRemoveItemScreen)
+				items := nm.List.Items()
+				if len(items) != 1 {
+					t.Fatalf("bad len for search results %d", len(items))
+				}
+				if items[0].(CommentItem).Comment.ID != comm2.ID {
+					t.Fatalf("bad search result")
+				}
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -179,3 +360,87 @@ func TestCommentListScreen(t *testing.T) {
 		})
 	}
 }
+
+type testSpamReporter struct {
+	spam []parlante.Comment
+	ham  []parlante.Comment
+	err  error
+}
+
+func (r *testSpamReporter) ReportSpam(comment parlante.Comment) error {
+	r.spam = append(r.spam, comment)
+	return r.err
+}
+
+func (r *testSpamReporter) ReportHam(comment parlante.Comment) error {
+	r.ham = append(r.ham, comment)
+	return r.err
+}
+
+func TestCommentModerator(t *testing.T) {
+	var tests = []struct {
+		testName    string
+		akismet     bool
+		status      parlante.CommentStatus
+		newStatus   parlante.CommentStatus
+		reporterErr error
+		spam        int
+		ham         int
+		err         bool
+	}{
+		{"report spam", true, parlante.CommentApproved,
+			parlante.CommentSpam, nil, 1, 0, false},
+		{"report ham", true, parlante.CommentSpam,
+			parlante.CommentApproved, nil, 0, 1, false},
+		{"approve pending", true, parlante.CommentPending,
+			parlante.CommentApproved, nil, 0, 0, false},
+		{"reject", true, parlante.CommentApproved,
+			parlante.CommentRejected, nil, 0, 0, false},
+		{"client without akismet", false, parlante.CommentApproved,
+			parlante.CommentSpam, nil, 0, 0, false},
+		{"reporter error", true, parlante.CommentApproved,
+			parlante.CommentSpam, errors.New("bad"), 1, 0, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			cs := parlante.NewClientStorageInMemory()
+			cd := parlante.NewClientDomainStorageInMemory()
+			comm := parlante.NewCommentStorageInMemory()
+			c1, _, _ := cs.CreateClient("a client")
+			if test.akismet {
+				cs.SetClientAkismet(c1, "http://akismet.bla", "key")
+			}
+			d1, _ := cd.AddClientDomain(c1, "domain.net")
+			d1.Moderate = test.status == parlante.CommentPending
+			comment, _ := comm.CreateComment(c1, d1, "zé", "the comment", "http://bla.net")
+			if test.status != comment.Status {
+				comm.SetCommentStatus(comment, test.status)
+				comment, _ = comm.GetCommentByID(comment.ID)
+			}
+			info := parlante.CommentRequestInfo{UserIP: "1.2.3.4"}
+			comm.SetCommentRequestInfo(comment, info)
+			reporter := &testSpamReporter{err: test.reporterErr}
+			moderator := CommentModerator{
+				Storage:       &comm,
+				ClientStorage: &cs,
+				Reporter:      reporter,
+				Status:        test.newStatus,
+			}
+
+			msg := moderator.Run(CommentItem{Comment: comment})().(ItemActionDoneMsg)
+			if (msg.Err != nil) != test.err {
+				t.Fatalf("bad error %s", msg.Err)
+			}
+			if len(reporter.spam) != test.spam || len(reporter.ham) != test.ham {
+				t.Fatalf("bad reports %d %d", len(reporter.spam), len(reporter.ham))
+			}
+			for _, r := range append(reporter.spam, reporter.ham...) {
+				if r.Client == nil || r.RequestInfo == nil ||
+					r.RequestInfo.UserIP != info.UserIP {
+					t.Fatalf("bad reported comment %v", r)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package tui
+
+import (
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jucacrispim/parlante"
+)
+
+type commentRevisionsMsg struct {
+	revisions []parlante.CommentRevision
+	err       error
+}
+
+// commentRevisionsScreen shows the current content of a comment and
+// all its previous versions.
+type commentRevisionsScreen struct {
+	mainScreen     mainScreen
+	CommentStorage parlante.CommentStorage
+	Comment        parlante.Comment
+	revisions      []parlante.CommentRevision
+	keys           ConfirmCancelKeyMap
+	err            error
+}
+
+func (m commentRevisionsScreen) Init() tea.Cmd {
+	return m.loadRevisions()
+}
+
+func (m commentRevisionsScreen) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	m.mainScreen.header.Update(msg)
+	switch msg := msg.(type) {
+	case commentRevisionsMsg:
+		m.err = msg.err
+		m.revisions = msg.revisions
+		return m, nil
+
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, m.keys.Confirm, m.keys.Cancel):
+			model := newCommentListScreen(&m.mainScreen)
+			return model, model.Init()
+		}
+	}
+	return m, nil
+}
+
+func (m commentRevisionsScreen) View() string {
+	s := m.mainScreen.header.View()
+	data := make(map[string]any)
+	data["name"] = m.Comment.Author
+	title := "  " + titleStyle.Render(
+		parlante.Tprintf(MESSAGE_COMMENT_REVISIONS, data))
+	s += title + "\n\n"
+
+	var content string
+	switch {
+	case m.err != nil:
+		content = m.err.Error()
+	case len(m.revisions) == 0:
+		content = MESSAGE_NO_COMMENT_REVISIONS
+	default:
+		content = m.revisionsView()
+	}
+	s += defaultTextStyle.Render(content) + "\n\n"
+
+	lines := strings.Split(s, "\n")
+	rest := m.mainScreen.list.Height() - len(lines) + 2
+
+	helpView := MESAGE_ENTER_TO_CONTINUE
+	s += strings.Repeat("\n", rest) + helpViewStyle.Render(helpView)
+	return s
+}
+
+func (m commentRevisionsScreen) revisionsView() string {
+	parts := []string{
+		MESSAGE_CURRENT_COMMENT_REVISION + "\n" + m.Comment.Content,
+	}
+	for _, rev := range m.revisions {
+		data := make(map[string]any)
+		data["date"] = fmtTimestamp(rev.Timestamp)
+		data["editor"] = rev.Editor
+		info := parlante.Tprintf(MESSAGE_COMMENT_REVISION_INFO, data)
+		parts = append(parts, info+"\n"+rev.Content)
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+func (m commentRevisionsScreen) loadRevisions() tea.Cmd {
+	return func() tea.Msg {
+		revs, err := m.CommentStorage.ListCommentRevisions(m.Comment)
+		return commentRevisionsMsg{revisions: revs, err: err}
+	}
+}
+
+func newCommentRevisionsScreen(
+	mainScreen mainScreen,
+	comment parlante.Comment) commentRevisionsScreen {
+	m := commentRevisionsScreen{
+		mainScreen:     mainScreen,
+		CommentStorage: mainScreen.CommentStorage,
+		Comment:        comment,
+		keys:           NewConfirmCancelKeyMap(),
+	}
+	return m
+}
+
+// fmtTimestamp formats a timestamp in the local timezone
+func fmtTimestamp(ts int64) string {
+	lang := strings.Split(os.Getenv("LANG"), ".")[0]
+	dtfmt := parlante.GetDateTimeFmt(lang)
+	dtstr, err := parlante.LocalizeTimestamp(ts, "Local", dtfmt)
+	if err != nil {
+		// notest
+		return ""
+	}
+	return dtstr
+}
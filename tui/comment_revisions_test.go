@@ -0,0 +1,121 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jucacrispim/parlante"
+)
+
+func TestCommentRevisionsScreen(t *testing.T) {
+
+	c := parlante.NewClientStorageInMemory()
+	cd := parlante.NewClientDomainStorageInMemory()
+	comm := parlante.NewCommentStorageInMemory()
+	main := newMainScreen(&c, &cd, &comm, nil, nil)
+
+	c1, _, _ := c.CreateClient("a client")
+	d1, _ := cd.AddClientDomain(c1, "domain.net")
+	comm1, _ := comm.CreateComment(c1, d1, "zé", "the coment", "http://bla.net")
+	comm2, _ := comm.CreateComment(c1, d1, "jão", "other comment", "http://bla.net")
+	comm1, _ = comm.UpdateComment(comm1, "the comment", "moderator")
+
+	var tests = []struct {
+		testName string
+		screenFn func() commentRevisionsScreen
+		msgFn    func(commentRevisionsScreen) tea.Msg
+		checkFn  func(tea.Model, tea.Cmd)
+	}{
+		{
+			"test load revisions",
+			func() commentRevisionsScreen {
+				return newCommentRevisionsScreen(main, comm1)
+			},
+			func(m commentRevisionsScreen) tea.Msg {
+				return m.Init()()
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				view := m.(commentRevisionsScreen).View()
+				if !strings.Contains(view, "the comment") ||
+					!strings.Contains(view, "the coment") ||
+					!strings.Contains(view, "moderator") {
+					t.Fatalf("revisions not shown %s", view)
+				}
+			},
+		},
+		{
+			"test load no revisions",
+			func() commentRevisionsScreen {
+				return newCommentRevisionsScreen(main, comm2)
+			},
+			func(m commentRevisionsScreen) tea.Msg {
+				return m.Init()()
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				view := m.(commentRevisionsScreen).View()
+				if !strings.Contains(view, MESSAGE_NO_COMMENT_REVISIONS) {
+					t.Fatalf("bad view without revisions %s", view)
+				}
+			},
+		},
+		{
+			"test load revisions with error",
+			func() commentRevisionsScreen {
+				comm.ForceListError(true)
+				return newCommentRevisionsScreen(main, comm1)
+			},
+			func(m commentRevisionsScreen) tea.Msg {
+				return m.Init()()
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				comm.ForceListError(false)
+				nm := m.(commentRevisionsScreen)
+				if nm.err == nil || !strings.Contains(nm.View(), nm.err.Error()) {
+					t.Fatalf("error not shown %s", nm.View())
+				}
+			},
+		},
+		{
+			"test back to comments",
+			func() commentRevisionsScreen {
+				return newCommentRevisionsScreen(main, comm1)
+			},
+			func(m commentRevisionsScreen) tea.Msg {
+				return tea.KeyMsg{Type: tea.KeyEnter}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				_, ok := m.(AddRemoveItemScreen)
+				if !ok {
+					t.Fatalf("bad model for back to comments")
+				}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			screen := test.screenFn()
+			msg := test.msgFn(screen)
+			m, cmd := screen.Update(msg)
+			test.checkFn(m, cmd)
+		})
+	}
+}
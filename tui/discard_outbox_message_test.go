@@ -0,0 +1,138 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jucacrispim/parlante"
+)
+
+func TestDiscardOutboxMessageScreen(t *testing.T) {
+	obs := parlante.NewOutboxStorageInMemory()
+	main := newMainScreen(nil, nil, nil, nil, &obs)
+
+	item, _ := obs.Enqueue(parlante.EmailMessage{
+		From: "blog@bla.net", To: []string{"me@bla.net"}, Subject: "Hi"})
+	item.Status = parlante.OutboxFailed
+	obs.UpdateMessage(item)
+
+	tests := []struct {
+		testName string
+		screenFn func() discardOutboxMessageScreen
+		msgFn    func(discardOutboxMessageScreen) tea.Msg
+		checkFn  func(tea.Model, tea.Cmd)
+	}{
+		{
+			"discard message successfully",
+			func() discardOutboxMessageScreen {
+				return newDiscardOutboxMessageScreen(main, item)
+			},
+			func(m discardOutboxMessageScreen) tea.Msg {
+				return m.discardMessage()()
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				_, ok := m.(AddRemoveItemScreen)
+				if !ok {
+					t.Fatalf("expected AddRemoveItemScreen, got %T", m)
+				}
+				items, _ := obs.ListFailed()
+				if len(items) != 0 {
+					t.Fatal("message was not discarded")
+				}
+			},
+		},
+		{
+			"discard message with error",
+			func() discardOutboxMessageScreen {
+				return newDiscardOutboxMessageScreen(main, item)
+			},
+			func(m discardOutboxMessageScreen) tea.Msg {
+				// the message was discarded in the previous test
+				return m.discardMessage()()
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				nm, ok := m.(discardOutboxMessageScreen)
+				if !ok {
+					t.Fatalf("expected discardOutboxMessageScreen, got %T", m)
+				}
+				if nm.err == nil || !strings.Contains(nm.View(), nm.err.Error()) {
+					t.Fatal("expected error to be shown")
+				}
+			},
+		},
+		{
+			"confirm discard via enter",
+			func() discardOutboxMessageScreen {
+				return newDiscardOutboxMessageScreen(main, item)
+			},
+			func(m discardOutboxMessageScreen) tea.Msg {
+				return tea.KeyMsg{Type: tea.KeyEnter}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				msg := cmd()
+				_, ok := msg.(discardOutboxMessageMsg)
+				if !ok {
+					t.Fatalf("expected discardOutboxMessageMsg, got %T", msg)
+				}
+			},
+		},
+		{
+			"cancel discard via esc",
+			func() discardOutboxMessageScreen {
+				return newDiscardOutboxMessageScreen(main, item)
+			},
+			func(m discardOutboxMessageScreen) tea.Msg {
+				return tea.KeyMsg{Type: tea.KeyEsc}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				_, ok := m.(AddRemoveItemScreen)
+				if !ok {
+					t.Fatalf("expected AddRemoveItemScreen, got %T", m)
+				}
+			},
+		},
+		{
+			"render view without error",
+			func() discardOutboxMessageScreen {
+				return newDiscardOutboxMessageScreen(main, item)
+			},
+			func(m discardOutboxMessageScreen) tea.Msg {
+				return nil
+			},
+			func(m tea.Model, _ tea.Cmd) {
+				view := m.(discardOutboxMessageScreen).View()
+				if !strings.Contains(view, MESSAGE_DISCARD_EMAIL) ||
+					!strings.Contains(view, "me@bla.net") {
+					t.Fatalf("missing expected elements %s", view)
+				}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			screen := test.screenFn()
+			msg := test.msgFn(screen)
+			m, cmd := screen.Update(msg)
+			test.checkFn(m, cmd)
+		})
+	}
+}
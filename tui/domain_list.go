@@ -18,6 +18,9 @@
 package tui
 
 import (
+	"slices"
+
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/jucacrispim/parlante"
@@ -31,7 +34,18 @@ func (i domainItem) Title() string { return i.domain.Domain }
 func (i domainItem) Description() string {
 	data := make(map[string]any)
 	data["clientName"] = i.domain.Client.Name
-	return parlante.Tprintf(MESSAGE_DOMAIN_DESCRIPTION, data)
+	descr := parlante.Tprintf(MESSAGE_DOMAIN_DESCRIPTION, data)
+	if i.domain.Moderate {
+		descr += " | " + MESSAGE_DOMAIN_MODERATED
+	}
+	if i.domain.Markdown {
+		descr += " | " + MESSAGE_DOMAIN_MARKDOWN
+	}
+	if i.domain.Order != "" && i.domain.Order != parlante.OrderOldest {
+		data := map[string]any{"order": i.domain.Order}
+		descr += " | " + parlante.Tprintf(MESSAGE_DOMAIN_ORDER, data)
+	}
+	return descr
 }
 func (i domainItem) FilterValue() string { return i.domain.Domain }
 
@@ -84,6 +98,58 @@ func (l DomainLoader) Load() tea.Cmd {
 	}
 }
 
+// DomainModerationToggler turns the pre-moderation of the selected
+// domain on/off
+type DomainModerationToggler struct {
+	Storage parlante.ClientDomainStorage
+}
+
+func (t DomainModerationToggler) Run(item list.Item) tea.Cmd {
+	return func() tea.Msg {
+		i := item.(domainItem)
+		err := t.Storage.SetDomainModeration(i.domain, !i.domain.Moderate)
+		return ItemActionDoneMsg{Err: err}
+	}
+}
+
+// DomainMarkdownToggler turns the markdown in the comments of the
+// selected domain on/off
+type DomainMarkdownToggler struct {
+	Storage parlante.ClientDomainStorage
+}
+
+func (t DomainMarkdownToggler) Run(item list.Item) tea.Cmd {
+	return func() tea.Msg {
+		i := item.(domainItem)
+		err := t.Storage.SetDomainMarkdown(i.domain, !i.domain.Markdown)
+		return ItemActionDoneMsg{Err: err}
+	}
+}
+
+// DomainOrderCycler changes the default comments order of the selected
+// domain to the next one in parlante.PageCommentOrders
+type DomainOrderCycler struct {
+	Storage parlante.ClientDomainStorage
+}
+
+func (c DomainOrderCycler) Run(item list.Item) tea.Cmd {
+	return func() tea.Msg {
+		i := item.(domainItem)
+		order := nextCommentOrder(i.domain.Order)
+		err := c.Storage.SetDomainOrder(i.domain, order)
+		return ItemActionDoneMsg{Err: err}
+	}
+}
+
+func nextCommentOrder(order parlante.CommentOrder) parlante.CommentOrder {
+	orders := parlante.PageCommentOrders
+	if order == "" {
+		order = parlante.OrderOldest
+	}
+	idx := slices.Index(orders, order)
+	return orders[(idx+1)%len(orders)]
+}
+
 func newDomainListScreen(mainScreen *mainScreen) AddRemoveItemScreen {
 
 	nav := DomainListNavigation{
@@ -100,5 +166,55 @@ func newDomainListScreen(mainScreen *mainScreen) AddRemoveItemScreen {
 		ShowHelp:        true,
 	}
 	s := NewAddRemoveItemScreen(&h, opts, nav, l.Load)
+	toggler := DomainModerationToggler{Storage: mainScreen.domainStorage}
+	markdownToggler := DomainMarkdownToggler{Storage: mainScreen.domainStorage}
+	orderCycler := DomainOrderCycler{Storage: mainScreen.domainStorage}
+	s.Actions = []ItemAction{
+		{
+			Key: key.NewBinding(
+				key.WithKeys("m"),
+				key.WithHelp("m", MESSAGE_KEY_HELP_MODERATION),
+			),
+			Run: toggler.Run,
+		},
+		{
+			Key: key.NewBinding(
+				key.WithKeys("M"),
+				key.WithHelp("M", MESSAGE_KEY_HELP_MARKDOWN),
+			),
+			Run: markdownToggler.Run,
+		},
+		{
+			Key: key.NewBinding(
+				key.WithKeys("o"),
+				key.WithHelp("o", MESSAGE_KEY_HELP_ORDER),
+			),
+			Run: orderCycler.Run,
+		},
+	}
+	s.ScreenActions = []ScreenAction{
+		{
+			Key: key.NewBinding(
+				key.WithKeys("t"),
+				key.WithHelp("t", MESSAGE_KEY_HELP_RATE_LIMITS),
+			),
+			Screen: func(item list.Item) tea.Model {
+				i := item.(domainItem)
+				return newDomainRateLimitsScreen(*mainScreen, i.domain)
+			},
+			ItemRequired: true,
+		},
+		{
+			Key: key.NewBinding(
+				key.WithKeys("n"),
+				key.WithHelp("n", MESSAGE_KEY_HELP_NOTIFICATIONS),
+			),
+			Screen: func(item list.Item) tea.Model {
+				i := item.(domainItem)
+				return newDomainNotificationsScreen(*mainScreen, i.domain)
+			},
+			ItemRequired: true,
+		},
+	}
 	return s
 }
@@ -57,7 +57,7 @@ func TestDomainListScreen(t *testing.T) {
 	c := parlante.NewClientStorageInMemory()
 	cd := parlante.NewClientDomainStorageInMemory()
 	comm := parlante.NewCommentStorageInMemory()
-	main := newMainScreen(&c, &cd, &comm)
+	main := newMainScreen(&c, &cd, &comm, nil, nil)
 
 	c1, _, _ := c.CreateClient("a client")
 	d1, _ := cd.AddClientDomain(c1, "bla.net")
@@ -175,6 +175,131 @@ func TestDomainListScreen(t *testing.T) {
 
 			},
 		},
+		{
+			"test toggle moderation",
+			func() AddRemoveItemScreen {
+				s := newDomainListScreen(&main)
+				items := s.Init()()
+				i := items.(ItemListMsg)
+				s.List.SetItems(i.Items)
+				return s
+			},
+			func(m AddRemoveItemScreen) tea.Msg {
+				return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'m'}}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				msg, ok := cmd().(ItemActionDoneMsg)
+				if !ok || msg.Err != nil {
+					t.Fatalf("bad msg for moderation action %v", msg)
+				}
+				nm := m.(AddRemoveItemScreen)
+				item := nm.List.SelectedItem().(domainItem)
+				d, _ := cd.GetClientDomain(c1, item.domain.Domain)
+				if !d.Moderate {
+					t.Fatalf("moderation not toggled")
+				}
+				item = domainItem{domain: d}
+				if !strings.Contains(item.Description(), MESSAGE_DOMAIN_MODERATED) {
+					t.Fatalf("bad description for moderated domain")
+				}
+			},
+		},
+		{
+			"test toggle markdown",
+			func() AddRemoveItemScreen {
+				s := newDomainListScreen(&main)
+				items := s.Init()()
+				i := items.(ItemListMsg)
+				s.List.SetItems(i.Items)
+				return s
+			},
+			func(m AddRemoveItemScreen) tea.Msg {
+				return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'M'}}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				msg, ok := cmd().(ItemActionDoneMsg)
+				if !ok || msg.Err != nil {
+					t.Fatalf("bad msg for markdown action %v", msg)
+				}
+				nm := m.(AddRemoveItemScreen)
+				item := nm.List.SelectedItem().(domainItem)
+				d, _ := cd.GetClientDomain(c1, item.domain.Domain)
+				if !d.Markdown {
+					t.Fatalf("markdown not toggled")
+				}
+				item = domainItem{domain: d}
+				if !strings.Contains(item.Description(), MESSAGE_DOMAIN_MARKDOWN) {
+					t.Fatalf("bad description for markdown domain")
+				}
+			},
+		},
+		{
+			"test cycle comments order",
+			func() AddRemoveItemScreen {
+				s := newDomainListScreen(&main)
+				items := s.Init()()
+				i := items.(ItemListMsg)
+				s.List.SetItems(i.Items)
+				return s
+			},
+			func(m AddRemoveItemScreen) tea.Msg {
+				return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'o'}}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				msg, ok := cmd().(ItemActionDoneMsg)
+				if !ok || msg.Err != nil {
+					t.Fatalf("bad msg for order action %v", msg)
+				}
+				nm := m.(AddRemoveItemScreen)
+				item := nm.List.SelectedItem().(domainItem)
+				d, _ := cd.GetClientDomain(c1, item.domain.Domain)
+				if d.Order != parlante.OrderNewest {
+					t.Fatalf("order not changed %s", d.Order)
+				}
+				item = domainItem{domain: d}
+				if !strings.Contains(item.Description(), string(d.Order)) {
+					t.Fatalf("bad description for domain order")
+				}
+			},
+		},
+		{
+			"test GetRateLimitsScreen",
+			func() AddRemoveItemScreen {
+				s := newDomainListScreen(&main)
+				items := s.Init()()
+				i := items.(ItemListMsg)
+				s.List.SetItems(i.Items)
+				return s
+			},
+			func(m AddRemoveItemScreen) tea.Msg {
+				return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'t'}}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				_, ok := m.(rateLimitsScreen)
+				if !ok {
+					t.Fatalf("bad model for rate limits screen %T", m)
+				}
+			},
+		},
+		{
+			"test GetNotificationsScreen",
+			func() AddRemoveItemScreen {
+				s := newDomainListScreen(&main)
+				items := s.Init()()
+				i := items.(ItemListMsg)
+				s.List.SetItems(i.Items)
+				return s
+			},
+			func(m AddRemoveItemScreen) tea.Msg {
+				return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'n'}}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				_, ok := m.(notificationsScreen)
+				if !ok {
+					t.Fatalf("bad model for notifications screen %T", m)
+				}
+			},
+		},
 	}
 
 	for _, test := range tests {
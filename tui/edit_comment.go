@@ -0,0 +1,138 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package tui
+
+import (
+	"os/user"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jucacrispim/parlante"
+)
+
+// DEFAULT_EDITOR is the editor for the comments revisions when the
+// current system user is unknown
+const DEFAULT_EDITOR = "moderator"
+
+type editCommentMsg struct {
+	comment parlante.Comment
+	err     error
+}
+
+type editCommentScreen struct {
+	mainScreen     mainScreen
+	CommentStorage parlante.CommentStorage
+	Comment        parlante.Comment
+	Editor         string
+	textarea       textarea.Model
+	keys           SaveCancelKeyMap
+	help           help.Model
+	err            error
+}
+
+func (m editCommentScreen) Init() tea.Cmd {
+	return textarea.Blink
+}
+
+func (m editCommentScreen) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	m.mainScreen.header.Update(msg)
+	switch msg := msg.(type) {
+	case editCommentMsg:
+		m.err = msg.err
+		if m.err != nil {
+			return m, nil
+		}
+		model := newCommentListScreen(&m.mainScreen)
+		return model, model.Init()
+
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, m.keys.Save):
+			return m, m.editComment()
+
+		case key.Matches(msg, m.keys.Cancel):
+			model := newCommentListScreen(&m.mainScreen)
+			return model, model.Init()
+		}
+	}
+	m.textarea, cmd = m.textarea.Update(msg)
+	return m, cmd
+}
+
+func (m editCommentScreen) View() string {
+	s := m.mainScreen.header.View()
+	data := make(map[string]any)
+	data["name"] = m.Comment.Author
+	title := "  " + titleStyle.Render(
+		parlante.Tprintf(MESSAGE_EDIT_COMMENT, data))
+	s += title + "\n\n"
+	if m.err != nil {
+		s += m.err.Error() + "\n\n"
+	}
+	s += m.textarea.View() + "\n\n"
+	lines := strings.Split(s, "\n")
+	rest := m.mainScreen.list.Height() - len(lines) + 2
+
+	helpView := m.help.View(m.keys)
+	s += strings.Repeat("\n", rest) + helpViewStyle.Render(helpView)
+	return s
+}
+
+func (m editCommentScreen) editComment() tea.Cmd {
+	return func() tea.Msg {
+		c, err := m.CommentStorage.UpdateComment(
+			m.Comment, m.textarea.Value(), m.Editor)
+		return editCommentMsg{comment: c, err: err}
+	}
+}
+
+func newEditCommentScreen(
+	mainScreen mainScreen,
+	comment parlante.Comment) editCommentScreen {
+	ta := textarea.New()
+	ta.SetWidth(60)
+	ta.SetHeight(8)
+	ta.ShowLineNumbers = false
+	ta.CharLimit = 0
+	ta.SetValue(comment.Content)
+	ta.Focus()
+	m := editCommentScreen{
+		mainScreen:     mainScreen,
+		CommentStorage: mainScreen.CommentStorage,
+		Comment:        comment,
+		Editor:         currentEditor(),
+		textarea:       ta,
+		keys:           NewSaveCancelKeyMap(),
+		help:           createHelp(),
+	}
+	return m
+}
+
+// currentEditor returns the name of the system user running the tui
+func currentEditor() string {
+	u, err := user.Current()
+	if err != nil || u.Username == "" {
+		// notest
+		return DEFAULT_EDITOR
+	}
+	return u.Username
+}
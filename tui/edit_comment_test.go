@@ -0,0 +1,142 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jucacrispim/parlante"
+)
+
+func TestEditCommentScreen(t *testing.T) {
+
+	c := parlante.NewClientStorageInMemory()
+	cd := parlante.NewClientDomainStorageInMemory()
+	comm := parlante.NewCommentStorageInMemory()
+	main := newMainScreen(&c, &cd, &comm, nil, nil)
+
+	c1, _, _ := c.CreateClient("a client")
+	d1, _ := cd.AddClientDomain(c1, "domain.net")
+	comm1, _ := comm.CreateComment(c1, d1, "zé", "the coment", "http://bla.net")
+
+	var tests = []struct {
+		testName string
+		screenFn func() editCommentScreen
+		msgFn    func(editCommentScreen) tea.Msg
+		checkFn  func(tea.Model, tea.Cmd)
+	}{
+		{
+			"test edit comment",
+			func() editCommentScreen {
+				s := newEditCommentScreen(main, comm1)
+				s.textarea.SetValue("the comment")
+				return s
+			},
+			func(m editCommentScreen) tea.Msg {
+				return m.editComment()()
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				_, ok := m.(AddRemoveItemScreen)
+				if !ok {
+					t.Fatalf("bad model for edit comment")
+				}
+				updated, _ := comm.GetCommentByID(comm1.ID)
+				if updated.Content != "the comment" || !updated.Edited() {
+					t.Fatalf("comment not edited %v", updated)
+				}
+			},
+		},
+		{
+			"test edit comment with error",
+			func() editCommentScreen {
+				s := newEditCommentScreen(main, comm1)
+				s.textarea.SetValue("")
+				return s
+			},
+			func(m editCommentScreen) tea.Msg {
+				return m.editComment()()
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				nm, ok := m.(editCommentScreen)
+				if !ok {
+					t.Fatalf("bad model for edit error")
+				}
+				if !strings.Contains(nm.View(), nm.err.Error()) {
+					t.Fatalf("error not shown %s", nm.View())
+				}
+			},
+		},
+		{
+			"test save",
+			func() editCommentScreen {
+				return newEditCommentScreen(main, comm1)
+			},
+			func(m editCommentScreen) tea.Msg {
+				return tea.KeyMsg{Type: tea.KeyCtrlS}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				_, ok := cmd().(editCommentMsg)
+				if !ok {
+					t.Fatalf("bad msg for save")
+				}
+			},
+		},
+		{
+			"test cancel edit",
+			func() editCommentScreen {
+				return newEditCommentScreen(main, comm1)
+			},
+			func(m editCommentScreen) tea.Msg {
+				return tea.KeyMsg{Type: tea.KeyEsc}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				_, ok := m.(AddRemoveItemScreen)
+				if !ok {
+					t.Fatalf("bad model for cancel edit")
+				}
+			},
+		},
+		{
+			"test View",
+			func() editCommentScreen {
+				return newEditCommentScreen(main, comm1)
+			},
+			func(m editCommentScreen) tea.Msg {
+				return nil
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				view := m.(editCommentScreen).View()
+				if !strings.Contains(view, comm1.Author) ||
+					!strings.Contains(view, MESSAGE_KEY_HELP_SAVE) {
+					t.Fatalf("missing expected elements %s", view)
+				}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			screen := test.screenFn()
+			msg := test.msgFn(screen)
+			m, cmd := screen.Update(msg)
+			test.checkFn(m, cmd)
+		})
+	}
+}
@@ -30,6 +30,8 @@ const (
 	screenClient nextScreenType = iota
 	screenDomain
 	screenComment
+	screenTrash
+	screenOutbox
 )
 
 type mainScreenKeyMap struct {
@@ -95,7 +97,12 @@ type mainScreen struct {
 	clientStorage  parlante.ClientStorage
 	domainStorage  parlante.ClientDomainStorage
 	CommentStorage parlante.CommentStorage
-	keys           *mainScreenKeyMap
+	trashStorage   parlante.TrashStorage
+	outboxStorage  parlante.OutboxStorage
+	// spamReporter reports the moderation of comments to the
+	// spam service of the client.
+	spamReporter parlante.SpamReporter
+	keys         *mainScreenKeyMap
 }
 
 func (m mainScreen) Init() tea.Cmd {
@@ -143,6 +150,12 @@ func (m mainScreen) getNextAction() (tea.Model, tea.Cmd) {
 	case screenComment:
 		c := newCommentListScreen(&m)
 		return c, c.Init()
+	case screenTrash:
+		c := newTrashListScreen(&m)
+		return c, c.Init()
+	case screenOutbox:
+		c := newOutboxListScreen(&m)
+		return c, c.Init()
 	}
 	return m, nil // notest
 }
@@ -150,7 +163,9 @@ func (m mainScreen) getNextAction() (tea.Model, tea.Cmd) {
 func newMainScreen(
 	cs parlante.ClientStorage,
 	ds parlante.ClientDomainStorage,
-	cos parlante.CommentStorage) mainScreen {
+	cos parlante.CommentStorage,
+	ts parlante.TrashStorage,
+	obs parlante.OutboxStorage) mainScreen {
 	items := []list.Item{
 		mainScreenItem{
 			MESSAGE_CLIENTS,
@@ -167,6 +182,16 @@ func newMainScreen(
 			MESSAGE_COMMENTS_SCREEN_DESCR,
 			screenComment,
 		},
+		mainScreenItem{
+			MESSAGE_TRASH,
+			MESSAGE_TRASH_SCREEN_DESCR,
+			screenTrash,
+		},
+		mainScreenItem{
+			MESSAGE_OUTBOX,
+			MESSAGE_OUTBOX_SCREEN_DESCR,
+			screenOutbox,
+		},
 	}
 
 	opts := ListOpts{
@@ -186,6 +211,9 @@ func newMainScreen(
 		clientStorage:  cs,
 		domainStorage:  ds,
 		CommentStorage: cos,
+		trashStorage:   ts,
+		outboxStorage:  obs,
+		spamReporter:   parlante.NewAkismetClient(),
 		keys:           &keys,
 	}
 	return m
@@ -46,6 +46,8 @@ func TestMainScreen(t *testing.T) {
 	c := parlante.NewClientStorageInMemory()
 	cd := parlante.NewClientDomainStorageInMemory()
 	comm := parlante.NewCommentStorageInMemory()
+	ts := parlante.NewTrashStorageInMemory()
+	obs := parlante.NewOutboxStorageInMemory()
 
 	var tests = []struct {
 		testName string
@@ -56,7 +58,7 @@ func TestMainScreen(t *testing.T) {
 		{
 			"test screen instance",
 			func() mainScreen {
-				return newMainScreen(&c, &cd, &comm)
+				return newMainScreen(&c, &cd, &comm, &ts, &obs)
 			},
 			nil,
 			func(m tea.Model, cmd tea.Cmd) {
@@ -73,7 +75,7 @@ func TestMainScreen(t *testing.T) {
 		{
 			"test help",
 			func() mainScreen {
-				return newMainScreen(&c, &cd, &comm)
+				return newMainScreen(&c, &cd, &comm, &ts, &obs)
 			},
 			nil,
 			func(m tea.Model, cmd tea.Cmd) {
@@ -86,7 +88,7 @@ func TestMainScreen(t *testing.T) {
 		{
 			"test select client",
 			func() mainScreen {
-				return newMainScreen(&c, &cd, &comm)
+				return newMainScreen(&c, &cd, &comm, &ts, &obs)
 			},
 			tea.KeyMsg{Type: tea.KeyEnter},
 			func(m tea.Model, cmd tea.Cmd) {
@@ -106,7 +108,7 @@ func TestMainScreen(t *testing.T) {
 		{
 			"test select domain",
 			func() mainScreen {
-				s := newMainScreen(&c, &cd, &comm)
+				s := newMainScreen(&c, &cd, &comm, &ts, &obs)
 				s.list.CursorDown()
 				return s
 			},
@@ -128,7 +130,7 @@ func TestMainScreen(t *testing.T) {
 		{
 			"test select comment",
 			func() mainScreen {
-				s := newMainScreen(&c, &cd, &comm)
+				s := newMainScreen(&c, &cd, &comm, &ts, &obs)
 				s.list.CursorDown()
 				s.list.CursorDown()
 				return s
@@ -148,6 +150,54 @@ func TestMainScreen(t *testing.T) {
 
 			},
 		},
+		{
+			"test select trash",
+			func() mainScreen {
+				s := newMainScreen(&c, &cd, &comm, &ts, &obs)
+				s.list.CursorDown()
+				s.list.CursorDown()
+				s.list.CursorDown()
+				return s
+			},
+			tea.KeyMsg{Type: tea.KeyEnter},
+			func(m tea.Model, cmd tea.Cmd) {
+				_, ok := m.(AddRemoveItemScreen)
+				if !ok {
+					t.Fatalf("Bad screen for trash")
+				}
+				r := cmd()
+				_, ok = r.(ItemListMsg)
+
+				if !ok {
+					t.Fatalf("bad load fn return for trash")
+				}
+
+			},
+		},
+		{
+			"test select outbox",
+			func() mainScreen {
+				s := newMainScreen(&c, &cd, &comm, &ts, &obs)
+				for range 4 {
+					s.list.CursorDown()
+				}
+				return s
+			},
+			tea.KeyMsg{Type: tea.KeyEnter},
+			func(m tea.Model, cmd tea.Cmd) {
+				_, ok := m.(AddRemoveItemScreen)
+				if !ok {
+					t.Fatalf("Bad screen for outbox")
+				}
+				r := cmd()
+				_, ok = r.(ItemListMsg)
+
+				if !ok {
+					t.Fatalf("bad load fn return for outbox")
+				}
+
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -27,6 +27,10 @@ var MESSAGE_DOMAINS = loc.Get("Domains")
 var MESSAGE_DOMAINS_SCREEN_DESCR = loc.Get("add / remove domains")
 var MESSAGE_COMMENTS = loc.Get("Comments")
 var MESSAGE_COMMENTS_SCREEN_DESCR = loc.Get("manage comments")
+var MESSAGE_TRASH = loc.Get("Trash")
+var MESSAGE_TRASH_SCREEN_DESCR = loc.Get("restore / purge removed items")
+var MESSAGE_OUTBOX = loc.Get("Failed emails")
+var MESSAGE_OUTBOX_SCREEN_DESCR = loc.Get("retry / discard emails that could not be sent")
 var MESSAGE_CHOOSE_ONE = loc.Get("Choose one")
 var MESSAGE_ADD_CLIENT = loc.Get("Add new client")
 var MESSAGE_CLIENT_ADDED_INFO = loc.Get("Client {{.clientName}} was added:\n\nKey: {{.key}}")
@@ -44,6 +48,74 @@ var MESSAGE_REMOVE_DOMAIN_CONFIRM = loc.Get(
 var MESSAGE_REMOVE_COMMENT = loc.Get("Remove comment")
 var MESSAGE_REMOVE_COMMENT_CONFIRM = loc.Get(
 	"Really want to remove comment from {{.name}} at {{.url}}?")
+var MESSAGE_COMMENT_DESCRIPTION = loc.Get("url: {{.url}} | {{.status}}")
+var MESSAGE_DOMAIN_MODERATED = loc.Get("pre-moderated")
+var MESSAGE_DOMAIN_MARKDOWN = loc.Get("markdown")
+var MESSAGE_DOMAIN_ORDER = loc.Get("order: {{.order}}")
+var MESSAGE_COMMENT_EDITED = loc.Get("edited")
+var MESSAGE_EDIT_COMMENT = loc.Get("Edit comment from {{.name}}")
+var MESSAGE_COMMENT_REVISIONS = loc.Get("Revisions of comment from {{.name}}")
+var MESSAGE_NO_COMMENT_REVISIONS = loc.Get("This comment was never edited")
+var MESSAGE_COMMENT_REVISION_INFO = loc.Get("{{.date}} by {{.editor}}")
+var MESSAGE_CURRENT_COMMENT_REVISION = loc.Get("Current version")
+var MESSAGE_TRASH_ITEM_DESCRIPTION = loc.Get("{{.type}} | removed at {{.date}}")
+var MESSAGE_PURGE_ITEM = loc.Get("Purge item")
+var MESSAGE_PURGE_ITEM_CONFIRM = loc.Get(
+	"Really want to purge {{.type}} {{.name}}? This can't be undone.")
+var MESSAGE_OUTBOX_MESSAGE_DESCRIPTION = loc.Get(
+	"to: {{.to}} | {{.attempts}} attempts | {{.error}}")
+var MESSAGE_DISCARD_EMAIL = loc.Get("Discard email")
+var MESSAGE_DISCARD_EMAIL_CONFIRM = loc.Get(
+	"Really want to discard email {{.subject}} to {{.to}}? It will never be sent.")
+
+var MESSAGE_TRASH_ITEM_TYPE = map[parlante.TrashItemType]string{
+	parlante.TrashClient:  loc.Get("client"),
+	parlante.TrashDomain:  loc.Get("domain"),
+	parlante.TrashComment: loc.Get("comment"),
+}
+
+var MESSAGE_SEARCH_COMMENTS = loc.Get("Search comments")
+var MESSAGE_SEARCH_TERMS = loc.Get("search terms")
+var MESSAGE_SEARCH_RESULTS_FOR = loc.Get("Comments matching {{.query}}")
+
+var MESSAGE_SPAM_EXPLANATION = loc.Get("Spam classification of comment from {{.name}}")
+var MESSAGE_SPAM_PROBABILITY = loc.Get("Spam probability: {{.prob}}")
+var MESSAGE_SPAM_MODEL_INFO = loc.Get(
+	"Model trained with {{.spam}} spam and {{.ham}} ham comments")
+var MESSAGE_SPAM_TOKENS = loc.Get("Most significant words:")
+var MESSAGE_SPAM_NO_TOKENS = loc.Get("No known words in this comment")
+var MESSAGE_RETRAIN_SPAM = loc.Get("Retrain spam model")
+var MESSAGE_RETRAIN_SPAM_CONFIRM = loc.Get(
+	"Really want to retrain the spam model using all approved and spam comments?")
+var MESSAGE_SPAM_RETRAINED = loc.Get("Spam model trained with {{.count}} comments")
+
+var MESSAGE_CLIENT_AKISMET = loc.Get("Akismet spam service for {{.clientName}}")
+var MESSAGE_AKISMET_KEY = loc.Get("akismet key. Empty to disable")
+
+var MESSAGE_CLIENT_RATE_LIMITS = loc.Get("Rate limits for {{.clientName}}")
+var MESSAGE_DOMAIN_RATE_LIMITS = loc.Get("Rate limits for {{.domain}}")
+var MESSAGE_RATE_LIMITS_HELP = loc.Get(
+	"Requests per period, like 5/1m. Empty to use the default")
+var MESSAGE_CLIENT_NOTIFICATIONS = loc.Get("Notifications for {{.clientName}}")
+var MESSAGE_DOMAIN_NOTIFICATIONS = loc.Get("Notifications for {{.domain}}")
+var MESSAGE_NOTIFICATIONS_HELP = loc.Get(
+	"New comments and messages are sent to the recipients. Domain settings override the client ones")
+var MESSAGE_NOTIFICATIONS_MODES_HELP = loc.Get(
+	"Add (hourly) or (daily) after a recipient to get a digest instead of one email for each comment")
+var MESSAGE_NOTIFICATIONS_SENDER = loc.Get("Sender")
+var MESSAGE_NOTIFICATIONS_RECIPIENTS = loc.Get("Recipients")
+var MESSAGE_RATE_LIMIT_ROUTES = map[parlante.RateLimitRoute]string{
+	parlante.RateLimitComment: loc.Get("comments"),
+	parlante.RateLimitPingMe:  loc.Get("ping me"),
+	parlante.RateLimitReact:   loc.Get("reactions"),
+}
+
+var MESSAGE_COMMENT_STATUS = map[parlante.CommentStatus]string{
+	parlante.CommentPending:  loc.Get("pending"),
+	parlante.CommentApproved: loc.Get("approved"),
+	parlante.CommentSpam:     loc.Get("spam"),
+	parlante.CommentRejected: loc.Get("rejected"),
+}
 
 var MESAGE_ENTER_TO_CONTINUE = loc.Get("Press enter to continue")
 
@@ -65,3 +137,20 @@ var MESSAGE_KEY_HELP_MORE = loc.Get("more")
 var MESSAGE_KEY_HELP_CLOSE_HELP = loc.Get("close help")
 var MESSAGE_KEY_HELP_QUIT = loc.Get("quit")
 var MESSAGE_KEY_HELP_SELECT = loc.Get("select")
+var MESSAGE_KEY_HELP_APPROVE = loc.Get("approve")
+var MESSAGE_KEY_HELP_REJECT = loc.Get("reject")
+var MESSAGE_KEY_HELP_SPAM = loc.Get("spam")
+var MESSAGE_KEY_HELP_MODERATION = loc.Get("toggle moderation")
+var MESSAGE_KEY_HELP_MARKDOWN = loc.Get("toggle markdown")
+var MESSAGE_KEY_HELP_ORDER = loc.Get("comments order")
+var MESSAGE_KEY_HELP_SEARCH = loc.Get("search")
+var MESSAGE_KEY_HELP_EDIT = loc.Get("edit")
+var MESSAGE_KEY_HELP_REVISIONS = loc.Get("revisions")
+var MESSAGE_KEY_HELP_EXPLAIN_SPAM = loc.Get("explain spam")
+var MESSAGE_KEY_HELP_RETRAIN_SPAM = loc.Get("retrain spam")
+var MESSAGE_KEY_HELP_AKISMET = loc.Get("spam service")
+var MESSAGE_KEY_HELP_RATE_LIMITS = loc.Get("rate limits")
+var MESSAGE_KEY_HELP_NOTIFICATIONS = loc.Get("notifications")
+var MESSAGE_KEY_HELP_SAVE = loc.Get("save")
+var MESSAGE_KEY_HELP_RESTORE = loc.Get("restore")
+var MESSAGE_KEY_HELP_RETRY = loc.Get("retry")
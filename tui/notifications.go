@@ -0,0 +1,184 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package tui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jucacrispim/parlante"
+)
+
+type notificationsMsg struct {
+	err error
+}
+
+// notificationsScreen changes the addresses of the notifications of a
+// client or a domain. Empty inputs remove the settings.
+type notificationsScreen struct {
+	mainScreen mainScreen
+	title      string
+	setFn      func(parlante.NotificationSettings) error
+	prevFn     func() AddRemoveItemScreen
+	inputs     []textinput.Model
+	focused    int
+	keys       ConfirmCancelKeyMap
+	help       help.Model
+	err        error
+}
+
+func (m notificationsScreen) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (m notificationsScreen) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	m.mainScreen.header.Update(msg)
+	switch msg := msg.(type) {
+	case notificationsMsg:
+		m.err = msg.err
+		if m.err != nil {
+			return m, nil
+		}
+		model := m.prevFn()
+		return model, model.Init()
+
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, m.keys.Confirm):
+			if m.focused < len(m.inputs)-1 {
+				m.inputs[m.focused].Blur()
+				m.focused++
+				m.inputs[m.focused].Focus()
+				return m, textinput.Blink
+			}
+			return m, m.save()
+
+		case key.Matches(msg, m.keys.Cancel):
+			model := m.prevFn()
+			return model, model.Init()
+		}
+	}
+	m.inputs[m.focused], cmd = m.inputs[m.focused].Update(msg)
+	return m, cmd
+}
+
+func (m notificationsScreen) View() string {
+	s := m.mainScreen.header.View()
+	s += "  " + titleStyle.Render(m.title) + "\n\n"
+	s += defaultTextStyle.Render(MESSAGE_NOTIFICATIONS_HELP) + "\n"
+	s += defaultTextStyle.Render(MESSAGE_NOTIFICATIONS_MODES_HELP) + "\n\n"
+	if m.err != nil {
+		s += defaultTextStyle.Render(m.err.Error()) + "\n\n"
+	}
+	for _, input := range m.inputs {
+		s += input.View() + "\n"
+	}
+	lines := strings.Split(s, "\n")
+	rest := m.mainScreen.list.Height() - len(lines) + 2
+
+	helpView := m.help.View(m.keys)
+	s += strings.Repeat("\n", rest) + helpViewStyle.Render(helpView)
+	return s
+}
+
+func (m notificationsScreen) save() tea.Cmd {
+	n, err := parlante.ParseNotificationSettings(
+		m.inputs[0].Value(), m.inputs[1].Value())
+	if err != nil {
+		return func() tea.Msg {
+			return notificationsMsg{err: err}
+		}
+	}
+	return func() tea.Msg {
+		return notificationsMsg{err: m.setFn(n)}
+	}
+}
+
+func newNotificationsScreen(
+	mainScreen mainScreen,
+	title string,
+	getFn func() (parlante.NotificationSettings, error),
+	setFn func(parlante.NotificationSettings) error,
+	prevFn func() AddRemoveItemScreen) notificationsScreen {
+	n, err := getFn()
+	sender := textinput.New()
+	sender.Prompt = MESSAGE_NOTIFICATIONS_SENDER + ": "
+	sender.SetValue(n.Sender)
+	recipients := textinput.New()
+	recipients.Prompt = MESSAGE_NOTIFICATIONS_RECIPIENTS + ": "
+	recipients.Placeholder = "me@example.com, you@example.com (daily)"
+	recipients.SetValue(n.RecipientsString())
+	inputs := []textinput.Model{sender, recipients}
+	for i := range inputs {
+		inputs[i].Width = 60
+		inputs[i].TextStyle = defaultTextStyle
+		inputs[i].PromptStyle = defaultTextStyle
+	}
+	inputs[0].Focus()
+	m := notificationsScreen{
+		mainScreen: mainScreen,
+		title:      title,
+		setFn:      setFn,
+		prevFn:     prevFn,
+		inputs:     inputs,
+		keys:       NewConfirmCancelKeyMap(),
+		help:       createHelp(),
+		err:        err,
+	}
+	return m
+}
+
+func newClientNotificationsScreen(
+	mainScreen mainScreen, client parlante.Client) notificationsScreen {
+	storage := mainScreen.domainStorage
+	data := make(map[string]any)
+	data["clientName"] = highlightTitleStyle.Render(client.Name)
+	title := parlante.Tprintf(MESSAGE_CLIENT_NOTIFICATIONS, data)
+	getFn := func() (parlante.NotificationSettings, error) {
+		return storage.GetClientNotifications(client)
+	}
+	setFn := func(n parlante.NotificationSettings) error {
+		return storage.SetClientNotifications(client, n)
+	}
+	prevFn := func() AddRemoveItemScreen {
+		return newClientListScreen(mainScreen)
+	}
+	return newNotificationsScreen(mainScreen, title, getFn, setFn, prevFn)
+}
+
+func newDomainNotificationsScreen(
+	mainScreen mainScreen, domain parlante.ClientDomain) notificationsScreen {
+	storage := mainScreen.domainStorage
+	data := make(map[string]any)
+	data["domain"] = highlightTitleStyle.Render(domain.Domain)
+	title := parlante.Tprintf(MESSAGE_DOMAIN_NOTIFICATIONS, data)
+	getFn := func() (parlante.NotificationSettings, error) {
+		return storage.GetDomainNotifications(domain)
+	}
+	setFn := func(n parlante.NotificationSettings) error {
+		return storage.SetDomainNotifications(domain, n)
+	}
+	prevFn := func() AddRemoveItemScreen {
+		return newDomainListScreen(&mainScreen)
+	}
+	return newNotificationsScreen(mainScreen, title, getFn, setFn, prevFn)
+}
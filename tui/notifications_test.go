@@ -0,0 +1,213 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package tui
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jucacrispim/parlante"
+)
+
+func TestNotificationsScreen(t *testing.T) {
+
+	c := parlante.NewClientStorageInMemory()
+	cd := parlante.NewClientDomainStorageInMemory()
+	comm := parlante.NewCommentStorageInMemory()
+	main := newMainScreen(&c, &cd, &comm, nil, nil)
+
+	c1, _, _ := c.CreateClient("a client")
+	d1, _ := cd.AddClientDomain(c1, "bla.net")
+	n := parlante.NotificationSettings{
+		Sender:     "blog@bla.net",
+		Recipients: []string{"me@bla.net", "you@bla.net"},
+	}
+	cd.SetClientNotifications(c1, n)
+
+	var tests = []struct {
+		testName string
+		screenFn func() notificationsScreen
+		msgFn    func(notificationsScreen) tea.Msg
+		checkFn  func(tea.Model, tea.Cmd)
+	}{
+		{
+			"test client view",
+			func() notificationsScreen {
+				return newClientNotificationsScreen(main, c1)
+			},
+			func(m notificationsScreen) tea.Msg {
+				return nil
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				view := m.View()
+				if !strings.Contains(view, c1.Name) ||
+					!strings.Contains(view, n.Sender) ||
+					!strings.Contains(view, n.RecipientsString()) {
+					t.Fatalf("bad view %s", view)
+				}
+			},
+		},
+		{
+			"test domain view",
+			func() notificationsScreen {
+				return newDomainNotificationsScreen(main, d1)
+			},
+			func(m notificationsScreen) tea.Msg {
+				return nil
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				view := m.View()
+				if !strings.Contains(view, d1.Domain) {
+					t.Fatalf("bad view %s", view)
+				}
+			},
+		},
+		{
+			"test load error",
+			func() notificationsScreen {
+				cd.ForceListError(true)
+				defer cd.ForceListError(false)
+				return newDomainNotificationsScreen(main, d1)
+			},
+			func(m notificationsScreen) tea.Msg {
+				return nil
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				nm := m.(notificationsScreen)
+				if nm.err == nil {
+					t.Fatalf("no error loading notifications")
+				}
+			},
+		},
+		{
+			"test enter goes to next field",
+			func() notificationsScreen {
+				return newClientNotificationsScreen(main, c1)
+			},
+			func(m notificationsScreen) tea.Msg {
+				return tea.KeyMsg{Type: tea.KeyEnter}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				nm := m.(notificationsScreen)
+				if nm.focused != 1 || !nm.inputs[1].Focused() ||
+					nm.inputs[0].Focused() {
+					t.Fatalf("bad focus %d", nm.focused)
+				}
+			},
+		},
+		{
+			"test save domain notifications",
+			func() notificationsScreen {
+				s := newDomainNotificationsScreen(main, d1)
+				s.inputs[1].SetValue("other@bla.net (daily)")
+				return s
+			},
+			func(m notificationsScreen) tea.Msg {
+				return m.save()()
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				_, ok := m.(AddRemoveItemScreen)
+				if !ok {
+					t.Fatalf("bad model after save %T", m)
+				}
+				dn, _ := cd.GetDomainNotifications(d1)
+				if !slices.Equal(dn.Recipients, []string{"other@bla.net"}) ||
+					dn.Mode("other@bla.net") != parlante.NotifyDaily {
+					t.Fatalf("bad domain notifications %+v", dn)
+				}
+			},
+		},
+		{
+			"test save empty removes notifications",
+			func() notificationsScreen {
+				s := newClientNotificationsScreen(main, c1)
+				s.inputs[0].SetValue("")
+				s.inputs[1].SetValue("")
+				return s
+			},
+			func(m notificationsScreen) tea.Msg {
+				return m.save()()
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				cn, _ := cd.GetClientNotifications(c1)
+				if !cn.IsZero() {
+					t.Fatalf("client notifications not removed %+v", cn)
+				}
+			},
+		},
+		{
+			"test save invalid email",
+			func() notificationsScreen {
+				s := newClientNotificationsScreen(main, c1)
+				s.inputs[1].SetValue("me@bla.net, bla")
+				return s
+			},
+			func(m notificationsScreen) tea.Msg {
+				return m.save()()
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				nm, ok := m.(notificationsScreen)
+				if !ok || nm.err == nil {
+					t.Fatalf("no error for invalid email")
+				}
+			},
+		},
+		{
+			"test save with error",
+			func() notificationsScreen {
+				return newClientNotificationsScreen(main, c1)
+			},
+			func(m notificationsScreen) tea.Msg {
+				return notificationsMsg{err: errors.New("bad save")}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				nm, ok := m.(notificationsScreen)
+				if !ok || !strings.Contains(nm.View(), "bad save") {
+					t.Fatalf("error not shown")
+				}
+			},
+		},
+		{
+			"test cancel",
+			func() notificationsScreen {
+				return newDomainNotificationsScreen(main, d1)
+			},
+			func(m notificationsScreen) tea.Msg {
+				return tea.KeyMsg{Type: tea.KeyEsc}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				_, ok := m.(AddRemoveItemScreen)
+				if !ok {
+					t.Fatalf("bad model for cancel")
+				}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			screen := test.screenFn()
+			msg := test.msgFn(screen)
+			m, cmd := screen.Update(msg)
+			test.checkFn(m, cmd)
+		})
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package tui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jucacrispim/parlante"
+)
+
+type OutboxItem struct {
+	Item parlante.OutboxMessage
+}
+
+func (i OutboxItem) Title() string { return i.Item.Message.Subject }
+func (i OutboxItem) Description() string {
+	data := make(map[string]any)
+	data["to"] = strings.Join(i.Item.Message.To, ", ")
+	data["attempts"] = i.Item.Attempts
+	data["error"] = i.Item.LastError
+	return parlante.Tprintf(MESSAGE_OUTBOX_MESSAGE_DESCRIPTION, data)
+}
+func (i OutboxItem) FilterValue() string { return i.Item.Message.Subject }
+
+type OutboxListNavigation struct {
+	MainScreen *mainScreen
+}
+
+// GetAddScreen returns the list screen because emails are not
+// added from here.
+func (n OutboxListNavigation) GetAddScreen() tea.Model {
+	s := newOutboxListScreen(n.MainScreen)
+	return s
+}
+
+func (n OutboxListNavigation) GetRemoveScreen(item list.Item) tea.Model {
+	i := item.(OutboxItem)
+	s := newDiscardOutboxMessageScreen(*n.MainScreen, i.Item)
+	return s
+}
+
+func (n OutboxListNavigation) GetPreviousScreen() tea.Model {
+	return *n.MainScreen
+}
+
+// OutboxLoader loads the emails that failed to be sent.
+type OutboxLoader struct {
+	Storage parlante.OutboxStorage
+}
+
+func (l OutboxLoader) Load() tea.Cmd {
+	return func() tea.Msg {
+		msgs, err := l.Storage.ListFailed()
+		if err != nil {
+			return ItemListMsg{Err: err}
+		}
+
+		items := make([]list.Item, 0)
+		for _, m := range msgs {
+			items = append(items, OutboxItem{Item: m})
+		}
+		return ItemListMsg{Items: items}
+	}
+}
+
+// OutboxRetrier puts the selected email back in the queue. It is sent
+// by the server.
+type OutboxRetrier struct {
+	Storage parlante.OutboxStorage
+}
+
+func (r OutboxRetrier) Run(item list.Item) tea.Cmd {
+	return func() tea.Msg {
+		i := item.(OutboxItem)
+		err := r.Storage.RetryMessage(i.Item)
+		return ItemActionDoneMsg{Err: err}
+	}
+}
+
+func newOutboxListScreen(mainScreen *mainScreen) AddRemoveItemScreen {
+	nav := OutboxListNavigation{
+		MainScreen: mainScreen,
+	}
+	l := OutboxLoader{
+		Storage: mainScreen.outboxStorage,
+	}
+	h := mainScreen.header
+	opts := ListOpts{
+		Title:           MESSAGE_OUTBOX,
+		ShowDescription: true,
+		ShowStatusBar:   true,
+		ShowHelp:        true,
+	}
+	s := NewAddRemoveItemScreen(&h, opts, nav, l.Load)
+	retrier := OutboxRetrier{Storage: mainScreen.outboxStorage}
+	s.Actions = []ItemAction{
+		{
+			Key: key.NewBinding(
+				key.WithKeys("r"),
+				key.WithHelp("r", MESSAGE_KEY_HELP_RETRY),
+			),
+			Run: retrier.Run,
+		},
+	}
+	return s
+}
@@ -0,0 +1,198 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jucacrispim/parlante"
+)
+
+func TestOutboxItem(t *testing.T) {
+	m := parlante.OutboxMessage{
+		ID: 1,
+		Message: parlante.EmailMessage{
+			To: []string{"me@bla.net", "you@bla.net"}, Subject: "Hi"},
+		Attempts:  8,
+		LastError: "connection refused",
+	}
+	item := OutboxItem{Item: m}
+
+	if item.Title() != "Hi" {
+		t.Fatalf("Bad title for item %s", item.Title())
+	}
+
+	descr := item.Description()
+	if !strings.Contains(descr, "me@bla.net, you@bla.net") ||
+		!strings.Contains(descr, "connection refused") {
+		t.Fatalf("Bad description for item %s", descr)
+	}
+
+	if item.FilterValue() != "Hi" {
+		t.Fatalf("Bad filter value for item %s", item.FilterValue())
+	}
+}
+
+func TestOutboxListScreen(t *testing.T) {
+	obs := parlante.NewOutboxStorageInMemory()
+	main := newMainScreen(nil, nil, nil, nil, &obs)
+
+	addFailed := func(subject string) parlante.OutboxMessage {
+		m, _ := obs.Enqueue(parlante.EmailMessage{
+			From: "blog@bla.net", To: []string{"me@bla.net"}, Subject: subject})
+		m.Status = parlante.OutboxFailed
+		m.Attempts = 8
+		obs.UpdateMessage(m)
+		return m
+	}
+	first := addFailed("first email")
+	second := addFailed("second email")
+	obs.Enqueue(parlante.EmailMessage{
+		From: "blog@bla.net", To: []string{"me@bla.net"}, Subject: "pending email"})
+
+	var tests = []struct {
+		testName string
+		screenFn func() AddRemoveItemScreen
+		msgFn    func(AddRemoveItemScreen) tea.Msg
+		checkFn  func(tea.Model, tea.Cmd)
+	}{
+		{
+			"test load outbox",
+			func() AddRemoveItemScreen {
+				return newOutboxListScreen(&main)
+			},
+			func(m AddRemoveItemScreen) tea.Msg {
+				return m.Init()()
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				nm, ok := m.(AddRemoveItemScreen)
+				if !ok {
+					t.Fatalf("bad model loading outbox")
+				}
+				view := nm.View()
+				if !strings.Contains(view, first.Message.Subject) ||
+					!strings.Contains(view, second.Message.Subject) ||
+					strings.Contains(view, "pending email") {
+					t.Fatalf("outbox not loaded %s", view)
+				}
+			},
+		},
+		{
+			"test load outbox with error",
+			func() AddRemoveItemScreen {
+				obs.ForceListError(true)
+				return newOutboxListScreen(&main)
+			},
+			func(m AddRemoveItemScreen) tea.Msg {
+				return m.Init()()
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				obs.ForceListError(false)
+				nm := m.(AddRemoveItemScreen)
+				if nm.err == nil {
+					t.Fatalf("No error with load outbox error")
+				}
+			},
+		},
+		{
+			"test GetAddScreen",
+			func() AddRemoveItemScreen {
+				return newOutboxListScreen(&main)
+			},
+			func(m AddRemoveItemScreen) tea.Msg {
+				return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'a'}}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				_, ok := m.(AddRemoveItemScreen)
+				if !ok {
+					t.Fatalf("bad model for add screen")
+				}
+			},
+		},
+		{
+			"test GetRemoveScreen",
+			func() AddRemoveItemScreen {
+				s := newOutboxListScreen(&main)
+				items := s.Init()()
+				s.List.SetItems(items.(ItemListMsg).Items)
+				s.List.CursorDown()
+				return s
+			},
+			func(m AddRemoveItemScreen) tea.Msg {
+				return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'d'}}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				nm, ok := m.(discardOutboxMessageScreen)
+				if !ok {
+					t.Fatalf("bad model for discard screen")
+				}
+				if nm.Item.ID != first.ID {
+					t.Fatalf("bad item on discard %v", nm.Item)
+				}
+			},
+		},
+		{
+			"test retry message",
+			func() AddRemoveItemScreen {
+				s := newOutboxListScreen(&main)
+				items := s.Init()()
+				s.List.SetItems(items.(ItemListMsg).Items)
+				return s
+			},
+			func(m AddRemoveItemScreen) tea.Msg {
+				return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'r'}}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				msg, ok := cmd().(ItemActionDoneMsg)
+				if !ok || msg.Err != nil {
+					t.Fatalf("bad msg for retry action %v", msg)
+				}
+				items, _ := obs.ListFailed()
+				if len(items) != 1 || items[0].ID != first.ID {
+					t.Fatalf("message not retried %v", items)
+				}
+			},
+		},
+		{
+			"test GetPreviousScreen",
+			func() AddRemoveItemScreen {
+				return newOutboxListScreen(&main)
+			},
+			func(m AddRemoveItemScreen) tea.Msg {
+				return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'b'}}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				_, ok := m.(mainScreen)
+				if !ok {
+					t.Fatalf("bad model for previous screen")
+				}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			screen := test.screenFn()
+			msg := test.msgFn(screen)
+			m, cmd := screen.Update(msg)
+			test.checkFn(m, cmd)
+		})
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package tui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/help"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jucacrispim/parlante"
+)
+
+type purgeTrashItemMsg struct {
+	Item parlante.TrashItem
+	err  error
+}
+
+type purgeTrashItemScreen struct {
+	mainScreen   mainScreen
+	TrashStorage parlante.TrashStorage
+	Item         parlante.TrashItem
+	help         help.Model
+	keys         ConfirmCancelKeyMap
+	err          error
+}
+
+func (m purgeTrashItemScreen) Init() tea.Cmd {
+	return nil
+}
+
+func (m purgeTrashItemScreen) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	m.mainScreen.header.Update(msg)
+	switch msg := msg.(type) {
+	case purgeTrashItemMsg:
+		if msg.err != nil {
+			m.err = msg.err
+			return m, nil
+		}
+		model := newTrashListScreen(&m.mainScreen)
+		return model, model.Init()
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "enter":
+			return m, m.purgeItem()
+
+		case "esc":
+			model := newTrashListScreen(&m.mainScreen)
+			return model, model.Init()
+
+		}
+	}
+	return m, nil
+}
+
+func (m purgeTrashItemScreen) View() string {
+	s := m.mainScreen.header.View()
+	title := "  " + titleStyle.Render(MESSAGE_PURGE_ITEM)
+	s += title + "\n\n\n"
+	var content string
+	if m.err != nil {
+		content = m.err.Error()
+	} else {
+		data := make(map[string]any, 0)
+		data["type"] = MESSAGE_TRASH_ITEM_TYPE[m.Item.Type]
+		data["name"] = m.Item.Name
+		content = parlante.Tprintf(MESSAGE_PURGE_ITEM_CONFIRM, data)
+	}
+	s += defaultTextStyle.Render(content)
+
+	lines := strings.Split(s, "\n")
+	rest := m.mainScreen.list.Height() - len(lines) + 2
+
+	helpView := m.help.View(m.keys)
+	s += strings.Repeat("\n", rest) + helpViewStyle.Render(helpView)
+
+	return s
+}
+
+func (m purgeTrashItemScreen) purgeItem() tea.Cmd {
+	return func() tea.Msg {
+		err := m.TrashStorage.PurgeItem(m.Item)
+		return purgeTrashItemMsg{
+			Item: m.Item,
+			err:  err,
+		}
+	}
+}
+
+func newPurgeTrashItemScreen(
+	mainScreen mainScreen,
+	item parlante.TrashItem) purgeTrashItemScreen {
+	m := purgeTrashItemScreen{
+		mainScreen:   mainScreen,
+		TrashStorage: mainScreen.trashStorage,
+		Item:         item,
+		keys:         NewConfirmCancelKeyMap(),
+		help:         createHelp(),
+	}
+	return m
+}
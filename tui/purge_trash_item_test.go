@@ -0,0 +1,137 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jucacrispim/parlante"
+)
+
+func TestPurgeTrashItemScreen(t *testing.T) {
+	ts := parlante.NewTrashStorageInMemory()
+	main := newMainScreen(nil, nil, nil, &ts, nil)
+
+	item := parlante.TrashItem{
+		Type: parlante.TrashDomain, ID: 1, Name: "bla.net", DeletedAt: 10}
+	ts.AddItem(item)
+
+	tests := []struct {
+		testName string
+		screenFn func() purgeTrashItemScreen
+		msgFn    func(purgeTrashItemScreen) tea.Msg
+		checkFn  func(tea.Model, tea.Cmd)
+	}{
+		{
+			"purge item successfully",
+			func() purgeTrashItemScreen {
+				return newPurgeTrashItemScreen(main, item)
+			},
+			func(m purgeTrashItemScreen) tea.Msg {
+				return m.purgeItem()()
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				_, ok := m.(AddRemoveItemScreen)
+				if !ok {
+					t.Fatalf("expected AddRemoveItemScreen, got %T", m)
+				}
+				items, _ := ts.ListTrash()
+				if len(items) != 0 {
+					t.Fatal("item was not purged")
+				}
+			},
+		},
+		{
+			"purge item with error",
+			func() purgeTrashItemScreen {
+				return newPurgeTrashItemScreen(main, item)
+			},
+			func(m purgeTrashItemScreen) tea.Msg {
+				// the item was purged in the previous test
+				return m.purgeItem()()
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				nm, ok := m.(purgeTrashItemScreen)
+				if !ok {
+					t.Fatalf("expected purgeTrashItemScreen, got %T", m)
+				}
+				if nm.err == nil || !strings.Contains(nm.View(), nm.err.Error()) {
+					t.Fatal("expected error to be shown")
+				}
+			},
+		},
+		{
+			"confirm purge via enter",
+			func() purgeTrashItemScreen {
+				return newPurgeTrashItemScreen(main, item)
+			},
+			func(m purgeTrashItemScreen) tea.Msg {
+				return tea.KeyMsg{Type: tea.KeyEnter}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				msg := cmd()
+				_, ok := msg.(purgeTrashItemMsg)
+				if !ok {
+					t.Fatalf("expected purgeTrashItemMsg, got %T", msg)
+				}
+			},
+		},
+		{
+			"cancel purge via esc",
+			func() purgeTrashItemScreen {
+				return newPurgeTrashItemScreen(main, item)
+			},
+			func(m purgeTrashItemScreen) tea.Msg {
+				return tea.KeyMsg{Type: tea.KeyEsc}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				_, ok := m.(AddRemoveItemScreen)
+				if !ok {
+					t.Fatalf("expected AddRemoveItemScreen, got %T", m)
+				}
+			},
+		},
+		{
+			"render view without error",
+			func() purgeTrashItemScreen {
+				return newPurgeTrashItemScreen(main, item)
+			},
+			func(m purgeTrashItemScreen) tea.Msg {
+				return nil
+			},
+			func(m tea.Model, _ tea.Cmd) {
+				view := m.(purgeTrashItemScreen).View()
+				if !strings.Contains(view, MESSAGE_PURGE_ITEM) ||
+					!strings.Contains(view, item.Name) {
+					t.Fatalf("missing expected elements %s", view)
+				}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			screen := test.screenFn()
+			msg := test.msgFn(screen)
+			m, cmd := screen.Update(msg)
+			test.checkFn(m, cmd)
+		})
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package tui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jucacrispim/parlante"
+)
+
+type rateLimitsMsg struct {
+	err error
+}
+
+// rateLimitsScreen changes the rate limits of a client or a domain.
+// There is one input for each route. An empty input removes the limit
+// so the default one is used.
+type rateLimitsScreen struct {
+	mainScreen mainScreen
+	title      string
+	setFn      func(parlante.RateLimitRoute, parlante.RateLimit) error
+	prevFn     func() AddRemoveItemScreen
+	inputs     []textinput.Model
+	focused    int
+	keys       ConfirmCancelKeyMap
+	help       help.Model
+	err        error
+}
+
+func (m rateLimitsScreen) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (m rateLimitsScreen) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	m.mainScreen.header.Update(msg)
+	switch msg := msg.(type) {
+	case rateLimitsMsg:
+		m.err = msg.err
+		if m.err != nil {
+			return m, nil
+		}
+		model := m.prevFn()
+		return model, model.Init()
+
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, m.keys.Confirm):
+			if m.focused < len(m.inputs)-1 {
+				m.inputs[m.focused].Blur()
+				m.focused++
+				m.inputs[m.focused].Focus()
+				return m, textinput.Blink
+			}
+			return m, m.save()
+
+		case key.Matches(msg, m.keys.Cancel):
+			model := m.prevFn()
+			return model, model.Init()
+		}
+	}
+	m.inputs[m.focused], cmd = m.inputs[m.focused].Update(msg)
+	return m, cmd
+}
+
+func (m rateLimitsScreen) View() string {
+	s := m.mainScreen.header.View()
+	s += "  " + titleStyle.Render(m.title) + "\n\n"
+	s += defaultTextStyle.Render(MESSAGE_RATE_LIMITS_HELP) + "\n\n"
+	if m.err != nil {
+		s += defaultTextStyle.Render(m.err.Error()) + "\n\n"
+	}
+	for _, input := range m.inputs {
+		s += input.View() + "\n"
+	}
+	lines := strings.Split(s, "\n")
+	rest := m.mainScreen.list.Height() - len(lines) + 2
+
+	helpView := m.help.View(m.keys)
+	s += strings.Repeat("\n", rest) + helpViewStyle.Render(helpView)
+	return s
+}
+
+func (m rateLimitsScreen) save() tea.Cmd {
+	limits := make([]parlante.RateLimit, len(m.inputs))
+	for i, input := range m.inputs {
+		limit, err := parlante.ParseRateLimit(input.Value())
+		if err != nil {
+			return func() tea.Msg {
+				return rateLimitsMsg{err: err}
+			}
+		}
+		limits[i] = limit
+	}
+	return func() tea.Msg {
+		for i, route := range parlante.RateLimitRoutes {
+			err := m.setFn(route, limits[i])
+			if err != nil {
+				return rateLimitsMsg{err: err}
+			}
+		}
+		return rateLimitsMsg{}
+	}
+}
+
+func newRateLimitsScreen(
+	mainScreen mainScreen,
+	title string,
+	getFn func(parlante.RateLimitRoute) (parlante.RateLimit, error),
+	setFn func(parlante.RateLimitRoute, parlante.RateLimit) error,
+	prevFn func() AddRemoveItemScreen) rateLimitsScreen {
+	var err error
+	inputs := make([]textinput.Model, 0, len(parlante.RateLimitRoutes))
+	for _, route := range parlante.RateLimitRoutes {
+		input := textinput.New()
+		input.Prompt = MESSAGE_RATE_LIMIT_ROUTES[route] + ": "
+		input.Placeholder = route.DefaultRateLimit()
+		limit, lerr := getFn(route)
+		if lerr != nil {
+			err = lerr
+		}
+		input.SetValue(limit.String())
+		input.Width = 20
+		input.TextStyle = defaultTextStyle
+		input.PromptStyle = defaultTextStyle
+		inputs = append(inputs, input)
+	}
+	inputs[0].Focus()
+	m := rateLimitsScreen{
+		mainScreen: mainScreen,
+		title:      title,
+		setFn:      setFn,
+		prevFn:     prevFn,
+		inputs:     inputs,
+		keys:       NewConfirmCancelKeyMap(),
+		help:       createHelp(),
+		err:        err,
+	}
+	return m
+}
+
+func newClientRateLimitsScreen(
+	mainScreen mainScreen, client parlante.Client) rateLimitsScreen {
+	storage := mainScreen.domainStorage
+	data := make(map[string]any)
+	data["clientName"] = highlightTitleStyle.Render(client.Name)
+	title := parlante.Tprintf(MESSAGE_CLIENT_RATE_LIMITS, data)
+	getFn := func(route parlante.RateLimitRoute) (parlante.RateLimit, error) {
+		return storage.GetClientRateLimit(client, route)
+	}
+	setFn := func(route parlante.RateLimitRoute, limit parlante.RateLimit) error {
+		return storage.SetClientRateLimit(client, route, limit)
+	}
+	prevFn := func() AddRemoveItemScreen {
+		return newClientListScreen(mainScreen)
+	}
+	return newRateLimitsScreen(mainScreen, title, getFn, setFn, prevFn)
+}
+
+func newDomainRateLimitsScreen(
+	mainScreen mainScreen, domain parlante.ClientDomain) rateLimitsScreen {
+	storage := mainScreen.domainStorage
+	data := make(map[string]any)
+	data["domain"] = highlightTitleStyle.Render(domain.Domain)
+	title := parlante.Tprintf(MESSAGE_DOMAIN_RATE_LIMITS, data)
+	getFn := func(route parlante.RateLimitRoute) (parlante.RateLimit, error) {
+		return storage.GetDomainRateLimit(domain, route)
+	}
+	setFn := func(route parlante.RateLimitRoute, limit parlante.RateLimit) error {
+		return storage.SetDomainRateLimit(domain, route, limit)
+	}
+	prevFn := func() AddRemoveItemScreen {
+		return newDomainListScreen(&mainScreen)
+	}
+	return newRateLimitsScreen(mainScreen, title, getFn, setFn, prevFn)
+}
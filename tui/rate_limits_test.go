@@ -0,0 +1,208 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package tui
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jucacrispim/parlante"
+)
+
+func TestRateLimitsScreen(t *testing.T) {
+
+	c := parlante.NewClientStorageInMemory()
+	cd := parlante.NewClientDomainStorageInMemory()
+	comm := parlante.NewCommentStorageInMemory()
+	main := newMainScreen(&c, &cd, &comm, nil, nil)
+
+	c1, _, _ := c.CreateClient("a client")
+	d1, _ := cd.AddClientDomain(c1, "bla.net")
+	limit := parlante.RateLimit{Requests: 3, Period: time.Minute}
+	cd.SetClientRateLimit(c1, parlante.RateLimitComment, limit)
+
+	var tests = []struct {
+		testName string
+		screenFn func() rateLimitsScreen
+		msgFn    func(rateLimitsScreen) tea.Msg
+		checkFn  func(tea.Model, tea.Cmd)
+	}{
+		{
+			"test client view",
+			func() rateLimitsScreen {
+				return newClientRateLimitsScreen(main, c1)
+			},
+			func(m rateLimitsScreen) tea.Msg {
+				return nil
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				view := m.View()
+				if !strings.Contains(view, c1.Name) ||
+					!strings.Contains(view, limit.String()) ||
+					!strings.Contains(view, parlante.DEFAULT_PINGME_RATE_LIMIT) {
+					t.Fatalf("bad view %s", view)
+				}
+			},
+		},
+		{
+			"test domain view",
+			func() rateLimitsScreen {
+				return newDomainRateLimitsScreen(main, d1)
+			},
+			func(m rateLimitsScreen) tea.Msg {
+				return nil
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				view := m.View()
+				if !strings.Contains(view, d1.Domain) {
+					t.Fatalf("bad view %s", view)
+				}
+			},
+		},
+		{
+			"test load error",
+			func() rateLimitsScreen {
+				cd.ForceListError(true)
+				defer cd.ForceListError(false)
+				return newDomainRateLimitsScreen(main, d1)
+			},
+			func(m rateLimitsScreen) tea.Msg {
+				return nil
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				nm := m.(rateLimitsScreen)
+				if nm.err == nil {
+					t.Fatalf("no error loading limits")
+				}
+			},
+		},
+		{
+			"test enter goes to next field",
+			func() rateLimitsScreen {
+				return newClientRateLimitsScreen(main, c1)
+			},
+			func(m rateLimitsScreen) tea.Msg {
+				return tea.KeyMsg{Type: tea.KeyEnter}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				nm := m.(rateLimitsScreen)
+				if nm.focused != 1 || !nm.inputs[1].Focused() ||
+					nm.inputs[0].Focused() {
+					t.Fatalf("bad focus %d", nm.focused)
+				}
+			},
+		},
+		{
+			"test save domain limits",
+			func() rateLimitsScreen {
+				s := newDomainRateLimitsScreen(main, d1)
+				s.inputs[0].SetValue("1/1h")
+				return s
+			},
+			func(m rateLimitsScreen) tea.Msg {
+				return m.save()()
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				_, ok := m.(AddRemoveItemScreen)
+				if !ok {
+					t.Fatalf("bad model after save %T", m)
+				}
+				l, _ := cd.GetDomainRateLimit(d1, parlante.RateLimitComment)
+				if l != (parlante.RateLimit{Requests: 1, Period: time.Hour}) {
+					t.Fatalf("bad domain limit %v", l)
+				}
+			},
+		},
+		{
+			"test save empty removes limit",
+			func() rateLimitsScreen {
+				s := newClientRateLimitsScreen(main, c1)
+				s.inputs[0].SetValue("")
+				return s
+			},
+			func(m rateLimitsScreen) tea.Msg {
+				return m.save()()
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				l, _ := cd.GetClientRateLimit(c1, parlante.RateLimitComment)
+				if !l.IsZero() {
+					t.Fatalf("client limit not removed %v", l)
+				}
+			},
+		},
+		{
+			"test save invalid limit",
+			func() rateLimitsScreen {
+				s := newClientRateLimitsScreen(main, c1)
+				s.inputs[1].SetValue("bla")
+				return s
+			},
+			func(m rateLimitsScreen) tea.Msg {
+				return m.save()()
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				nm, ok := m.(rateLimitsScreen)
+				if !ok || nm.err == nil {
+					t.Fatalf("no error for invalid limit")
+				}
+			},
+		},
+		{
+			"test save with error",
+			func() rateLimitsScreen {
+				return newClientRateLimitsScreen(main, c1)
+			},
+			func(m rateLimitsScreen) tea.Msg {
+				return rateLimitsMsg{err: errors.New("bad save")}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				nm, ok := m.(rateLimitsScreen)
+				if !ok || !strings.Contains(nm.View(), "bad save") {
+					t.Fatalf("error not shown")
+				}
+			},
+		},
+		{
+			"test cancel",
+			func() rateLimitsScreen {
+				return newDomainRateLimitsScreen(main, d1)
+			},
+			func(m rateLimitsScreen) tea.Msg {
+				return tea.KeyMsg{Type: tea.KeyEsc}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				_, ok := m.(AddRemoveItemScreen)
+				if !ok {
+					t.Fatalf("bad model for cancel")
+				}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			screen := test.screenFn()
+			msg := test.msgFn(screen)
+			m, cmd := screen.Update(msg)
+			test.checkFn(m, cmd)
+		})
+	}
+}
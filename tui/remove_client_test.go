@@ -29,7 +29,7 @@ import (
 func TestRemoveClientScreen(t *testing.T) {
 
 	cs := parlante.NewClientStorageInMemory()
-	main := newMainScreen(&cs, nil, nil)
+	main := newMainScreen(&cs, nil, nil, nil, nil)
 
 	clientToRemove, _, _ := cs.CreateClient("client-to-remove")
 
@@ -30,7 +30,7 @@ func TestRemoveCommentScreen(t *testing.T) {
 	cs := parlante.NewClientStorageInMemory()
 	ds := parlante.NewClientDomainStorageInMemory()
 	cmts := parlante.NewCommentStorageInMemory()
-	main := newMainScreen(&cs, &ds, &cmts)
+	main := newMainScreen(&cs, &ds, &cmts, nil, nil)
 
 	client, _, _ := cs.CreateClient("client")
 	domain, _ := ds.AddClientDomain(client, "domain.net")
@@ -29,7 +29,7 @@ import (
 func TestRemoveDomainScreen(t *testing.T) {
 	cs := parlante.NewClientStorageInMemory()
 	ds := parlante.NewClientDomainStorageInMemory()
-	main := newMainScreen(&cs, &ds, nil)
+	main := newMainScreen(&cs, &ds, nil, nil, nil)
 
 	client, _, _ := cs.CreateClient("client")
 	ds.AddClientDomain(client, "to-be-removed")
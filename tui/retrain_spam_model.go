@@ -0,0 +1,115 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package tui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jucacrispim/parlante"
+)
+
+type retrainSpamModelMsg struct {
+	count int
+	err   error
+}
+
+// retrainSpamModelScreen asks for confirmation and then trains the
+// spam model again from all the moderated comments.
+type retrainSpamModelScreen struct {
+	mainScreen     mainScreen
+	CommentStorage parlante.CommentStorage
+	count          int
+	done           bool
+	help           help.Model
+	keys           ConfirmCancelKeyMap
+	err            error
+}
+
+func (m retrainSpamModelScreen) Init() tea.Cmd {
+	return nil
+}
+
+func (m retrainSpamModelScreen) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	m.mainScreen.header.Update(msg)
+	switch msg := msg.(type) {
+	case retrainSpamModelMsg:
+		m.err = msg.err
+		m.count = msg.count
+		m.done = true
+		return m, nil
+
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, m.keys.Confirm) && !m.done:
+			return m, m.retrain()
+
+		case key.Matches(msg, m.keys.Confirm, m.keys.Cancel):
+			model := newCommentListScreen(&m.mainScreen)
+			return model, model.Init()
+		}
+	}
+	return m, nil
+}
+
+func (m retrainSpamModelScreen) View() string {
+	s := m.mainScreen.header.View()
+	title := "  " + titleStyle.Render(MESSAGE_RETRAIN_SPAM)
+	s += title + "\n\n\n"
+	var content string
+	switch {
+	case m.err != nil:
+		content = m.err.Error()
+	case m.done:
+		data := make(map[string]any)
+		data["count"] = m.count
+		content = parlante.Tprintf(MESSAGE_SPAM_RETRAINED, data)
+	default:
+		content = MESSAGE_RETRAIN_SPAM_CONFIRM
+	}
+	s += defaultTextStyle.Render(content)
+
+	lines := strings.Split(s, "\n")
+	rest := m.mainScreen.list.Height() - len(lines) + 2
+
+	helpView := MESAGE_ENTER_TO_CONTINUE
+	if !m.done {
+		helpView = m.help.View(m.keys)
+	}
+	s += strings.Repeat("\n", rest) + helpViewStyle.Render(helpView)
+	return s
+}
+
+func (m retrainSpamModelScreen) retrain() tea.Cmd {
+	return func() tea.Msg {
+		n, err := parlante.RetrainSpamModel(m.CommentStorage)
+		return retrainSpamModelMsg{count: n, err: err}
+	}
+}
+
+func newRetrainSpamModelScreen(mainScreen mainScreen) retrainSpamModelScreen {
+	m := retrainSpamModelScreen{
+		mainScreen:     mainScreen,
+		CommentStorage: mainScreen.CommentStorage,
+		keys:           NewConfirmCancelKeyMap(),
+		help:           createHelp(),
+	}
+	return m
+}
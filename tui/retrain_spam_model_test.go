@@ -0,0 +1,143 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jucacrispim/parlante"
+)
+
+func TestRetrainSpamModelScreen(t *testing.T) {
+
+	c := parlante.NewClientStorageInMemory()
+	cd := parlante.NewClientDomainStorageInMemory()
+	comm := parlante.NewCommentStorageInMemory()
+	main := newMainScreen(&c, &cd, &comm, nil, nil)
+
+	c1, _, _ := c.CreateClient("a client")
+	d1, _ := cd.AddClientDomain(c1, "domain.net")
+	comm.CreateComment(c1, d1, "zé", "nice post", "http://bla.net")
+	spam, _ := comm.CreateComment(c1, d1, "bot", "cheap pills", "http://bla.net")
+	comm.SetCommentStatus(spam, parlante.CommentSpam)
+
+	var tests = []struct {
+		testName string
+		screenFn func() retrainSpamModelScreen
+		msgFn    func(retrainSpamModelScreen) tea.Msg
+		checkFn  func(tea.Model, tea.Cmd)
+	}{
+		{
+			"test confirm view",
+			func() retrainSpamModelScreen {
+				return newRetrainSpamModelScreen(main)
+			},
+			func(m retrainSpamModelScreen) tea.Msg {
+				return nil
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				view := m.View()
+				if !strings.Contains(view, MESSAGE_RETRAIN_SPAM_CONFIRM) {
+					t.Fatalf("bad confirm view %s", view)
+				}
+			},
+		},
+		{
+			"test confirm retrain",
+			func() retrainSpamModelScreen {
+				return newRetrainSpamModelScreen(main)
+			},
+			func(m retrainSpamModelScreen) tea.Msg {
+				return tea.KeyMsg{Type: tea.KeyEnter}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				msg, ok := cmd().(retrainSpamModelMsg)
+				if !ok {
+					t.Fatalf("bad msg for retrain")
+				}
+				if msg.err != nil || msg.count != 2 {
+					t.Fatalf("bad retrain %d %s", msg.count, msg.err)
+				}
+			},
+		},
+		{
+			"test retrain done",
+			func() retrainSpamModelScreen {
+				return newRetrainSpamModelScreen(main)
+			},
+			func(m retrainSpamModelScreen) tea.Msg {
+				return retrainSpamModelMsg{count: 2}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				data := map[string]any{"count": 2}
+				expected := parlante.Tprintf(MESSAGE_SPAM_RETRAINED, data)
+				if !strings.Contains(m.View(), expected) {
+					t.Fatalf("bad view after retrain %s", m.View())
+				}
+				nm, _ := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+				_, ok := nm.(AddRemoveItemScreen)
+				if !ok {
+					t.Fatalf("bad model after retrain done")
+				}
+			},
+		},
+		{
+			"test retrain with error",
+			func() retrainSpamModelScreen {
+				comm.ForceListError(true)
+				return newRetrainSpamModelScreen(main)
+			},
+			func(m retrainSpamModelScreen) tea.Msg {
+				return m.retrain()()
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				comm.ForceListError(false)
+				nm := m.(retrainSpamModelScreen)
+				if nm.err == nil || !strings.Contains(nm.View(), nm.err.Error()) {
+					t.Fatalf("error not shown %s", nm.View())
+				}
+			},
+		},
+		{
+			"test cancel",
+			func() retrainSpamModelScreen {
+				return newRetrainSpamModelScreen(main)
+			},
+			func(m retrainSpamModelScreen) tea.Msg {
+				return tea.KeyMsg{Type: tea.KeyEsc}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				_, ok := m.(AddRemoveItemScreen)
+				if !ok {
+					t.Fatalf("bad model for cancel")
+				}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			screen := test.screenFn()
+			msg := test.msgFn(screen)
+			m, cmd := screen.Update(msg)
+			test.checkFn(m, cmd)
+		})
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package tui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type searchCommentsScreen struct {
+	mainScreen mainScreen
+	textinput  textinput.Model
+	keys       ConfirmCancelKeyMap
+	help       help.Model
+}
+
+func (m searchCommentsScreen) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (m searchCommentsScreen) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	m.mainScreen.header.Update(msg)
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, m.keys.Confirm):
+			query := strings.TrimSpace(m.textinput.Value())
+			if query == "" {
+				return m, nil
+			}
+			model := newCommentSearchResultScreen(&m.mainScreen, query)
+			return model, model.Init()
+
+		case key.Matches(msg, m.keys.Cancel):
+			model := newCommentListScreen(&m.mainScreen)
+			return model, model.Init()
+		}
+
+	}
+	m.textinput, cmd = m.textinput.Update(msg)
+	return m, cmd
+}
+
+func (m searchCommentsScreen) View() string {
+	s := m.mainScreen.header.View()
+	title := "  " + titleStyle.Render(MESSAGE_SEARCH_COMMENTS)
+	s += title + "\n\n"
+	s += m.textinput.View() + "\n\n"
+	lines := strings.Split(s, "\n")
+	rest := m.mainScreen.list.Height() - len(lines) + 2
+
+	helpView := m.help.View(m.keys)
+	s += strings.Repeat("\n", rest) + helpViewStyle.Render(helpView)
+	return s
+}
+
+func newSearchCommentsScreen(mainScreen mainScreen) searchCommentsScreen {
+	ti := textinput.New()
+	ti.Width = 40
+	ti.Placeholder = MESSAGE_SEARCH_TERMS
+	ti.TextStyle = defaultTextStyle
+	ti.PromptStyle = defaultTextStyle
+	ti.Focus()
+	m := searchCommentsScreen{
+		mainScreen: mainScreen,
+		textinput:  ti,
+		keys:       NewConfirmCancelKeyMap(),
+		help:       createHelp(),
+	}
+	return m
+}
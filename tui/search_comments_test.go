@@ -0,0 +1,117 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jucacrispim/parlante"
+)
+
+func TestSearchCommentsScreen(t *testing.T) {
+
+	c := parlante.NewClientStorageInMemory()
+	cd := parlante.NewClientDomainStorageInMemory()
+	comm := parlante.NewCommentStorageInMemory()
+	main := newMainScreen(&c, &cd, &comm, nil, nil)
+
+	var tests = []struct {
+		testName string
+		screenFn func() searchCommentsScreen
+		msgFn    func(searchCommentsScreen) tea.Msg
+		checkFn  func(tea.Model, tea.Cmd)
+	}{
+		{
+			"test confirm search",
+			func() searchCommentsScreen {
+				s := newSearchCommentsScreen(main)
+				s.textinput.SetValue("some thing")
+				return s
+			},
+			func(m searchCommentsScreen) tea.Msg {
+				return tea.KeyMsg{Type: tea.KeyEnter}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				nm, ok := m.(AddRemoveItemScreen)
+				if !ok {
+					t.Fatalf("bad model for confirm search")
+				}
+				if !strings.Contains(nm.List.Title, "some thing") {
+					t.Fatalf("bad title for search results %s", nm.List.Title)
+				}
+			},
+		},
+		{
+			"test confirm empty search",
+			func() searchCommentsScreen {
+				return newSearchCommentsScreen(main)
+			},
+			func(m searchCommentsScreen) tea.Msg {
+				return tea.KeyMsg{Type: tea.KeyEnter}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				_, ok := m.(searchCommentsScreen)
+				if !ok {
+					t.Fatalf("bad model for empty search")
+				}
+			},
+		},
+		{
+			"test cancel search",
+			func() searchCommentsScreen {
+				return newSearchCommentsScreen(main)
+			},
+			func(m searchCommentsScreen) tea.Msg {
+				return tea.KeyMsg{Type: tea.KeyEsc}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				_, ok := m.(AddRemoveItemScreen)
+				if !ok {
+					t.Fatalf("bad model for cancel search")
+				}
+			},
+		},
+		{
+			"test View",
+			func() searchCommentsScreen {
+				return newSearchCommentsScreen(main)
+			},
+			func(m searchCommentsScreen) tea.Msg {
+				return nil
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				view := m.(searchCommentsScreen).View()
+				if !strings.Contains(view, MESSAGE_SEARCH_COMMENTS) ||
+					!strings.Contains(view, MESSAGE_KEY_HELP_CONFIRM) {
+					t.Fatalf("missing expected elements %s", view)
+				}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			screen := test.screenFn()
+			msg := test.msgFn(screen)
+			m, cmd := screen.Update(msg)
+			test.checkFn(m, cmd)
+		})
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jucacrispim/parlante"
+)
+
+type spamExplanationMsg struct {
+	classification parlante.SpamClassification
+	model          parlante.SpamModel
+	err            error
+}
+
+// spamExplanationScreen shows the spam probability of a comment and
+// the words that most influenced it.
+type spamExplanationScreen struct {
+	mainScreen     mainScreen
+	CommentStorage parlante.CommentStorage
+	Comment        parlante.Comment
+	classification parlante.SpamClassification
+	model          parlante.SpamModel
+	loaded         bool
+	keys           ConfirmCancelKeyMap
+	err            error
+}
+
+func (m spamExplanationScreen) Init() tea.Cmd {
+	return m.classify()
+}
+
+func (m spamExplanationScreen) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	m.mainScreen.header.Update(msg)
+	switch msg := msg.(type) {
+	case spamExplanationMsg:
+		m.err = msg.err
+		m.classification = msg.classification
+		m.model = msg.model
+		m.loaded = true
+		return m, nil
+
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, m.keys.Confirm, m.keys.Cancel):
+			model := newCommentListScreen(&m.mainScreen)
+			return model, model.Init()
+		}
+	}
+	return m, nil
+}
+
+func (m spamExplanationScreen) View() string {
+	s := m.mainScreen.header.View()
+	data := make(map[string]any)
+	data["name"] = m.Comment.Author
+	title := "  " + titleStyle.Render(
+		parlante.Tprintf(MESSAGE_SPAM_EXPLANATION, data))
+	s += title + "\n\n"
+
+	var content string
+	switch {
+	case m.err != nil:
+		content = m.err.Error()
+	case m.loaded:
+		content = m.explanationView()
+	}
+	s += defaultTextStyle.Render(content) + "\n\n"
+
+	lines := strings.Split(s, "\n")
+	rest := m.mainScreen.list.Height() - len(lines) + 2
+
+	helpView := MESAGE_ENTER_TO_CONTINUE
+	s += strings.Repeat("\n", rest) + helpViewStyle.Render(helpView)
+	return s
+}
+
+func (m spamExplanationScreen) explanationView() string {
+	data := make(map[string]any)
+	data["spam"] = m.model.SpamComments
+	data["ham"] = m.model.HamComments
+	data["prob"] = fmtProbability(m.classification.Probability)
+	parts := []string{
+		parlante.Tprintf(MESSAGE_SPAM_MODEL_INFO, data),
+		parlante.Tprintf(MESSAGE_SPAM_PROBABILITY, data),
+	}
+	if len(m.classification.Tokens) == 0 {
+		parts = append(parts, MESSAGE_SPAM_NO_TOKENS)
+		return strings.Join(parts, "\n\n")
+	}
+	tokens := make([]string, 0, len(m.classification.Tokens))
+	for _, t := range m.classification.Tokens {
+		tokens = append(tokens, fmt.Sprintf(
+			"  %s: %s", t.Token, fmtProbability(t.Probability)))
+	}
+	parts = append(parts, MESSAGE_SPAM_TOKENS+"\n"+strings.Join(tokens, "\n"))
+	return strings.Join(parts, "\n\n")
+}
+
+func (m spamExplanationScreen) classify() tea.Cmd {
+	return func() tea.Msg {
+		c, model, err := parlante.ClassifyComment(m.CommentStorage, m.Comment)
+		return spamExplanationMsg{classification: c, model: model, err: err}
+	}
+}
+
+func newSpamExplanationScreen(
+	mainScreen mainScreen,
+	comment parlante.Comment) spamExplanationScreen {
+	m := spamExplanationScreen{
+		mainScreen:     mainScreen,
+		CommentStorage: mainScreen.CommentStorage,
+		Comment:        comment,
+		keys:           NewConfirmCancelKeyMap(),
+	}
+	return m
+}
+
+// fmtProbability formats a probability as a percentage
+func fmtProbability(p float64) string {
+	return fmt.Sprintf("%.1f%%", p*100)
+}
@@ -0,0 +1,123 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jucacrispim/parlante"
+)
+
+func TestSpamExplanationScreen(t *testing.T) {
+
+	c := parlante.NewClientStorageInMemory()
+	cd := parlante.NewClientDomainStorageInMemory()
+	comm := parlante.NewCommentStorageInMemory()
+	main := newMainScreen(&c, &cd, &comm, nil, nil)
+
+	c1, _, _ := c.CreateClient("a client")
+	d1, _ := cd.AddClientDomain(c1, "domain.net")
+	spam, _ := comm.CreateComment(c1, d1, "bot", "cheap pills", "http://bla.net")
+	ham, _ := comm.CreateComment(c1, d1, "zé", "nice post", "http://bla.net")
+	comm.TrainSpamModel(spam, true)
+	comm.TrainSpamModel(ham, false)
+	other, _ := comm.CreateComment(c1, d1, "jão", "what?", "http://bla.net")
+
+	var tests = []struct {
+		testName string
+		screenFn func() spamExplanationScreen
+		msgFn    func(spamExplanationScreen) tea.Msg
+		checkFn  func(tea.Model, tea.Cmd)
+	}{
+		{
+			"test explain spam",
+			func() spamExplanationScreen {
+				return newSpamExplanationScreen(main, spam)
+			},
+			func(m spamExplanationScreen) tea.Msg {
+				return m.Init()()
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				view := m.(spamExplanationScreen).View()
+				if !strings.Contains(view, MESSAGE_SPAM_TOKENS) ||
+					!strings.Contains(view, "pills") ||
+					!strings.Contains(view, "author:bot") {
+					t.Fatalf("tokens not shown %s", view)
+				}
+			},
+		},
+		{
+			"test explain without known tokens",
+			func() spamExplanationScreen {
+				return newSpamExplanationScreen(main, other)
+			},
+			func(m spamExplanationScreen) tea.Msg {
+				return m.Init()()
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				view := m.(spamExplanationScreen).View()
+				if !strings.Contains(view, MESSAGE_SPAM_NO_TOKENS) {
+					t.Fatalf("bad view without tokens %s", view)
+				}
+			},
+		},
+		{
+			"test explain with error",
+			func() spamExplanationScreen {
+				comm.ForceListError(true)
+				return newSpamExplanationScreen(main, spam)
+			},
+			func(m spamExplanationScreen) tea.Msg {
+				return m.Init()()
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				comm.ForceListError(false)
+				nm := m.(spamExplanationScreen)
+				if nm.err == nil || !strings.Contains(nm.View(), nm.err.Error()) {
+					t.Fatalf("error not shown %s", nm.View())
+				}
+			},
+		},
+		{
+			"test back to comments",
+			func() spamExplanationScreen {
+				return newSpamExplanationScreen(main, spam)
+			},
+			func(m spamExplanationScreen) tea.Msg {
+				return tea.KeyMsg{Type: tea.KeyEnter}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				_, ok := m.(AddRemoveItemScreen)
+				if !ok {
+					t.Fatalf("bad model for back to comments")
+				}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			screen := test.screenFn()
+			msg := test.msgFn(screen)
+			m, cmd := screen.Update(msg)
+			test.checkFn(m, cmd)
+		})
+	}
+}
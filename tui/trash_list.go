@@ -0,0 +1,119 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package tui
+
+import (
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jucacrispim/parlante"
+)
+
+type TrashItem struct {
+	Item parlante.TrashItem
+}
+
+func (i TrashItem) Title() string { return i.Item.Name }
+func (i TrashItem) Description() string {
+	data := make(map[string]any)
+	data["type"] = MESSAGE_TRASH_ITEM_TYPE[i.Item.Type]
+	data["date"] = fmtTimestamp(i.Item.DeletedAt)
+	return parlante.Tprintf(MESSAGE_TRASH_ITEM_DESCRIPTION, data)
+}
+func (i TrashItem) FilterValue() string { return i.Item.Name }
+
+type TrashListNavigation struct {
+	MainScreen *mainScreen
+}
+
+// GetAddScreen returns the list screen because nothing is added
+// to the trash from here.
+func (n TrashListNavigation) GetAddScreen() tea.Model {
+	s := newTrashListScreen(n.MainScreen)
+	return s
+}
+
+func (n TrashListNavigation) GetRemoveScreen(item list.Item) tea.Model {
+	i := item.(TrashItem)
+	s := newPurgeTrashItemScreen(*n.MainScreen, i.Item)
+	return s
+}
+
+func (n TrashListNavigation) GetPreviousScreen() tea.Model {
+	return *n.MainScreen
+}
+
+type TrashLoader struct {
+	Storage parlante.TrashStorage
+}
+
+func (l TrashLoader) Load() tea.Cmd {
+	return func() tea.Msg {
+		trash, err := l.Storage.ListTrash()
+		if err != nil {
+			return ItemListMsg{Err: err}
+		}
+
+		items := make([]list.Item, 0)
+		for _, i := range trash {
+			items = append(items, TrashItem{Item: i})
+		}
+		return ItemListMsg{Items: items}
+	}
+}
+
+// TrashRestorer puts the selected item back in its place
+type TrashRestorer struct {
+	Storage parlante.TrashStorage
+}
+
+func (r TrashRestorer) Run(item list.Item) tea.Cmd {
+	return func() tea.Msg {
+		i := item.(TrashItem)
+		err := r.Storage.RestoreItem(i.Item)
+		return ItemActionDoneMsg{Err: err}
+	}
+}
+
+func newTrashListScreen(mainScreen *mainScreen) AddRemoveItemScreen {
+	nav := TrashListNavigation{
+		MainScreen: mainScreen,
+	}
+	l := TrashLoader{
+		Storage: mainScreen.trashStorage,
+	}
+	h := mainScreen.header
+	opts := ListOpts{
+		Title:           MESSAGE_TRASH,
+		ShowDescription: true,
+		ShowStatusBar:   true,
+		ShowHelp:        true,
+	}
+	s := NewAddRemoveItemScreen(&h, opts, nav, l.Load)
+	restorer := TrashRestorer{Storage: mainScreen.trashStorage}
+	s.Actions = []ItemAction{
+		{
+			Key: key.NewBinding(
+				key.WithKeys("r"),
+				key.WithHelp("r", MESSAGE_KEY_HELP_RESTORE),
+			),
+			Run: restorer.Run,
+		},
+	}
+	return s
+}
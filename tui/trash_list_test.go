@@ -0,0 +1,185 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jucacrispim/parlante"
+)
+
+func TestTrashItem(t *testing.T) {
+	i := parlante.TrashItem{
+		Type: parlante.TrashDomain, ID: 1, Name: "bla.net", DeletedAt: 1234}
+	item := TrashItem{Item: i}
+
+	if item.Title() != i.Name {
+		t.Fatalf("Bad title for item %s", item.Title())
+	}
+
+	if !strings.Contains(item.Description(),
+		MESSAGE_TRASH_ITEM_TYPE[parlante.TrashDomain]) {
+		t.Fatalf("Bad description for item %s", item.Description())
+	}
+
+	if item.FilterValue() != i.Name {
+		t.Fatalf("Bad filter value for item %s", item.FilterValue())
+	}
+}
+
+func TestTrashListScreen(t *testing.T) {
+	ts := parlante.NewTrashStorageInMemory()
+	main := newMainScreen(nil, nil, nil, &ts, nil)
+
+	client := parlante.TrashItem{
+		Type: parlante.TrashClient, ID: 1, Name: "a client", DeletedAt: 20}
+	comment := parlante.TrashItem{
+		Type: parlante.TrashComment, ID: 1, Name: "zé @ bla.net", DeletedAt: 10}
+
+	var tests = []struct {
+		testName string
+		screenFn func() AddRemoveItemScreen
+		msgFn    func(AddRemoveItemScreen) tea.Msg
+		checkFn  func(tea.Model, tea.Cmd)
+	}{
+		{
+			"test load trash",
+			func() AddRemoveItemScreen {
+				ts.AddItem(client)
+				ts.AddItem(comment)
+				return newTrashListScreen(&main)
+			},
+			func(m AddRemoveItemScreen) tea.Msg {
+				return m.Init()()
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				nm, ok := m.(AddRemoveItemScreen)
+				if !ok {
+					t.Fatalf("bad model loading trash")
+				}
+				view := nm.View()
+				if !strings.Contains(view, client.Name) ||
+					!strings.Contains(view, comment.Name) {
+					t.Fatalf("trash not loaded %s", view)
+				}
+			},
+		},
+		{
+			"test load trash with error",
+			func() AddRemoveItemScreen {
+				ts.ForceListError(true)
+				return newTrashListScreen(&main)
+			},
+			func(m AddRemoveItemScreen) tea.Msg {
+				return m.Init()()
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				ts.ForceListError(false)
+				nm := m.(AddRemoveItemScreen)
+				if nm.err == nil {
+					t.Fatalf("No error with load trash error")
+				}
+			},
+		},
+		{
+			"test GetAddScreen",
+			func() AddRemoveItemScreen {
+				return newTrashListScreen(&main)
+			},
+			func(m AddRemoveItemScreen) tea.Msg {
+				return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'a'}}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				_, ok := m.(AddRemoveItemScreen)
+				if !ok {
+					t.Fatalf("bad model for add screen")
+				}
+			},
+		},
+		{
+			"test GetRemoveScreen",
+			func() AddRemoveItemScreen {
+				s := newTrashListScreen(&main)
+				items := s.Init()()
+				s.List.SetItems(items.(ItemListMsg).Items)
+				s.List.CursorDown()
+				return s
+			},
+			func(m AddRemoveItemScreen) tea.Msg {
+				return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'d'}}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				nm, ok := m.(purgeTrashItemScreen)
+				if !ok {
+					t.Fatalf("bad model for purge screen")
+				}
+				if nm.Item != comment {
+					t.Fatalf("bad item on purge %v", nm.Item)
+				}
+			},
+		},
+		{
+			"test restore item",
+			func() AddRemoveItemScreen {
+				s := newTrashListScreen(&main)
+				items := s.Init()()
+				s.List.SetItems(items.(ItemListMsg).Items)
+				return s
+			},
+			func(m AddRemoveItemScreen) tea.Msg {
+				return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'r'}}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				msg, ok := cmd().(ItemActionDoneMsg)
+				if !ok || msg.Err != nil {
+					t.Fatalf("bad msg for restore action %v", msg)
+				}
+				items, _ := ts.ListTrash()
+				if len(items) != 1 || items[0] != comment {
+					t.Fatalf("item not restored %v", items)
+				}
+			},
+		},
+		{
+			"test GetPreviousScreen",
+			func() AddRemoveItemScreen {
+				return newTrashListScreen(&main)
+			},
+			func(m AddRemoveItemScreen) tea.Msg {
+				return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'b'}}
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				_, ok := m.(mainScreen)
+				if !ok {
+					t.Fatalf("bad model for previous screen")
+				}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			screen := test.screenFn()
+			msg := test.msgFn(screen)
+			m, cmd := screen.Update(msg)
+			test.checkFn(m, cmd)
+		})
+	}
+}
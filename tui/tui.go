@@ -155,6 +155,30 @@ type ItemListMsg struct {
 // A function used to load the items of a list
 type LoadItemsFn func() tea.Cmd
 
+// ItemAction is an action, other than add/remove, performed in the
+// selected item of a list. When the action is done it must
+// send an ItemActionDoneMsg.
+type ItemAction struct {
+	Key key.Binding
+	Run func(list.Item) tea.Cmd
+}
+
+// ItemActionDoneMsg is sent when an ItemAction is done. The items
+// of the list are reloaded after it.
+type ItemActionDoneMsg struct {
+	Err error
+}
+
+// ScreenAction is an action that shows another screen. Screen
+// receives the selected item, that is nil if the list is empty. If
+// ItemRequired is true the action is only available when an item
+// is selected.
+type ScreenAction struct {
+	Key          key.Binding
+	Screen       func(list.Item) tea.Model
+	ItemRequired bool
+}
+
 // A screen with a header and a list in it. It has key bindings to
 // add/remove items from the list and for navigation between screens
 type AddRemoveItemScreen struct {
@@ -164,9 +188,12 @@ type AddRemoveItemScreen struct {
 	Navigation AddRemoveScreenNavigation
 	KeyMap     ListKeyMap
 	LoadItems  LoadItemsFn
-	ShowAll    bool
-	keys       *ListKeyMap
-	err        error
+	Actions    []ItemAction
+	// Actions that show other screens
+	ScreenActions []ScreenAction
+	ShowAll       bool
+	keys          *ListKeyMap
+	err           error
 }
 
 func (m AddRemoveItemScreen) Init() tea.Cmd {
@@ -184,10 +211,31 @@ func (m AddRemoveItemScreen) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 		m.List.SetItems(msg.Items)
+	case ItemActionDoneMsg:
+		if msg.Err != nil {
+			m.err = msg.Err
+			return m, nil
+		}
+		return m, m.LoadItems()
 	case tea.KeyMsg:
 		if m.List.FilterState() == list.Filtering {
 			break
 		}
+		item := m.List.SelectedItem()
+		for _, action := range m.Actions {
+			if key.Matches(msg, action.Key) && item != nil {
+				return m, action.Run(item)
+			}
+		}
+		for _, action := range m.ScreenActions {
+			if action.ItemRequired && item == nil {
+				continue
+			}
+			if key.Matches(msg, action.Key) {
+				screen := action.Screen(item)
+				return screen, screen.Init()
+			}
+		}
 		switch {
 		case key.Matches(msg, m.keys.Add):
 			screen := m.Navigation.GetAddScreen()
@@ -229,8 +277,17 @@ func (m AddRemoveItemScreen) ShortHelp() []key.Binding {
 	kb := []key.Binding{
 		m.keys.CursorUp, m.keys.CursorDown,
 		m.keys.Add}
-	if len(m.List.Items()) > 0 {
+	hasItems := len(m.List.Items()) > 0
+	for _, action := range m.ScreenActions {
+		if !action.ItemRequired || hasItems {
+			kb = append(kb, action.Key)
+		}
+	}
+	if hasItems {
 		kb = append(kb, m.keys.Remove)
+		for _, action := range m.Actions {
+			kb = append(kb, action.Key)
+		}
 	}
 	kb = append(kb,
 		[]key.Binding{m.keys.PrevScreen,
@@ -244,6 +301,12 @@ func (m AddRemoveItemScreen) FullHelp() [][]key.Binding {
 		m.keys.Add,
 		m.keys.Remove,
 	}
+	for _, action := range m.Actions {
+		col = append(col, action.Key)
+	}
+	for _, action := range m.ScreenActions {
+		col = append(col, action.Key)
+	}
 	h = slices.Insert(h, 2, col)
 	h[3] = slices.Insert(h[3], 0, m.keys.PrevScreen)
 	return h
@@ -405,6 +468,34 @@ func (k ConfirmCancelKeyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{{k.Confirm, k.Cancel}}
 }
 
+// SaveCancelKeyMap is used in screens where enter is part of the
+// input, like the ones with a textarea.
+type SaveCancelKeyMap struct {
+	Cancel key.Binding
+	Save   key.Binding
+}
+
+func (k SaveCancelKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Save, k.Cancel}
+}
+
+func (k SaveCancelKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{{k.Save, k.Cancel}}
+}
+
+func NewSaveCancelKeyMap() SaveCancelKeyMap {
+	return SaveCancelKeyMap{
+		Cancel: key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("esc", MESSAGE_KEY_HELP_CANCEL),
+		),
+		Save: key.NewBinding(
+			key.WithKeys("ctrl+s"),
+			key.WithHelp("ctrl+s", MESSAGE_KEY_HELP_SAVE),
+		),
+	}
+}
+
 func NewConfirmCancelKeyMap() ConfirmCancelKeyMap {
 	return ConfirmCancelKeyMap{
 		Cancel: key.NewBinding(
@@ -441,9 +532,11 @@ func isVterm() bool {
 func NewTui(
 	cs parlante.ClientStorage,
 	ds parlante.ClientDomainStorage,
-	cos parlante.CommentStorage) *tea.Program {
+	cos parlante.CommentStorage,
+	ts parlante.TrashStorage,
+	obs parlante.OutboxStorage) *tea.Program {
 	// notest
-	m := newMainScreen(cs, ds, cos)
+	m := newMainScreen(cs, ds, cos, ts, obs)
 	p := tea.NewProgram(m, tea.WithAltScreen())
 	return p
 }
@@ -255,6 +255,38 @@ func TestAddRemoveItemScreen(t *testing.T) {
 				}
 			},
 		},
+		{
+			"test item action",
+			ItemListMsg{
+				Items: []list.Item{
+					listItem("item 1"),
+				},
+			},
+			func(m tea.Model, cmd tea.Cmd) {
+				nm, _ := m.(AddRemoveItemScreen)
+				view := nm.View()
+				if !strings.Contains(view, "x test action") {
+					t.Fatalf("action key not in help %s", view)
+				}
+				_, cmd = nm.Update(
+					tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'x'}})
+				msg := cmd()
+				_, cmd = nm.Update(msg)
+				if cmd == nil {
+					t.Fatalf("items not reloaded after action")
+				}
+			},
+		},
+		{
+			"test item action with error",
+			ItemActionDoneMsg{Err: errors.New("Error in action")},
+			func(m tea.Model, cmd tea.Cmd) {
+				view := m.View()
+				if !strings.Contains(view, "Error in action") {
+					t.Fatalf("Error missing in view")
+				}
+			},
+		},
 		{
 			"test get help key",
 			nil,
@@ -350,7 +382,21 @@ func newTestScreen() AddRemoveItemScreen {
 			return nil
 		}
 	}
-	return NewAddRemoveItemScreen(&header, opts, testNav{}, load)
+	s := NewAddRemoveItemScreen(&header, opts, testNav{}, load)
+	s.Actions = []ItemAction{
+		{
+			Key: key.NewBinding(
+				key.WithKeys("x"),
+				key.WithHelp("x", "test action"),
+			),
+			Run: func(list.Item) tea.Cmd {
+				return func() tea.Msg {
+					return ItemActionDoneMsg{}
+				}
+			},
+		},
+	}
+	return s
 }
 
 type listItem string
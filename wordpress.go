@@ -0,0 +1,115 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package parlante
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+// wordPressDateLayout is the layout of the dates in WXR files.
+const wordPressDateLayout = "2006-01-02 15:04:05"
+
+// The elements are matched without namespace because each version of
+// WXR has its own namespace.
+type wordPressExport struct {
+	Items []wordPressItem `xml:"channel>item"`
+}
+
+type wordPressItem struct {
+	Link     string             `xml:"link"`
+	Comments []wordPressComment `xml:"comment"`
+}
+
+type wordPressComment struct {
+	ID          string `xml:"comment_id"`
+	Author      string `xml:"comment_author"`
+	AuthorEmail string `xml:"comment_author_email"`
+	AuthorURL   string `xml:"comment_author_url"`
+	Date        string `xml:"comment_date"`
+	DateGMT     string `xml:"comment_date_gmt"`
+	Content     string `xml:"comment_content"`
+	Approved    string `xml:"comment_approved"`
+	Type        string `xml:"comment_type"`
+	Parent      string `xml:"comment_parent"`
+}
+
+// ParseWordPressExport reads the comments of a WordPress eXtended RSS
+// (WXR) export. The page of a comment is the permalink of its post.
+// Approved comments are imported as approved, the ones waiting for
+// moderation as pending and spam as spam. Comments in the trash,
+// pingbacks and trackbacks are skipped.
+func ParseWordPressExport(r io.Reader) (ImportSource, error) {
+	var export wordPressExport
+	err := xml.NewDecoder(r).Decode(&export)
+	if err != nil {
+		return ImportSource{}, err
+	}
+
+	src := ImportSource{Comments: make([]ImportedComment, 0)}
+	for _, item := range export.Items {
+		for _, wc := range item.Comments {
+			status, ok := wordPressStatus(wc.Approved)
+			commentType := strings.TrimSpace(wc.Type)
+			if !ok || (commentType != "" && commentType != "comment") {
+				src.Skipped++
+				continue
+			}
+			ic := ImportedComment{
+				Source:    wordPressSource(wc.ID),
+				Author:    strings.TrimSpace(wc.Author),
+				Email:     strings.TrimSpace(wc.AuthorEmail),
+				Website:   strings.TrimSpace(wc.AuthorURL),
+				Content:   importedText(wc.Content),
+				PageURL:   strings.TrimSpace(item.Link),
+				Timestamp: importTimestamp(wordPressDateLayout, wc.DateGMT),
+				Status:    status,
+			}
+			// old exports have no gmt dates, only the local ones.
+			if ic.Timestamp <= 0 {
+				ic.Timestamp = importTimestamp(wordPressDateLayout, wc.Date)
+			}
+			parent := strings.TrimSpace(wc.Parent)
+			if parent != "" && parent != "0" {
+				ic.ParentSource = wordPressSource(parent)
+			}
+			src.Comments = append(src.Comments, ic)
+		}
+	}
+	return src, nil
+}
+
+// wordPressStatus returns the status of an imported comment for
+// the approved field of a WordPress comment. Returns false for the
+// comments that must not be imported.
+func wordPressStatus(approved string) (CommentStatus, bool) {
+	switch strings.TrimSpace(approved) {
+	case "1":
+		return CommentApproved, true
+	case "0":
+		return CommentPending, true
+	case "spam":
+		return CommentSpam, true
+	}
+	return "", false
+}
+
+func wordPressSource(id string) string {
+	return "wordpress:" + strings.TrimSpace(id)
+}
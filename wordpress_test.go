@@ -0,0 +1,155 @@
+// Copyright 2025 Juca Crispim <juca@poraodojuca.dev>
+
+// This file is part of parlante.
+
+// parlante is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// parlante is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with parlante. If not, see <http://www.gnu.org/licenses/>.
+
+package parlante
+
+import (
+	"strings"
+	"testing"
+)
+
+const wordPressExportXML = `<?xml version="1.0" encoding="UTF-8" ?>
+<rss version="2.0"
+	xmlns:excerpt="http://wordpress.org/export/1.2/excerpt/"
+	xmlns:content="http://purl.org/rss/1.0/modules/content/"
+	xmlns:wfw="http://wellformedweb.org/CommentAPI/"
+	xmlns:dc="http://purl.org/dc/elements/1.1/"
+	xmlns:wp="http://wordpress.org/export/1.2/">
+<channel>
+	<title>My blog</title>
+	<link>http://bla.net</link>
+	<wp:base_site_url>http://bla.net</wp:base_site_url>
+	<item>
+		<title>Hello world</title>
+		<link>http://bla.net/hello-world/</link>
+		<wp:post_id>1</wp:post_id>
+		<wp:post_type><![CDATA[post]]></wp:post_type>
+		<wp:comment>
+			<wp:comment_id>10</wp:comment_id>
+			<wp:comment_author><![CDATA[Zé]]></wp:comment_author>
+			<wp:comment_author_email><![CDATA[ze@bla.net]]></wp:comment_author_email>
+			<wp:comment_author_url>http://ze.net</wp:comment_author_url>
+			<wp:comment_date><![CDATA[2015-03-02 07:00:00]]></wp:comment_date>
+			<wp:comment_date_gmt><![CDATA[2015-03-02 10:00:00]]></wp:comment_date_gmt>
+			<wp:comment_content><![CDATA[First line
+<a href="http://x.net">a link</a>]]></wp:comment_content>
+			<wp:comment_approved><![CDATA[1]]></wp:comment_approved>
+			<wp:comment_type><![CDATA[comment]]></wp:comment_type>
+			<wp:comment_parent>0</wp:comment_parent>
+		</wp:comment>
+		<wp:comment>
+			<wp:comment_id>11</wp:comment_id>
+			<wp:comment_author><![CDATA[Maria]]></wp:comment_author>
+			<wp:comment_date><![CDATA[2015-03-03 10:00:00]]></wp:comment_date>
+			<wp:comment_date_gmt><![CDATA[0000-00-00 00:00:00]]></wp:comment_date_gmt>
+			<wp:comment_content><![CDATA[A reply]]></wp:comment_content>
+			<wp:comment_approved><![CDATA[0]]></wp:comment_approved>
+			<wp:comment_type><![CDATA[]]></wp:comment_type>
+			<wp:comment_parent>10</wp:comment_parent>
+		</wp:comment>
+		<wp:comment>
+			<wp:comment_id>12</wp:comment_id>
+			<wp:comment_author><![CDATA[Spammer]]></wp:comment_author>
+			<wp:comment_date_gmt><![CDATA[2015-03-04 10:00:00]]></wp:comment_date_gmt>
+			<wp:comment_content><![CDATA[buy stuff]]></wp:comment_content>
+			<wp:comment_approved><![CDATA[spam]]></wp:comment_approved>
+			<wp:comment_parent>0</wp:comment_parent>
+		</wp:comment>
+		<wp:comment>
+			<wp:comment_id>13</wp:comment_id>
+			<wp:comment_content><![CDATA[removed]]></wp:comment_content>
+			<wp:comment_approved><![CDATA[trash]]></wp:comment_approved>
+		</wp:comment>
+		<wp:comment>
+			<wp:comment_id>14</wp:comment_id>
+			<wp:comment_content><![CDATA[linked you]]></wp:comment_content>
+			<wp:comment_approved><![CDATA[1]]></wp:comment_approved>
+			<wp:comment_type><![CDATA[pingback]]></wp:comment_type>
+		</wp:comment>
+	</item>
+	<item>
+		<title>No comments</title>
+		<link>http://bla.net/no-comments/</link>
+	</item>
+</channel>
+</rss>`
+
+func TestParseWordPressExport(t *testing.T) {
+	src, err := ParseWordPressExport(strings.NewReader(wordPressExportXML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(src.Comments) != 3 || src.Skipped != 2 {
+		t.Fatalf("bad comments %d skipped %d", len(src.Comments), src.Skipped)
+	}
+	expected := ImportedComment{
+		Source:    "wordpress:10",
+		Author:    "Zé",
+		Email:     "ze@bla.net",
+		Website:   "http://ze.net",
+		Content:   "First line\na link (http://x.net)",
+		PageURL:   "http://bla.net/hello-world/",
+		Timestamp: 1425290400,
+		Status:    CommentApproved,
+	}
+	if src.Comments[0] != expected {
+		t.Fatalf("bad first comment %+v", src.Comments[0])
+	}
+	reply := src.Comments[1]
+	if reply.ParentSource != "wordpress:10" || reply.Status != CommentPending ||
+		reply.Timestamp != 1425376800 {
+		t.Fatalf("bad reply %+v", reply)
+	}
+	if src.Comments[2].Status != CommentSpam {
+		t.Fatalf("bad spam comment %+v", src.Comments[2])
+	}
+
+	_, err = ParseWordPressExport(strings.NewReader("<rss><channel>"))
+	if err == nil {
+		t.Fatalf("no error for bad xml")
+	}
+}
+
+func TestImportComments_WordPress(t *testing.T) {
+	cs := NewClientStorageInMemory()
+	ds := NewClientDomainStorageInMemory()
+	comms := NewCommentStorageInMemory()
+	c, _, _ := cs.CreateClient("test client")
+	d, _ := ds.AddClientDomain(c, "bla.net")
+
+	src, _ := ParseWordPressExport(strings.NewReader(wordPressExportXML))
+	for range 2 {
+		_, err := ImportComments(comms, c, d, src, ImportOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	comments, _ := comms.ListComments(CommentsFilter{})
+	if len(comments) != 3 {
+		t.Fatalf("bad imported comments %d", len(comments))
+	}
+	for i, status := range []CommentStatus{
+		CommentApproved, CommentPending, CommentSpam} {
+		if comments[i].Status != status ||
+			comments[i].Hidden != (status != CommentApproved) {
+			t.Fatalf("bad status for comment %+v", comments[i])
+		}
+	}
+	if comments[1].ParentID != comments[0].ID {
+		t.Fatalf("bad reply %+v", comments[1])
+	}
+}